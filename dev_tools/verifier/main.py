@@ -2,16 +2,30 @@ import json
 import uuid
 import os
 import sys
+from datetime import datetime, timezone
 
 import pika
 
-message = {
+# Payload of a zkp.verification.positive message, as the blockchain-client
+# decodes it (incoming.ZkpVerifiedPositiveDto)
+payload = {
     "event_id": str(uuid.uuid4()),
-    "identity_id": "9ac41446-8945-4a7d-accf-47621830127e",
-    "day": 15,
-    "month": 7,
-    "year": 1990,
-    "schema": "12324235525474364465786756"
+    "resolved_values": [
+        {"key": "day", "value": "15", "type": "int", "verification_positive": True},
+        {"key": "month", "value": "7", "type": "int", "verification_positive": True},
+        {"key": "year", "value": "1990", "type": "int", "verification_positive": True},
+    ],
+}
+
+message_id = str(uuid.uuid4())
+message = {
+    "type": "zkp.verification.positive",
+    "schema_version": 1,
+    "message_id": message_id,
+    "correlation_id": payload["event_id"],
+    "produced_at": datetime.now(timezone.utc).isoformat(),
+    "producer": "verifier-mock",
+    "payload": payload,
 }
 
 def resolve_lan_host() -> str:
@@ -38,7 +52,14 @@ channel.basic_publish(
     body=json.dumps(message),
     properties=pika.BasicProperties(
         content_type='application/json',
-        delivery_mode=2
+        delivery_mode=2,
+        message_id=message_id,
+        correlation_id=message["correlation_id"],
+        type=message["type"],
+        app_id=message["producer"],
+        # A verifier copies the x-ordering-key header of the request it answers,
+        # so the blockchain client keeps the proofs of one identity in order
+        headers={"x-ordering-key": os.environ.get("IDENTITY_ID", payload["event_id"])},
     )
 )
 
@@ -167,6 +167,35 @@ for try in $(seq 1 $max_try); do
   sleep 1
 done
 
+# ---------- secrets ----------
+# The vault master key wraps every identity data key, so it is generated once
+# and kept across runs
+VAULT_KEY_FILE=".secrets/vault_master_key"
+if [[ ! -s "${VAULT_KEY_FILE}" ]]; then
+  mkdir -p .secrets
+  (umask 077 && head -c 32 /dev/urandom | base64 > "${VAULT_KEY_FILE}")
+  log "Generated vault master key in ${VAULT_KEY_FILE}"
+fi
+
+# Ed25519 seed the api signs with under its did:web
+DID_KEY_FILE=".secrets/did_private_key"
+if [[ ! -s "${DID_KEY_FILE}" ]]; then
+  mkdir -p .secrets
+  (umask 077 && head -c 32 /dev/urandom | base64 > "${DID_KEY_FILE}")
+  log "Generated DID signing key in ${DID_KEY_FILE}"
+fi
+
+# HMAC keys of the internal service calls; operators sign their calls to the
+# failure admin routes with internal_auth_operator
+for service in api blockchain_client operator; do
+  AUTH_KEY_FILE=".secrets/internal_auth_${service}"
+  if [[ ! -s "${AUTH_KEY_FILE}" ]]; then
+    mkdir -p .secrets
+    (umask 077 && head -c 32 /dev/urandom | base64 > "${AUTH_KEY_FILE}")
+    log "Generated internal auth key in ${AUTH_KEY_FILE}"
+  fi
+done
+
 # ---------- docker compose ----------
 log "setup ready, starting docker"
 # Run in the foreground so Ctrl+C triggers our trap, then cleanup runs.
@@ -2,31 +2,91 @@ package main
 
 import (
 	"api/src/database"
-	"os"
+	"errors"
+	"fmt"
+	"pkg-common/did"
+	httpclient "pkg-common/http_client"
 	"pkg-common/logger"
 	"pkg-common/rabbitmq"
+	"pkg-common/rest"
+	"pkg-common/tracing"
+	"pkg-common/utilities"
 )
 
 type ApiConfigJson struct {
-	LoggerConf   logger.LoggerConfigJson     `json:"logger"`
-	RabbitmqConf rabbitmq.RabbimqConfigJson  `json:"rabbitmq"`
-	RestConf     ApiClientRestConfigJson     `json:"rest"`
-	DatabaseConf ApiClientDatabaseConfigJson `json:"database"`
+	LoggerConf    logger.LoggerConfigJson     `json:"logger"`
+	RabbitmqConf  rabbitmq.RabbimqConfigJson  `json:"rabbitmq"`
+	RestConf      ApiClientRestConfigJson     `json:"rest"`
+	DatabaseConf  ApiClientDatabaseConfigJson `json:"database"`
+	RateLimitConf rest.RateLimitConfigJson    `json:"rate_limit"`
+	TracingConf   tracing.TracingConfigJson   `json:"tracing"`
+	InternalAuth  rest.InternalAuthConfigJson `json:"internal_auth"`
+
+	BlockchainClientConf httpclient.ConfigJson    `json:"blockchain_client"`
+	VaultConf            ApiClientVaultConfigJson `json:"vault"`
+	DidConf              ApiClientDidConfigJson   `json:"did"`
+
+	// LanHost is the address the api is reached at from the LAN, used for
+	// its did:web identity and the links it hands out
+	LanHost string `json:"lan_host"`
+}
+
+// EnvAliases keeps the variables the deployments set before the API__ scheme.
+func (acj ApiConfigJson) EnvAliases() map[string]string {
+	return map[string]string{
+		"DB_CONNECTION_STRING":  "database.connection_string",
+		"VAULT_MASTER_KEY":      "vault.master_key",
+		utilities.LanHostEnvKey: "lan_host",
+	}
 }
 
 func (acj ApiConfigJson) MapToDomain() ApiConfig {
 	return ApiConfig{
-		LoggerConf:   acj.LoggerConf.MapToDomain(),
-		RabbitmqConf: acj.RabbitmqConf.MapToDomain(),
-		RestConf:     acj.RestConf.MapToDomain(),
+		LoggerConf:    acj.LoggerConf.MapToDomain(),
+		RabbitmqConf:  acj.RabbitmqConf.MapToDomain(),
+		RestConf:      acj.RestConf.MapToDomain(),
+		DatabaseConf:  acj.DatabaseConf.MapToDomain(),
+		RateLimitConf: acj.RateLimitConf.MapToDomain(),
+		TracingConf:   acj.TracingConf.MapToDomain(),
+		InternalAuth:  acj.InternalAuth.MapToDomain(),
+
+		BlockchainClientConf: acj.BlockchainClientConf.MapToDomain(),
+		VaultConf:            acj.VaultConf.MapToDomain(),
+		DidConf:              acj.DidConf.MapToDomain(),
+
+		LanHost: acj.LanHost,
 	}
 }
 
 type ApiConfig struct {
-	LoggerConf   logger.LoggerConfig
-	RabbitmqConf rabbitmq.RabbitmqConfig
-	RestConf     ApiClientRestConfig
-	DatabaseConf ApiClientDatabaseConfig
+	LoggerConf    logger.LoggerConfig
+	RabbitmqConf  rabbitmq.RabbitmqConfig
+	RestConf      ApiClientRestConfig
+	DatabaseConf  ApiClientDatabaseConfig
+	RateLimitConf rest.RateLimitConfig
+	TracingConf   tracing.TracingConfig
+	InternalAuth  rest.InternalAuthConfig
+
+	BlockchainClientConf httpclient.Config
+	VaultConf            ApiClientVaultConfig
+	DidConf              ApiClientDidConfig
+
+	LanHost string
+}
+
+func (ac ApiConfig) Validate() error {
+	var cv utilities.ConfigValidation
+	cv.Require("rest.port", ac.RestConf.Port)
+	cv.Require("database.connection_string", ac.DatabaseConf.ConnectionString)
+	cv.Require("vault.master_key", ac.VaultConf.MasterKey)
+	cv.Require("internal_auth.service", ac.InternalAuth.Service)
+	cv.Require("internal_auth.secret", ac.InternalAuth.Secret)
+	cv.Require("blockchain_client.base_url", ac.BlockchainClientConf.BaseUrl)
+	cv.Require("lan_host", ac.LanHost)
+	_, didKeyErr := did.ParsePrivateKey(ac.DidConf.PrivateKey)
+	cv.Check("did.private_key", didKeyErr == nil, "expected a base64 Ed25519 seed or private key")
+
+	return errors.Join(cv.Err(), ac.LoggerConf.Validate(), ac.RabbitmqConf.Validate())
 }
 
 type AppConfig interface {
@@ -45,25 +105,37 @@ func (ac ApiConfig) GetRestApiPort() uint16 {
 	return ac.RestConf.Port
 }
 
+func (ac ApiConfig) GetTracingConfig() tracing.TracingConfig {
+	return ac.TracingConf
+}
+
 func (ac ApiConfig) GetDatabaseConnectionString() string {
-	// Prefer environment variable if set, fallback to zkpconfig.json value
-	if env := os.Getenv("DB_CONNECTION_STRING"); env != "" {
-		return env
-	}
 	return ac.DatabaseConf.ConnectionString
 }
 
+func (ac ApiConfig) GetVaultMasterKey() string {
+	return ac.VaultConf.MasterKey
+}
+
+// BaseURL is the public origin of the api on the LAN.
+func (ac ApiConfig) BaseURL() string {
+	return fmt.Sprintf("http://%s:9000", ac.LanHost)
+}
+
 type ApiClientRestConfigJson struct {
-	Port uint16 `json:"port"`
+	Port           uint16   `json:"port"`
+	TrustedProxies []string `json:"trusted_proxies"`
 }
 
 type ApiClientRestConfig struct {
-	Port uint16
+	Port           uint16
+	TrustedProxies []string
 }
 
 func (acrcj ApiClientRestConfigJson) MapToDomain() ApiClientRestConfig {
 	return ApiClientRestConfig{
-		Port: acrcj.Port,
+		Port:           acrcj.Port,
+		TrustedProxies: acrcj.TrustedProxies,
 	}
 }
 
@@ -80,3 +152,49 @@ func (acdcj ApiClientDatabaseConfigJson) MapToDomain() ApiClientDatabaseConfig {
 		ConnectionString: acdcj.ConnectionString,
 	}
 }
+
+type ApiClientVaultConfigJson struct {
+	MasterKeyId string `json:"master_key_id"`
+	MasterKey   string `json:"master_key"`
+}
+
+type ApiClientVaultConfig struct {
+	MasterKeyId string
+	MasterKey   string
+}
+
+func (acvcj ApiClientVaultConfigJson) MapToDomain() ApiClientVaultConfig {
+	keyId := acvcj.MasterKeyId
+	if keyId == "" {
+		keyId = "local"
+	}
+
+	return ApiClientVaultConfig{
+		MasterKeyId: keyId,
+		MasterKey:   acvcj.MasterKey,
+	}
+}
+
+// ApiClientDidConfigJson holds the key the api signs verifier requests and
+// receipts with; its public half is published in the did:web document.
+type ApiClientDidConfigJson struct {
+	KeyFragment string `json:"key_fragment"`
+	PrivateKey  string `json:"private_key"` // base64 Ed25519 seed or private key
+}
+
+type ApiClientDidConfig struct {
+	KeyFragment string
+	PrivateKey  string
+}
+
+func (acdcj ApiClientDidConfigJson) MapToDomain() ApiClientDidConfig {
+	fragment := acdcj.KeyFragment
+	if fragment == "" {
+		fragment = did.DefaultKeyFragment
+	}
+
+	return ApiClientDidConfig{
+		KeyFragment: fragment,
+		PrivateKey:  acdcj.PrivateKey,
+	}
+}
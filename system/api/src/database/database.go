@@ -1,8 +1,11 @@
 package database
 
 import (
+	"context"
+	"pkg-common/health"
 	"pkg-common/logger"
 	"sync"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -70,3 +73,14 @@ func indexOf(s, substr string) int {
 	}
 	return -1
 }
+
+// HealthCheck pings the underlying connection pool.
+func HealthCheck() health.Check {
+	return health.NewCheck("database", 2*time.Second, func(ctx context.Context) error {
+		sqlDb, err := GetDatabaseConnection().DB()
+		if err != nil {
+			return err
+		}
+		return sqlDb.PingContext(ctx)
+	})
+}
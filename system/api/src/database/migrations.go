@@ -19,6 +19,8 @@ func AutoMigrate(db *gorm.DB) error {
 		&model.ZeroKnowledgeProof{},
 		&model.ZkpProofFailure{},
 		&model.OutboxEvent{},
+		&model.IdentityDataKey{},
+		&model.VaultRecord{},
 		&model.LogAuditEntry{},
 	}
 
@@ -17,7 +17,7 @@ const docTemplate = `{
     "paths": {
         "/v1/identity": {
             "post": {
-                "description": "Creates an identity with optional parent_id",
+                "description": "Creates an identity with optional parent_id (UUID of the parent identity)",
                 "consumes": [
                     "application/json"
                 ],
@@ -41,7 +41,7 @@ const docTemplate = `{
                                     "type": "string"
                                 },
                                 "parent_id": {
-                                    "type": "integer"
+                                    "type": "string"
                                 }
                             }
                         }
@@ -119,6 +119,13 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
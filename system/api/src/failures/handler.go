@@ -0,0 +1,199 @@
+package failures
+
+import (
+	"api/src/model"
+	"errors"
+	"net/http"
+	"pkg-common/rest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Scopes internal callers need for the failure admin routes
+const (
+	ReadScope  = "failures:read"
+	WriteScope = "failures:write"
+)
+
+type FailureHandler struct {
+	service FailureService
+}
+
+func NewFailureHandler(service FailureService) *FailureHandler {
+	return &FailureHandler{
+		service: service,
+	}
+}
+
+// ListFailures godoc
+// @Summary      List failed proof attempts
+// @Description  Lists failures reported by the proof pipeline, newest first, with the state of their outbox event
+// @Tags         Failures
+// @Produce      json
+// @Param        reason_code        query     string  false  "Reason code"
+// @Param        event_id           query     string  false  "Event ID"
+// @Param        from               query     string  false  "Created at or after (RFC3339)"
+// @Param        to                 query     string  false  "Created before (RFC3339)"
+// @Param        include_discarded  query     bool    false  "Include discarded failures"
+// @Param        limit              query     int     false  "Page size (max 1000)"
+// @Param        offset             query     int     false  "Page offset"
+// @Success      200  {object}  model.ProofFailurePage
+// @Failure      400  {object}  map[string]string
+// @Router       /v1/internal/failures [get]
+func (h *FailureHandler) ListFailures(c *gin.Context) {
+	limit, offset, err := rest.ParsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	from, to, ok := parseRange(c)
+	if !ok {
+		return
+	}
+
+	page, err := h.service.ListFailures(FailureFilter{
+		ReasonCode:       c.Query("reason_code"),
+		EventId:          c.Query("event_id"),
+		From:             from,
+		To:               to,
+		IncludeDiscarded: c.Query("include_discarded") == "true",
+		Limit:            limit,
+		Offset:           offset,
+	})
+	if err != nil {
+		respondWithError(c, err, "Failed to retrieve failures")
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// GetFailedEvent godoc
+// @Summary      Inspect a failed event
+// @Description  Returns the outbox event with its original request and every failure reported for it
+// @Tags         Failures
+// @Produce      json
+// @Param        event_id  path      string  true  "Event ID"
+// @Success      200  {object}  model.FailedEventDto
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /v1/internal/outbox/{event_id} [get]
+func (h *FailureHandler) GetFailedEvent(c *gin.Context) {
+	event, err := h.service.GetFailedEvent(c.Param("event_id"))
+	if err != nil {
+		respondWithError(c, err, "Failed to retrieve event")
+		return
+	}
+	c.JSON(http.StatusOK, event)
+}
+
+// RequeueEvent godoc
+// @Summary      Requeue a failed event
+// @Description  Makes a pending, dead-lettered or discarded event, or a published one whose result is overdue, due for publication again
+// @Tags         Failures
+// @Produce      json
+// @Param        event_id  path      string  true  "Event ID"
+// @Success      200  {object}  model.FailedEventDto
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /v1/internal/outbox/{event_id}/requeue [post]
+func (h *FailureHandler) RequeueEvent(c *gin.Context) {
+	event, err := h.service.Requeue(c.Param("event_id"))
+	if err != nil {
+		respondWithError(c, err, "Failed to requeue event")
+		return
+	}
+	c.JSON(http.StatusOK, event)
+}
+
+// DiscardFailures godoc
+// @Summary      Discard failed events
+// @Description  Stops retrying the selected events and hides their failures from the default listing
+// @Tags         Failures
+// @Accept       json
+// @Produce      json
+// @Param        request  body      model.DiscardFailuresRequest  true  "Events to discard"
+// @Success      200  {object}  model.DiscardReportDto
+// @Failure      400  {object}  map[string]string
+// @Router       /v1/internal/failures/discard [post]
+func (h *FailureHandler) DiscardFailures(c *gin.Context) {
+	var req model.DiscardFailuresRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.service.Discard(req)
+	if err != nil {
+		respondWithError(c, err, "Failed to discard failures")
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetFailureStats godoc
+// @Summary      Failure statistics
+// @Description  Counts failures per reason code and time bucket; defaults to daily buckets over the last 7 days
+// @Tags         Failures
+// @Produce      json
+// @Param        bucket  query     string  false  "hour, day or week"
+// @Param        from    query     string  false  "Start (RFC3339)"
+// @Param        to      query     string  false  "End (RFC3339)"
+// @Success      200  {object}  model.FailureStatsDto
+// @Failure      400  {object}  map[string]string
+// @Router       /v1/internal/failures/stats [get]
+func (h *FailureHandler) GetFailureStats(c *gin.Context) {
+	from, to, ok := parseRange(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.service.Stats(c.Query("bucket"), from, to)
+	if err != nil {
+		respondWithError(c, err, "Failed to compute failure statistics")
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+func parseRange(c *gin.Context) (*time.Time, *time.Time, bool) {
+	from, err := parseTimeQuery(c, "from")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC3339 timestamp"})
+		return nil, nil, false
+	}
+	to, err := parseTimeQuery(c, "to")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC3339 timestamp"})
+		return nil, nil, false
+	}
+	return from, to, true
+}
+
+func parseTimeQuery(c *gin.Context, key string) (*time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func respondWithError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, ErrEventNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNotRequeueable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidEventId), errors.Is(err, ErrEmptySelection),
+		errors.Is(err, ErrInvalidBucket), errors.Is(err, ErrInvalidStatsRange):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+	}
+}
@@ -0,0 +1,140 @@
+package failures
+
+import (
+	"api/src/database"
+	"api/src/model"
+	"api/src/outbox"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type FailureFilter struct {
+	ReasonCode       string
+	EventId          string
+	From             *time.Time
+	To               *time.Time
+	IncludeDiscarded bool
+	Limit            int
+	Offset           int
+}
+
+// FailureRecord is a reported failure joined with the state of its outbox
+// event; the event columns are empty when the event no longer exists.
+type FailureRecord struct {
+	model.ZkpProofFailure
+	EventStatus model.OutboxStatus
+	Retry       int
+}
+
+type FailureStat struct {
+	BucketStart time.Time
+	ReasonCode  string
+	Count       int64
+}
+
+type FailureRepository interface {
+	ListFailures(filter FailureFilter) ([]FailureRecord, int64, error)
+	// GetEvent returns the outbox event including processed ones.
+	GetEvent(eventId string) (model.OutboxEvent, error)
+	GetEventFailures(eventId string) ([]model.ZkpProofFailure, error)
+	// GetEventIdsByReason lists the events with not yet discarded failures of
+	// the reason code.
+	GetEventIdsByReason(reasonCode string) ([]string, error)
+	// Discard marks the failures of the events as discarded and stops their
+	// retries in one transaction.
+	Discard(eventIds []string) (model.DiscardReportDto, error)
+	Stats(bucket string, from, to time.Time) ([]FailureStat, error)
+}
+
+type failureRepository struct {
+	db *gorm.DB
+}
+
+func NewFailureRepository() FailureRepository {
+	return &failureRepository{db: database.GetDatabaseConnection()}
+}
+
+func (r *failureRepository) ListFailures(filter FailureFilter) ([]FailureRecord, int64, error) {
+	query := r.db.Table("zkp_proof_failures AS f").
+		Joins("LEFT JOIN outbox_events o ON o.event_id::text = f.event_id")
+	if filter.ReasonCode != "" {
+		query = query.Where("f.reason_code = ?", filter.ReasonCode)
+	}
+	if filter.EventId != "" {
+		query = query.Where("f.event_id = ?", filter.EventId)
+	}
+	if filter.From != nil {
+		query = query.Where("f.created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("f.created_at < ?", *filter.To)
+	}
+	if !filter.IncludeDiscarded {
+		query = query.Where("f.discarded_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var records []FailureRecord
+	err := query.
+		Select("f.*, o.status AS event_status, COALESCE(o.retry, 0) AS retry").
+		Order("f.created_at DESC, f.id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Scan(&records).Error
+	return records, total, err
+}
+
+func (r *failureRepository) GetEvent(eventId string) (model.OutboxEvent, error) {
+	var event model.OutboxEvent
+	err := r.db.Unscoped().First(&event, "event_id = ?", eventId).Error
+	return event, err
+}
+
+func (r *failureRepository) GetEventFailures(eventId string) ([]model.ZkpProofFailure, error) {
+	var failures []model.ZkpProofFailure
+	err := r.db.Where("event_id = ?", eventId).Order("created_at, id").Find(&failures).Error
+	return failures, err
+}
+
+func (r *failureRepository) GetEventIdsByReason(reasonCode string) ([]string, error) {
+	var eventIds []string
+	err := r.db.Model(&model.ZkpProofFailure{}).
+		Distinct("event_id").
+		Where("reason_code = ? AND discarded_at IS NULL", reasonCode).
+		Pluck("event_id", &eventIds).Error
+	return eventIds, err
+}
+
+func (r *failureRepository) Discard(eventIds []string) (model.DiscardReportDto, error) {
+	var report model.DiscardReportDto
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.ZkpProofFailure{}).
+			Where("event_id IN ? AND discarded_at IS NULL", eventIds).
+			Update("discarded_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		report.Failures = result.RowsAffected
+
+		events, err := outbox.NewRepoWithDB(tx).Discard(eventIds)
+		report.Events = events
+		return err
+	})
+	return report, err
+}
+
+func (r *failureRepository) Stats(bucket string, from, to time.Time) ([]FailureStat, error) {
+	var stats []FailureStat
+	err := r.db.Model(&model.ZkpProofFailure{}).
+		Select("date_trunc(?, created_at) AS bucket_start, reason_code, COUNT(*) AS count", bucket).
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Group("bucket_start, reason_code").
+		Order("bucket_start, reason_code").
+		Scan(&stats).Error
+	return stats, err
+}
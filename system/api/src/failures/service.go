@@ -0,0 +1,218 @@
+package failures
+
+import (
+	"api/src/model"
+	"api/src/outbox"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrEventNotFound     = errors.New("outbox event not found")
+	ErrInvalidEventId    = errors.New("event_id must be a valid UUID")
+	ErrNotRequeueable    = errors.New("event is waiting for its result or already processed")
+	ErrEmptySelection    = errors.New("event_ids or reason_code is required")
+	ErrInvalidBucket     = errors.New("bucket must be one of hour, day, week")
+	ErrInvalidStatsRange = errors.New("from must be before to")
+)
+
+// DefaultStatsWindow is the period covered by statistics without a from date.
+const DefaultStatsWindow = 7 * 24 * time.Hour
+
+var statsBuckets = map[string]bool{"hour": true, "day": true, "week": true}
+
+type FailureService interface {
+	ListFailures(filter FailureFilter) (*model.ProofFailurePage, error)
+	GetFailedEvent(eventId string) (*model.FailedEventDto, error)
+	Requeue(eventId string) (*model.FailedEventDto, error)
+	Discard(req model.DiscardFailuresRequest) (*model.DiscardReportDto, error)
+	Stats(bucket string, from, to *time.Time) (*model.FailureStatsDto, error)
+}
+
+type failureService struct {
+	repository FailureRepository
+	outboxRepo outbox.OutboxRepository
+	now        func() time.Time
+}
+
+func NewFailureService(repository FailureRepository, outboxRepo outbox.OutboxRepository) FailureService {
+	return &failureService{
+		repository: repository,
+		outboxRepo: outboxRepo,
+		now:        time.Now,
+	}
+}
+
+func (s *failureService) ListFailures(filter FailureFilter) (*model.ProofFailurePage, error) {
+	if filter.EventId != "" {
+		if _, err := uuid.Parse(filter.EventId); err != nil {
+			return nil, ErrInvalidEventId
+		}
+	}
+
+	records, total, err := s.repository.ListFailures(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]model.ProofFailureDto, 0, len(records))
+	for _, record := range records {
+		items = append(items, model.ProofFailureDto{
+			EventId:     record.EventId,
+			ReasonCode:  record.ReasonCode,
+			Error:       record.Error,
+			EventStatus: record.EventStatus,
+			Retry:       record.Retry,
+			DiscardedAt: record.DiscardedAt,
+			CreatedAt:   record.CreatedAt,
+		})
+	}
+	return &model.ProofFailurePage{Items: items, Total: total, Limit: filter.Limit, Offset: filter.Offset}, nil
+}
+
+func (s *failureService) GetFailedEvent(eventId string) (*model.FailedEventDto, error) {
+	if _, err := uuid.Parse(eventId); err != nil {
+		return nil, ErrInvalidEventId
+	}
+
+	event, err := s.repository.GetEvent(eventId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrEventNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	failures, err := s.repository.GetEventFailures(eventId)
+	if err != nil {
+		return nil, err
+	}
+
+	dto := &model.FailedEventDto{
+		EventId:        event.EventId,
+		IdentityId:     event.IdentityId,
+		SchemaId:       event.SchemaId,
+		Status:         event.Status,
+		Retry:          event.Retry,
+		LastError:      event.LastError,
+		NextAttemptAt:  event.NextAttemptAt,
+		RequestMessage: event.RequestMessage,
+		Failures:       make([]model.FailureAttemptDto, 0, len(failures)),
+		CreatedAt:      event.CreatedAt,
+	}
+	for _, failure := range failures {
+		dto.Failures = append(dto.Failures, model.FailureAttemptDto{
+			ReasonCode:  failure.ReasonCode,
+			Error:       failure.Error,
+			RequestBody: requestBody(failure.RequestBody),
+			CreatedAt:   failure.CreatedAt,
+		})
+	}
+	return dto, nil
+}
+
+func (s *failureService) Requeue(eventId string) (*model.FailedEventDto, error) {
+	id, err := uuid.Parse(eventId)
+	if err != nil {
+		return nil, ErrInvalidEventId
+	}
+
+	err = s.outboxRepo.Requeue(id)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, ErrEventNotFound
+	case errors.Is(err, outbox.ErrNotRequeueable):
+		return nil, ErrNotRequeueable
+	case err != nil:
+		return nil, err
+	}
+	return s.GetFailedEvent(eventId)
+}
+
+func (s *failureService) Discard(req model.DiscardFailuresRequest) (*model.DiscardReportDto, error) {
+	eventIds := req.EventIds
+	for _, eventId := range eventIds {
+		if _, err := uuid.Parse(eventId); err != nil {
+			return nil, ErrInvalidEventId
+		}
+	}
+	if req.ReasonCode != "" {
+		byReason, err := s.repository.GetEventIdsByReason(req.ReasonCode)
+		if err != nil {
+			return nil, err
+		}
+		eventIds = append(eventIds, byReason...)
+	}
+	if len(req.EventIds) == 0 && req.ReasonCode == "" {
+		return nil, ErrEmptySelection
+	}
+	if len(eventIds) == 0 {
+		return &model.DiscardReportDto{}, nil
+	}
+
+	report, err := s.repository.Discard(eventIds)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (s *failureService) Stats(bucket string, from, to *time.Time) (*model.FailureStatsDto, error) {
+	if bucket == "" {
+		bucket = "day"
+	}
+	if !statsBuckets[bucket] {
+		return nil, ErrInvalidBucket
+	}
+
+	end := s.now()
+	if to != nil {
+		end = *to
+	}
+	start := end.Add(-DefaultStatsWindow)
+	if from != nil {
+		start = *from
+	}
+	if !start.Before(end) {
+		return nil, ErrInvalidStatsRange
+	}
+
+	stats, err := s.repository.Stats(bucket, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	dto := &model.FailureStatsDto{
+		Bucket: bucket,
+		From:   start,
+		To:     end,
+		Totals: map[string]int64{},
+		Series: make([]model.FailureStatDto, 0, len(stats)),
+	}
+	for _, stat := range stats {
+		dto.Totals[stat.ReasonCode] += stat.Count
+		dto.Series = append(dto.Series, model.FailureStatDto{
+			BucketStart: stat.BucketStart,
+			ReasonCode:  stat.ReasonCode,
+			Count:       stat.Count,
+		})
+	}
+	return dto, nil
+}
+
+// requestBody returns the failed message as JSON, or as a JSON string when
+// the consumer could not even parse it.
+func requestBody(raw []byte) json.RawMessage {
+	if len(raw) == 0 {
+		return nil
+	}
+	if json.Valid(raw) {
+		return raw
+	}
+	quoted, _ := json.Marshal(string(raw))
+	return quoted
+}
@@ -2,7 +2,11 @@ package identity
 
 import (
 	"api/src/model"
+	"api/src/vault"
+	"errors"
 	"net/http"
+	"pkg-common/logger"
+	"pkg-common/rest"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,26 +15,33 @@ type Handler struct {
 	Service *Service
 }
 
-func NewHandler() *Handler {
-	return &Handler{Service: NewService()}
+func NewHandler(attributeVault vault.Vault) *Handler {
+	return &Handler{Service: NewService(attributeVault)}
+}
+
+type identityRequest struct {
+	IdentityName string  `json:"identity_name"`
+	ParentId     *string `json:"parent_id,omitempty"`
+}
+
+type moveIdentityRequest struct {
+	ParentId *string `json:"parent_id"`
 }
 
 // CreateIdentity godoc
 // @Summary      Create a new identity
-// @Description  Creates an identity with optional parent_id
+// @Description  Creates an identity with optional parent_id (UUID of the parent identity)
 // @Tags         Identity
 // @Accept       json
 // @Produce      json
-// @Param        body  body      object{identity_name=string,parent_id=int}  true  "Identity info"
-// @Success      201  {object}  map[string]interface{}
+// @Param        body  body      object{identity_name=string,parent_id=string}  true  "Identity info"
+// @Success      201  {object}  model.IdentityDto
 // @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /v1/identity [post]
 func (h *Handler) CreateIdentity(c *gin.Context) {
-	var req struct {
-		IdentityName string `json:"identity_name"`
-		ParentId     *int   `json:"parent_id,omitempty"` // Accept parent_id as optional
-	}
+	var req identityRequest
 	if err := c.ShouldBindJSON(&req); err != nil || req.IdentityName == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
@@ -38,20 +49,45 @@ func (h *Handler) CreateIdentity(c *gin.Context) {
 
 	identity, err := h.Service.CreateIdentity(req.IdentityName, req.ParentId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create identity: " + err.Error()})
+		respondWithError(c, err, "Could not create identity")
 		return
 	}
+	c.JSON(http.StatusCreated, identity)
+}
 
-	var _ *model.Identity
-	if identity.ParentId != nil {
-		_, _ = h.Service.GetIdentityById(identity.Parent.IdentityId)
+// ListIdentities godoc
+// @Summary      List identities
+// @Description  Lists identities with optional name (substring) and parent filters
+// @Tags         Identity
+// @Produce      json
+// @Param        name       query     string  false  "Name contains"
+// @Param        parent_id  query     string  false  "Parent identity ID"
+// @Param        root_only  query     bool    false  "Only identities without a parent"
+// @Param        limit      query     int     false  "Page size (max 1000)"
+// @Param        offset     query     int     false  "Page offset"
+// @Success      200  {object}  model.IdentityPage
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /v1/identity [get]
+func (h *Handler) ListIdentities(c *gin.Context) {
+	limit, offset, err := rest.ParsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"identity_id":   identity.IdentityId,
-		"identity_name": identity.IdentityName,
-		"parent_id":     identity.ParentId,
-	})
+	var parentId *string
+	if p, ok := c.GetQuery("parent_id"); ok {
+		parentId = &p
+	}
+	rootOnly := c.Query("root_only") == "true"
+
+	page, err := h.Service.ListIdentities(c.Query("name"), parentId, rootOnly, limit, offset)
+	if err != nil {
+		respondWithError(c, err, "Failed to list identities")
+		return
+	}
+	c.JSON(http.StatusOK, page)
 }
 
 // GetIdentity godoc
@@ -61,20 +97,150 @@ func (h *Handler) CreateIdentity(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        id   path      string  true  "Identity ID"
-// @Success      200  {object}  map[string]interface{}
+// @Success      200  {object}  model.IdentityDto
 // @Failure      404  {object}  map[string]string
 // @Router       /v1/identity/{id} [get]
 func (h *Handler) GetIdentity(c *gin.Context) {
-	id := c.Param("id")
-	identity, err := h.Service.GetIdentityById(id)
+	identity, err := h.Service.GetIdentityById(identityId(c))
+	if err != nil {
+		respondWithError(c, err, "Failed to get identity")
+		return
+	}
+	c.JSON(http.StatusOK, identity)
+}
+
+// UpdateIdentity godoc
+// @Summary      Rename identity
+// @Description  Renames an identity; parent_id is rejected, moves go through /v1/identity/{id}/parent
+// @Tags         Identity
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string                       true  "Identity ID"
+// @Param        body  body      object{identity_name=string}  true  "New name"
+// @Success      200  {object}  model.IdentityDto
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /v1/identity/{id} [put]
+func (h *Handler) UpdateIdentity(c *gin.Context) {
+	var req identityRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.IdentityName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if req.ParentId != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "parent_id cannot be updated here, use PUT /v1/identity/{id}/parent"})
+		return
+	}
+
+	identity, err := h.Service.RenameIdentity(identityId(c), req.IdentityName)
+	if err != nil {
+		respondWithError(c, err, "Could not update identity")
+		return
+	}
+	c.JSON(http.StatusOK, identity)
+}
+
+// MoveIdentity godoc
+// @Summary      Move identity to a new parent
+// @Description  Re-parents an identity; a null parent_id makes it a root. Cycles are rejected.
+// @Tags         Identity
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string                    true  "Identity ID"
+// @Param        body  body      object{parent_id=string}  true  "New parent"
+// @Success      200  {object}  model.IdentityDto
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /v1/identity/{id}/parent [put]
+func (h *Handler) MoveIdentity(c *gin.Context) {
+	var req moveIdentityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	identity, err := h.Service.MoveIdentity(identityId(c), req.ParentId)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Identity not found"})
+		respondWithError(c, err, "Could not move identity")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"identity_id":   identity.IdentityId,
-		"identity_name": identity.IdentityName,
-	})
+	c.JSON(http.StatusOK, identity)
+}
+
+// DeleteIdentity godoc
+// @Summary      Delete identity
+// @Description  Soft-deletes an identity without sub-identities
+// @Tags         Identity
+// @Param        id   path      string  true  "Identity ID"
+// @Success      204
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /v1/identity/{id} [delete]
+func (h *Handler) DeleteIdentity(c *gin.Context) {
+	if err := h.Service.DeleteIdentity(identityId(c)); err != nil {
+		respondWithError(c, err, "Could not delete identity")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetChildren godoc
+// @Summary      Get sub-identities
+// @Description  Returns direct children, or the whole subtree with recursive=true
+// @Tags         Identity
+// @Produce      json
+// @Param        id         path      string  true   "Identity ID"
+// @Param        recursive  query     bool    false  "Include all descendants"
+// @Success      200  {array}   model.IdentityDto
+// @Failure      404  {object}  map[string]string
+// @Router       /v1/identity/{id}/children [get]
+func (h *Handler) GetChildren(c *gin.Context) {
+	children, err := h.Service.GetChildren(identityId(c), c.Query("recursive") == "true")
+	if err != nil {
+		respondWithError(c, err, "Failed to get sub-identities")
+		return
+	}
+	c.JSON(http.StatusOK, children)
+}
+
+// GetAncestors godoc
+// @Summary      Get ancestors
+// @Description  Returns the chain of parents from the direct parent up to the root
+// @Tags         Identity
+// @Produce      json
+// @Param        id   path      string  true  "Identity ID"
+// @Success      200  {array}   model.IdentityDto
+// @Failure      404  {object}  map[string]string
+// @Router       /v1/identity/{id}/ancestors [get]
+func (h *Handler) GetAncestors(c *gin.Context) {
+	ancestors, err := h.Service.GetAncestors(identityId(c))
+	if err != nil {
+		respondWithError(c, err, "Failed to get ancestors")
+		return
+	}
+	c.JSON(http.StatusOK, ancestors)
+}
+
+// identityId returns the identity of the route and adds it to the log fields
+// of the request.
+func identityId(c *gin.Context) string {
+	id := c.Param("id")
+	c.Request = c.Request.WithContext(logger.ContextWithField(c.Request.Context(), logger.IdentityIdKey, id))
+	return id
+}
+
+func respondWithError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, ErrIdentityNotFound), errors.Is(err, ErrParentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrHierarchyCycle), errors.Is(err, ErrHasChildren):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		logger.Default().WithContext(c.Request.Context()).Error(err, msg)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+	}
 }
 
 // QueueVerification godoc
@@ -86,6 +252,7 @@ func (h *Handler) GetIdentity(c *gin.Context) {
 // @Param        body  body      model.ZeroKnowledgeProofVerificationRequest  true  "Verification request"
 // @Success      202  {object}  map[string]string
 // @Failure      400  {object}  map[string]string
+// @Failure      422  {object}  map[string]interface{}
 // @Failure      500  {object}  map[string]string
 // @Router       /v1/identity/verify [post]
 func (h *Handler) QueueVerification(c *gin.Context) {
@@ -94,7 +261,17 @@ func (h *Handler) QueueVerification(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
 		return
 	}
-	if err := h.Service.QueueVerification(req); err != nil {
+	ctx := logger.ContextWithField(c.Request.Context(), logger.IdentityIdKey, req.IdentityId)
+	if err := h.Service.QueueVerification(ctx, req); err != nil {
+		var validationErr *SchemaValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":  "Schema validation failed",
+				"fields": validationErr.Fields,
+			})
+			return
+		}
+		logger.Default().WithContext(ctx).Error(err, "Failed to queue verification")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue verification"})
 		return
 	}
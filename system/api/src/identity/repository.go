@@ -3,16 +3,40 @@ package identity
 import (
 	"api/src/database"
 	"api/src/model"
+	"encoding/json"
+	"fmt"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// maxHierarchyDepth bounds the recursive hierarchy queries, so corrupted data
+// can never make them run forever.
+const maxHierarchyDepth = 256
+
+type IdentityFilter struct {
+	Name     string
+	ParentId *int
+	RootOnly bool
+	Limit    int
+	Offset   int
+}
+
 type Repository interface {
+	WithTx(tx *gorm.DB) Repository
 	Create(identity *model.Identity) error
+	Update(identity *model.Identity) error
+	Delete(identity *model.Identity) error
+	LockForUpdate(pks []int) error
 	GetById(id string) (*model.Identity, error)
+	GetByPk(pk int) (*model.Identity, error)
 	GetByName(name string) (*model.Identity, error)
+	List(filter IdentityFilter) ([]model.Identity, int64, error)
 	GetSubIdentities(parentId int) ([]model.Identity, error)
+	GetDescendants(pk int) ([]model.Identity, error)
+	GetAncestors(pk int) ([]model.Identity, error)
+	GetIdentityIds(pks []int) (map[int]string, error)
 	GetSchemaById(id uuid.UUID) (model.Schema, error)
 }
 
@@ -24,30 +48,154 @@ func NewRepository() Repository {
 	return &gormRepository{db: database.GetDatabaseConnection()}
 }
 
+// WithTx allows the repository to run operations within a transaction
+func (r *gormRepository) WithTx(tx *gorm.DB) Repository {
+	if tx == nil {
+		return r
+	}
+	return &gormRepository{db: tx}
+}
+
 func (r *gormRepository) Create(identity *model.Identity) error {
 	return r.db.Create(identity).Error
 }
 
+func (r *gormRepository) Update(identity *model.Identity) error {
+	return r.db.Model(identity).Select("identity_name", "parent_id").Updates(identity).Error
+}
+
+func (r *gormRepository) Delete(identity *model.Identity) error {
+	return r.db.Delete(identity).Error
+}
+
+// LockForUpdate takes row locks on the given identities until the surrounding
+// transaction ends. Rows are locked in primary key order to avoid deadlocks.
+func (r *gormRepository) LockForUpdate(pks []int) error {
+	if len(pks) == 0 {
+		return nil
+	}
+	var locked []model.Identity
+	return r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").Where("id IN ?", pks).Order("id").Find(&locked).Error
+}
+
 func (r *gormRepository) GetById(id string) (*model.Identity, error) {
 	var identity model.Identity
 	err := r.db.Where("identity_id = ?", id).First(&identity).Error
 	return &identity, err
 }
 
+func (r *gormRepository) GetByPk(pk int) (*model.Identity, error) {
+	var identity model.Identity
+	err := r.db.First(&identity, pk).Error
+	return &identity, err
+}
+
 func (r *gormRepository) GetByName(name string) (*model.Identity, error) {
 	var identity model.Identity
 	err := r.db.Where("identity_name = ?", name).First(&identity).Error
 	return &identity, err
 }
 
+func (r *gormRepository) List(filter IdentityFilter) ([]model.Identity, int64, error) {
+	query := r.db.Model(&model.Identity{})
+	if filter.Name != "" {
+		query = query.Where("identity_name ILIKE ?", "%"+filter.Name+"%")
+	}
+	if filter.ParentId != nil {
+		query = query.Where("parent_id = ?", *filter.ParentId)
+	} else if filter.RootOnly {
+		query = query.Where("parent_id IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var identities []model.Identity
+	err := query.Order("id").Limit(filter.Limit).Offset(filter.Offset).Find(&identities).Error
+	return identities, total, err
+}
+
 func (r *gormRepository) GetSubIdentities(parentId int) ([]model.Identity, error) {
 	var subs []model.Identity
-	err := r.db.Where("parent_id = ?", parentId).Find(&subs).Error
+	err := r.db.Where("parent_id = ?", parentId).Order("id").Find(&subs).Error
 	return subs, err
 }
 
+// GetDescendants returns the whole subtree below pk, closest levels first.
+func (r *gormRepository) GetDescendants(pk int) ([]model.Identity, error) {
+	var descendants []model.Identity
+	err := r.db.Raw(`
+		WITH RECURSIVE descendants AS (
+			SELECT id, parent_id, 1 AS depth
+			FROM identities
+			WHERE parent_id = ? AND deleted_at IS NULL
+			UNION ALL
+			SELECT i.id, i.parent_id, d.depth + 1
+			FROM identities i
+			JOIN descendants d ON i.parent_id = d.id
+			WHERE i.deleted_at IS NULL AND d.depth < ?
+		)
+		SELECT i.*
+		FROM identities i
+		JOIN descendants d ON i.id = d.id
+		ORDER BY d.depth, i.id`, pk, maxHierarchyDepth).
+		Scan(&descendants).Error
+	return descendants, err
+}
+
+// GetAncestors returns the chain from the parent of pk up to the root.
+func (r *gormRepository) GetAncestors(pk int) ([]model.Identity, error) {
+	var ancestors []model.Identity
+	err := r.db.Raw(`
+		WITH RECURSIVE ancestors AS (
+			SELECT p.id, p.parent_id, 1 AS depth
+			FROM identities c
+			JOIN identities p ON p.id = c.parent_id
+			WHERE c.id = ? AND p.deleted_at IS NULL
+			UNION ALL
+			SELECT p.id, p.parent_id, a.depth + 1
+			FROM identities p
+			JOIN ancestors a ON p.id = a.parent_id
+			WHERE p.deleted_at IS NULL AND a.depth < ?
+		)
+		SELECT i.*
+		FROM identities i
+		JOIN ancestors a ON i.id = a.id
+		ORDER BY a.depth`, pk, maxHierarchyDepth).
+		Scan(&ancestors).Error
+	return ancestors, err
+}
+
+// GetIdentityIds resolves internal primary keys to public UUIDs. Soft-deleted
+// identities are included so children of a deleted parent still resolve.
+func (r *gormRepository) GetIdentityIds(pks []int) (map[int]string, error) {
+	ids := make(map[int]string, len(pks))
+	if len(pks) == 0 {
+		return ids, nil
+	}
+
+	var identities []model.Identity
+	if err := r.db.Unscoped().Select("id", "identity_id").Where("id IN ?", pks).Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	for _, identity := range identities {
+		ids[identity.Id] = identity.IdentityId
+	}
+	return ids, nil
+}
+
 func (r *gormRepository) GetSchemaById(id uuid.UUID) (model.Schema, error) {
+	var verified model.VerifiedSchema
+	if err := r.db.Where("schema_id = ?", id.String()).First(&verified).Error; err != nil {
+		return model.Schema{}, err
+	}
+
 	var schema model.Schema
-	err := r.db.Where("schema_id = ?", id.String()).First(&schema).Error
-	return schema, err
+	if err := json.Unmarshal([]byte(verified.Schema), &schema); err != nil {
+		return model.Schema{}, fmt.Errorf("verified schema %s is not valid JSON: %w", id, err)
+	}
+	return schema, nil
 }
@@ -1,94 +1,327 @@
 package identity
 
 import (
+	"api/src/database"
 	"api/src/model"
 	"api/src/outbox"
-	"fmt"
-	"pkg-common/rabbitmq"
-	"pkg-common/utilities"
-	"strings"
+	"api/src/vault"
+	"context"
+	"errors"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type Service struct {
-	IdentityRepo      Repository
-	OutboxRepo        outbox.OutboxRepository
-	RabbitmqPublisher rabbitmq.IRabbitmqPublisher
+	IdentityRepo Repository
+	OutboxRepo   outbox.OutboxRepository
+	Vault        vault.Vault
+	// Transaction runs fn in a database transaction; the vault record and the
+	// outbox event of a verification request are written together.
+	Transaction func(fn func(tx *gorm.DB) error) error
 }
 
-func NewService() *Service {
-	// TODO: remove hardcoding
+func NewService(attributeVault vault.Vault) *Service {
 	return &Service{
-		IdentityRepo:      NewRepository(),
-		OutboxRepo:        outbox.NewRepo(),
-		RabbitmqPublisher: rabbitmq.GetPublisher("VerifiersUnverifiedPublisher")}
+		IdentityRepo: NewRepository(),
+		OutboxRepo:   outbox.NewRepo(),
+		Vault:        attributeVault,
+		Transaction: func(fn func(tx *gorm.DB) error) error {
+			return database.GetDatabaseConnection().Transaction(fn)
+		},
+	}
 }
 
-func (s *Service) CreateIdentity(name string, parentId *int) (*model.Identity, error) {
+var (
+	ErrIdentityNotFound = errors.New("identity not found")
+	ErrParentNotFound   = errors.New("parent identity not found")
+	ErrHierarchyCycle   = errors.New("identity cannot be moved below itself or its descendants")
+	ErrHasChildren      = errors.New("identity still has sub-identities")
+)
+
+func (s *Service) CreateIdentity(name string, parentId *string) (*model.IdentityDto, error) {
+	identity := &model.Identity{
+		IdentityId:   uuid.New().String(),
+		IdentityName: name,
+	}
+
 	if parentId != nil {
-		parent, err := s.IdentityRepo.GetById(fmt.Sprint(*parentId))
+		parent, err := s.getIdentity(*parentId, ErrParentNotFound)
 		if err != nil {
-			return nil, fmt.Errorf("parent identity with Id %d does not exist", *parentId)
+			return nil, err
 		}
-		_ = parent
+		identity.ParentId = &parent.Id
 	}
 
-	id := uuid.New().String()
-	identity := &model.Identity{
-		IdentityId:   id,
-		IdentityName: name,
-		ParentId:     parentId,
-	}
 	if err := s.IdentityRepo.Create(identity); err != nil {
 		return nil, err
 	}
+	return s.toDto(identity)
+}
 
-	return identity, nil
+func (s *Service) GetIdentityById(id string) (*model.IdentityDto, error) {
+	identity, err := s.getIdentity(id, ErrIdentityNotFound)
+	if err != nil {
+		return nil, err
+	}
+	return s.toDto(identity)
 }
 
-func (s *Service) GetIdentityById(id string) (*model.Identity, error) {
-	return s.IdentityRepo.GetById(id)
+func (s *Service) ListIdentities(name string, parentId *string, rootOnly bool, limit, offset int) (*model.IdentityPage, error) {
+	filter := IdentityFilter{Name: name, RootOnly: rootOnly, Limit: limit, Offset: offset}
+	if parentId != nil {
+		parent, err := s.getIdentity(*parentId, ErrParentNotFound)
+		if err != nil {
+			return nil, err
+		}
+		filter.ParentId = &parent.Id
+	}
+
+	identities, total, err := s.IdentityRepo.List(filter)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.toDtos(identities)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.IdentityPage{Items: items, Total: total, Limit: limit, Offset: offset}, nil
 }
 
-func (s *Service) QueueVerification(req model.ZeroKnowledgeProofVerificationRequest) error {
-	schemaId, err := uuid.Parse(req.SchemaId)
+func (s *Service) RenameIdentity(id, name string) (*model.IdentityDto, error) {
+	identity, err := s.getIdentity(id, ErrIdentityNotFound)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	identity.IdentityName = name
+	if err := s.IdentityRepo.Update(identity); err != nil {
+		return nil, err
 	}
+	return s.toDto(identity)
+}
+
+// MoveIdentity re-parents an identity, or turns it into a root when parentId is
+// nil. Moving an identity below itself or any of its descendants is rejected.
+// The cycle check and the update run in one transaction that locks the moved
+// identity and the new ancestor chain, so concurrent moves cannot form a cycle.
+func (s *Service) MoveIdentity(id string, parentId *string) (*model.IdentityDto, error) {
+	var moved *model.Identity
+	err := s.Transaction(func(tx *gorm.DB) error {
+		repo := s.IdentityRepo.WithTx(tx)
+		identity, err := findIdentity(repo, id, ErrIdentityNotFound)
+		if err != nil {
+			return err
+		}
+
+		var parent *model.Identity
+		lock := []int{identity.Id}
+		if parentId != nil {
+			parent, err = findIdentity(repo, *parentId, ErrParentNotFound)
+			if err != nil {
+				return err
+			}
+			if parent.Id == identity.Id {
+				return ErrHierarchyCycle
+			}
+			lock = append(lock, parent.Id)
+		}
+
+		// Lock until the ancestor chain read after locking is fully covered;
+		// a locked row cannot be re-parented by anyone else.
+		locked := map[int]bool{}
+		for len(lock) > 0 {
+			if err := repo.LockForUpdate(lock); err != nil {
+				return err
+			}
+			for _, pk := range lock {
+				locked[pk] = true
+			}
+			lock = nil
 
-	identityId, err := uuid.Parse(req.SchemaId)
+			if parent == nil {
+				break
+			}
+			ancestors, err := repo.GetAncestors(parent.Id)
+			if err != nil {
+				return err
+			}
+			for _, ancestor := range ancestors {
+				if ancestor.Id == identity.Id {
+					return ErrHierarchyCycle
+				}
+				if !locked[ancestor.Id] {
+					lock = append(lock, ancestor.Id)
+				}
+			}
+		}
+
+		// Re-read under the lock so a concurrent rename is not overwritten.
+		identity, err = repo.GetByPk(identity.Id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrIdentityNotFound
+		}
+		if err != nil {
+			return err
+		}
+		identity.ParentId = nil
+		if parent != nil {
+			identity.ParentId = &parent.Id
+		}
+		if err := repo.Update(identity); err != nil {
+			return err
+		}
+		moved = identity
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.toDto(moved)
+}
+
+// DeleteIdentity soft-deletes a leaf identity. Sub-identities have to be moved
+// or deleted first so the hierarchy never contains orphans.
+func (s *Service) DeleteIdentity(id string) error {
+	identity, err := s.getIdentity(id, ErrIdentityNotFound)
 	if err != nil {
 		return err
 	}
 
-	// do validation if types are correct before procedding
-	schema, err := s.IdentityRepo.GetSchemaById(schemaId)
+	children, err := s.IdentityRepo.GetSubIdentities(identity.Id)
 	if err != nil {
 		return err
 	}
-	if !validateSchema(schema, req) {
-		return fmt.Errorf("Schema validation failed, types are incorrect")
+	if len(children) > 0 {
+		return ErrHasChildren
 	}
 
-	zkpStringFieds := utilities.Map(req.Fields, func(field model.ZkpField) string {
-		val, _ := field.Serialize()
-		return string(val)
-	})
+	return s.IdentityRepo.Delete(identity)
+}
 
-	msgBody := strings.Join(zkpStringFieds, ", ")
+// GetChildren returns direct sub-identities, or the whole subtree when
+// recursive is set.
+func (s *Service) GetChildren(id string, recursive bool) ([]model.IdentityDto, error) {
+	identity, err := s.getIdentity(id, ErrIdentityNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	var children []model.Identity
+	if recursive {
+		children, err = s.IdentityRepo.GetDescendants(identity.Id)
+	} else {
+		children, err = s.IdentityRepo.GetSubIdentities(identity.Id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.toDtos(children)
+}
+
+// GetAncestors returns the chain from the direct parent up to the root.
+func (s *Service) GetAncestors(id string) ([]model.IdentityDto, error) {
+	identity, err := s.getIdentity(id, ErrIdentityNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	ancestors, err := s.IdentityRepo.GetAncestors(identity.Id)
+	if err != nil {
+		return nil, err
+	}
+	return s.toDtos(ancestors)
+}
+
+func (s *Service) getIdentity(id string, notFound error) (*model.Identity, error) {
+	return findIdentity(s.IdentityRepo, id, notFound)
+}
+
+func findIdentity(repo Repository, id string, notFound error) (*model.Identity, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, notFound
+	}
+
+	identity, err := repo.GetById(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, notFound
+	}
+	return identity, err
+}
 
-	eventId, err := s.OutboxRepo.NewEvent(
-		identityId,
-		schemaId,
-		msgBody,
-	)
+func (s *Service) toDto(identity *model.Identity) (*model.IdentityDto, error) {
+	dtos, err := s.toDtos([]model.Identity{*identity})
+	if err != nil {
+		return nil, err
+	}
+	return &dtos[0], nil
+}
+
+func (s *Service) toDtos(identities []model.Identity) ([]model.IdentityDto, error) {
+	var parentPks []int
+	for _, identity := range identities {
+		if identity.ParentId != nil {
+			parentPks = append(parentPks, *identity.ParentId)
+		}
+	}
+	parentIds, err := s.IdentityRepo.GetIdentityIds(parentPks)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]model.IdentityDto, 0, len(identities))
+	for _, identity := range identities {
+		dto := model.IdentityDto{
+			IdentityId:   identity.IdentityId,
+			IdentityName: identity.IdentityName,
+			CreatedAt:    identity.CreatedAt,
+			UpdatedAt:    identity.UpdatedAt,
+		}
+		if identity.ParentId != nil {
+			parentId := parentIds[*identity.ParentId]
+			dto.ParentId = &parentId
+		}
+		dtos = append(dtos, dto)
+	}
+	return dtos, nil
+}
+
+func (s *Service) QueueVerification(ctx context.Context, req model.ZeroKnowledgeProofVerificationRequest) error {
+	var idErrs []FieldError
+	schemaId, err := uuid.Parse(req.SchemaId)
+	if err != nil {
+		idErrs = append(idErrs, FieldError{Key: "schema_id", Message: "must be a valid UUID"})
+	}
+	identityId, err := uuid.Parse(req.IdentityId)
+	if err != nil {
+		idErrs = append(idErrs, FieldError{Key: "identity_id", Message: "must be a valid UUID"})
+	}
+	if len(idErrs) > 0 {
+		return &SchemaValidationError{Fields: idErrs}
+	}
+
+	schema, err := s.IdentityRepo.GetSchemaById(schemaId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &SchemaValidationError{Fields: []FieldError{{Key: "schema_id", Message: "schema does not exist"}}}
+	}
 	if err != nil {
 		return err
 	}
+	if fieldErrs := ValidateSchema(schema, req); len(fieldErrs) > 0 {
+		return &SchemaValidationError{Fields: fieldErrs}
+	}
+
+	// Attribute values are kept in the vault; messages only carry the reference.
+	// Publication is left to the outbox relay, so a request is either fully
+	// recorded or not at all.
+	return s.Transaction(func(tx *gorm.DB) error {
+		attributesRef, err := s.Vault.WithTx(tx).Store(identityId.String(), req.Fields)
+		if err != nil {
+			return err
+		}
 
-	return s.RabbitmqPublisher.Publish(model.ZeroKnowledgeProofToVerification{
-		EventId: eventId.String(),
-		Data:    msgBody,
+		_, err = s.OutboxRepo.WithTx(tx).NewEvent(identityId, schemaId, attributesRef)
+		return err
 	})
 }
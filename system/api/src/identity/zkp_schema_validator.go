@@ -1,8 +1,119 @@
 package identity
 
-import "api/src/model"
+import (
+	"api/src/model"
+	"fmt"
+	"strings"
+)
 
-// mock validation
-func validateSchema(schema model.Schema, request model.ZeroKnowledgeProofVerificationRequest) bool {
-	return true
+type FieldError struct {
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+// SchemaValidationError lists every field of a verification request that does
+// not satisfy the verified schema, so the caller can fix them all at once.
+type SchemaValidationError struct {
+	Fields []FieldError
+}
+
+func (e *SchemaValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		messages = append(messages, fmt.Sprintf("%s: %s", f.Key, f.Message))
+	}
+	return "schema validation failed: " + strings.Join(messages, "; ")
+}
+
+type valueType string
+
+const (
+	numberType  valueType = "number"
+	stringType  valueType = "string"
+	booleanType valueType = "boolean"
+	invalidType valueType = "invalid"
+)
+
+// ValidateSchema checks that every constrained key is present in the request
+// with a value of the constraint's type, that the comparison is applicable to
+// that type, and that no fields outside of the schema are submitted. Whether
+// the value satisfies the constraint is left to the proof itself.
+func ValidateSchema(schema model.Schema, request model.ZeroKnowledgeProofVerificationRequest) []FieldError {
+	var errs []FieldError
+
+	fields := make(map[string]model.ZkpField, len(request.Fields))
+	for _, f := range request.Fields {
+		if f.Key == "" {
+			errs = append(errs, FieldError{Key: f.Key, Message: "field key is empty"})
+			continue
+		}
+		if _, exists := fields[f.Key]; exists {
+			errs = append(errs, FieldError{Key: f.Key, Message: "field submitted more than once"})
+			continue
+		}
+		fields[f.Key] = f
+	}
+
+	constrained := make(map[string]bool, len(schema.Constraints))
+	for _, c := range schema.Constraints {
+		constrained[c.Key] = true
+
+		expected := typeOf(c.Value)
+		if expected == invalidType {
+			errs = append(errs, FieldError{Key: c.Key, Message: "schema constraint has unsupported value type"})
+			continue
+		}
+		if !isApplicable(c.Comparison, expected) {
+			errs = append(errs, FieldError{
+				Key:     c.Key,
+				Message: fmt.Sprintf("comparison '%s' is not applicable to %s values", c.Comparison, expected),
+			})
+			continue
+		}
+
+		field, ok := fields[c.Key]
+		if !ok {
+			errs = append(errs, FieldError{Key: c.Key, Message: "required field is missing"})
+			continue
+		}
+		if actual := typeOf(field.Value); actual != expected {
+			errs = append(errs, FieldError{
+				Key:     c.Key,
+				Message: fmt.Sprintf("expected %s value, got %s", expected, actual),
+			})
+		}
+	}
+
+	for _, f := range request.Fields {
+		if f.Key != "" && !constrained[f.Key] {
+			errs = append(errs, FieldError{Key: f.Key, Message: "field is not defined by the schema"})
+		}
+	}
+
+	return errs
+}
+
+func isApplicable(comparison model.ComparisonType, t valueType) bool {
+	switch comparison {
+	case model.LessEquals, model.GreaterThan:
+		return t == numberType
+	case model.Equls, model.NotEquals:
+		return true
+	default:
+		return false
+	}
+}
+
+// typeOf classifies a value decoded by encoding/json.
+func typeOf(value any) valueType {
+	switch value.(type) {
+	case float64, float32, int, int32, int64, uint, uint32, uint64:
+		return numberType
+	case string:
+		return stringType
+	case bool:
+		return booleanType
+	default:
+		return invalidType
+	}
 }
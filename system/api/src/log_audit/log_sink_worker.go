@@ -1,13 +1,11 @@
 package logaudit
 
 import (
-	"encoding/json"
+	"context"
 
 	"pkg-common/logger"
 	"pkg-common/rabbitmq"
 	logger_message "pkg-common/utilities/logger"
-
-	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 const (
@@ -20,14 +18,14 @@ type LogSinkWorker struct {
 	logger   *logger.Logger
 }
 
-func NewLogSinkWorker() *LogSinkWorker {
+func NewLogSinkWorker(registry *rabbitmq.Registry) *LogSinkWorker {
 
 	repository := NewLogAuditRepository()
 	service := NewLogAuditService(repository)
 
 	return &LogSinkWorker{
 		service:  service,
-		consumer: rabbitmq.GetConsumer(rabbitmq.ConsumerAlias(logConsumerAlias)),
+		consumer: registry.Consumer(logConsumerAlias),
 		logger:   logger.New(),
 	}
 }
@@ -36,16 +34,28 @@ func (w *LogSinkWorker) GetServiceName() string {
 	return logConsumerAlias
 }
 
-func (w *LogSinkWorker) StartService() {
-	w.consumer.StartConsuming(func(d amqp.Delivery) {
+func (w *LogSinkWorker) Stop(ctx context.Context) error {
+	return w.consumer.Drain(ctx)
+}
+
+func (w *LogSinkWorker) StartService(ctx context.Context) error {
+	router := rabbitmq.NewRouter().On(logger_message.LoggerMessageType, func(_ context.Context, msg rabbitmq.Message) rabbitmq.Outcome {
 		var logMessage logger_message.LoggerMessage
 
-		if err := json.Unmarshal(d.Body, &logMessage); err != nil {
-			return
+		if err := msg.Decode(&logMessage); err != nil {
+			return rabbitmq.DeadLetter(err)
+		}
+		if logMessage.Service == "" {
+			// sent before services stamped their name on entries
+			logMessage.Service = msg.Producer
 		}
 
 		if err := w.service.ProcessLogMessage(logMessage); err != nil {
-			return
+			return rabbitmq.RetryLater(err)
 		}
+
+		return rabbitmq.Ack()
 	})
+
+	return w.consumer.StartConsuming(ctx, router.Handle)
 }
@@ -2,15 +2,14 @@ package logaudit
 
 import (
 	"api/src/model"
+	"encoding/json"
+	"maps"
 	"time"
 
+	"pkg-common/logger"
 	logger_message "pkg-common/utilities/logger"
 )
 
-const (
-	apiServiceName = "api"
-)
-
 type LogAuditService interface {
 	ProcessLogMessage(logMessage logger_message.LoggerMessage) error
 	GetLogEntries(limit, offset int) ([]model.LogAuditEntry, error)
@@ -30,10 +29,21 @@ func NewLogAuditService(repository LogAuditRepository) LogAuditService {
 
 func (s *logAuditService) ProcessLogMessage(logMessage logger_message.LoggerMessage) error {
 	logEntry := model.LogAuditEntry{
-		Level:     logMessage.Level,
-		Message:   logMessage.Message,
-		Timestamp: time.Unix(logMessage.Timestamp.T, 0).UTC(),
-		Service:   apiServiceName,
+		Level:      logMessage.Level,
+		Message:    logMessage.Message,
+		Timestamp:  time.Unix(logMessage.Timestamp.T, 0).UTC(),
+		Service:    logMessage.Service,
+		IdentityId: logMessage.Field(logger.IdentityIdKey),
+	}
+
+	fields := maps.Clone(logMessage.Fields)
+	delete(fields, logger.IdentityIdKey)
+	if len(fields) > 0 {
+		encoded, err := json.Marshal(fields)
+		if err != nil {
+			return err
+		}
+		logEntry.Fields = string(encoded)
 	}
 
 	return s.repository.CreateLogEntry(logEntry)
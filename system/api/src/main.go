@@ -3,19 +3,26 @@ package main
 import (
 	"api/src/database"
 	"api/src/docs"
+	"api/src/failures"
 	"api/src/identity"
 	logaudit "api/src/log_audit"
 	"api/src/middleware"
 	"api/src/outbox"
+	"api/src/privacy"
+	"api/src/proofs"
+	"api/src/vault"
 	zkpfailed "api/src/zkp/failed"
 	zkpresult "api/src/zkp/results"
 	"api/src/zkprequest"
 	"fmt"
 	appbuilder "pkg-common/app_builder"
+	"pkg-common/did"
+	"pkg-common/health"
+	httpclient "pkg-common/http_client"
 	"pkg-common/logger"
-	"pkg-common/rabbitmq"
 	"pkg-common/rest"
 	"pkg-common/utilities"
+	"strings"
 	"time"
 )
 
@@ -27,22 +34,70 @@ import (
 func main() {
 
 	var zkpHandler *zkprequest.Handler
-
-	lanHost := utilities.ResolveLanHost()
-	apiBaseURL := fmt.Sprintf("http://%s:9000", lanHost)
-	docs.SwaggerInfo.Host = fmt.Sprintf("%s:9000", lanHost)
+	var zkpService *zkprequest.Service
+	var rateLimiter *rest.RateLimiter
 
 	var logAuditHandler *logaudit.LogAuditHandler
+	var identityHandler *identity.Handler
+	var proofHandler *proofs.ProofHandler
+	var attributeVault vault.Vault
+	var vaultHandler *vault.VaultHandler
+	var privacyHandler *privacy.PrivacyHandler
+	var failureHandler *failures.FailureHandler
+	var internalAuth *rest.InternalAuthenticator
 
 	appbuilder.New[ApiConfigJson, ApiConfig]().
-		InitLogger(logger.GlobalLoggerConfig{}).
+		InitLogger(logger.GlobalLoggerConfig{Service: "api"}).
 		ResolveEnvironment().
 		LoadConfig("config.json").
+		AddTracing("api").
 		WithOption(func(a *appbuilder.AppBuilder[ApiConfigJson, ApiConfig]) {
+			apiBaseURL := a.Config.BaseURL()
+			docs.SwaggerInfo.Host = fmt.Sprintf("%s:9000", a.Config.LanHost)
+
 			// ----- DATABASE + MIGRATIONS -----
 			database.ConnectToDatabase(a)
 			database.RunMigrations(true)
 
+			// ----- VERIFIER DID (did:web of the public origin) -----
+			// Without a configured key every restart publishes a new one and
+			// invalidates the requests and receipts signed before it
+			signingKey, _ := did.ParsePrivateKey(a.Config.DidConf.PrivateKey)
+			if signingKey == nil {
+				if a.Environment == utilities.EnvProd {
+					panic("did.private_key is required in prod")
+				}
+				a.Logger.Warn("No did.private_key configured, signing with a key generated for this run")
+			}
+			verifierIdentity, err := did.NewWebIdentity(apiBaseURL, a.Config.DidConf.KeyFragment, signingKey)
+			if err != nil {
+				panic(err)
+			}
+
+			// ----- INTERNAL SERVICE AUTH -----
+			if missing := a.Config.InternalAuth.MissingSecrets(); len(missing) > 0 {
+				if a.Environment == utilities.EnvProd {
+					panic("internal auth secrets are required in prod: " + strings.Join(missing, ", "))
+				}
+				a.Logger.Warnf("No internal auth secret configured for %s, those callers are rejected", strings.Join(missing, ", "))
+			}
+			internalTokens := rest.NewInternalTokenIssuer(a.Config.InternalAuth)
+			internalAuth = rest.NewInternalAuthenticator(a.Config.InternalAuth)
+
+			// ----- BLOCKCHAIN CLIENT (internal HTTP) -----
+			blockchainClient := httpclient.NewClient(
+				a.Config.BlockchainClientConf,
+				httpclient.WithInternalAuth(internalTokens, "blockchain-client"),
+			)
+
+			// ----- ATTRIBUTE VAULT (local KMS stand-in) -----
+			keyManager, err := vault.NewLocalKeyManager(a.Config.VaultConf.MasterKeyId, a.Config.GetVaultMasterKey())
+			if err != nil {
+				panic(err)
+			}
+			attributeVault = vault.NewVault(vault.NewRepository(), keyManager)
+			vaultHandler = vault.NewVaultHandler(attributeVault)
+
 			// ----- ZKP SERVICE FIXED -----
 			svc := zkprequest.NewService(
 				&zkprequest.InMemoryStore{},
@@ -55,15 +110,44 @@ func main() {
 				func(s *zkprequest.Service) {
 					s.TTL = 5 * time.Minute
 				},
+				func(s *zkprequest.Service) {
+					s.Identity = verifierIdentity
+				},
 			)
 
-			zkpHandler = zkprequest.NewHandler(svc)
+			// ----- RATE LIMITING (public endpoints) -----
+			rateLimiter = rest.NewRateLimiter(
+				rest.NewInMemoryRateLimitStore(),
+				a.Config.RateLimitConf,
+			)
+
+			zkpService = svc
+			zkpHandler = zkprequest.NewHandler(svc).WithRateLimiter(rateLimiter)
 
 			// ----- LOG AUDIT SERVICE -----
 			logAuditRepo := logaudit.NewLogAuditRepository()
 			logAuditService := logaudit.NewLogAuditService(logAuditRepo)
 			logAuditHandler = logaudit.NewLogAuditHandler(logAuditService)
 
+			// ----- PROOF HISTORY -----
+			proofService := proofs.NewProofService(
+				proofs.NewProofRepository(),
+				proofs.NewHttpBlockchainVerifier(blockchainClient),
+			)
+			proofHandler = proofs.NewProofHandler(proofService)
+
+			// ----- PRIVACY (export + right to erasure) -----
+			privacyService := privacy.NewPrivacyService(
+				privacy.NewRepository(),
+				attributeVault,
+				privacy.NewHttpChainCloser(blockchainClient),
+			)
+			privacyHandler = privacy.NewPrivacyHandler(privacyService)
+
+			// ----- FAILURE ADMIN (internal) -----
+			failureService := failures.NewFailureService(failures.NewFailureRepository(), outbox.NewRepo())
+			failureHandler = failures.NewFailureHandler(failureService)
+
 			// ----- FRONT (CSS + HTML TEMPLATE) TYLKO DLA identity-api -----
 			a.ServeTemplates = true
 			a.TemplatesGlob = "templates/*.html" // zkp_request_presentation.html
@@ -71,42 +155,64 @@ func main() {
 			a.ServeCSS = true
 			a.CSSRoute = "/app.css"
 			a.CSSFilePath = "./static/app.css"
+
+			a.TrustedProxies = a.Config.RestConf.TrustedProxies
 		}).
 
 		// ----- RABBITMQ -----
 		InitRabbitmqConnection().
 		InitRabbitmqRegistries().
+		AddRabbitmqLogSink("LogPublisher").
 		WithOption(func(a *appbuilder.AppBuilder[ApiConfigJson, ApiConfig]) {
-			// ----- RABBITMQ LOGGING SINK -----
-			logPublisher := rabbitmq.GetPublisher("LogPublisher")
-			loggerInstance := logger.Default()
-			logSink := rabbitmq.CreateRabbitmqLoggerSink(logPublisher)
-			logger.AddSinkToLoggerInstance(loggerInstance, logSink)
+			// ----- IDENTITY (needs database and publishers) -----
+			identityHandler = identity.NewHandler(attributeVault)
+
+			// ----- WORKERS -----
+			a.AddWorkerServices(
+				zkpfailed.NewZeroKnowledgeProofFailedHandler(a.Registry, zkpfailed.NewFailedZkpService()),
+				zkpresult.NewZeroKnowledgeProofHandler(a.Registry),
+				outbox.NewOutboxWorker(a.Registry, outbox.NewRepo()),
+				logaudit.NewLogSinkWorker(a.Registry),
+			)
 		}).
-		// ----- WORKERS -----
-		AddWorkerServices(
-			zkpfailed.NewZeroKnowledgeProofFailedHandler(),
-			zkpresult.NewZeroKnowledgeProofHandler(),
-			outbox.NewOutboxWorker(),
-			logaudit.NewLogSinkWorker(),
+
+		// ----- HEALTH (/healthz, /readyz) -----
+		AddHealthChecks(
+			database.HealthCheck(),
+			health.NewCheck("artifact_store", time.Second, zkpService.CheckArtifactStore),
 		).
 
 		// ----- CORS (ONE GOOD MIDDLEWARE) -----
 		AddGinMiddleware(
 			rest.NewMiddleware("*", middleware.CORSMiddleware()),
-			rest.NewMiddleware("v1/internal", rest.InternalAuthMiddleware()),
+			rest.NewMiddleware("*", rest.RequestIdMiddleware()),
+			rest.NewMiddleware("v1/internal", rest.InternalAuthMiddleware(internalAuth)),
+			rest.NewMiddleware("v1", rateLimiter.Middleware(rest.RateLimitBucketDefault)),
 		).
 
 		// ----- ROUTES -----
 		AddGinRoutes(
-			rest.NewRoute(rest.POST, "v1", "identity", identity.NewHandler().CreateIdentity),
-			rest.NewRoute(rest.GET, "v1", "identity/:id", identity.NewHandler().GetIdentity),
-			rest.NewRoute(rest.POST, "v1", "identity/verify", identity.NewHandler().QueueVerification),
+			rest.NewRoute(rest.GET, "", ".well-known/did.json", zkpHandler.DIDDocument),
+			rest.NewRoute(rest.POST, "v1", "identity", identityHandler.CreateIdentity),
+			rest.NewRoute(rest.GET, "v1", "identity", identityHandler.ListIdentities),
+			rest.NewRoute(rest.GET, "v1", "identity/:id", identityHandler.GetIdentity),
+			rest.NewRoute(rest.PUT, "v1", "identity/:id", identityHandler.UpdateIdentity),
+			rest.NewRoute(rest.DELETE, "v1", "identity/:id", identityHandler.DeleteIdentity),
+			rest.NewRoute(rest.PUT, "v1", "identity/:id/parent", identityHandler.MoveIdentity),
+			rest.NewRoute(rest.GET, "v1", "identity/:id/children", identityHandler.GetChildren),
+			rest.NewRoute(rest.GET, "v1", "identity/:id/ancestors", identityHandler.GetAncestors),
+			rest.NewRoute(rest.GET, "v1", "identity/:id/proofs", proofHandler.GetIdentityProofs),
+			rest.NewRoute(rest.GET, "v1", "identity/:id/export", privacyHandler.ExportIdentity),
+			rest.NewRoute(rest.DELETE, "v1", "identity/:id/data", privacyHandler.EraseIdentity),
+			rest.NewRoute(rest.POST, "v1", "identity/verify", identityHandler.QueueVerification),
+
+			// PROOF ROUTES:
+			rest.NewRoute(rest.GET, "v1", "proofs/:id", proofHandler.GetProof),
 
 			// ZKP Presentation Request
 			rest.NewRoute(rest.POST, "v1", "presentations/create", zkpHandler.CreatePresentation),
 
-			rest.NewRoute(rest.POST, "v1", "presentations/verify", zkpHandler.VerifyPresentation),
+			rest.NewRoute(rest.POST, "v1", "presentations/verify", rateLimiter.Wrap(rest.RateLimitBucketVerify, zkpHandler.VerifyPresentation)),
 			rest.NewRoute(rest.GET, "v1", "presentations/:request_id", zkpHandler.ShowPresentation),
 			rest.NewRoute(rest.GET, "v1", "presentations/:request_id/descriptor", zkpHandler.Descriptor),
 			rest.NewRoute(rest.GET, "v1", "presentations/:request_id/status", zkpHandler.Status),
@@ -117,6 +223,16 @@ func main() {
 			rest.NewRoute(rest.GET, "v1", "artifacts/:hash/vk", zkpHandler.GetVK),
 			rest.NewRoute(rest.GET, "v1", "artifacts/:hash/pk", zkpHandler.GetPK),
 
+			// FAILURE ADMIN ROUTES (internal auth):
+			rest.NewRoute(rest.GET, "v1/internal", "failures", rest.RequireScope(failures.ReadScope, failureHandler.ListFailures)),
+			rest.NewRoute(rest.GET, "v1/internal", "failures/stats", rest.RequireScope(failures.ReadScope, failureHandler.GetFailureStats)),
+			rest.NewRoute(rest.POST, "v1/internal", "failures/discard", rest.RequireScope(failures.WriteScope, failureHandler.DiscardFailures)),
+			rest.NewRoute(rest.GET, "v1/internal", "outbox/:event_id", rest.RequireScope(failures.ReadScope, failureHandler.GetFailedEvent)),
+			rest.NewRoute(rest.POST, "v1/internal", "outbox/:event_id/requeue", rest.RequireScope(failures.WriteScope, failureHandler.RequeueEvent)),
+
+			// ATTRIBUTE RESOLUTION FOR VERIFIERS (internal auth):
+			rest.NewRoute(rest.GET, "v1/internal", "attributes/:reference", rest.RequireScope(vault.ReadScope, vaultHandler.ResolveAttributes)),
+
 			// LOG AUDIT ROUTES:
 			rest.NewRoute(rest.GET, "v1", "logs", logAuditHandler.GetLogEntries),
 			rest.NewRoute(rest.GET, "v1", "logs/service/:service", logAuditHandler.GetLogEntriesByService),
@@ -126,6 +242,7 @@ func main() {
 			rest.NewRoute(rest.POST, "v1", "presentations/verify-blocking", zkpHandler.VerifyBlocking),
 		).
 		AddSwagger().
+		AddMetrics().
 		InitGinRouter().
 		Build().
 		Start()
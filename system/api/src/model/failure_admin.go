@@ -0,0 +1,73 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ProofFailureDto is one reported failure of a verification attempt together
+// with the current state of its outbox event.
+type ProofFailureDto struct {
+	EventId     string       `json:"event_id"`
+	ReasonCode  string       `json:"reason_code"`
+	Error       string       `json:"error"`
+	EventStatus OutboxStatus `json:"event_status,omitempty"`
+	Retry       int          `json:"retry"`
+	DiscardedAt *time.Time   `json:"discarded_at"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+type ProofFailurePage struct {
+	Items  []ProofFailureDto `json:"items"`
+	Total  int64             `json:"total"`
+	Limit  int               `json:"limit"`
+	Offset int               `json:"offset"`
+}
+
+// FailedEventDto is an outbox event with every failure reported for it. The
+// request message is the vault reference, attribute values are never shown.
+type FailedEventDto struct {
+	EventId        string              `json:"event_id"`
+	IdentityId     string              `json:"identity_id"`
+	SchemaId       string              `json:"schema_id"`
+	Status         OutboxStatus        `json:"status"`
+	Retry          int                 `json:"retry"`
+	LastError      string              `json:"last_error,omitempty"`
+	NextAttemptAt  time.Time           `json:"next_attempt_at"`
+	RequestMessage string              `json:"request_message"`
+	Failures       []FailureAttemptDto `json:"failures"`
+	CreatedAt      time.Time           `json:"created_at"`
+}
+
+type FailureAttemptDto struct {
+	ReasonCode  string          `json:"reason_code"`
+	Error       string          `json:"error"`
+	RequestBody json.RawMessage `json:"request_body,omitempty"` // message the consumer failed on
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// DiscardFailuresRequest selects failed events either by id or by reason code.
+type DiscardFailuresRequest struct {
+	EventIds   []string `json:"event_ids"`
+	ReasonCode string   `json:"reason_code"`
+}
+
+type DiscardReportDto struct {
+	Events   int64 `json:"events"`
+	Failures int64 `json:"failures"`
+}
+
+// FailureStatDto counts failures of one reason code within one time bucket.
+type FailureStatDto struct {
+	BucketStart time.Time `json:"bucket_start"`
+	ReasonCode  string    `json:"reason_code"`
+	Count       int64     `json:"count"`
+}
+
+type FailureStatsDto struct {
+	Bucket string           `json:"bucket"`
+	From   time.Time        `json:"from"`
+	To     time.Time        `json:"to"`
+	Totals map[string]int64 `json:"totals"`
+	Series []FailureStatDto `json:"series"`
+}
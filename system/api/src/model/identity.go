@@ -1,9 +1,35 @@
 package model
 
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
 type Identity struct {
 	Id           int       `gorm:"primaryKey;autoIncrement"`
 	IdentityId   string    `gorm:"uniqueIndex;type:uuid;not null"` // public/business ID (e.g., UUID)
-	IdentityName string    `gorm:"not null"` // human-readable name
-	ParentId     *int      // references Id of parent identity (nullable)
-	Parent       *Identity `gorm:"-"` // ignored by GORM for migration
+	IdentityName string    `gorm:"not null"`                       // human-readable name
+	ParentId     *int      `gorm:"index"`                          // references Id of parent identity (nullable)
+	Parent       *Identity `gorm:"-"`                              // ignored by GORM for migration
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DeletedAt    gorm.DeletedAt `gorm:"index"`
+}
+
+// IdentityDto is the public representation of an identity: it only ever
+// exposes UUIDs, never the internal numeric keys.
+type IdentityDto struct {
+	IdentityId   string    `json:"identity_id"`
+	IdentityName string    `json:"identity_name"`
+	ParentId     *string   `json:"parent_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type IdentityPage struct {
+	Items  []IdentityDto `json:"items"`
+	Total  int64         `json:"total"`
+	Limit  int           `json:"limit"`
+	Offset int           `json:"offset"`
 }
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// IdentityExportDto is everything the system stores about one identity, as
+// returned by the data export endpoint.
+type IdentityExportDto struct {
+	Identity      IdentityDto             `json:"identity"`
+	ChildIds      []string                `json:"child_ids"`
+	Verifications []VerificationExportDto `json:"verifications"`
+	Failures      []ProofFailureExportDto `json:"failures"`
+	Proofs        []ProofDto              `json:"proofs"`
+	LogEntries    []LogAuditEntry         `json:"log_entries"`
+	ExportedAt    time.Time               `json:"exported_at"`
+}
+
+// VerificationExportDto is a queued verification request with its attributes
+// decrypted from the vault.
+type VerificationExportDto struct {
+	EventId         string     `json:"event_id"`
+	SchemaId        string     `json:"schema_id"`
+	Attributes      []ZkpField `json:"attributes,omitempty"`
+	AttributesError string     `json:"attributes_error,omitempty"`
+	LegacyData      string     `json:"legacy_data,omitempty"` // raw values queued before the vault existed
+	Retry           int        `json:"retry"`
+	ProcessedAt     *time.Time `json:"processed_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+type ProofFailureExportDto struct {
+	EventId     string `json:"event_id"`
+	RequestBody string `json:"request_body"`
+	Error       string `json:"error"`
+	ReasonCode  string `json:"reason_code"`
+}
+
+// ErasureReportDto summarises what an erasure request removed.
+type ErasureReportDto struct {
+	IdentityId           string    `json:"identity_id"`
+	Verifications        int64     `json:"verifications"`
+	Failures             int64     `json:"failures"`
+	Proofs               int64     `json:"proofs"`
+	ClosedAccounts       int       `json:"closed_accounts"`
+	AnonymizedLogEntries int64     `json:"anonymized_log_entries"`
+	ErasedAt             time.Time `json:"erased_at"`
+}
@@ -7,14 +7,18 @@ import (
 )
 
 type LogAuditEntry struct {
-	ID        uint           `gorm:"primaryKey;autoIncrement" json:"id"`
-	Level     string         `gorm:"type:varchar(10);not null;index" json:"level"`
-	Message   string         `gorm:"type:text;not null" json:"message"`
-	Timestamp time.Time      `gorm:"type:timestamp;not null;index" json:"timestamp"`
-	Service   string         `gorm:"type:varchar(50);not null;index" json:"service"`
-	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Level     string    `gorm:"type:varchar(10);not null;index" json:"level"`
+	Message   string    `gorm:"type:text;not null" json:"message"`
+	Timestamp time.Time `gorm:"type:timestamp;not null;index" json:"timestamp"`
+	Service   string    `gorm:"type:varchar(50);not null;index" json:"service"`
+	// IdentityId is set when the entry concerns an identity, so erasure finds it
+	// without searching the message; Fields holds the other fields as JSON.
+	IdentityId string         `gorm:"type:varchar(64);index" json:"identity_id,omitempty"`
+	Fields     string         `gorm:"type:text" json:"fields,omitempty"`
+	CreatedAt  time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt  time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (LogAuditEntry) TableName() string {
@@ -1,19 +1,33 @@
 package model
 
 import (
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+type OutboxStatus string
+
+const (
+	OutboxPending   OutboxStatus = "pending"   // waiting for the relay, first publish or a retry
+	OutboxPublished OutboxStatus = "published" // handed to RabbitMQ, waiting for a result
+	OutboxProcessed OutboxStatus = "processed" // result stored
+	OutboxDead      OutboxStatus = "dead"      // gave up after MaxRetries attempts
+	OutboxDiscarded OutboxStatus = "discarded" // dropped by an operator, never retried
+)
+
 type OutboxEvent struct {
-	Id             uint           `gorm:"primaryKey;autoIncrement"`
-	EventId        string         `gorm:"uniqueIndex;type:uuid;not null"`
-	IdentityId     string         `gorm:"type:uuid;not null"`
-	SchemaId       string         `gorm:"type:uuid;not null"`
-	Retry          int            `gorm:"default:0"`
-	ToProcess      bool           `gorm:"default:false;index"`
-	RequestMessage string         `gorm:"type:text;not null"`
+	Id             uint         `gorm:"primaryKey;autoIncrement"`
+	EventId        string       `gorm:"uniqueIndex;type:uuid;not null"`
+	IdentityId     string       `gorm:"type:uuid;not null"`
+	SchemaId       string       `gorm:"type:uuid;not null"`
+	Retry          int          `gorm:"default:0"` // failed attempts so far
+	Status         OutboxStatus `gorm:"type:varchar(16);not null;default:pending;index:idx_outbox_due,priority:1"`
+	NextAttemptAt  time.Time    `gorm:"not null;default:CURRENT_TIMESTAMP;index:idx_outbox_due,priority:2"`
+	LastError      string       `gorm:"type:text"`
+	PublishedAt    *time.Time
+	RequestMessage string         `gorm:"type:text;not null"` // vault reference, never raw attribute values
 	ProcessedAt    gorm.DeletedAt `gorm:"index"`
 	CreatedAt      time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP"`
 	UpdatedAt      time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP"`
@@ -23,9 +37,17 @@ type OutboxEvent struct {
 	Schema   VerifiedSchema `gorm:"-"` // Reference handled by SchemaId field
 }
 
+// IdempotencyKey identifies one publish attempt of the event: redeliveries of
+// the same attempt share it, while a retry after a failure gets a new one.
+func (oe OutboxEvent) IdempotencyKey() string {
+	return fmt.Sprintf("%s:%d", oe.EventId, oe.Retry)
+}
+
 func (oe OutboxEvent) MapToZkpVerifcationRequest() ZeroKnowledgeProofToVerification {
 	return ZeroKnowledgeProofToVerification{
-		EventId: oe.EventId,
-		Data:    oe.RequestMessage, // might need to strip some data here
+		EventId:        oe.EventId,
+		IdempotencyKey: oe.IdempotencyKey(),
+		Data:           oe.RequestMessage,
+		IdentityId:     oe.IdentityId,
 	}
 }
@@ -1,9 +1,13 @@
 package model
 
+import "time"
+
 type ZkpProofFailure struct {
 	Id          int `gorm:"primaryKey;autoIncrement"`
 	EventId     string
 	RequestBody []byte
 	Error       string
-	ReasonCode  string
+	ReasonCode  string     `gorm:"index"`
+	DiscardedAt *time.Time // set when an operator discards the failed event
+	CreatedAt   time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP;index"`
 }
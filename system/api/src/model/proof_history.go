@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// ProofDto is the public view of a stored proof, keyed by UUIDs only.
+type ProofDto struct {
+	ProofId        string      `json:"proof_id"`
+	IdentityId     string      `json:"identity_id"`
+	SchemaId       string      `json:"schema_id"`
+	Signature      string      `json:"signature"`
+	AccountId      string      `json:"account_id"`
+	ProofSize      int         `json:"proof_size"`
+	Status         ProofStatus `json:"status"`
+	LastVerifiedAt *time.Time  `json:"last_verified_at"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+}
+
+type ProofPage struct {
+	Items  []ProofDto `json:"items"`
+	Total  int64      `json:"total"`
+	Limit  int        `json:"limit"`
+	Offset int        `json:"offset"`
+}
+
+// ProofVerificationDto is returned when a proof is re-verified on chain.
+type ProofVerificationDto struct {
+	ProofDto
+	VerificationError string `json:"verification_error,omitempty"`
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// IdentityDataKey is the per-identity data key, stored only in its wrapped
+// (KMS-encrypted) form. Deleting it crypto-shreds every VaultRecord of the
+// identity.
+type IdentityDataKey struct {
+	Id         int    `gorm:"primaryKey;autoIncrement"`
+	IdentityId string `gorm:"uniqueIndex;type:uuid;not null"`
+	KeyId      string `gorm:"not null"` // id of the master key that wrapped it
+	WrappedKey []byte `gorm:"not null"`
+	CreatedAt  time.Time
+}
+
+// VaultRecord holds a set of identity attributes encrypted under the
+// identity's data key. Other tables reference it by RecordId only.
+type VaultRecord struct {
+	Id         int    `gorm:"primaryKey;autoIncrement"`
+	RecordId   string `gorm:"uniqueIndex;type:uuid;not null"`
+	IdentityId string `gorm:"index;type:uuid;not null"`
+	Nonce      []byte `gorm:"not null"`
+	Ciphertext []byte `gorm:"not null"`
+	CreatedAt  time.Time
+}
+
+// VaultAttributesDto is what a verifier gets back when it resolves the
+// attribute reference of a verification request.
+type VaultAttributesDto struct {
+	Reference  string     `json:"reference"`
+	Attributes []ZkpField `json:"attributes"`
+}
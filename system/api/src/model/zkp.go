@@ -2,17 +2,33 @@ package model
 
 import (
 	"pkg-common/utilities"
+	"time"
 
 	"github.com/consensys/gnark/backend/groth16"
 	"github.com/consensys/gnark/backend/witness"
 )
 
+type ProofStatus string
+
+const (
+	ProofStored   ProofStatus = "stored"   // written on chain, never re-verified
+	ProofVerified ProofStatus = "verified" // last on-chain re-verification succeeded
+	ProofInvalid  ProofStatus = "invalid"  // last on-chain re-verification failed
+)
+
 type ZeroKnowledgeProof struct {
-	Id                      int `gorm:"primaryKey;autoIncrement"`
-	DigitalIdentitySchemaId int // foreign key to VerifiedSchema
-	SuperIdentityId         int // foreign key
-	ProofReference          string
-	AccountId               string
+	Id                      int    `gorm:"primaryKey;autoIncrement"`
+	ProofId                 string `gorm:"uniqueIndex;type:uuid"` // public ID
+	EventId                 string `gorm:"index"`
+	DigitalIdentitySchemaId int    `gorm:"index"` // foreign key to VerifiedSchema
+	SuperIdentityId         int    `gorm:"index"` // foreign key
+	ProofReference          string // Solana transaction signature
+	AccountId               string // Solana account holding the proof
+	ProofSize               int
+	Status                  ProofStatus `gorm:"default:stored"`
+	LastVerifiedAt          *time.Time
+	CreatedAt               time.Time `gorm:"index"`
+	UpdatedAt               time.Time
 }
 
 type ZeroKnowledgeProofVerificationRequest struct {
@@ -50,9 +66,36 @@ type ZeroKnowledgeProofResult struct {
 	TxHash        string
 }
 
+// ZkpVerificationRequestedType is the message type of requests to the verifier.
+const ZkpVerificationRequestedType = "zkp.verification.requested"
+
 type ZeroKnowledgeProofToVerification struct {
-	EventId string `json:"event_id"`
-	Data    string `json:"data"`
+	EventId        string `json:"event_id"`
+	IdempotencyKey string `json:"idempotency_key"`
+	Data           string `json:"data"` // vault reference to the attributes to prove
+	IdentityId     string `json:"-"`    // only travels as the ordering key header
+}
+
+// MessageId is published as the AMQP message id so consumers can drop
+// redeliveries of the same attempt.
+func (req ZeroKnowledgeProofToVerification) MessageId() string {
+	return req.IdempotencyKey
+}
+
+// CorrelationId ties the request, the verifier's answer and the blockchain
+// client's result to the outbox event.
+func (req ZeroKnowledgeProofToVerification) CorrelationId() string {
+	return req.EventId
+}
+
+func (req ZeroKnowledgeProofToVerification) MessageType() string {
+	return ZkpVerificationRequestedType
+}
+
+// OrderingKey keeps the verifications of one identity in order when the
+// blockchain client handles requests concurrently.
+func (req ZeroKnowledgeProofToVerification) OrderingKey() string {
+	return req.IdentityId
 }
 
 func (req ZeroKnowledgeProofToVerification) Serialize() ([]byte, error) {
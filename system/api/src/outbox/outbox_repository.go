@@ -3,20 +3,82 @@ package outbox
 import (
 	"api/src/database"
 	"api/src/model"
+	"context"
+	"errors"
+	"pkg-common/metrics"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-const MaxRetries = 5
+const (
+	MaxRetries  = 5
+	BaseBackoff = 10 * time.Second
+	MaxBackoff  = 10 * time.Minute
+
+	// ResultTimeout is how long a published event waits for a result before
+	// the attempt is counted as failed
+	ResultTimeout = 15 * time.Minute
+)
+
+// Backoff returns the delay before the attempt following the given number of
+// failed attempts, doubling from BaseBackoff up to MaxBackoff.
+func Backoff(failedAttempts int) time.Duration {
+	delay := BaseBackoff
+	for i := 1; i < failedAttempts; i++ {
+		delay *= 2
+		if delay >= MaxBackoff {
+			return MaxBackoff
+		}
+	}
+	return delay
+}
+
+// ErrNotRequeueable is returned for events that are in flight or already
+// processed.
+var ErrNotRequeueable = errors.New("outbox event cannot be requeued")
+
+// Requeueable reports whether an operator may requeue event: any event that
+// is not processed, except one published less than ResultTimeout ago, whose
+// result may still arrive.
+func Requeueable(event model.OutboxEvent, now time.Time) bool {
+	if event.Status == model.OutboxProcessed || event.ProcessedAt.Valid {
+		return false
+	}
+	if event.Status == model.OutboxPublished {
+		return event.PublishedAt != nil && now.Sub(*event.PublishedAt) >= ResultTimeout
+	}
+	return true
+}
 
 type OutboxRepository interface {
 	GetEvent(uuid.UUID) (model.OutboxEvent, error)
 	NewEvent(identityId, schemaId uuid.UUID, reqestMsg string) (uuid.UUID, error)
-	GetUnprocessedEvents() ([]model.OutboxEvent, error)
+	// ClaimDueEvents locks up to limit pending events whose next attempt is
+	// due, skipping rows claimed by other relays, and hands each to publish.
+	// Published events move to OutboxPublished, failed ones are rescheduled.
+	// The rows stay locked until all are handled, so once ctx is done the
+	// remaining events are left pending for the next run.
+	ClaimDueEvents(ctx context.Context, limit int, publish func(context.Context, model.OutboxEvent) error) (int, error)
+	CountPending() (int64, error)
+	// SweepStalePublished counts a failed attempt for up to limit events
+	// published before olderThan that are still waiting for a result, so a
+	// lost message is retried or dead-lettered instead of waiting forever.
+	SweepStalePublished(olderThan time.Time, limit int) (int, error)
 	MarkEventAsProcessed(uuid.UUID) error
-	UpdateRetryValue(eventId uuid.UUID) error
+	// ScheduleRetry records a failed attempt of a published event and either
+	// schedules the next one or dead-letters the event. Events that are not
+	// waiting for a result are left alone, so duplicate failures are no-ops.
+	ScheduleRetry(eventId uuid.UUID, reason string) error
+	// Requeue makes a pending, dead or discarded event, or a published one
+	// that got no result within ResultTimeout, due immediately. The retry
+	// count is kept, so a dead-lettered event gets one more attempt.
+	Requeue(eventId uuid.UUID) error
+	// Discard stops retrying the given events unless they are in flight or
+	// processed, and returns how many were discarded.
+	Discard(eventIds []string) (int64, error)
 	WithTx(*gorm.DB) OutboxRepository
 }
 
@@ -62,7 +124,8 @@ func (or *outboxRepository) NewEvent(identityId, schemaId uuid.UUID, reqestMsg s
 		IdentityId:     identityId.String(),
 		SchemaId:       schemaId.String(),
 		RequestMessage: reqestMsg,
-		ToProcess:      false,
+		Status:         model.OutboxPending,
+		NextAttemptAt:  time.Now(),
 		Retry:          0,
 	}
 
@@ -70,10 +133,76 @@ func (or *outboxRepository) NewEvent(identityId, schemaId uuid.UUID, reqestMsg s
 	return eventId, result.Error
 }
 
-func (or *outboxRepository) GetUnprocessedEvents() ([]model.OutboxEvent, error) {
-	var events []model.OutboxEvent
-	result := or.db.Model(&model.OutboxEvent{}).Where("to_process = ?", true).Find(&events)
-	return events, result.Error
+func (or *outboxRepository) ClaimDueEvents(ctx context.Context, limit int, publish func(context.Context, model.OutboxEvent) error) (int, error) {
+	published := 0
+	err := or.db.Transaction(func(tx *gorm.DB) error {
+		var events []model.OutboxEvent
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", model.OutboxPending, time.Now()).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&events).Error
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			if ctx.Err() != nil {
+				break
+			}
+			if err := publish(ctx, event); err != nil {
+				if err := scheduleRetry(tx, event, err.Error()); err != nil {
+					return err
+				}
+				continue
+			}
+
+			now := time.Now()
+			err := tx.Model(&model.OutboxEvent{}).
+				Where("id = ?", event.Id).
+				Updates(map[string]interface{}{
+					"status":       model.OutboxPublished,
+					"published_at": now,
+					"updated_at":   now,
+				}).Error
+			if err != nil {
+				return err
+			}
+			published++
+		}
+		return nil
+	})
+	return published, err
+}
+
+func (or *outboxRepository) CountPending() (int64, error) {
+	var count int64
+	err := or.db.Model(&model.OutboxEvent{}).Where("status = ?", model.OutboxPending).Count(&count).Error
+	return count, err
+}
+
+func (or *outboxRepository) SweepStalePublished(olderThan time.Time, limit int) (int, error) {
+	swept := 0
+	err := or.db.Transaction(func(tx *gorm.DB) error {
+		var events []model.OutboxEvent
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND published_at < ?", model.OutboxPublished, olderThan).
+			Order("published_at").
+			Limit(limit).
+			Find(&events).Error
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			if err := scheduleRetry(tx, event, "no result received after publishing"); err != nil {
+				return err
+			}
+			swept++
+		}
+		return nil
+	})
+	return swept, err
 }
 
 func (or *outboxRepository) MarkEventAsProcessed(eventId uuid.UUID) error {
@@ -90,36 +219,92 @@ func (or *outboxRepository) MarkEventAsProcessed(eventId uuid.UUID) error {
 		// Set processed_at to current time (soft delete)
 		result := tx.Model(&model.OutboxEvent{}).
 			Where("event_id = ?", eventId.String()).
-			Update("processed_at", time.Now())
+			Updates(map[string]interface{}{
+				"status":       model.OutboxProcessed,
+				"processed_at": time.Now(),
+			})
 		return result.Error
 	})
 }
 
-// TODO: optimize this query
-func (or *outboxRepository) UpdateRetryValue(eventId uuid.UUID) error {
+func (or *outboxRepository) ScheduleRetry(eventId uuid.UUID, reason string) error {
 	return or.db.Transaction(func(tx *gorm.DB) error {
-		repo := or.WithTx(tx)
-
-		// Get the event first
-		res, err := repo.GetEvent(eventId)
+		var event model.OutboxEvent
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&event, "event_id = ?", eventId.String()).Error
 		if err != nil {
 			return err
 		}
+		if event.Status != model.OutboxPublished {
+			return nil
+		}
+		return scheduleRetry(tx, event, reason)
+	})
+}
 
-		// Update retry count
-		err = tx.Model(&model.OutboxEvent{}).
-			Where("event_id = ?", eventId.String()).
-			Update("retry", res.Retry+1).
-			Error
+// scheduleRetry counts a failed attempt and moves the event back to pending
+// after a backoff, or to the dead-letter state once MaxRetries is reached.
+func scheduleRetry(tx *gorm.DB, event model.OutboxEvent, reason string) error {
+	retry := event.Retry + 1
+	now := time.Now()
+	updates := map[string]interface{}{
+		"retry":      retry,
+		"last_error": reason,
+		"updated_at": now,
+	}
+
+	if retry >= MaxRetries {
+		updates["status"] = model.OutboxDead
+	} else {
+		updates["status"] = model.OutboxPending
+		updates["next_attempt_at"] = now.Add(Backoff(retry))
+	}
+
+	result := tx.Model(&model.OutboxEvent{}).Where("id = ?", event.Id).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("outbox event disappeared while rescheduling")
+	}
+	if retry >= MaxRetries {
+		metrics.OutboxDeadLettered.Inc()
+	}
+	return nil
+}
+
+func (or *outboxRepository) Requeue(eventId uuid.UUID) error {
+	return or.db.Transaction(func(tx *gorm.DB) error {
+		var event model.OutboxEvent
+		err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&event, "event_id = ?", eventId.String()).Error
 		if err != nil {
 			return err
 		}
-
-		// If max retries reached, mark as processed
-		if res.Retry >= MaxRetries {
-			return repo.MarkEventAsProcessed(eventId)
+		now := time.Now()
+		if !Requeueable(event, now) {
+			return ErrNotRequeueable
 		}
 
-		return nil
+		return tx.Model(&model.OutboxEvent{}).
+			Where("id = ?", event.Id).
+			Updates(map[string]interface{}{
+				"status":          model.OutboxPending,
+				"next_attempt_at": now,
+				"updated_at":      now,
+			}).Error
 	})
 }
+
+func (or *outboxRepository) Discard(eventIds []string) (int64, error) {
+	if len(eventIds) == 0 {
+		return 0, nil
+	}
+	result := or.db.Model(&model.OutboxEvent{}).
+		Where("event_id IN ? AND status IN ?", eventIds, []model.OutboxStatus{model.OutboxPending, model.OutboxDead}).
+		Updates(map[string]interface{}{
+			"status":     model.OutboxDiscarded,
+			"updated_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
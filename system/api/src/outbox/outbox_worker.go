@@ -1,24 +1,48 @@
 package outbox
 
 import (
+	"context"
+	"fmt"
 	"pkg-common/logger"
+	"pkg-common/metrics"
 	"pkg-common/rabbitmq"
+	"sync"
+	"time"
+
+	"api/src/model"
 
 	"github.com/robfig/cron"
 )
 
-const outboxWorkerName = "OutboxCronWorker"
+const (
+	outboxWorkerName = "OutboxCronWorker"
+
+	// relayBatchSize bounds how many rows one relay run keeps locked
+	relayBatchSize = 100
+	// relayRunTimeout bounds how long one relay run keeps its rows locked;
+	// events it did not get to stay pending for the next run
+	relayRunTimeout = 30 * time.Second
+	// publishTimeout bounds the wait for the broker confirm of one event
+	publishTimeout = 5 * time.Second
+)
 
+// OutboxWorker is the relay of the transactional outbox: events are written
+// in the same transaction as the business data and only this worker
+// publishes them.
 type OutboxWorker struct {
 	publisher  rabbitmq.IRabbitmqPublisher
 	repository OutboxRepository
 	cron       *cron.Cron
+
+	// relayMu is held for the duration of a relay run so Stop can wait for it
+	relayMu sync.Mutex
+	stopped bool
 }
 
-func NewOutboxWorker() rabbitmq.WorkerService {
+func NewOutboxWorker(registry *rabbitmq.Registry, repository OutboxRepository) rabbitmq.WorkerService {
 	return &OutboxWorker{
-		publisher:  rabbitmq.GetPublisher("VerifiersUnverifiedPublisher"),
-		repository: NewRepo(),
+		publisher:  registry.Publisher("VerifiersUnverifiedPublisher"),
+		repository: repository,
 		cron:       cron.New(),
 	}
 }
@@ -27,28 +51,75 @@ func (ow *OutboxWorker) GetServiceName() string {
 	return outboxWorkerName
 }
 
-func (ow *OutboxWorker) StartService() {
-	// change based on env for dev minute for prod hour
-	err := ow.cron.AddFunc("@every 1m", func() { ow.processOutboxEvents() })
+func (ow *OutboxWorker) StartService(ctx context.Context) error {
+	err := ow.cron.AddFunc("@every 2s", func() { ow.processOutboxEvents() })
 	if err != nil {
 		logger.Default().Errorf(err, "Could not add function to %s", outboxWorkerName)
+		return err
 	}
 
 	ow.cron.Start()
+	<-ctx.Done()
+	return nil
+}
+
+// Stop halts the schedule and waits for a relay run that is still publishing.
+// Events it has not published yet stay pending for the next instance.
+func (ow *OutboxWorker) Stop(ctx context.Context) error {
+	ow.cron.Stop()
+
+	idle := make(chan struct{})
+	go func() {
+		ow.relayMu.Lock()
+		ow.stopped = true
+		ow.relayMu.Unlock()
+		close(idle)
+	}()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("outbox relay run still in progress: %w", ctx.Err())
+	}
 }
 
 func (ow *OutboxWorker) processOutboxEvents() {
+	ow.relayMu.Lock()
+	defer ow.relayMu.Unlock()
+	if ow.stopped {
+		return
+	}
+
 	outboxLogger := logger.Default()
 
-	events, err := ow.repository.GetUnprocessedEvents()
+	swept, err := ow.repository.SweepStalePublished(time.Now().Add(-ResultTimeout), relayBatchSize)
 	if err != nil {
-		outboxLogger.Error(err, "Could not read events from database")
+		outboxLogger.Error(err, "Could not sweep stale outbox events")
+	}
+	if swept > 0 {
+		outboxLogger.Warnf("%d outbox events got no result within %s and were rescheduled", swept, ResultTimeout)
 	}
 
-	for _, e := range events {
-		err := ow.publisher.Publish(e.MapToZkpVerifcationRequest())
+	ctx, cancel := context.WithTimeout(context.Background(), relayRunTimeout)
+	defer cancel()
+	published, err := ow.repository.ClaimDueEvents(ctx, relayBatchSize, func(ctx context.Context, e model.OutboxEvent) error {
+		ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+		defer cancel()
+		err := ow.publisher.PublishConfirmed(ctx, e.MapToZkpVerifcationRequest())
 		if err != nil {
-			outboxLogger.Error(err, "Can't publish to queue")
+			outboxLogger.With(logger.IdentityIdKey, e.IdentityId).Errorf(err, "Can't publish outbox event %s", e.EventId)
 		}
+		return err
+	})
+	if err != nil {
+		outboxLogger.Error(err, "Could not relay outbox events")
+	}
+	if published > 0 {
+		outboxLogger.Debugf("Relayed %d outbox events", published)
+	}
+
+	if backlog, err := ow.repository.CountPending(); err == nil {
+		metrics.OutboxBacklog.Set(float64(backlog))
 	}
 }
@@ -0,0 +1,44 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	httpclient "pkg-common/http_client"
+)
+
+// ErrChainUnavailable means a proof account could not be closed; erasure is
+// aborted before any row is deleted so the request can be retried.
+var ErrChainUnavailable = errors.New("blockchain client unavailable")
+
+const CloseAccountScope = "accounts:close"
+
+// ChainCloser closes the Solana accounts that hold an identity's proofs.
+type ChainCloser interface {
+	CloseAccount(ctx context.Context, account string) error
+}
+
+// httpChainCloser calls the internal close endpoint of blockchain-client. An
+// account that no longer exists counts as closed, so retries are idempotent.
+type httpChainCloser struct {
+	client *httpclient.Client
+}
+
+func NewHttpChainCloser(client *httpclient.Client) ChainCloser {
+	return &httpChainCloser{
+		client: client,
+	}
+}
+
+func (c *httpChainCloser) CloseAccount(ctx context.Context, account string) error {
+	_, err := c.client.Send(ctx, http.MethodDelete, "/v1/internal/accounts/"+url.PathEscape(account), nil,
+		httpclient.WithScopes(CloseAccountScope))
+
+	var httpErr *httpclient.HTTPError
+	if err == nil || (errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound) {
+		return nil
+	}
+	return fmt.Errorf("%w: closing account %s: %v", ErrChainUnavailable, account, err)
+}
@@ -0,0 +1,69 @@
+package privacy
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PrivacyHandler struct {
+	service PrivacyService
+}
+
+func NewPrivacyHandler(service PrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{
+		service: service,
+	}
+}
+
+// ExportIdentity godoc
+// @Summary      Export identity data
+// @Description  Returns everything stored about an identity: the identity, its verification requests with decrypted attributes, failures, proofs and log entries mentioning it
+// @Tags         Privacy
+// @Produce      json
+// @Param        id   path      string  true  "Identity ID"
+// @Success      200  {object}  model.IdentityExportDto
+// @Failure      404  {object}  map[string]string
+// @Router       /v1/identity/{id}/export [get]
+func (h *PrivacyHandler) ExportIdentity(c *gin.Context) {
+	export, err := h.service.ExportIdentity(c.Param("id"))
+	if err != nil {
+		respondWithError(c, err, "Failed to export identity data")
+		return
+	}
+	c.JSON(http.StatusOK, export)
+}
+
+// EraseIdentity godoc
+// @Summary      Erase identity data
+// @Description  Right to erasure: shreds the identity's encrypted attributes, closes its proof accounts on chain, deletes its rows and anonymizes log entries mentioning it
+// @Tags         Privacy
+// @Produce      json
+// @Param        id   path      string  true  "Identity ID"
+// @Success      200  {object}  model.ErasureReportDto
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      502  {object}  map[string]string
+// @Router       /v1/identity/{id}/data [delete]
+func (h *PrivacyHandler) EraseIdentity(c *gin.Context) {
+	report, err := h.service.EraseIdentity(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondWithError(c, err, "Failed to erase identity data")
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+func respondWithError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, ErrIdentityNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrHasChildren):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrChainUnavailable):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+	}
+}
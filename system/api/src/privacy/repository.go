@@ -0,0 +1,193 @@
+package privacy
+
+import (
+	"api/src/database"
+	"api/src/model"
+	"api/src/proofs"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// erasedPlaceholder replaces identifiers of an erased identity in log messages.
+const erasedPlaceholder = "[erased]"
+
+// IdentityData is every row that belongs to one identity.
+type IdentityData struct {
+	Identity   model.Identity
+	ParentId   *string
+	ChildIds   []string
+	Events     []model.OutboxEvent
+	Failures   []model.ZkpProofFailure
+	Proofs     []proofs.ProofRecord
+	LogEntries []model.LogAuditEntry
+}
+
+// identifiers returns the public IDs that can appear in log messages.
+func (d *IdentityData) identifiers() []string {
+	ids := []string{d.Identity.IdentityId}
+	for _, e := range d.Events {
+		ids = append(ids, e.EventId)
+	}
+	for _, p := range d.Proofs {
+		ids = append(ids, p.ProofId)
+	}
+	return ids
+}
+
+type ErasureResult struct {
+	Events     int64
+	Failures   int64
+	Proofs     int64
+	LogEntries int64
+}
+
+type Repository interface {
+	GetIdentityData(identityId string) (*IdentityData, error)
+	// Erase deletes the identity's rows, anonymizes log entries mentioning it
+	// and records the audit entry, all in one transaction.
+	Erase(data *IdentityData, audit model.LogAuditEntry) (ErasureResult, error)
+	CreateAuditEntry(audit model.LogAuditEntry) error
+}
+
+type gormRepository struct {
+	db *gorm.DB
+}
+
+func NewRepository() Repository {
+	return &gormRepository{db: database.GetDatabaseConnection()}
+}
+
+// NewRepositoryWithDB creates a repository on db, for tests.
+func NewRepositoryWithDB(db *gorm.DB) Repository {
+	return &gormRepository{db: db}
+}
+
+func (r *gormRepository) GetIdentityData(identityId string) (*IdentityData, error) {
+	// soft-deleted identities and children still hold personal data, which
+	// export and erasure must cover
+	var data IdentityData
+	if err := r.db.Unscoped().Where("identity_id = ?", identityId).First(&data.Identity).Error; err != nil {
+		return nil, err
+	}
+
+	if data.Identity.ParentId != nil {
+		var parent model.Identity
+		err := r.db.Unscoped().Select("identity_id").First(&parent, *data.Identity.ParentId).Error
+		if err != nil {
+			return nil, err
+		}
+		data.ParentId = &parent.IdentityId
+	}
+
+	err := r.db.Unscoped().Model(&model.Identity{}).
+		Where("parent_id = ?", data.Identity.Id).
+		Order("id").
+		Pluck("identity_id", &data.ChildIds).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// processed events are soft deleted, they still belong to the identity
+	err = r.db.Unscoped().Where("identity_id = ?", identityId).Order("id").Find(&data.Events).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if eventIds := eventIds(data.Events); len(eventIds) > 0 {
+		err = r.db.Where("event_id IN ?", eventIds).Order("id").Find(&data.Failures).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = r.db.Table("zero_knowledge_proofs AS p").
+		Joins("JOIN verified_schemas AS s ON s.id = p.digital_identity_schema_id").
+		Select("p.*, ? AS identity_id, s.schema_id", identityId).
+		Where("p.super_identity_id = ?", data.Identity.Id).
+		Order("p.created_at, p.id").
+		Scan(&data.Proofs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.logEntriesQuery(r.db, data.identifiers()).Order("timestamp").Find(&data.LogEntries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+func (r *gormRepository) Erase(data *IdentityData, audit model.LogAuditEntry) (ErasureResult, error) {
+	var result ErasureResult
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		ids := data.identifiers()
+
+		// anonymize first: the identifiers are only known while the rows exist
+		var entries []model.LogAuditEntry
+		if err := r.logEntriesQuery(tx, ids).Find(&entries).Error; err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			message, fields := entry.Message, entry.Fields
+			for _, id := range ids {
+				message = strings.ReplaceAll(message, id, erasedPlaceholder)
+				fields = strings.ReplaceAll(fields, id, erasedPlaceholder)
+			}
+			anonymized := map[string]any{"message": message, "fields": fields, "identity_id": ""}
+			if err := tx.Model(&entry).Updates(anonymized).Error; err != nil {
+				return err
+			}
+		}
+		result.LogEntries = int64(len(entries))
+
+		if eventIds := eventIds(data.Events); len(eventIds) > 0 {
+			res := tx.Where("event_id IN ?", eventIds).Delete(&model.ZkpProofFailure{})
+			if res.Error != nil {
+				return res.Error
+			}
+			result.Failures = res.RowsAffected
+		}
+
+		res := tx.Unscoped().Where("identity_id = ?", data.Identity.IdentityId).Delete(&model.OutboxEvent{})
+		if res.Error != nil {
+			return res.Error
+		}
+		result.Events = res.RowsAffected
+
+		res = tx.Where("super_identity_id = ?", data.Identity.Id).Delete(&model.ZeroKnowledgeProof{})
+		if res.Error != nil {
+			return res.Error
+		}
+		result.Proofs = res.RowsAffected
+
+		// hard delete: a soft-deleted row would still hold the name
+		if err := tx.Unscoped().Delete(&data.Identity).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&audit).Error
+	})
+	return result, err
+}
+
+func (r *gormRepository) CreateAuditEntry(audit model.LogAuditEntry) error {
+	return r.db.Create(&audit).Error
+}
+
+func (r *gormRepository) logEntriesQuery(db *gorm.DB, ids []string) *gorm.DB {
+	query := db.Model(&model.LogAuditEntry{}).Where("identity_id IN ?", ids)
+	for _, id := range ids {
+		query = query.Or("message LIKE ?", "%"+id+"%").Or("fields LIKE ?", "%"+id+"%")
+	}
+	return query
+}
+
+func eventIds(events []model.OutboxEvent) []string {
+	ids := make([]string, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.EventId)
+	}
+	return ids
+}
@@ -0,0 +1,184 @@
+package privacy
+
+import (
+	"api/src/model"
+	"api/src/proofs"
+	"api/src/vault"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const auditService = "api"
+
+var (
+	ErrIdentityNotFound = errors.New("identity not found")
+	ErrHasChildren      = errors.New("identity has child identities, erase them first")
+)
+
+type PrivacyService interface {
+	ExportIdentity(identityId string) (*model.IdentityExportDto, error)
+	EraseIdentity(ctx context.Context, identityId string) (*model.ErasureReportDto, error)
+}
+
+type privacyService struct {
+	repository Repository
+	vault      vault.Vault
+	chain      ChainCloser
+}
+
+func NewPrivacyService(repository Repository, attributeVault vault.Vault, chain ChainCloser) PrivacyService {
+	return &privacyService{repository: repository, vault: attributeVault, chain: chain}
+}
+
+func (s *privacyService) ExportIdentity(identityId string) (*model.IdentityExportDto, error) {
+	data, err := s.getData(identityId)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &model.IdentityExportDto{
+		Identity:      toIdentityDto(data),
+		ChildIds:      data.ChildIds,
+		Verifications: make([]model.VerificationExportDto, 0, len(data.Events)),
+		Failures:      make([]model.ProofFailureExportDto, 0, len(data.Failures)),
+		Proofs:        make([]model.ProofDto, 0, len(data.Proofs)),
+		LogEntries:    data.LogEntries,
+		ExportedAt:    time.Now().UTC(),
+	}
+	if export.ChildIds == nil {
+		export.ChildIds = []string{}
+	}
+	if export.LogEntries == nil {
+		export.LogEntries = []model.LogAuditEntry{}
+	}
+
+	for _, e := range data.Events {
+		verification := model.VerificationExportDto{
+			EventId:   e.EventId,
+			SchemaId:  e.SchemaId,
+			Retry:     e.Retry,
+			CreatedAt: e.CreatedAt,
+		}
+		if e.ProcessedAt.Valid {
+			verification.ProcessedAt = &e.ProcessedAt.Time
+		}
+		// events queued before the vault existed hold the raw values
+		if attributes, err := s.vault.Load(e.RequestMessage); err == nil {
+			verification.Attributes = attributes
+		} else if errors.Is(err, vault.ErrInvalidReference) {
+			verification.LegacyData = e.RequestMessage
+		} else {
+			verification.AttributesError = err.Error()
+		}
+		export.Verifications = append(export.Verifications, verification)
+	}
+
+	for _, f := range data.Failures {
+		export.Failures = append(export.Failures, model.ProofFailureExportDto{
+			EventId:     f.EventId,
+			RequestBody: string(f.RequestBody),
+			Error:       f.Error,
+			ReasonCode:  f.ReasonCode,
+		})
+	}
+
+	for _, p := range data.Proofs {
+		export.Proofs = append(export.Proofs, proofs.ToDto(p))
+	}
+
+	// the export itself is an access to personal data and is audited
+	if err := s.repository.CreateAuditEntry(auditEntry("Exported data of identity " + identityId)); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// EraseIdentity removes everything stored about an identity. The steps are
+// ordered so a failure at any point leaves a state the request can be retried
+// from: the proof accounts are closed on chain first, since closing an account
+// that is already gone succeeds, then the vault key is shredded, and finally
+// the rows are deleted in a single transaction. Shredding last keeps the
+// attributes readable, and exportable, while the chain is unavailable.
+func (s *privacyService) EraseIdentity(ctx context.Context, identityId string) (*model.ErasureReportDto, error) {
+	data, err := s.getData(identityId)
+	if err != nil {
+		return nil, err
+	}
+	if len(data.ChildIds) > 0 {
+		return nil, ErrHasChildren
+	}
+
+	closed := 0
+	for _, p := range data.Proofs {
+		if p.AccountId == "" {
+			continue
+		}
+		if err := s.chain.CloseAccount(ctx, p.AccountId); err != nil {
+			return nil, err
+		}
+		closed++
+	}
+
+	if err := s.vault.Shred(identityId); err != nil {
+		return nil, fmt.Errorf("shred attributes: %w", err)
+	}
+
+	erasedAt := time.Now().UTC()
+	// the audit entry must not identify the subject, only that an erasure ran
+	audit := auditEntry(fmt.Sprintf(
+		"Erased identity data: %d verifications, %d failures, %d proofs, %d accounts closed",
+		len(data.Events), len(data.Failures), len(data.Proofs), closed,
+	))
+	audit.Timestamp = erasedAt
+
+	result, err := s.repository.Erase(data, audit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ErasureReportDto{
+		IdentityId:           identityId,
+		Verifications:        result.Events,
+		Failures:             result.Failures,
+		Proofs:               result.Proofs,
+		ClosedAccounts:       closed,
+		AnonymizedLogEntries: result.LogEntries,
+		ErasedAt:             erasedAt,
+	}, nil
+}
+
+func (s *privacyService) getData(identityId string) (*IdentityData, error) {
+	if _, err := uuid.Parse(identityId); err != nil {
+		return nil, ErrIdentityNotFound
+	}
+
+	data, err := s.repository.GetIdentityData(identityId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrIdentityNotFound
+	}
+	return data, err
+}
+
+func toIdentityDto(data *IdentityData) model.IdentityDto {
+	return model.IdentityDto{
+		IdentityId:   data.Identity.IdentityId,
+		IdentityName: data.Identity.IdentityName,
+		ParentId:     data.ParentId,
+		CreatedAt:    data.Identity.CreatedAt,
+		UpdatedAt:    data.Identity.UpdatedAt,
+	}
+}
+
+func auditEntry(message string) model.LogAuditEntry {
+	return model.LogAuditEntry{
+		Level:     "info",
+		Message:   message,
+		Timestamp: time.Now().UTC(),
+		Service:   auditService,
+	}
+}
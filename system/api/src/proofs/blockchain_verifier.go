@@ -0,0 +1,62 @@
+package proofs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	httpclient "pkg-common/http_client"
+	"strconv"
+)
+
+// ErrBlockchainUnavailable means the proof could not be checked, as opposed
+// to being checked and found invalid.
+var ErrBlockchainUnavailable = errors.New("blockchain client unavailable")
+
+const VerifyProofScope = "proofs:verify"
+
+type VerificationResult struct {
+	Valid bool
+	Error string
+}
+
+// BlockchainVerifier re-checks a proof stored on chain.
+type BlockchainVerifier interface {
+	VerifyOnChain(ctx context.Context, signature, account string, size int) (VerificationResult, error)
+}
+
+// httpBlockchainVerifier calls the internal verify endpoint of blockchain-client,
+// which fetches the proof account and runs groth16 verification on it.
+type httpBlockchainVerifier struct {
+	client *httpclient.Client
+}
+
+func NewHttpBlockchainVerifier(client *httpclient.Client) BlockchainVerifier {
+	return &httpBlockchainVerifier{
+		client: client,
+	}
+}
+
+func (v *httpBlockchainVerifier) VerifyOnChain(ctx context.Context, signature, account string, size int) (VerificationResult, error) {
+	query := url.Values{}
+	query.Set("signature", signature)
+	query.Set("account", account)
+	query.Set("size", strconv.Itoa(size))
+
+	_, err := v.client.Send(ctx, http.MethodGet, "/v1/internal/verify", nil,
+		httpclient.WithQuery(query),
+		httpclient.WithScopes(VerifyProofScope))
+	if err == nil {
+		return VerificationResult{Valid: true}, nil
+	}
+
+	// Only a rejected request or a proof that does not verify is a verdict;
+	// anything else, an expired token or a 404 from a lagging RPC node
+	// included, leaves the proof unchecked
+	var httpErr *httpclient.HTTPError
+	if errors.As(err, &httpErr) && (httpErr.StatusCode == http.StatusBadRequest || httpErr.StatusCode == http.StatusUnprocessableEntity) {
+		return VerificationResult{Valid: false, Error: httpErr.Message()}, nil
+	}
+	return VerificationResult{}, fmt.Errorf("%w: %v", ErrBlockchainUnavailable, err)
+}
@@ -0,0 +1,128 @@
+package proofs
+
+import (
+	"errors"
+	"net/http"
+	"pkg-common/logger"
+	"pkg-common/rest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProofHandler struct {
+	service ProofService
+}
+
+func NewProofHandler(service ProofService) *ProofHandler {
+	return &ProofHandler{
+		service: service,
+	}
+}
+
+// GetIdentityProofs godoc
+// @Summary      List proofs of an identity
+// @Description  Lists stored proofs, newest first, optionally filtered by schema and creation date range
+// @Tags         Proofs
+// @Produce      json
+// @Param        id         path      string  true   "Identity ID"
+// @Param        schema_id  query     string  false  "Schema ID"
+// @Param        from       query     string  false  "Created at or after (RFC3339)"
+// @Param        to         query     string  false  "Created before (RFC3339)"
+// @Param        limit      query     int     false  "Page size (max 1000)"
+// @Param        offset     query     int     false  "Page offset"
+// @Success      200  {object}  model.ProofPage
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /v1/identity/{id}/proofs [get]
+func (h *ProofHandler) GetIdentityProofs(c *gin.Context) {
+	limit, offset, err := rest.ParsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	from, err := parseTimeQuery(c, "from")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC3339 timestamp"})
+		return
+	}
+	to, err := parseTimeQuery(c, "to")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC3339 timestamp"})
+		return
+	}
+
+	identityId := c.Param("id")
+	c.Request = c.Request.WithContext(logger.ContextWithField(c.Request.Context(), logger.IdentityIdKey, identityId))
+
+	page, err := h.service.ListIdentityProofs(identityId, ProofFilter{
+		SchemaId: c.Query("schema_id"),
+		From:     from,
+		To:       to,
+		Limit:    limit,
+		Offset:   offset,
+	})
+	if err != nil {
+		respondWithError(c, err, "Failed to retrieve proofs")
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// GetProof godoc
+// @Summary      Get proof details
+// @Description  Returns a stored proof; with reverify=true it is fetched from chain and verified again
+// @Tags         Proofs
+// @Produce      json
+// @Param        id        path      string  true   "Proof ID"
+// @Param        reverify  query     bool    false  "Re-verify the proof on chain"
+// @Success      200  {object}  model.ProofVerificationDto
+// @Failure      404  {object}  map[string]string
+// @Failure      502  {object}  map[string]string
+// @Router       /v1/proofs/{id} [get]
+func (h *ProofHandler) GetProof(c *gin.Context) {
+	if c.Query("reverify") == "true" {
+		proof, err := h.service.ReverifyProof(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			respondWithError(c, err, "Failed to re-verify proof")
+			return
+		}
+		c.JSON(http.StatusOK, proof)
+		return
+	}
+
+	proof, err := h.service.GetProof(c.Param("id"))
+	if err != nil {
+		respondWithError(c, err, "Failed to retrieve proof")
+		return
+	}
+	c.JSON(http.StatusOK, proof)
+}
+
+func parseTimeQuery(c *gin.Context, key string) (*time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func respondWithError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, ErrIdentityNotFound), errors.Is(err, ErrProofNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidSchemaId):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrBlockchainUnavailable):
+		logger.Default().WithContext(c.Request.Context()).Warn(err.Error())
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		logger.Default().WithContext(c.Request.Context()).Error(err, msg)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+	}
+}
@@ -0,0 +1,109 @@
+package proofs
+
+import (
+	"api/src/database"
+	"api/src/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ProofFilter struct {
+	IdentityPk *int
+	SchemaId   string
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
+
+// ProofRecord is a stored proof joined with the public IDs of its identity
+// and schema.
+type ProofRecord struct {
+	model.ZeroKnowledgeProof
+	IdentityId string
+	SchemaId   string
+}
+
+type ProofRepository interface {
+	GetIdentityPk(identityId string) (int, error)
+	ListProofs(filter ProofFilter) ([]ProofRecord, int64, error)
+	GetProof(proofId string) (ProofRecord, error)
+	UpdateVerificationStatus(proofPk int, status model.ProofStatus, verifiedAt time.Time) error
+}
+
+type proofRepository struct {
+	db *gorm.DB
+}
+
+func NewProofRepository() ProofRepository {
+	return &proofRepository{db: database.GetDatabaseConnection()}
+}
+
+func (r *proofRepository) GetIdentityPk(identityId string) (int, error) {
+	var identity model.Identity
+	err := r.db.Select("id").Where("identity_id = ?", identityId).First(&identity).Error
+	return identity.Id, err
+}
+
+func (r *proofRepository) ListProofs(filter ProofFilter) ([]ProofRecord, int64, error) {
+	query := r.baseQuery()
+	if filter.IdentityPk != nil {
+		query = query.Where("p.super_identity_id = ?", *filter.IdentityPk)
+	}
+	if filter.SchemaId != "" {
+		query = query.Where("s.schema_id = ?", filter.SchemaId)
+	}
+	if filter.From != nil {
+		query = query.Where("p.created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("p.created_at < ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var records []ProofRecord
+	err := query.
+		Select("p.*, i.identity_id, s.schema_id").
+		Order("p.created_at DESC, p.id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Scan(&records).Error
+	return records, total, err
+}
+
+func (r *proofRepository) GetProof(proofId string) (ProofRecord, error) {
+	var records []ProofRecord
+	err := r.baseQuery().
+		Select("p.*, i.identity_id, s.schema_id").
+		Where("p.proof_id = ?", proofId).
+		Limit(1).
+		Scan(&records).Error
+	if err != nil {
+		return ProofRecord{}, err
+	}
+	if len(records) == 0 {
+		return ProofRecord{}, gorm.ErrRecordNotFound
+	}
+	return records[0], nil
+}
+
+func (r *proofRepository) UpdateVerificationStatus(proofPk int, status model.ProofStatus, verifiedAt time.Time) error {
+	return r.db.Model(&model.ZeroKnowledgeProof{}).
+		Where("id = ?", proofPk).
+		Updates(map[string]interface{}{
+			"status":           status,
+			"last_verified_at": verifiedAt,
+			"updated_at":       verifiedAt,
+		}).Error
+}
+
+func (r *proofRepository) baseQuery() *gorm.DB {
+	return r.db.Table("zero_knowledge_proofs AS p").
+		Joins("JOIN identities AS i ON i.id = p.super_identity_id").
+		Joins("JOIN verified_schemas AS s ON s.id = p.digital_identity_schema_id")
+}
@@ -0,0 +1,133 @@
+package proofs
+
+import (
+	"api/src/model"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrIdentityNotFound = errors.New("identity not found")
+	ErrProofNotFound    = errors.New("proof not found")
+	ErrInvalidSchemaId  = errors.New("schema_id must be a valid UUID")
+)
+
+type ProofService interface {
+	ListIdentityProofs(identityId string, filter ProofFilter) (*model.ProofPage, error)
+	GetProof(proofId string) (*model.ProofDto, error)
+	ReverifyProof(ctx context.Context, proofId string) (*model.ProofVerificationDto, error)
+}
+
+type proofService struct {
+	repository ProofRepository
+	verifier   BlockchainVerifier
+	now        func() time.Time
+}
+
+func NewProofService(repository ProofRepository, verifier BlockchainVerifier) ProofService {
+	return &proofService{
+		repository: repository,
+		verifier:   verifier,
+		now:        time.Now,
+	}
+}
+
+func (s *proofService) ListIdentityProofs(identityId string, filter ProofFilter) (*model.ProofPage, error) {
+	if _, err := uuid.Parse(identityId); err != nil {
+		return nil, ErrIdentityNotFound
+	}
+	if filter.SchemaId != "" {
+		if _, err := uuid.Parse(filter.SchemaId); err != nil {
+			return nil, ErrInvalidSchemaId
+		}
+	}
+
+	identityPk, err := s.repository.GetIdentityPk(identityId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrIdentityNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	filter.IdentityPk = &identityPk
+
+	records, total, err := s.repository.ListProofs(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]model.ProofDto, 0, len(records))
+	for _, record := range records {
+		items = append(items, ToDto(record))
+	}
+	return &model.ProofPage{Items: items, Total: total, Limit: filter.Limit, Offset: filter.Offset}, nil
+}
+
+func (s *proofService) GetProof(proofId string) (*model.ProofDto, error) {
+	record, err := s.getRecord(proofId)
+	if err != nil {
+		return nil, err
+	}
+	dto := ToDto(record)
+	return &dto, nil
+}
+
+// ReverifyProof asks blockchain-client to fetch the proof from chain and verify
+// it again, then records the outcome as the proof status.
+func (s *proofService) ReverifyProof(ctx context.Context, proofId string) (*model.ProofVerificationDto, error) {
+	record, err := s.getRecord(proofId)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.verifier.VerifyOnChain(ctx, record.ProofReference, record.AccountId, record.ProofSize)
+	if err != nil {
+		return nil, err
+	}
+
+	status := model.ProofInvalid
+	if result.Valid {
+		status = model.ProofVerified
+	}
+	verifiedAt := s.now().UTC()
+	if err := s.repository.UpdateVerificationStatus(record.Id, status, verifiedAt); err != nil {
+		return nil, err
+	}
+
+	record.Status = status
+	record.LastVerifiedAt = &verifiedAt
+	record.UpdatedAt = verifiedAt
+	return &model.ProofVerificationDto{ProofDto: ToDto(record), VerificationError: result.Error}, nil
+}
+
+func (s *proofService) getRecord(proofId string) (ProofRecord, error) {
+	if _, err := uuid.Parse(proofId); err != nil {
+		return ProofRecord{}, ErrProofNotFound
+	}
+
+	record, err := s.repository.GetProof(proofId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ProofRecord{}, ErrProofNotFound
+	}
+	return record, err
+}
+
+// ToDto maps a proof record to its public representation.
+func ToDto(record ProofRecord) model.ProofDto {
+	return model.ProofDto{
+		ProofId:        record.ProofId,
+		IdentityId:     record.IdentityId,
+		SchemaId:       record.SchemaId,
+		Signature:      record.ProofReference,
+		AccountId:      record.AccountId,
+		ProofSize:      record.ProofSize,
+		Status:         record.Status,
+		LastVerifiedAt: record.LastVerifiedAt,
+		CreatedAt:      record.CreatedAt,
+		UpdatedAt:      record.UpdatedAt,
+	}
+}
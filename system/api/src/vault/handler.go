@@ -0,0 +1,52 @@
+package vault
+
+import (
+	"api/src/model"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadScope is the scope verifiers need to resolve attribute references.
+const ReadScope = "attributes:read"
+
+type VaultHandler struct {
+	vault Vault
+}
+
+func NewVaultHandler(vault Vault) *VaultHandler {
+	return &VaultHandler{
+		vault: vault,
+	}
+}
+
+// ResolveAttributes godoc
+// @Summary      Resolve an attribute reference
+// @Description  Decrypts the attributes a verification request refers to. Verification messages carry only the vault reference, so verifiers call this with an internal token holding the attributes:read scope.
+// @Tags         Vault
+// @Produce      json
+// @Param        reference  path      string  true  "Vault reference (vault:<record id>)"
+// @Success      200  {object}  model.VaultAttributesDto
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      410  {object}  map[string]string "Attributes were erased"
+// @Failure      500  {object}  map[string]string
+// @Router       /v1/internal/attributes/{reference} [get]
+func (h *VaultHandler) ResolveAttributes(c *gin.Context) {
+	reference := c.Param("reference")
+
+	attributes, err := h.vault.Load(reference)
+	switch {
+	case errors.Is(err, ErrInvalidReference):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrShredded):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve attributes"})
+	default:
+		c.JSON(http.StatusOK, model.VaultAttributesDto{Reference: reference, Attributes: attributes})
+	}
+}
@@ -0,0 +1,80 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+const dataKeySize = 32
+
+var ErrUnknownMasterKey = errors.New("unknown master key")
+
+// KeyManager wraps and unwraps data keys with a master key that never leaves
+// it, mirroring the GenerateDataKey/Decrypt API of cloud KMS services.
+type KeyManager interface {
+	GenerateDataKey() (plaintext []byte, wrapped []byte, keyId string, err error)
+	UnwrapDataKey(keyId string, wrapped []byte) ([]byte, error)
+}
+
+// localKeyManager is the local KMS stand-in: it wraps data keys with an
+// AES-256-GCM master key held in process memory.
+type localKeyManager struct {
+	keyId     string
+	masterKey cipher.AEAD
+}
+
+// NewLocalKeyManager builds a KeyManager from a base64-encoded 32 byte master key.
+func NewLocalKeyManager(keyId, masterKeyB64 string) (KeyManager, error) {
+	masterKey, err := base64.StdEncoding.DecodeString(masterKeyB64)
+	if err != nil {
+		return nil, fmt.Errorf("vault master key is not valid base64: %w", err)
+	}
+	if len(masterKey) != dataKeySize {
+		return nil, fmt.Errorf("vault master key must be %d bytes, got %d", dataKeySize, len(masterKey))
+	}
+
+	aead, err := newAead(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	return &localKeyManager{keyId: keyId, masterKey: aead}, nil
+}
+
+func (km *localKeyManager) GenerateDataKey() ([]byte, []byte, string, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, "", err
+	}
+
+	nonce := make([]byte, km.masterKey.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, "", err
+	}
+	// the nonce is prepended to the wrapped key
+	wrapped := km.masterKey.Seal(nonce, nonce, dataKey, []byte(km.keyId))
+	return dataKey, wrapped, km.keyId, nil
+}
+
+func (km *localKeyManager) UnwrapDataKey(keyId string, wrapped []byte) ([]byte, error) {
+	if keyId != km.keyId {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMasterKey, keyId)
+	}
+
+	nonceSize := km.masterKey.NonceSize()
+	if len(wrapped) < nonceSize {
+		return nil, errors.New("wrapped data key is truncated")
+	}
+	return km.masterKey.Open(nil, wrapped[:nonceSize], wrapped[nonceSize:], []byte(keyId))
+}
+
+func newAead(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
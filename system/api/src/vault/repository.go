@@ -0,0 +1,72 @@
+package vault
+
+import (
+	"api/src/database"
+	"api/src/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	GetDataKey(identityId string) (*model.IdentityDataKey, error)
+	CreateDataKey(key *model.IdentityDataKey) error
+	DeleteDataKey(identityId string) error
+	SaveRecord(record *model.VaultRecord) error
+	GetRecord(recordId string) (*model.VaultRecord, error)
+	DeleteRecords(identityId string) error
+	WithTx(*gorm.DB) Repository
+}
+
+type gormRepository struct {
+	db *gorm.DB
+}
+
+func NewRepository() Repository {
+	return &gormRepository{db: database.GetDatabaseConnection()}
+}
+
+func NewRepositoryWithDB(db *gorm.DB) Repository {
+	return &gormRepository{db: db}
+}
+
+// WithTx allows the repository to run operations within a transaction
+func (r *gormRepository) WithTx(tx *gorm.DB) Repository {
+	if tx == nil {
+		return r
+	}
+	return &gormRepository{db: tx}
+}
+
+func (r *gormRepository) GetDataKey(identityId string) (*model.IdentityDataKey, error) {
+	var key model.IdentityDataKey
+	err := r.db.Where("identity_id = ?", identityId).First(&key).Error
+	return &key, err
+}
+
+// CreateDataKey inserts the key unless the identity already has one. It does
+// not fail on the conflict, so it is safe inside a caller's transaction.
+func (r *gormRepository) CreateDataKey(key *model.IdentityDataKey) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "identity_id"}},
+		DoNothing: true,
+	}).Create(key).Error
+}
+
+func (r *gormRepository) DeleteDataKey(identityId string) error {
+	return r.db.Where("identity_id = ?", identityId).Delete(&model.IdentityDataKey{}).Error
+}
+
+func (r *gormRepository) SaveRecord(record *model.VaultRecord) error {
+	return r.db.Create(record).Error
+}
+
+func (r *gormRepository) GetRecord(recordId string) (*model.VaultRecord, error) {
+	var record model.VaultRecord
+	err := r.db.Where("record_id = ?", recordId).First(&record).Error
+	return &record, err
+}
+
+func (r *gormRepository) DeleteRecords(identityId string) error {
+	return r.db.Where("identity_id = ?", identityId).Delete(&model.VaultRecord{}).Error
+}
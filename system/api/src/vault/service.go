@@ -0,0 +1,186 @@
+package vault
+
+import (
+	"api/src/model"
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const referencePrefix = "vault:"
+
+var (
+	ErrRecordNotFound   = errors.New("vault record not found")
+	ErrShredded         = errors.New("identity data key has been shredded")
+	ErrInvalidReference = errors.New("invalid vault reference")
+)
+
+// Vault keeps identity attributes encrypted at rest. Each identity has its own
+// data key wrapped by the KeyManager, so shredding that key makes every record
+// of the identity unreadable without touching the records themselves.
+type Vault interface {
+	// Store encrypts the attributes and returns the reference to put in
+	// messages instead of the values.
+	Store(identityId string, attributes []model.ZkpField) (string, error)
+	Load(reference string) ([]model.ZkpField, error)
+	// Shred deletes the identity's data key and its records.
+	Shred(identityId string) error
+	// WithTx returns a vault whose records are written within tx.
+	WithTx(tx *gorm.DB) Vault
+}
+
+type vault struct {
+	repo Repository
+	keys KeyManager
+}
+
+func NewVault(repo Repository, keys KeyManager) Vault {
+	return &vault{repo: repo, keys: keys}
+}
+
+func (v *vault) WithTx(tx *gorm.DB) Vault {
+	if tx == nil {
+		return v
+	}
+	return &vault{repo: v.repo.WithTx(tx), keys: v.keys}
+}
+
+func Reference(recordId string) string {
+	return referencePrefix + recordId
+}
+
+func ParseReference(reference string) (string, error) {
+	recordId, ok := strings.CutPrefix(reference, referencePrefix)
+	if !ok {
+		return "", ErrInvalidReference
+	}
+	if _, err := uuid.Parse(recordId); err != nil {
+		return "", ErrInvalidReference
+	}
+	return recordId, nil
+}
+
+func (v *vault) Store(identityId string, attributes []model.ZkpField) (string, error) {
+	dataKey, err := v.dataKey(identityId, true)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := json.Marshal(attributes)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newAead(dataKey)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	recordId := uuid.NewString()
+	record := &model.VaultRecord{
+		RecordId:   recordId,
+		IdentityId: identityId,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, additionalData(recordId, identityId)),
+	}
+	if err := v.repo.SaveRecord(record); err != nil {
+		return "", err
+	}
+	return Reference(recordId), nil
+}
+
+func (v *vault) Load(reference string) ([]model.ZkpField, error) {
+	recordId, err := ParseReference(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := v.repo.GetRecord(recordId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := v.dataKey(record.IdentityId, false)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAead(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, record.Nonce, record.Ciphertext, additionalData(record.RecordId, record.IdentityId))
+	if err != nil {
+		return nil, fmt.Errorf("vault record %s failed to decrypt: %w", recordId, err)
+	}
+
+	var attributes []model.ZkpField
+	if err := json.Unmarshal(plaintext, &attributes); err != nil {
+		return nil, err
+	}
+	return attributes, nil
+}
+
+// Shred deletes the data key first: once it is gone the records are
+// unrecoverable even if deleting them fails or a backup still holds them.
+func (v *vault) Shred(identityId string) error {
+	if err := v.repo.DeleteDataKey(identityId); err != nil {
+		return err
+	}
+	return v.repo.DeleteRecords(identityId)
+}
+
+// dataKey returns the unwrapped data key of the identity, creating it on first
+// use when create is set.
+func (v *vault) dataKey(identityId string, create bool) ([]byte, error) {
+	stored, err := v.repo.GetDataKey(identityId)
+	if err == nil {
+		return v.keys.UnwrapDataKey(stored.KeyId, stored.WrappedKey)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if !create {
+		return nil, ErrShredded
+	}
+
+	plaintext, wrapped, keyId, err := v.keys.GenerateDataKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := v.repo.CreateDataKey(&model.IdentityDataKey{
+		IdentityId: identityId,
+		KeyId:      keyId,
+		WrappedKey: wrapped,
+	}); err != nil {
+		return nil, err
+	}
+
+	// another request may have created the key concurrently; the stored one wins
+	stored, err = v.repo.GetDataKey(identityId)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(stored.WrappedKey, wrapped) {
+		return plaintext, nil
+	}
+	return v.keys.UnwrapDataKey(stored.KeyId, stored.WrappedKey)
+}
+
+// additionalData binds a ciphertext to its record and identity, so records
+// cannot be swapped between identities in the database.
+func additionalData(recordId, identityId string) []byte {
+	return []byte(recordId + "|" + identityId)
+}
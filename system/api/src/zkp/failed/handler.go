@@ -1,12 +1,10 @@
 package zkpfailed
 
 import (
-	"encoding/json"
+	"context"
 	dtocommon "pkg-common/dto_common"
 	"pkg-common/logger"
 	"pkg-common/rabbitmq"
-
-	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 const (
@@ -16,12 +14,16 @@ const (
 type ZeroKnowledgeProofFailedHandler struct {
 	service  ZkpFailedService
 	consumer rabbitmq.IRabbitmqConsumer
+	// processed keeps a redelivered failure from counting as another attempt
+	processed rabbitmq.IdempotencyStore
 }
 
-func NewZeroKnowledgeProofFailedHandler() *ZeroKnowledgeProofFailedHandler {
+func NewZeroKnowledgeProofFailedHandler(registry *rabbitmq.Registry, service ZkpFailedService) *ZeroKnowledgeProofFailedHandler {
 	return &ZeroKnowledgeProofFailedHandler{
-		service:  newFailedZkpService(),
-		consumer: rabbitmq.GetConsumer(proofFailuresConsumerAlias),
+		service:  service,
+		consumer: registry.Consumer(proofFailuresConsumerAlias),
+
+		processed: rabbitmq.NewMemoryIdempotencyStore(rabbitmq.DefaultIdempotencyWindow, rabbitmq.DefaultIdempotencyCapacity),
 	}
 }
 
@@ -29,19 +31,29 @@ func (h *ZeroKnowledgeProofFailedHandler) GetServiceName() string {
 	return proofFailuresConsumerAlias
 }
 
-func (h *ZeroKnowledgeProofFailedHandler) StartService() {
+func (h *ZeroKnowledgeProofFailedHandler) Stop(ctx context.Context) error {
+	return h.consumer.Drain(ctx)
+}
+
+func (h *ZeroKnowledgeProofFailedHandler) StartService(ctx context.Context) error {
 	zkpLogger := logger.Default()
-	h.consumer.StartConsuming(func(d amqp.Delivery) {
+	zkpLogger.Info("Listening for ZKP proof failures...")
+
+	router := rabbitmq.NewRouter().On(dtocommon.ZkpProofFailureType, func(ctx context.Context, msg rabbitmq.Message) rabbitmq.Outcome {
+		messageLogger := zkpLogger.WithContext(ctx)
 		var resp dtocommon.ZkpProofFailureDto
-		if err := json.Unmarshal(d.Body, &resp); err != nil {
-			zkpLogger.Errorf(err, "Failed to unmarshal result")
-			return
+		if err := msg.Decode(&resp); err != nil {
+			messageLogger.Errorf(err, "Failed to unmarshal result")
+			return rabbitmq.DeadLetter(err)
 		}
 		// Save to DB, update state, etc
 		if err := h.service.SaveFailedAndUpdateOutbox(resp); err != nil {
-			zkpLogger.Errorf(err, "Failed to process verification result")
+			messageLogger.Errorf(err, "Failed to process verification result")
+			return rabbitmq.RetryLater(err)
 		}
-	})
 
-	zkpLogger.Info("Listening for ZKP verification results...")
+		return rabbitmq.Ack()
+	}).Deduplicate(h.processed, rabbitmq.ByMessageId)
+
+	return h.consumer.StartConsuming(ctx, router.Handle)
 }
@@ -3,6 +3,7 @@ package zkpfailed
 import (
 	"api/src/model"
 	"api/src/outbox"
+	"encoding/json"
 	dtocommon "pkg-common/dto_common"
 
 	"github.com/google/uuid"
@@ -18,7 +19,7 @@ type zkpFailedService struct {
 }
 
 // Constructor (injects the repo)
-func newFailedZkpService() ZkpFailedService {
+func NewFailedZkpService() ZkpFailedService {
 	return &zkpFailedService{
 		zkpRepo:    newFailedZkpRepository(),
 		outboxRepo: outbox.NewRepo(),
@@ -28,7 +29,7 @@ func newFailedZkpService() ZkpFailedService {
 func (zfs *zkpFailedService) SaveFailedAndUpdateOutbox(data dtocommon.ZkpProofFailureDto) error {
 	entity := model.ZkpProofFailure{
 		EventId:     data.EventId,
-		RequestBody: data.ReqestBody,
+		RequestBody: RedactRequestBody(data.ReqestBody),
 		Error:       data.Error,
 		ReasonCode:  string(data.ReasonCode),
 	}
@@ -38,5 +39,52 @@ func (zfs *zkpFailedService) SaveFailedAndUpdateOutbox(data dtocommon.ZkpProofFa
 		return err
 	}
 
-	return zfs.outboxRepo.UpdateRetryValue(uuid.MustParse(data.EventId))
+	return zfs.outboxRepo.ScheduleRetry(uuid.MustParse(data.EventId), data.Error)
+}
+
+const redacted = "[redacted]"
+
+// keptFields identify a verifier message and the checks it reports on without
+// revealing attribute values.
+var keptFields = map[string]bool{
+	"event_id":              true,
+	"key":                   true,
+	"type":                  true,
+	"verification_positive": true,
+}
+
+// RedactRequestBody keeps the shape of the message the blockchain client failed
+// on, for operators, but replaces every value outside keptFields: the verifier
+// echoes attribute values, which belong only in the vault. Bodies that are not
+// JSON are dropped.
+func RedactRequestBody(body []byte) []byte {
+	var decoded any
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return nil
+	}
+	redactedBody, err := json.Marshal(redactValue(decoded, false))
+	if err != nil {
+		return nil
+	}
+	return redactedBody
+}
+
+func redactValue(node any, keep bool) any {
+	switch value := node.(type) {
+	case map[string]any:
+		for key, child := range value {
+			value[key] = redactValue(child, keptFields[key])
+		}
+		return value
+	case []any:
+		for i, child := range value {
+			value[i] = redactValue(child, keep)
+		}
+		return value
+	default:
+		if keep || value == nil {
+			return value
+		}
+		return redacted
+	}
 }
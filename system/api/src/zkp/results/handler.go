@@ -1,12 +1,10 @@
 package zkpresult
 
 import (
-	"encoding/json"
+	"context"
 	dtocommon "pkg-common/dto_common"
 	"pkg-common/logger"
 	"pkg-common/rabbitmq"
-
-	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 const (
@@ -18,10 +16,10 @@ type ZeroKnowledgeProofHandler struct {
 	consumer rabbitmq.IRabbitmqConsumer
 }
 
-func NewZeroKnowledgeProofHandler() *ZeroKnowledgeProofHandler {
+func NewZeroKnowledgeProofHandler(registry *rabbitmq.Registry) *ZeroKnowledgeProofHandler {
 	return &ZeroKnowledgeProofHandler{
 		service:  NewZkpService(),
-		consumer: rabbitmq.GetConsumer(proofResultsConsumerAlias),
+		consumer: registry.Consumer(proofResultsConsumerAlias),
 	}
 }
 
@@ -29,19 +27,29 @@ func (h *ZeroKnowledgeProofHandler) GetServiceName() string {
 	return proofResultsConsumerAlias
 }
 
-func (h *ZeroKnowledgeProofHandler) StartService() {
+func (h *ZeroKnowledgeProofHandler) Stop(ctx context.Context) error {
+	return h.consumer.Drain(ctx)
+}
+
+func (h *ZeroKnowledgeProofHandler) StartService(ctx context.Context) error {
 	zkpLogger := logger.Default()
-	h.consumer.StartConsuming(func(d amqp.Delivery) {
+	zkpLogger.Info("Listening for ZKP verification results...")
+
+	router := rabbitmq.NewRouter().On(dtocommon.ZkpProofResultType, func(ctx context.Context, msg rabbitmq.Message) rabbitmq.Outcome {
+		messageLogger := zkpLogger.WithContext(ctx)
 		var resp dtocommon.ZkpProofResultDto
-		if err := json.Unmarshal(d.Body, &resp); err != nil {
-			zkpLogger.Errorf(err, "Failed to unmarshal result")
-			return
+		if err := msg.Decode(&resp); err != nil {
+			messageLogger.Errorf(err, "Failed to unmarshal result")
+			return rabbitmq.DeadLetter(err)
 		}
 		// Save to DB, update state, etc
 		if err := h.service.ProcessVerificationResult(resp); err != nil {
-			zkpLogger.Errorf(err, "Failed to process verification result")
+			messageLogger.Errorf(err, "Failed to process verification result")
+			return rabbitmq.RetryLater(err)
 		}
+
+		return rabbitmq.Ack()
 	})
 
-	zkpLogger.Info("Listening for ZKP verification results...")
+	return h.consumer.StartConsuming(ctx, router.Handle)
 }
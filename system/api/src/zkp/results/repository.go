@@ -5,13 +5,12 @@ import (
 	"api/src/model"
 	"errors"
 
-	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type ZkpRepository interface {
 	GetIdentityByUUID(uuid string) (model.Identity, error)
-	FindOrCreateVerifiedSchema(schemaStr string, superIdentityId int) (model.VerifiedSchema, error)
+	FindOrCreateVerifiedSchema(schemaId string, superIdentityId int) (model.VerifiedSchema, error)
 	SaveZeroKnowledgeProof(zkp *model.ZeroKnowledgeProof) error
 }
 
@@ -29,14 +28,15 @@ func (r *zkpRepository) GetIdentityByUUID(uuid string) (model.Identity, error) {
 	return identity, err
 }
 
-func (r *zkpRepository) FindOrCreateVerifiedSchema(schemaStr string, superIdentityId int) (model.VerifiedSchema, error) {
+// FindOrCreateVerifiedSchema resolves the schema an outbox event was queued
+// against by its public ID, registering it when it is not known yet.
+func (r *zkpRepository) FindOrCreateVerifiedSchema(schemaId string, superIdentityId int) (model.VerifiedSchema, error) {
 	var schema model.VerifiedSchema
-	err := r.db.Where("schema = ?", schemaStr).First(&schema).Error
+	err := r.db.Where("schema_id = ?", schemaId).First(&schema).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		schema = model.VerifiedSchema{
-			SchemaId:        uuid.New().String(),
+			SchemaId:        schemaId,
 			SuperIdentityId: superIdentityId,
-			Schema:          schemaStr,
 		}
 		err = r.db.Create(&schema).Error
 	}
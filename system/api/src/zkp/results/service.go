@@ -3,10 +3,12 @@ package zkpresult
 import (
 	"api/src/model"
 	"api/src/outbox"
+	"errors"
 	dtocommon "pkg-common/dto_common"
 	"pkg-common/logger"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // Service interface
@@ -33,6 +35,16 @@ func (s *zkpService) ProcessVerificationResult(resp dtocommon.ZkpProofResultDto)
 	zkpLogger := logger.Default()
 
 	event, err := s.outboxRepo.GetEvent(uuid.MustParse(resp.EventId))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Redelivered result of an event that was already processed
+		zkpLogger.Infof("Skipping result of already processed event: %s", resp.EventId)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	zkpLogger = zkpLogger.With(logger.IdentityIdKey, event.IdentityId)
 
 	// 1. Get identity (by UUID string)
 	identity, err := s.identityRepo.GetIdentityByUUID(event.IdentityId)
@@ -48,10 +60,14 @@ func (s *zkpService) ProcessVerificationResult(resp dtocommon.ZkpProofResultDto)
 
 	// 3. Save the proof
 	zkp := &model.ZeroKnowledgeProof{
+		ProofId:                 uuid.New().String(),
+		EventId:                 event.EventId,
 		DigitalIdentitySchemaId: schema.Id,
 		SuperIdentityId:         identity.Id,
 		ProofReference:          resp.Signature,
 		AccountId:               resp.AccountId,
+		ProofSize:               resp.ProofSize,
+		Status:                  model.ProofStored,
 	}
 	if err := s.identityRepo.SaveZeroKnowledgeProof(zkp); err != nil {
 		return err
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"pkg-common/rest"
 	"time"
 
 	"log/slog"
@@ -15,8 +16,9 @@ import (
 )
 
 type Handler struct {
-	svc *Service
-	log *slog.Logger
+	svc     *Service
+	log     *slog.Logger
+	limiter *rest.RateLimiter
 }
 
 // Domyślny logger (text na stdout)
@@ -25,6 +27,35 @@ func NewHandler(s *Service) *Handler {
 	return &Handler{svc: s, log: l}
 }
 
+// WithRateLimiter enables the schema_setup budget for requests that would
+// trigger circuit compilation + Groth16 setup for an unseen schema.
+func (h *Handler) WithRateLimiter(rl *rest.RateLimiter) *Handler {
+	h.limiter = rl
+	return h
+}
+
+// allowSchemaSetup charges the schema_setup bucket only when the schema is not cached yet.
+func (h *Handler) allowSchemaSetup(c *gin.Context, schemaJSON string) bool {
+	if h.limiter == nil || h.svc.IsSchemaKnown(schemaJSON) {
+		return true
+	}
+	if !h.limiter.Allow(c, rest.RateLimitBucketSchemaSetup) {
+		h.log.Warn("schema_setup.rate_limited", "ip", c.ClientIP())
+		return false
+	}
+	return true
+}
+
+// DIDDocument serves the did:web document of this verifier.
+func (h *Handler) DIDDocument(c *gin.Context) {
+	if h.svc.Identity == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "verifier DID not configured"})
+		return
+	}
+	c.Header("Content-Type", "application/did+json")
+	c.JSON(http.StatusOK, h.svc.Identity.Document)
+}
+
 // ---------- Helpers ----------
 
 // Zwraca: humanURL (HTML), descriptorURL (JSON dla walleta), deeplink (opcjonalny)
@@ -69,6 +100,10 @@ func (h *Handler) CreatePresentation(c *gin.Context) {
 		return
 	}
 
+	if !h.allowSchemaSetup(c, in.SchemaJSON) {
+		return
+	}
+
 	start := time.Now()
 	req, err := h.svc.CreateRequestFromSchema(in.SchemaJSON, time.Now())
 	if err != nil {
@@ -340,6 +375,10 @@ func (h *Handler) VerifyBlocking(c *gin.Context) {
 		return
 	}
 
+	if !h.allowSchemaSetup(c, in.SchemaJSON) {
+		return
+	}
+
 	start := time.Now()
 	req, err := h.svc.CreateRequestFromSchema(in.SchemaJSON, time.Now())
 	if err != nil {
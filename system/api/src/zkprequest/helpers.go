@@ -2,12 +2,15 @@ package zkprequest
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"fmt"
 	"github.com/consensys/gnark/backend/groth16"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"pkg-common/metrics"
 	"pkg-common/zkp"
+	"time"
 )
 
 func sha256Hex(b []byte) string {
@@ -15,6 +18,22 @@ func sha256Hex(b []byte) string {
 	return fmt.Sprintf("%x", h[:])
 }
 
+// IsSchemaKnown reports whether VK/PK for the schema are already cached,
+// i.e. whether creating a request for it is cheap.
+func (s *Service) IsSchemaKnown(schemaJSON string) bool {
+	canon, err := canonicalJSON(schemaJSON)
+	if err != nil {
+		return false
+	}
+	hash := "sha256:" + sha256Hex([]byte(canon))
+
+	s.cacheMu.RLock()
+	defer s.cacheMu.RUnlock()
+	_, vkOK := s.vkCache[hash]
+	_, pkOK := s.pkCache[hash]
+	return vkOK && pkOK
+}
+
 // ensureVKForSchema
 func (s *Service) ensureVKForSchema(canon string) (string, error) {
 	hash := "sha256:" + sha256Hex([]byte(canon))
@@ -42,6 +61,7 @@ func (s *Service) ensureVKForSchema(canon string) (string, error) {
 	}
 
 	// 3) kompilacja R1CS na *tej samej* krzywej co wallet
+	compileStart := time.Now()
 	ccs, err := frontend.Compile(
 		zkp.ElipticalCurveID.ScalarField(),
 		r1cs.NewBuilder,
@@ -50,12 +70,15 @@ func (s *Service) ensureVKForSchema(canon string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	metrics.ObserveCircuitOperation(metrics.CircuitCompile, compileStart)
 
 	// 4) Setup Groth16
+	setupStart := time.Now()
 	pk, vk, err := groth16.Setup(ccs)
 	if err != nil {
 		return "", err
 	}
+	metrics.ObserveCircuitOperation(metrics.CircuitSetup, setupStart)
 
 	var vkBuf, pkBuf bytes.Buffer
 	if _, err := vk.WriteTo(&vkBuf); err != nil {
@@ -84,3 +107,31 @@ func (s *Service) ensureVKForSchema(canon string) (string, error) {
 
 	return hash, nil
 }
+
+// CheckArtifactStore reports the VK/PK store unhealthy when it cannot be read
+// before ctx expires (e.g. a writer is stuck) or when a VK lost its PK pair.
+func (s *Service) CheckArtifactStore(ctx context.Context) error {
+	locked := make(chan struct{})
+	go func() {
+		s.cacheMu.RLock()
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+	case <-ctx.Done():
+		go func() {
+			<-locked
+			s.cacheMu.RUnlock()
+		}()
+		return fmt.Errorf("artifact store is locked: %w", ctx.Err())
+	}
+	defer s.cacheMu.RUnlock()
+
+	for hash := range s.vkCache {
+		if len(s.pkCache[hash]) == 0 {
+			return fmt.Errorf("artifact store is missing pk for %s", hash)
+		}
+	}
+	return nil
+}
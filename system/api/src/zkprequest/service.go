@@ -18,6 +18,8 @@ import (
 	"github.com/consensys/gnark/backend/groth16"
 	"github.com/google/uuid"
 
+	"pkg-common/did"
+	"pkg-common/metrics"
 	"pkg-common/zkp"
 )
 
@@ -49,6 +51,10 @@ type Service struct {
 	// allow accepting ad-hoc schema_json from RP
 	AllowAdHocSchema bool
 
+	// did:web identity of this verifier; when set, webhook payloads are sent
+	// with a receipt signed by its key
+	Identity *did.WebIdentity
+
 	// --- blocking waiters ---
 	waiters   map[string][]chan verifyResult // requestID -> list of listeners
 	waitersMu sync.Mutex
@@ -137,6 +143,7 @@ func (s *Service) CreateRequestFromSchema(schemaJSON string, now time.Time) (Pre
 	if err := s.Store.Save(req); err != nil {
 		return PresentationRequest{}, err
 	}
+	metrics.IncPresentationState(metrics.PresentationCreated)
 	return req, nil
 }
 
@@ -215,7 +222,10 @@ func (s *Service) VerifySubmission(sub ProofSubmission) (PresentationRequest, er
 	if _, err := vk.ReadFrom(newBytesReader(vkb)); err != nil {
 		return recordFail(&req, "cannot load server VK")
 	}
-	if err := groth16.Verify(pkg.Proof, vk, pkg.PublicWitness); err != nil {
+	verifyStart := time.Now()
+	err = groth16.Verify(pkg.Proof, vk, pkg.PublicWitness)
+	metrics.ObserveCircuitOperation(metrics.CircuitVerify, verifyStart)
+	if err != nil {
 		return recordFail(&req, "verify failed")
 	}
 
@@ -338,12 +348,27 @@ func (s *Service) postWebhook(req PresentationRequest, ok bool, reason string) {
 		r.Header.Set("X-ZKP-Signature", sig)
 	}
 
+	if receipt, err := s.signReceipt(b); err == nil && receipt != "" {
+		r.Header.Set("X-ZKP-Receipt", receipt)
+	}
+
 	http.DefaultClient.Do(r) //nolint:errcheck
 }
 
+// signReceipt wraps a webhook payload in a compact JWS signed by the verifier's
+// DID key, so relying parties can check it by resolving the kid.
+func (s *Service) signReceipt(payload []byte) (string, error) {
+	if s.Identity == nil {
+		return "", nil
+	}
+	return s.Identity.Signer.Sign(payload, "zkp-receipt+jwt")
+}
+
 // ---- helpers ----
 
 func (s *Service) setVerdict(id string, v verdict) {
+	metrics.IncPresentationState(v.State)
+
 	s.verdictsMu.Lock()
 	s.verdicts[id] = v
 	s.verdictsMu.Unlock()
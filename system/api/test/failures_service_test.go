@@ -0,0 +1,190 @@
+package test
+
+import (
+	"api/src/failures"
+	"api/src/model"
+	"api/src/outbox"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type fakeFailureRepo struct {
+	outbox    *fakeOutboxRepo
+	failures  []model.ZkpProofFailure
+	stats     []failures.FailureStat
+	discarded []string
+}
+
+func (r *fakeFailureRepo) ListFailures(filter failures.FailureFilter) ([]failures.FailureRecord, int64, error) {
+	var records []failures.FailureRecord
+	for _, failure := range r.failures {
+		if filter.ReasonCode != "" && failure.ReasonCode != filter.ReasonCode {
+			continue
+		}
+		records = append(records, failures.FailureRecord{ZkpProofFailure: failure})
+	}
+	return records, int64(len(records)), nil
+}
+
+func (r *fakeFailureRepo) GetEvent(eventId string) (model.OutboxEvent, error) {
+	return r.outbox.GetEvent(uuid.MustParse(eventId))
+}
+
+func (r *fakeFailureRepo) GetEventFailures(eventId string) ([]model.ZkpProofFailure, error) {
+	var found []model.ZkpProofFailure
+	for _, failure := range r.failures {
+		if failure.EventId == eventId {
+			found = append(found, failure)
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeFailureRepo) GetEventIdsByReason(reasonCode string) ([]string, error) {
+	var eventIds []string
+	for _, failure := range r.failures {
+		if failure.ReasonCode == reasonCode {
+			eventIds = append(eventIds, failure.EventId)
+		}
+	}
+	return eventIds, nil
+}
+
+func (r *fakeFailureRepo) Discard(eventIds []string) (model.DiscardReportDto, error) {
+	r.discarded = append(r.discarded, eventIds...)
+	events, err := r.outbox.Discard(eventIds)
+	return model.DiscardReportDto{Events: events, Failures: int64(len(eventIds))}, err
+}
+
+func (r *fakeFailureRepo) Stats(bucket string, from, to time.Time) ([]failures.FailureStat, error) {
+	return r.stats, nil
+}
+
+func newFailureFixture(status model.OutboxStatus) (*fakeFailureRepo, string) {
+	eventId := uuid.New().String()
+	outboxRepo := &fakeOutboxRepo{events: []model.OutboxEvent{{EventId: eventId, Status: status, Retry: 5}}}
+	return &fakeFailureRepo{
+		outbox: outboxRepo,
+		failures: []model.ZkpProofFailure{
+			{EventId: eventId, ReasonCode: "SolanaBlockchainError", Error: "timeout", RequestBody: []byte(`{"event_id":"x"}`)},
+			{EventId: eventId, ReasonCode: "UnmarshalError", Error: "bad json", RequestBody: []byte("not json")},
+		},
+	}, eventId
+}
+
+func TestGetFailedEventShowsRequests(t *testing.T) {
+	repo, eventId := newFailureFixture(model.OutboxDead)
+	svc := failures.NewFailureService(repo, repo.outbox)
+
+	event, err := svc.GetFailedEvent(eventId)
+	if err != nil {
+		t.Fatalf("GetFailedEvent returned error: %v", err)
+	}
+	if event.Status != model.OutboxDead || len(event.Failures) != 2 {
+		t.Fatalf("Unexpected event: %+v", event)
+	}
+	if string(event.Failures[0].RequestBody) != `{"event_id":"x"}` || string(event.Failures[1].RequestBody) != `"not json"` {
+		t.Errorf("Expected request bodies as JSON, got %s and %s", event.Failures[0].RequestBody, event.Failures[1].RequestBody)
+	}
+
+	if _, err := svc.GetFailedEvent(uuid.New().String()); !errors.Is(err, failures.ErrEventNotFound) {
+		t.Errorf("Expected ErrEventNotFound, got %v", err)
+	}
+	if _, err := svc.GetFailedEvent("not-a-uuid"); !errors.Is(err, failures.ErrInvalidEventId) {
+		t.Errorf("Expected ErrInvalidEventId, got %v", err)
+	}
+}
+
+func TestRequeueFailedEvent(t *testing.T) {
+	repo, eventId := newFailureFixture(model.OutboxDead)
+	svc := failures.NewFailureService(repo, repo.outbox)
+
+	event, err := svc.Requeue(eventId)
+	if err != nil {
+		t.Fatalf("Requeue returned error: %v", err)
+	}
+	if event.Status != model.OutboxPending {
+		t.Errorf("Expected requeued event to be pending, got %s", event.Status)
+	}
+
+	publishedAt := time.Now()
+	repo.outbox.events[0].Status = model.OutboxPublished
+	repo.outbox.events[0].PublishedAt = &publishedAt
+	if _, err := svc.Requeue(eventId); !errors.Is(err, failures.ErrNotRequeueable) {
+		t.Errorf("Expected ErrNotRequeueable for an in-flight event, got %v", err)
+	}
+
+	// A published event whose result is overdue can be requeued
+	publishedAt = publishedAt.Add(-outbox.ResultTimeout)
+	if _, err := svc.Requeue(eventId); err != nil {
+		t.Errorf("Expected a stale published event to be requeued, got %v", err)
+	}
+
+	repo.outbox.events[0].Status = model.OutboxProcessed
+	if _, err := svc.Requeue(eventId); !errors.Is(err, failures.ErrNotRequeueable) {
+		t.Errorf("Expected ErrNotRequeueable for a processed event, got %v", err)
+	}
+	if _, err := svc.Requeue(uuid.New().String()); !errors.Is(err, failures.ErrEventNotFound) {
+		t.Errorf("Expected ErrEventNotFound, got %v", err)
+	}
+	if _, err := svc.Requeue("not-a-uuid"); !errors.Is(err, failures.ErrInvalidEventId) {
+		t.Errorf("Expected ErrInvalidEventId, got %v", err)
+	}
+}
+
+func TestDiscardFailures(t *testing.T) {
+	repo, eventId := newFailureFixture(model.OutboxDead)
+	svc := failures.NewFailureService(repo, repo.outbox)
+
+	if _, err := svc.Discard(model.DiscardFailuresRequest{}); !errors.Is(err, failures.ErrEmptySelection) {
+		t.Errorf("Expected ErrEmptySelection, got %v", err)
+	}
+	if _, err := svc.Discard(model.DiscardFailuresRequest{EventIds: []string{"nope"}}); !errors.Is(err, failures.ErrInvalidEventId) {
+		t.Errorf("Expected ErrInvalidEventId, got %v", err)
+	}
+
+	report, err := svc.Discard(model.DiscardFailuresRequest{ReasonCode: "UnmarshalError"})
+	if err != nil {
+		t.Fatalf("Discard returned error: %v", err)
+	}
+	if report.Events != 1 || len(repo.discarded) != 1 || repo.discarded[0] != eventId {
+		t.Errorf("Expected the event to be discarded by reason code, got %+v", report)
+	}
+	if repo.outbox.events[0].Status != model.OutboxDiscarded {
+		t.Errorf("Expected discarded status, got %s", repo.outbox.events[0].Status)
+	}
+}
+
+func TestFailureStats(t *testing.T) {
+	repo, _ := newFailureFixture(model.OutboxDead)
+	day := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo.stats = []failures.FailureStat{
+		{BucketStart: day, ReasonCode: "SolanaBlockchainError", Count: 3},
+		{BucketStart: day.Add(24 * time.Hour), ReasonCode: "SolanaBlockchainError", Count: 2},
+		{BucketStart: day, ReasonCode: "UnmarshalError", Count: 1},
+	}
+	svc := failures.NewFailureService(repo, repo.outbox)
+
+	stats, err := svc.Stats("", nil, nil)
+	if err != nil {
+		t.Fatalf("Stats returned error: %v", err)
+	}
+	if stats.Bucket != "day" || stats.To.Sub(stats.From) != failures.DefaultStatsWindow {
+		t.Errorf("Expected daily buckets over the default window, got %s from %s to %s", stats.Bucket, stats.From, stats.To)
+	}
+	if stats.Totals["SolanaBlockchainError"] != 5 || stats.Totals["UnmarshalError"] != 1 || len(stats.Series) != 3 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	if _, err := svc.Stats("minute", nil, nil); !errors.Is(err, failures.ErrInvalidBucket) {
+		t.Errorf("Expected ErrInvalidBucket, got %v", err)
+	}
+	to := day
+	from := day.Add(time.Hour)
+	if _, err := svc.Stats("hour", &from, &to); !errors.Is(err, failures.ErrInvalidStatsRange) {
+		t.Errorf("Expected ErrInvalidStatsRange, got %v", err)
+	}
+}
@@ -0,0 +1,293 @@
+package test
+
+import (
+	"api/src/identity"
+	"api/src/model"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// fakeIdentityRepo keeps identities in memory; hierarchy queries walk the
+// parent pointers the same way the recursive CTEs do.
+type fakeIdentityRepo struct {
+	identities map[int]*model.Identity
+	nextPk     int
+	locked     []int
+}
+
+func newFakeIdentityRepo() *fakeIdentityRepo {
+	return &fakeIdentityRepo{identities: map[int]*model.Identity{}, nextPk: 1}
+}
+
+func (r *fakeIdentityRepo) WithTx(*gorm.DB) identity.Repository {
+	return r
+}
+
+func (r *fakeIdentityRepo) LockForUpdate(pks []int) error {
+	r.locked = append(r.locked, pks...)
+	return nil
+}
+
+func (r *fakeIdentityRepo) Create(identity *model.Identity) error {
+	identity.Id = r.nextPk
+	r.nextPk++
+	stored := *identity
+	r.identities[identity.Id] = &stored
+	return nil
+}
+
+func (r *fakeIdentityRepo) Update(identity *model.Identity) error {
+	stored := *identity
+	r.identities[identity.Id] = &stored
+	return nil
+}
+
+func (r *fakeIdentityRepo) Delete(identity *model.Identity) error {
+	delete(r.identities, identity.Id)
+	return nil
+}
+
+func (r *fakeIdentityRepo) GetById(id string) (*model.Identity, error) {
+	for _, identity := range r.identities {
+		if identity.IdentityId == id {
+			found := *identity
+			return &found, nil
+		}
+	}
+	return &model.Identity{}, gorm.ErrRecordNotFound
+}
+
+func (r *fakeIdentityRepo) GetByPk(pk int) (*model.Identity, error) {
+	identity, ok := r.identities[pk]
+	if !ok {
+		return &model.Identity{}, gorm.ErrRecordNotFound
+	}
+	found := *identity
+	return &found, nil
+}
+
+func (r *fakeIdentityRepo) GetByName(name string) (*model.Identity, error) {
+	for _, identity := range r.identities {
+		if identity.IdentityName == name {
+			found := *identity
+			return &found, nil
+		}
+	}
+	return &model.Identity{}, gorm.ErrRecordNotFound
+}
+
+func (r *fakeIdentityRepo) List(filter identity.IdentityFilter) ([]model.Identity, int64, error) {
+	var result []model.Identity
+	for pk := 1; pk < r.nextPk; pk++ {
+		if identity, ok := r.identities[pk]; ok {
+			result = append(result, *identity)
+		}
+	}
+	return result, int64(len(result)), nil
+}
+
+func (r *fakeIdentityRepo) GetSubIdentities(parentId int) ([]model.Identity, error) {
+	var subs []model.Identity
+	for pk := 1; pk < r.nextPk; pk++ {
+		if identity, ok := r.identities[pk]; ok && identity.ParentId != nil && *identity.ParentId == parentId {
+			subs = append(subs, *identity)
+		}
+	}
+	return subs, nil
+}
+
+func (r *fakeIdentityRepo) GetDescendants(pk int) ([]model.Identity, error) {
+	var result []model.Identity
+	level := []int{pk}
+	for len(level) > 0 {
+		var next []int
+		for _, parent := range level {
+			subs, _ := r.GetSubIdentities(parent)
+			for _, sub := range subs {
+				result = append(result, sub)
+				next = append(next, sub.Id)
+			}
+		}
+		level = next
+	}
+	return result, nil
+}
+
+func (r *fakeIdentityRepo) GetAncestors(pk int) ([]model.Identity, error) {
+	var result []model.Identity
+	current := r.identities[pk]
+	for current != nil && current.ParentId != nil {
+		current = r.identities[*current.ParentId]
+		if current != nil {
+			result = append(result, *current)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeIdentityRepo) GetIdentityIds(pks []int) (map[int]string, error) {
+	ids := map[int]string{}
+	for _, pk := range pks {
+		if identity, ok := r.identities[pk]; ok {
+			ids[pk] = identity.IdentityId
+		}
+	}
+	return ids, nil
+}
+
+func (r *fakeIdentityRepo) GetSchemaById(id uuid.UUID) (model.Schema, error) {
+	return model.Schema{}, gorm.ErrRecordNotFound
+}
+
+// buildHierarchy creates root -> child -> grandchild.
+func buildHierarchy(t *testing.T) (*identity.Service, []*model.IdentityDto) {
+	t.Helper()
+	svc := &identity.Service{
+		IdentityRepo: newFakeIdentityRepo(),
+		Transaction: func(fn func(tx *gorm.DB) error) error {
+			return fn(nil)
+		},
+	}
+
+	root, err := svc.CreateIdentity("root", nil)
+	if err != nil {
+		t.Fatalf("CreateIdentity returned error: %v", err)
+	}
+	child, err := svc.CreateIdentity("child", &root.IdentityId)
+	if err != nil {
+		t.Fatalf("CreateIdentity returned error: %v", err)
+	}
+	grandchild, err := svc.CreateIdentity("grandchild", &child.IdentityId)
+	if err != nil {
+		t.Fatalf("CreateIdentity returned error: %v", err)
+	}
+	return svc, []*model.IdentityDto{root, child, grandchild}
+}
+
+func TestCreateIdentityExposesParentUUID(t *testing.T) {
+	_, ids := buildHierarchy(t)
+
+	if ids[0].ParentId != nil {
+		t.Error("Expected root identity to have no parent")
+	}
+	if ids[1].ParentId == nil || *ids[1].ParentId != ids[0].IdentityId {
+		t.Errorf("Expected child parent_id to be root UUID %s, got %v", ids[0].IdentityId, ids[1].ParentId)
+	}
+}
+
+func TestCreateIdentityWithUnknownParent(t *testing.T) {
+	svc := &identity.Service{IdentityRepo: newFakeIdentityRepo()}
+	missing := uuid.New().String()
+
+	if _, err := svc.CreateIdentity("orphan", &missing); !errors.Is(err, identity.ErrParentNotFound) {
+		t.Errorf("Expected ErrParentNotFound, got %v", err)
+	}
+}
+
+func TestIdentityHierarchyQueries(t *testing.T) {
+	svc, ids := buildHierarchy(t)
+
+	children, _ := svc.GetChildren(ids[0].IdentityId, false)
+	if len(children) != 1 || children[0].IdentityId != ids[1].IdentityId {
+		t.Errorf("Expected only direct child, got %v", children)
+	}
+
+	descendants, _ := svc.GetChildren(ids[0].IdentityId, true)
+	if len(descendants) != 2 {
+		t.Errorf("Expected 2 descendants, got %d", len(descendants))
+	}
+
+	ancestors, _ := svc.GetAncestors(ids[2].IdentityId)
+	if len(ancestors) != 2 || ancestors[0].IdentityId != ids[1].IdentityId || ancestors[1].IdentityId != ids[0].IdentityId {
+		t.Errorf("Expected ancestors ordered from parent to root, got %v", ancestors)
+	}
+}
+
+func TestMoveIdentityRejectsCycles(t *testing.T) {
+	svc, ids := buildHierarchy(t)
+
+	if _, err := svc.MoveIdentity(ids[0].IdentityId, &ids[2].IdentityId); !errors.Is(err, identity.ErrHierarchyCycle) {
+		t.Errorf("Expected moving root below its grandchild to fail with ErrHierarchyCycle, got %v", err)
+	}
+	if _, err := svc.MoveIdentity(ids[1].IdentityId, &ids[1].IdentityId); !errors.Is(err, identity.ErrHierarchyCycle) {
+		t.Errorf("Expected moving identity below itself to fail with ErrHierarchyCycle, got %v", err)
+	}
+
+	moved, err := svc.MoveIdentity(ids[2].IdentityId, &ids[0].IdentityId)
+	if err != nil {
+		t.Fatalf("Expected valid move to succeed, got %v", err)
+	}
+	if *moved.ParentId != ids[0].IdentityId {
+		t.Errorf("Expected new parent %s, got %s", ids[0].IdentityId, *moved.ParentId)
+	}
+
+	root, err := svc.MoveIdentity(ids[1].IdentityId, nil)
+	if err != nil || root.ParentId != nil {
+		t.Errorf("Expected move to nil parent to create a root, got %v, %v", root, err)
+	}
+}
+
+func TestMoveIdentityLocksMovedIdentityAndNewAncestors(t *testing.T) {
+	svc, ids := buildHierarchy(t)
+	sibling, err := svc.CreateIdentity("sibling", &ids[0].IdentityId)
+	if err != nil {
+		t.Fatalf("CreateIdentity returned error: %v", err)
+	}
+	repo := svc.IdentityRepo.(*fakeIdentityRepo)
+	repo.locked = nil
+
+	if _, err := svc.MoveIdentity(sibling.IdentityId, &ids[2].IdentityId); err != nil {
+		t.Fatalf("MoveIdentity returned error: %v", err)
+	}
+
+	// sibling (4) below grandchild (3), whose chain is child (2) and root (1).
+	locked := map[int]bool{}
+	for _, pk := range repo.locked {
+		locked[pk] = true
+	}
+	for _, pk := range []int{1, 2, 3, 4} {
+		if !locked[pk] {
+			t.Errorf("Expected identity %d to be locked, got %v", pk, repo.locked)
+		}
+	}
+}
+
+func TestUpdateIdentityRejectsParentId(t *testing.T) {
+	svc, ids := buildHierarchy(t)
+	engine := gin.New()
+	engine.PUT("/v1/identity/:id", (&identity.Handler{Service: svc}).UpdateIdentity)
+
+	body := `{"identity_name":"renamed","parent_id":"` + ids[0].IdentityId + `"}`
+	req := httptest.NewRequest(http.MethodPut, "/v1/identity/"+ids[2].IdentityId, strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400, got %d", recorder.Code)
+	}
+	unchanged, _ := svc.GetIdentityById(ids[2].IdentityId)
+	if unchanged.IdentityName != "grandchild" || *unchanged.ParentId != ids[1].IdentityId {
+		t.Errorf("Expected identity to stay unchanged, got %+v", unchanged)
+	}
+}
+
+func TestDeleteIdentityRequiresLeaf(t *testing.T) {
+	svc, ids := buildHierarchy(t)
+
+	if err := svc.DeleteIdentity(ids[1].IdentityId); !errors.Is(err, identity.ErrHasChildren) {
+		t.Errorf("Expected ErrHasChildren, got %v", err)
+	}
+	if err := svc.DeleteIdentity(ids[2].IdentityId); err != nil {
+		t.Errorf("Expected leaf deletion to succeed, got %v", err)
+	}
+	if _, err := svc.GetIdentityById(ids[2].IdentityId); !errors.Is(err, identity.ErrIdentityNotFound) {
+		t.Errorf("Expected deleted identity to be gone, got %v", err)
+	}
+}
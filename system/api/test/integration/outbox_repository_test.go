@@ -4,6 +4,7 @@ import (
 	"api/src/model"
 	"api/src/outbox"
 	"api/test/integration/utils"
+	"context"
 	"fmt"
 	"os"
 	"testing"
@@ -65,7 +66,7 @@ func TestMain(m *testing.M) {
 	os.Exit(code)
 }
 
-func createTestEvent(t *testing.T, db *gorm.DB, toProcess bool) uuid.UUID {
+func createTestEvent(t *testing.T, db *gorm.DB, status model.OutboxStatus) uuid.UUID {
 	eventId, err := uuid.NewRandom()
 	assert.NoError(t, err)
 
@@ -80,7 +81,8 @@ func createTestEvent(t *testing.T, db *gorm.DB, toProcess bool) uuid.UUID {
 		IdentityId:     identityId.String(),
 		SchemaId:       schemaId.String(),
 		Retry:          0,
-		ToProcess:      toProcess,
+		Status:         status,
+		NextAttemptAt:  time.Now().Add(-time.Second),
 		RequestMessage: "test message",
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
@@ -103,7 +105,7 @@ func TestGetEvent(t *testing.T) {
 	assert.Error(t, err)
 
 	// Test getting existing event
-	eventId := createTestEvent(t, db, false)
+	eventId := createTestEvent(t, db, model.OutboxPending)
 	event, err := repo.GetEvent(eventId)
 	assert.NoError(t, err)
 	assert.Equal(t, eventId.String(), event.EventId)
@@ -130,7 +132,7 @@ func TestNewEvent(t *testing.T) {
 		assert.Equal(t, schemaId.String(), event.SchemaId)
 		assert.Equal(t, "test message", event.RequestMessage)
 		assert.Equal(t, 0, event.Retry)
-		assert.False(t, event.ToProcess)
+		assert.Equal(t, model.OutboxPending, event.Status)
 		assert.NotEmpty(t, event.CreatedAt)
 		assert.NotEmpty(t, event.UpdatedAt)
 		assert.Empty(t, event.ProcessedAt)
@@ -156,7 +158,7 @@ func TestNewEvent(t *testing.T) {
 
 		identityId, _ := uuid.NewRandom()
 		schemaId, _ := uuid.NewRandom()
-		existingEventId := createTestEvent(t, db, false)
+		existingEventId := createTestEvent(t, db, model.OutboxPending)
 
 		// Try to create event with same ID
 		event := model.OutboxEvent{
@@ -171,76 +173,152 @@ func TestNewEvent(t *testing.T) {
 	})
 }
 
-func TestGetUnprocessedEvents(t *testing.T) {
+func TestClaimDueEvents(t *testing.T) {
+	publishOk := func(context.Context, model.OutboxEvent) error { return nil }
+
 	t.Run("empty database", func(t *testing.T) {
 		db := setupTestDB(t)
 		repo := outbox.NewRepoWithDB(db)
 
-		events, err := repo.GetUnprocessedEvents()
+		published, err := repo.ClaimDueEvents(context.Background(), 10, publishOk)
 		assert.NoError(t, err)
-		assert.Empty(t, events)
+		assert.Equal(t, 0, published)
 	})
 
-	t.Run("only processed events", func(t *testing.T) {
+	t.Run("publishes only due pending events", func(t *testing.T) {
 		db := setupTestDB(t)
 		repo := outbox.NewRepoWithDB(db)
 
-		_ = createTestEvent(t, db, false)
-		_ = createTestEvent(t, db, false)
+		dueId := createTestEvent(t, db, model.OutboxPending)
+		_ = createTestEvent(t, db, model.OutboxPublished)
+		notDueId := createTestEvent(t, db, model.OutboxPending)
+		assert.NoError(t, db.Model(&model.OutboxEvent{}).
+			Where("event_id = ?", notDueId.String()).
+			Update("next_attempt_at", time.Now().Add(time.Hour)).Error)
+
+		var claimed []string
+		published, err := repo.ClaimDueEvents(context.Background(), 10, func(_ context.Context, e model.OutboxEvent) error {
+			claimed = append(claimed, e.EventId)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, published)
+		assert.Equal(t, []string{dueId.String()}, claimed)
 
-		events, err := repo.GetUnprocessedEvents()
+		event, err := repo.GetEvent(dueId)
 		assert.NoError(t, err)
-		assert.Empty(t, events)
+		assert.Equal(t, model.OutboxPublished, event.Status)
+		assert.NotNil(t, event.PublishedAt)
 	})
 
-	t.Run("single unprocessed event", func(t *testing.T) {
+	t.Run("respects limit", func(t *testing.T) {
 		db := setupTestDB(t)
 		repo := outbox.NewRepoWithDB(db)
 
-		eventId := createTestEvent(t, db, true)
-		_ = createTestEvent(t, db, false)
+		for i := 0; i < 3; i++ {
+			_ = createTestEvent(t, db, model.OutboxPending)
+		}
+
+		published, err := repo.ClaimDueEvents(context.Background(), 2, publishOk)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, published)
 
-		events, err := repo.GetUnprocessedEvents()
+		pending, err := repo.CountPending()
 		assert.NoError(t, err)
-		assert.Len(t, events, 1)
-		assert.Equal(t, eventId.String(), events[0].EventId)
-		assert.True(t, events[0].ToProcess)
+		assert.Equal(t, int64(1), pending)
 	})
 
-	t.Run("multiple mixed events", func(t *testing.T) {
+	t.Run("failed publish is rescheduled with backoff", func(t *testing.T) {
 		db := setupTestDB(t)
 		repo := outbox.NewRepoWithDB(db)
 
-		// Create mix of processed and unprocessed events
-		unprocessedIds := make([]string, 0)
-		for i := 0; i < 3; i++ {
-			if i%2 == 0 {
-				eventId := createTestEvent(t, db, true)
-				unprocessedIds = append(unprocessedIds, eventId.String())
-			} else {
-				_ = createTestEvent(t, db, false)
-			}
-		}
+		eventId := createTestEvent(t, db, model.OutboxPending)
+
+		published, err := repo.ClaimDueEvents(context.Background(), 10, func(context.Context, model.OutboxEvent) error {
+			return fmt.Errorf("broker down")
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 0, published)
+
+		event, err := repo.GetEvent(eventId)
+		assert.NoError(t, err)
+		assert.Equal(t, model.OutboxPending, event.Status)
+		assert.Equal(t, 1, event.Retry)
+		assert.Equal(t, "broker down", event.LastError)
+		assert.True(t, event.NextAttemptAt.After(time.Now()))
 
-		events, err := repo.GetUnprocessedEvents()
+		// Not due yet, so the next run leaves it alone
+		published, err = repo.ClaimDueEvents(context.Background(), 10, publishOk)
 		assert.NoError(t, err)
-		assert.Len(t, events, 2)
+		assert.Equal(t, 0, published)
+	})
 
-		// Verify all returned events are unprocessed and match our created IDs
-		eventIds := make([]string, 0)
+	t.Run("stops publishing once the run context is done", func(t *testing.T) {
+		db := setupTestDB(t)
+		repo := outbox.NewRepoWithDB(db)
+
+		firstId := createTestEvent(t, db, model.OutboxPending)
+		secondId := createTestEvent(t, db, model.OutboxPending)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		published, err := repo.ClaimDueEvents(ctx, 10, func(context.Context, model.OutboxEvent) error {
+			cancel()
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, published)
+
+		// Whichever event came second was left pending without a failed attempt
+		var events []model.OutboxEvent
+		assert.NoError(t, db.Where("event_id IN ?", []string{firstId.String(), secondId.String()}).Order("id").Find(&events).Error)
+		statuses := []model.OutboxStatus{events[0].Status, events[1].Status}
+		assert.ElementsMatch(t, []model.OutboxStatus{model.OutboxPublished, model.OutboxPending}, statuses)
 		for _, event := range events {
-			assert.True(t, event.ToProcess)
-			eventIds = append(eventIds, event.EventId)
+			assert.Zero(t, event.Retry)
 		}
-		assert.ElementsMatch(t, unprocessedIds, eventIds)
 	})
 }
 
+func TestSweepStalePublished(t *testing.T) {
+	db := setupTestDB(t)
+	repo := outbox.NewRepoWithDB(db)
+
+	staleId := createTestEvent(t, db, model.OutboxPublished)
+	freshId := createTestEvent(t, db, model.OutboxPublished)
+	lastTryId := createTestEvent(t, db, model.OutboxPublished)
+	setPublishedAt := func(id uuid.UUID, at time.Time, retry int) {
+		assert.NoError(t, db.Model(&model.OutboxEvent{}).
+			Where("event_id = ?", id.String()).
+			Updates(map[string]interface{}{"published_at": at, "retry": retry}).Error)
+	}
+	setPublishedAt(staleId, time.Now().Add(-time.Hour), 0)
+	setPublishedAt(freshId, time.Now(), 0)
+	setPublishedAt(lastTryId, time.Now().Add(-time.Hour), outbox.MaxRetries-1)
+
+	swept, err := repo.SweepStalePublished(time.Now().Add(-outbox.ResultTimeout), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, swept)
+
+	event, err := repo.GetEvent(staleId)
+	assert.NoError(t, err)
+	assert.Equal(t, model.OutboxPending, event.Status)
+	assert.Equal(t, 1, event.Retry)
+
+	event, err = repo.GetEvent(freshId)
+	assert.NoError(t, err)
+	assert.Equal(t, model.OutboxPublished, event.Status)
+
+	event, err = repo.GetEvent(lastTryId)
+	assert.NoError(t, err)
+	assert.Equal(t, model.OutboxDead, event.Status)
+}
+
 func TestMarkEventAsProcessed(t *testing.T) {
 	db := setupTestDB(t)
 	repo := outbox.NewRepoWithDB(db)
 
-	eventId := createTestEvent(t, db, true)
+	eventId := createTestEvent(t, db, model.OutboxPublished)
 
 	// Mark as processed
 	err := repo.MarkEventAsProcessed(eventId)
@@ -251,26 +329,30 @@ func TestMarkEventAsProcessed(t *testing.T) {
 	assert.Error(t, err)
 }
 
-func TestUpdateRetryValue(t *testing.T) {
-	t.Run("successful retry increments", func(t *testing.T) {
+func TestScheduleRetry(t *testing.T) {
+	t.Run("retries until dead-lettered", func(t *testing.T) {
 		db := setupTestDB(t)
 		repo := outbox.NewRepoWithDB(db)
 
-		eventId := createTestEvent(t, db, true)
+		eventId := createTestEvent(t, db, model.OutboxPublished)
 
-		// Test updating retry count
-		for i := 1; i <= outbox.MaxRetries+1; i++ {
-			err := repo.UpdateRetryValue(eventId)
+		for i := 1; i <= outbox.MaxRetries; i++ {
+			err := repo.ScheduleRetry(eventId, "verification failed")
 			assert.NoError(t, err)
 
-			if i <= outbox.MaxRetries {
-				event, err := repo.GetEvent(eventId)
-				assert.NoError(t, err)
-				assert.Equal(t, i, event.Retry)
+			event, err := repo.GetEvent(eventId)
+			assert.NoError(t, err)
+			assert.Equal(t, i, event.Retry)
+			assert.Equal(t, "verification failed", event.LastError)
+
+			if i < outbox.MaxRetries {
+				assert.Equal(t, model.OutboxPending, event.Status)
+				// Simulate the relay publishing the event again
+				assert.NoError(t, db.Model(&model.OutboxEvent{}).
+					Where("event_id = ?", eventId.String()).
+					Update("status", model.OutboxPublished).Error)
 			} else {
-				// After max retries, event should be marked as processed (deleted)
-				_, err := repo.GetEvent(eventId)
-				assert.Error(t, err)
+				assert.Equal(t, model.OutboxDead, event.Status)
 			}
 		}
 	})
@@ -280,48 +362,49 @@ func TestUpdateRetryValue(t *testing.T) {
 		repo := outbox.NewRepoWithDB(db)
 
 		nonExistentId, _ := uuid.NewRandom()
-		err := repo.UpdateRetryValue(nonExistentId)
+		err := repo.ScheduleRetry(nonExistentId, "failed")
 		assert.Error(t, err)
 	})
 
-	t.Run("concurrent retry updates", func(t *testing.T) {
+	t.Run("duplicate failure is ignored", func(t *testing.T) {
 		db := setupTestDB(t)
 		repo := outbox.NewRepoWithDB(db)
 
-		eventId := createTestEvent(t, db, true)
+		eventId := createTestEvent(t, db, model.OutboxPublished)
 
-		// Simulate concurrent updates
-		repo1 := outbox.NewRepoWithDB(db)
-		repo2 := outbox.NewRepoWithDB(db)
-
-		err1 := repo1.UpdateRetryValue(eventId)
+		err1 := repo.ScheduleRetry(eventId, "failed")
 		assert.NoError(t, err1)
 
-		err2 := repo2.UpdateRetryValue(eventId)
+		err2 := repo.ScheduleRetry(eventId, "failed")
 		assert.NoError(t, err2)
 
 		event, err := repo.GetEvent(eventId)
 		assert.NoError(t, err)
-		assert.Equal(t, 2, event.Retry)
+		assert.Equal(t, 1, event.Retry)
 	})
 
 	t.Run("retry on already processed event", func(t *testing.T) {
 		db := setupTestDB(t)
 		repo := outbox.NewRepoWithDB(db)
 
-		eventId := createTestEvent(t, db, true)
+		eventId := createTestEvent(t, db, model.OutboxPublished)
 		err := repo.MarkEventAsProcessed(eventId)
 		assert.NoError(t, err)
 
-		err = repo.UpdateRetryValue(eventId)
-		assert.Error(t, err)
+		err = repo.ScheduleRetry(eventId, "failed")
+		assert.NoError(t, err)
+
+		var event model.OutboxEvent
+		assert.NoError(t, db.Unscoped().First(&event, "event_id = ?", eventId.String()).Error)
+		assert.Equal(t, model.OutboxProcessed, event.Status)
+		assert.Equal(t, 0, event.Retry)
 	})
 }
 
 func TestConcurrentOperations(t *testing.T) {
 	t.Run("concurrent mark as processed", func(t *testing.T) {
 		db := setupTestDB(t)
-		eventId := createTestEvent(t, db, true)
+		eventId := createTestEvent(t, db, model.OutboxPublished)
 
 		// Create two repository instances to simulate concurrent operations
 		repo1 := outbox.NewRepoWithDB(db)
@@ -342,14 +425,14 @@ func TestTransactionRollback(t *testing.T) {
 		db := setupTestDB(t)
 		repo := outbox.NewRepoWithDB(db)
 
-		eventId := createTestEvent(t, db, true)
+		eventId := createTestEvent(t, db, model.OutboxPublished)
 
 		// Begin a transaction in a closure to ensure proper cleanup
 		err := db.Transaction(func(tx *gorm.DB) error {
 			txRepo := repo.WithTx(tx)
 
 			// Update event within transaction
-			if err := txRepo.UpdateRetryValue(eventId); err != nil {
+			if err := txRepo.ScheduleRetry(eventId, "failed"); err != nil {
 				return err
 			}
 
@@ -0,0 +1,42 @@
+package integration
+
+import (
+	"api/src/model"
+	"api/src/privacy"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// A soft-deleted identity still holds its name, so erasure must find and
+// remove it, and report its soft-deleted children.
+func TestEraseSoftDeletedIdentity(t *testing.T) {
+	db := setupTestDB(t)
+	repo := privacy.NewRepositoryWithDB(db)
+
+	parent := model.Identity{IdentityId: uuid.NewString(), IdentityName: "parent"}
+	assert.NoError(t, db.Create(&parent).Error)
+	child := model.Identity{IdentityId: uuid.NewString(), IdentityName: "child", ParentId: &parent.Id}
+	assert.NoError(t, db.Create(&child).Error)
+	assert.NoError(t, db.Delete(&child).Error)
+	assert.NoError(t, db.Delete(&parent).Error)
+
+	data, err := repo.GetIdentityData(parent.IdentityId)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{child.IdentityId}, data.ChildIds)
+
+	data, err = repo.GetIdentityData(child.IdentityId)
+	assert.NoError(t, err)
+	_, err = repo.Erase(data, model.LogAuditEntry{Level: "INFO", Message: "Erased identity data", Service: "api", Timestamp: time.Now()})
+	assert.NoError(t, err)
+
+	var remaining int64
+	assert.NoError(t, db.Unscoped().Model(&model.Identity{}).Where("identity_id = ?", child.IdentityId).Count(&remaining).Error)
+	assert.Zero(t, remaining)
+
+	data, err = repo.GetIdentityData(parent.IdentityId)
+	assert.NoError(t, err)
+	assert.Empty(t, data.ChildIds)
+}
@@ -0,0 +1,26 @@
+package integration
+
+import (
+	"api/src/model"
+	"api/src/vault"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// A second data key for the same identity is ignored without an error, so the
+// surrounding transaction stays usable and the first key is kept.
+func TestCreateDataKeyKeepsExistingKey(t *testing.T) {
+	db := setupTestDB(t)
+	repo := vault.NewRepositoryWithDB(db)
+	identityId := uuid.NewString()
+
+	assert.NoError(t, repo.CreateDataKey(&model.IdentityDataKey{IdentityId: identityId, KeyId: "first", WrappedKey: []byte{1}}))
+	assert.NoError(t, repo.CreateDataKey(&model.IdentityDataKey{IdentityId: identityId, KeyId: "second", WrappedKey: []byte{2}}))
+
+	stored, err := repo.GetDataKey(identityId)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", stored.KeyId)
+	assert.Equal(t, []byte{1}, stored.WrappedKey)
+}
@@ -0,0 +1,71 @@
+package test
+
+import (
+	"api/src/log_audit"
+	"api/src/model"
+	"encoding/json"
+	"testing"
+
+	"pkg-common/logger"
+	logger_message "pkg-common/utilities/logger"
+	"pkg-common/utilities/timeutil"
+)
+
+type fakeLogAuditRepo struct {
+	entries []model.LogAuditEntry
+}
+
+func (r *fakeLogAuditRepo) CreateLogEntry(entry model.LogAuditEntry) error {
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *fakeLogAuditRepo) GetLogEntries(limit, offset int) ([]model.LogAuditEntry, error) {
+	return r.entries, nil
+}
+
+func (r *fakeLogAuditRepo) GetLogEntriesByService(service string, limit, offset int) ([]model.LogAuditEntry, error) {
+	return nil, nil
+}
+
+func (r *fakeLogAuditRepo) GetLogEntriesByLevel(level string, limit, offset int) ([]model.LogAuditEntry, error) {
+	return nil, nil
+}
+
+func TestProcessLogMessageKeepsFields(t *testing.T) {
+	repo := &fakeLogAuditRepo{}
+	service := logaudit.NewLogAuditService(repo)
+
+	err := service.ProcessLogMessage(logger_message.LoggerMessage{
+		Level:     "error",
+		Message:   "Failed to process verification result",
+		Timestamp: timeutil.NowUTC(),
+		Service:   "blockchain-client",
+		Fields: map[string]any{
+			logger.IdentityIdKey: "6f1c2e0a-3c1f-4a4e-9a57-0d4d0c8f2b11",
+			logger.RequestIdKey:  "req-1",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	entry := repo.entries[0]
+	if entry.Service != "blockchain-client" {
+		t.Errorf("Expected service of the producer, got %s", entry.Service)
+	}
+	if entry.IdentityId != "6f1c2e0a-3c1f-4a4e-9a57-0d4d0c8f2b11" {
+		t.Errorf("Expected identity id column, got %q", entry.IdentityId)
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(entry.Fields), &fields); err != nil {
+		t.Fatalf("Fields are not JSON: %v", err)
+	}
+	if fields[logger.RequestIdKey] != "req-1" {
+		t.Errorf("Expected request id in fields, got %v", fields)
+	}
+	if _, ok := fields[logger.IdentityIdKey]; ok {
+		t.Error("Expected identity id to be stored only in its column")
+	}
+}
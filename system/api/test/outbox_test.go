@@ -0,0 +1,171 @@
+package test
+
+import (
+	"api/src/identity"
+	"api/src/model"
+	"api/src/outbox"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type fakeOutboxRepo struct {
+	events   []model.OutboxEvent
+	newError error
+}
+
+func (r *fakeOutboxRepo) GetEvent(eventId uuid.UUID) (model.OutboxEvent, error) {
+	for _, event := range r.events {
+		if event.EventId == eventId.String() {
+			return event, nil
+		}
+	}
+	return model.OutboxEvent{}, gorm.ErrRecordNotFound
+}
+
+func (r *fakeOutboxRepo) NewEvent(identityId, schemaId uuid.UUID, reqestMsg string) (uuid.UUID, error) {
+	if r.newError != nil {
+		return uuid.Nil, r.newError
+	}
+	eventId := uuid.New()
+	r.events = append(r.events, model.OutboxEvent{
+		EventId:        eventId.String(),
+		IdentityId:     identityId.String(),
+		SchemaId:       schemaId.String(),
+		RequestMessage: reqestMsg,
+		Status:         model.OutboxPending,
+	})
+	return eventId, nil
+}
+
+func (r *fakeOutboxRepo) ClaimDueEvents(ctx context.Context, limit int, publish func(context.Context, model.OutboxEvent) error) (int, error) {
+	return 0, nil
+}
+
+func (r *fakeOutboxRepo) CountPending() (int64, error) {
+	return int64(len(r.events)), nil
+}
+
+func (r *fakeOutboxRepo) SweepStalePublished(olderThan time.Time, limit int) (int, error) {
+	return 0, nil
+}
+
+func (r *fakeOutboxRepo) MarkEventAsProcessed(uuid.UUID) error {
+	return nil
+}
+
+func (r *fakeOutboxRepo) ScheduleRetry(eventId uuid.UUID, reason string) error {
+	return nil
+}
+
+func (r *fakeOutboxRepo) Requeue(eventId uuid.UUID) error {
+	for i, event := range r.events {
+		if event.EventId != eventId.String() {
+			continue
+		}
+		if !outbox.Requeueable(event, time.Now()) {
+			return outbox.ErrNotRequeueable
+		}
+		r.events[i].Status = model.OutboxPending
+		return nil
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (r *fakeOutboxRepo) Discard(eventIds []string) (int64, error) {
+	var discarded int64
+	for i, event := range r.events {
+		for _, id := range eventIds {
+			if event.EventId == id && (event.Status == model.OutboxPending || event.Status == model.OutboxDead) {
+				r.events[i].Status = model.OutboxDiscarded
+				discarded++
+			}
+		}
+	}
+	return discarded, nil
+}
+
+func (r *fakeOutboxRepo) WithTx(*gorm.DB) outbox.OutboxRepository {
+	return r
+}
+
+// schemaIdentityRepo serves the same age schema for every id.
+type schemaIdentityRepo struct {
+	*fakeIdentityRepo
+}
+
+func (r schemaIdentityRepo) GetSchemaById(id uuid.UUID) (model.Schema, error) {
+	return model.Schema{Constraints: []model.Constraint{
+		{Key: "age", Comparison: model.GreaterThan, Value: float64(18)},
+	}}, nil
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	if outbox.Backoff(1) != outbox.BaseBackoff {
+		t.Errorf("Expected first retry after %s, got %s", outbox.BaseBackoff, outbox.Backoff(1))
+	}
+	if outbox.Backoff(3) != 4*outbox.BaseBackoff {
+		t.Errorf("Expected backoff to double per attempt, got %s", outbox.Backoff(3))
+	}
+	if outbox.Backoff(50) != outbox.MaxBackoff {
+		t.Errorf("Expected backoff to be capped at %s, got %s", outbox.MaxBackoff, outbox.Backoff(50))
+	}
+}
+
+func TestOutboxIdempotencyKey(t *testing.T) {
+	event := model.OutboxEvent{EventId: uuid.New().String(), RequestMessage: "vault:ref"}
+
+	msg := event.MapToZkpVerifcationRequest()
+	if msg.MessageId() != event.EventId+":0" || msg.Data != "vault:ref" {
+		t.Errorf("Unexpected message: %+v", msg)
+	}
+
+	event.Retry = 2
+	if event.MapToZkpVerifcationRequest().MessageId() == msg.MessageId() {
+		t.Error("Expected a retry to get a new idempotency key")
+	}
+}
+
+func TestQueueVerificationWritesOutboxInTransaction(t *testing.T) {
+	attributeVault, vaultRepo := newTestVault(t)
+	outboxRepo := &fakeOutboxRepo{}
+	transactions := 0
+	svc := &identity.Service{
+		IdentityRepo: schemaIdentityRepo{newFakeIdentityRepo()},
+		OutboxRepo:   outboxRepo,
+		Vault:        attributeVault,
+		Transaction: func(fn func(tx *gorm.DB) error) error {
+			transactions++
+			return fn(nil)
+		},
+	}
+	req := model.ZeroKnowledgeProofVerificationRequest{
+		IdentityId: uuid.New().String(),
+		SchemaId:   uuid.New().String(),
+		Fields:     []model.ZkpField{{Key: "age", Value: float64(30)}},
+	}
+
+	if err := svc.QueueVerification(context.Background(), req); err != nil {
+		t.Fatalf("QueueVerification returned error: %v", err)
+	}
+	if transactions != 1 || len(outboxRepo.events) != 1 {
+		t.Fatalf("Expected one outbox event written in one transaction, got %d events in %d transactions", len(outboxRepo.events), transactions)
+	}
+	event := outboxRepo.events[0]
+	if !strings.HasPrefix(event.RequestMessage, "vault:") || event.Status != model.OutboxPending {
+		t.Errorf("Expected pending event carrying a vault reference, got %+v", event)
+	}
+	if len(vaultRepo.records) != 1 {
+		t.Errorf("Expected attributes to be stored in the vault")
+	}
+
+	outboxRepo.newError = errors.New("insert failed")
+	if err := svc.QueueVerification(context.Background(), req); err == nil {
+		t.Error("Expected outbox failure to fail the request")
+	}
+}
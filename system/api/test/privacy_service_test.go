@@ -0,0 +1,222 @@
+package test
+
+import (
+	"api/src/model"
+	"api/src/privacy"
+	"api/src/proofs"
+	"api/src/vault"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type fakePrivacyRepo struct {
+	data   map[string]*privacy.IdentityData
+	erased []string
+	audits []model.LogAuditEntry
+}
+
+func (r *fakePrivacyRepo) GetIdentityData(identityId string) (*privacy.IdentityData, error) {
+	data, ok := r.data[identityId]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return data, nil
+}
+
+func (r *fakePrivacyRepo) Erase(data *privacy.IdentityData, audit model.LogAuditEntry) (privacy.ErasureResult, error) {
+	r.erased = append(r.erased, data.Identity.IdentityId)
+	r.audits = append(r.audits, audit)
+	delete(r.data, data.Identity.IdentityId)
+	return privacy.ErasureResult{
+		Events:   int64(len(data.Events)),
+		Failures: int64(len(data.Failures)),
+		Proofs:   int64(len(data.Proofs)),
+	}, nil
+}
+
+func (r *fakePrivacyRepo) CreateAuditEntry(audit model.LogAuditEntry) error {
+	r.audits = append(r.audits, audit)
+	return nil
+}
+
+type fakeChainCloser struct {
+	closed []string
+	err    error
+}
+
+func (c *fakeChainCloser) CloseAccount(ctx context.Context, account string) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.closed = append(c.closed, account)
+	return nil
+}
+
+func newPrivacyFixture(t *testing.T) (privacy.PrivacyService, *fakePrivacyRepo, *fakeChainCloser, vault.Vault, string) {
+	t.Helper()
+	v, _ := newTestVault(t)
+	identityId := uuid.NewString()
+
+	ref, err := v.Store(identityId, []model.ZkpField{{Key: "birth_year", Value: "1990"}})
+	if err != nil {
+		t.Fatalf("Store returned error: %v", err)
+	}
+
+	repo := &fakePrivacyRepo{data: map[string]*privacy.IdentityData{
+		identityId: {
+			Identity: model.Identity{Id: 1, IdentityId: identityId, IdentityName: "alice"},
+			Events: []model.OutboxEvent{
+				{EventId: uuid.NewString(), SchemaId: uuid.NewString(), RequestMessage: ref},
+				{EventId: uuid.NewString(), SchemaId: uuid.NewString(), RequestMessage: `{"key":"age","value":30}`},
+			},
+			Failures: []model.ZkpProofFailure{{EventId: "e1", Error: "boom", ReasonCode: "SOLANA"}},
+			Proofs: []proofs.ProofRecord{
+				{ZeroKnowledgeProof: model.ZeroKnowledgeProof{ProofId: uuid.NewString(), AccountId: "acc-1"}},
+				{ZeroKnowledgeProof: model.ZeroKnowledgeProof{ProofId: uuid.NewString(), AccountId: "acc-2"}},
+			},
+		},
+	}}
+	chain := &fakeChainCloser{}
+	return privacy.NewPrivacyService(repo, v, chain), repo, chain, v, identityId
+}
+
+func TestExportIdentityDecryptsAttributes(t *testing.T) {
+	svc, repo, _, _, identityId := newPrivacyFixture(t)
+
+	export, err := svc.ExportIdentity(identityId)
+	if err != nil {
+		t.Fatalf("ExportIdentity returned error: %v", err)
+	}
+
+	if export.Identity.IdentityName != "alice" || len(export.Proofs) != 2 || len(export.Failures) != 1 {
+		t.Errorf("Unexpected export: %+v", export)
+	}
+	if len(export.Verifications) != 2 {
+		t.Fatalf("Expected 2 verifications, got %d", len(export.Verifications))
+	}
+	if attrs := export.Verifications[0].Attributes; len(attrs) != 1 || attrs[0].Value != "1990" {
+		t.Errorf("Expected decrypted vault attributes, got %+v", export.Verifications[0])
+	}
+	if export.Verifications[1].LegacyData == "" {
+		t.Error("Expected pre-vault request to be exported as legacy data")
+	}
+	if len(repo.audits) != 1 {
+		t.Errorf("Expected export to be audited, got %d audit entries", len(repo.audits))
+	}
+}
+
+func TestEraseIdentityShredsClosesAndDeletes(t *testing.T) {
+	svc, repo, chain, v, identityId := newPrivacyFixture(t)
+	ref := repo.data[identityId].Events[0].RequestMessage
+
+	report, err := svc.EraseIdentity(context.Background(), identityId)
+	if err != nil {
+		t.Fatalf("EraseIdentity returned error: %v", err)
+	}
+
+	if report.Verifications != 2 || report.Proofs != 2 || report.ClosedAccounts != 2 {
+		t.Errorf("Unexpected erasure report: %+v", report)
+	}
+	if len(chain.closed) != 2 {
+		t.Errorf("Expected both proof accounts to be closed, got %v", chain.closed)
+	}
+	if _, err := v.Load(ref); err == nil {
+		t.Error("Expected vault attributes to be unreadable after erasure")
+	}
+	if len(repo.erased) != 1 || len(repo.audits) != 1 {
+		t.Fatalf("Expected one erase with one audit entry, got %d and %d", len(repo.erased), len(repo.audits))
+	}
+	if strings.Contains(repo.audits[0].Message, identityId) {
+		t.Error("Expected erasure audit entry not to identify the subject")
+	}
+
+	if _, err := svc.EraseIdentity(context.Background(), identityId); !errors.Is(err, privacy.ErrIdentityNotFound) {
+		t.Errorf("Expected ErrIdentityNotFound on second erasure, got %v", err)
+	}
+}
+
+func TestEraseIdentityAbortsWhenChainUnavailable(t *testing.T) {
+	svc, repo, chain, v, identityId := newPrivacyFixture(t)
+	ref := repo.data[identityId].Events[0].RequestMessage
+	chain.err = fmt.Errorf("%w: down", privacy.ErrChainUnavailable)
+
+	if _, err := svc.EraseIdentity(context.Background(), identityId); !errors.Is(err, privacy.ErrChainUnavailable) {
+		t.Fatalf("Expected ErrChainUnavailable, got %v", err)
+	}
+	if len(repo.erased) != 0 {
+		t.Error("Expected rows to be kept so the erasure can be retried")
+	}
+	if _, err := v.Load(ref); err != nil {
+		t.Errorf("Expected attributes to stay readable until the accounts are closed, got %v", err)
+	}
+
+	chain.err = nil
+	if _, err := svc.EraseIdentity(context.Background(), identityId); err != nil {
+		t.Fatalf("Expected the retried erasure to succeed, got %v", err)
+	}
+	if _, err := v.Load(ref); err == nil {
+		t.Error("Expected vault attributes to be unreadable after the retry")
+	}
+}
+
+func TestEraseIdentityWithChildren(t *testing.T) {
+	svc, repo, chain, _, identityId := newPrivacyFixture(t)
+	repo.data[identityId].ChildIds = []string{uuid.NewString()}
+
+	if _, err := svc.EraseIdentity(context.Background(), identityId); !errors.Is(err, privacy.ErrHasChildren) {
+		t.Errorf("Expected ErrHasChildren, got %v", err)
+	}
+	if len(chain.closed) != 0 || len(repo.erased) != 0 {
+		t.Error("Expected nothing to be erased")
+	}
+}
+
+func TestPrivacyServiceRejectsInvalidId(t *testing.T) {
+	svc, _, _, _, _ := newPrivacyFixture(t)
+
+	if _, err := svc.ExportIdentity("not-a-uuid"); !errors.Is(err, privacy.ErrIdentityNotFound) {
+		t.Errorf("Expected ErrIdentityNotFound, got %v", err)
+	}
+}
+
+func TestHttpChainCloserTreatsMissingAccountAsClosed(t *testing.T) {
+	issuer, authorized := newBlockchainClientAuth(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, privacy.CloseAccountScope) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodDelete {
+			t.Errorf("Expected DELETE, got %s", r.Method)
+		}
+		switch r.URL.Path {
+		case "/v1/internal/accounts/gone":
+			w.WriteHeader(http.StatusNotFound)
+		case "/v1/internal/accounts/broken":
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(`{"error":"rpc down"}`))
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	closer := privacy.NewHttpChainCloser(newBlockchainHttpClient(server.URL, issuer))
+	if err := closer.CloseAccount(context.Background(), "acc"); err != nil {
+		t.Errorf("Expected close to succeed, got %v", err)
+	}
+	if err := closer.CloseAccount(context.Background(), "gone"); err != nil {
+		t.Errorf("Expected missing account to count as closed, got %v", err)
+	}
+	if err := closer.CloseAccount(context.Background(), "broken"); !errors.Is(err, privacy.ErrChainUnavailable) {
+		t.Errorf("Expected ErrChainUnavailable, got %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+package test
+
+import (
+	"api/src/model"
+	"api/src/privacy"
+	"api/src/proofs"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	httpclient "pkg-common/http_client"
+	"pkg-common/logger"
+	"pkg-common/rest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type fakeProofRepo struct {
+	identities map[string]int
+	records    []proofs.ProofRecord
+	lastFilter proofs.ProofFilter
+	updated    map[int]model.ProofStatus
+	listErr    error
+}
+
+func (r *fakeProofRepo) GetIdentityPk(identityId string) (int, error) {
+	pk, ok := r.identities[identityId]
+	if !ok {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return pk, nil
+}
+
+func (r *fakeProofRepo) ListProofs(filter proofs.ProofFilter) ([]proofs.ProofRecord, int64, error) {
+	r.lastFilter = filter
+	if r.listErr != nil {
+		return nil, 0, r.listErr
+	}
+	return r.records, int64(len(r.records)), nil
+}
+
+func (r *fakeProofRepo) GetProof(proofId string) (proofs.ProofRecord, error) {
+	for _, record := range r.records {
+		if record.ProofId == proofId {
+			return record, nil
+		}
+	}
+	return proofs.ProofRecord{}, gorm.ErrRecordNotFound
+}
+
+func (r *fakeProofRepo) UpdateVerificationStatus(proofPk int, status model.ProofStatus, verifiedAt time.Time) error {
+	r.updated[proofPk] = status
+	return nil
+}
+
+type fakeVerifier struct {
+	result proofs.VerificationResult
+	err    error
+}
+
+func (v fakeVerifier) VerifyOnChain(ctx context.Context, signature, account string, size int) (proofs.VerificationResult, error) {
+	return v.result, v.err
+}
+
+func newProofFixture() (*fakeProofRepo, string, string) {
+	identityId := uuid.New().String()
+	proofId := uuid.New().String()
+	record := proofs.ProofRecord{IdentityId: identityId, SchemaId: uuid.New().String()}
+	record.Id = 7
+	record.ProofId = proofId
+	record.ProofReference = "sig"
+	record.AccountId = "acc"
+	record.Status = model.ProofStored
+
+	return &fakeProofRepo{
+		identities: map[string]int{identityId: 3},
+		records:    []proofs.ProofRecord{record},
+		updated:    map[int]model.ProofStatus{},
+	}, identityId, proofId
+}
+
+func TestListIdentityProofs(t *testing.T) {
+	repo, identityId, proofId := newProofFixture()
+	svc := proofs.NewProofService(repo, fakeVerifier{})
+
+	page, err := svc.ListIdentityProofs(identityId, proofs.ProofFilter{Limit: 10})
+	if err != nil {
+		t.Fatalf("ListIdentityProofs returned error: %v", err)
+	}
+	if repo.lastFilter.IdentityPk == nil || *repo.lastFilter.IdentityPk != 3 {
+		t.Error("Expected listing to be scoped to the identity primary key")
+	}
+	if page.Total != 1 || page.Items[0].ProofId != proofId || page.Items[0].Signature != "sig" {
+		t.Errorf("Unexpected page: %+v", page)
+	}
+
+	if _, err := svc.ListIdentityProofs(uuid.New().String(), proofs.ProofFilter{}); !errors.Is(err, proofs.ErrIdentityNotFound) {
+		t.Errorf("Expected ErrIdentityNotFound, got %v", err)
+	}
+	if _, err := svc.ListIdentityProofs(identityId, proofs.ProofFilter{SchemaId: "nope"}); !errors.Is(err, proofs.ErrInvalidSchemaId) {
+		t.Errorf("Expected ErrInvalidSchemaId, got %v", err)
+	}
+}
+
+func TestProofHandlerLogsWithRequestFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.InitDefaultLogger(logger.GlobalLoggerConfig{Service: "api"})
+	entries := make(chan logger.Entry, 16)
+	logger.AddSinkToLoggerInstance(logger.Default(), func(entry logger.Entry) {
+		select {
+		case entries <- entry:
+		default:
+		}
+	})
+
+	repo, identityId, _ := newProofFixture()
+	repo.listErr = errors.New("database unavailable")
+	engine := gin.New()
+	engine.Use(rest.RequestIdMiddleware())
+	engine.GET("/v1/identity/:id/proofs", proofs.NewProofHandler(proofs.NewProofService(repo, fakeVerifier{})).GetIdentityProofs)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/identity/"+identityId+"/proofs", nil)
+	req.Header.Set(rest.RequestIdHeader, "request-1")
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected 500, got %d", recorder.Code)
+	}
+	for {
+		select {
+		case entry := <-entries:
+			if entry.Message != "Failed to retrieve proofs" {
+				continue
+			}
+			if entry.Fields[logger.RequestIdKey] != "request-1" || entry.Fields[logger.IdentityIdKey] != identityId {
+				t.Errorf("Expected request and identity fields, got %v", entry.Fields)
+			}
+			return
+		default:
+			t.Fatal("Expected the failure to be logged")
+		}
+	}
+}
+
+func TestReverifyProofRecordsStatus(t *testing.T) {
+	repo, _, proofId := newProofFixture()
+
+	svc := proofs.NewProofService(repo, fakeVerifier{result: proofs.VerificationResult{Valid: false, Error: "Account not found"}})
+	result, err := svc.ReverifyProof(context.Background(), proofId)
+	if err != nil {
+		t.Fatalf("ReverifyProof returned error: %v", err)
+	}
+	if result.Status != model.ProofInvalid || result.VerificationError != "Account not found" || result.LastVerifiedAt == nil {
+		t.Errorf("Expected invalid status with error, got %+v", result)
+	}
+	if repo.updated[7] != model.ProofInvalid {
+		t.Error("Expected invalid status to be persisted")
+	}
+
+	svc = proofs.NewProofService(repo, fakeVerifier{err: proofs.ErrBlockchainUnavailable})
+	if _, err := svc.ReverifyProof(context.Background(), proofId); !errors.Is(err, proofs.ErrBlockchainUnavailable) {
+		t.Errorf("Expected ErrBlockchainUnavailable, got %v", err)
+	}
+}
+
+// newBlockchainClientAuth returns the token issuer of api and a check the
+// fake blockchain-client runs on each request.
+func newBlockchainClientAuth(t *testing.T) (*rest.InternalTokenIssuer, func(r *http.Request, scope string) bool) {
+	t.Helper()
+	issuer := rest.NewInternalTokenIssuer(rest.InternalAuthConfig{Service: "api", Secret: []byte("api-secret")})
+	authenticator := rest.NewInternalAuthenticator(rest.InternalAuthConfig{
+		Service: "blockchain-client",
+		TrustedServices: map[string]rest.InternalTrustedService{
+			"api": {Secret: []byte("api-secret"), Scopes: []string{proofs.VerifyProofScope, privacy.CloseAccountScope}},
+		},
+	})
+	return issuer, func(r *http.Request, scope string) bool {
+		caller, err := authenticator.Authenticate(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if err != nil || !caller.HasScope(scope) {
+			t.Errorf("Expected authorized call with scope %s, got %+v, %v", scope, caller, err)
+			return false
+		}
+		return true
+	}
+}
+
+func newBlockchainHttpClient(baseUrl string, issuer *rest.InternalTokenIssuer) *httpclient.Client {
+	return httpclient.NewClient(
+		httpclient.Config{BaseUrl: baseUrl, Timeout: time.Second},
+		httpclient.WithInternalAuth(issuer, "blockchain-client"),
+	)
+}
+
+func TestHttpBlockchainVerifier(t *testing.T) {
+	issuer, authorized := newBlockchainClientAuth(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, proofs.VerifyProofScope) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/v1/internal/verify" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		switch r.URL.Query().Get("signature") {
+		case "valid":
+			w.WriteHeader(http.StatusOK)
+		case "missing":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"error":"Account not found"}`))
+		case "lagging":
+			w.WriteHeader(http.StatusNotFound)
+		case "crashed":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	verifier := proofs.NewHttpBlockchainVerifier(newBlockchainHttpClient(server.URL, issuer))
+	ctx := context.Background()
+
+	if result, err := verifier.VerifyOnChain(ctx, "valid", "acc", 10); err != nil || !result.Valid {
+		t.Errorf("Expected valid result, got %+v, %v", result, err)
+	}
+	if result, err := verifier.VerifyOnChain(ctx, "missing", "acc", 10); err != nil || result.Valid || result.Error != "Account not found" {
+		t.Errorf("Expected invalid result with error, got %+v, %v", result, err)
+	}
+	for _, signature := range []string{"down", "lagging", "crashed"} {
+		if _, err := verifier.VerifyOnChain(ctx, signature, "acc", 10); !errors.Is(err, proofs.ErrBlockchainUnavailable) {
+			t.Errorf("Expected ErrBlockchainUnavailable for %s, got %v", signature, err)
+		}
+	}
+}
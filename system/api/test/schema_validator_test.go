@@ -0,0 +1,107 @@
+package test
+
+import (
+	"api/src/identity"
+	"api/src/model"
+	"encoding/json"
+	"testing"
+)
+
+func decodeFields(t *testing.T, raw string) []model.ZkpField {
+	t.Helper()
+	var fields []model.ZkpField
+	if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+		t.Fatalf("Invalid test fixture: %v", err)
+	}
+	return fields
+}
+
+func decodeSchema(t *testing.T, raw string) model.Schema {
+	t.Helper()
+	var schema model.Schema
+	if err := json.Unmarshal([]byte(raw), &schema); err != nil {
+		t.Fatalf("Invalid test fixture: %v", err)
+	}
+	return schema
+}
+
+func errorsByKey(errs []identity.FieldError) map[string]string {
+	result := make(map[string]string, len(errs))
+	for _, e := range errs {
+		result[e.Key] = e.Message
+	}
+	return result
+}
+
+func TestValidateSchemaAcceptsMatchingRequest(t *testing.T) {
+	schema := decodeSchema(t, `{"constraints": [
+		{"key": "age", "comparison_type": "gt", "value": 18},
+		{"key": "country", "comparison_type": "eq", "value": "PL"},
+		{"key": "banned", "comparison_type": "not", "value": true}
+	]}`)
+	req := model.ZeroKnowledgeProofVerificationRequest{
+		Fields: decodeFields(t, `[
+			{"key": "age", "value": 21},
+			{"key": "country", "value": "PL"},
+			{"key": "banned", "value": false}
+		]`),
+	}
+
+	if errs := identity.ValidateSchema(schema, req); len(errs) != 0 {
+		t.Errorf("Expected no validation errors, got %v", errs)
+	}
+}
+
+func TestValidateSchemaReportsEveryField(t *testing.T) {
+	schema := decodeSchema(t, `{"constraints": [
+		{"key": "age", "comparison_type": "le", "value": 65},
+		{"key": "country", "comparison_type": "eq", "value": "PL"},
+		{"key": "name", "comparison_type": "gt", "value": "A"},
+		{"key": "score", "comparison_type": "between", "value": 1}
+	]}`)
+	req := model.ZeroKnowledgeProofVerificationRequest{
+		Fields: decodeFields(t, `[
+			{"key": "age", "value": "forty"},
+			{"key": "name", "value": "Bob"},
+			{"key": "score", "value": 3},
+			{"key": "extra", "value": 1}
+		]`),
+	}
+
+	errs := errorsByKey(identity.ValidateSchema(schema, req))
+
+	expected := map[string]string{
+		"age":     "expected number value, got string",
+		"country": "required field is missing",
+		"name":    "comparison 'gt' is not applicable to string values",
+		"score":   "comparison 'between' is not applicable to number values",
+		"extra":   "field is not defined by the schema",
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %v", len(expected), errs)
+	}
+	for key, message := range expected {
+		if errs[key] != message {
+			t.Errorf("Expected error '%s' for %s, got '%s'", message, key, errs[key])
+		}
+	}
+}
+
+func TestValidateSchemaRejectsDuplicateFields(t *testing.T) {
+	schema := decodeSchema(t, `{"constraints": [{"key": "age", "comparison_type": "gt", "value": 18}]}`)
+	req := model.ZeroKnowledgeProofVerificationRequest{
+		Fields: decodeFields(t, `[{"key": "age", "value": 20}, {"key": "age", "value": 30}]`),
+	}
+
+	errs := errorsByKey(identity.ValidateSchema(schema, req))
+	if errs["age"] != "field submitted more than once" {
+		t.Errorf("Expected duplicate field error, got %v", errs)
+	}
+}
+
+func TestSchemaValidationErrorMessage(t *testing.T) {
+	err := &identity.SchemaValidationError{Fields: []identity.FieldError{{Key: "age", Message: "required field is missing"}}}
+	if err.Error() != "schema validation failed: age: required field is missing" {
+		t.Errorf("Unexpected error message: %s", err.Error())
+	}
+}
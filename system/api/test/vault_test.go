@@ -0,0 +1,278 @@
+package test
+
+import (
+	"api/src/model"
+	"api/src/vault"
+	zkpfailed "api/src/zkp/failed"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type fakeVaultRepo struct {
+	keys    map[string]model.IdentityDataKey
+	records map[string]model.VaultRecord
+}
+
+func newFakeVaultRepo() *fakeVaultRepo {
+	return &fakeVaultRepo{
+		keys:    make(map[string]model.IdentityDataKey),
+		records: make(map[string]model.VaultRecord),
+	}
+}
+
+func (r *fakeVaultRepo) WithTx(*gorm.DB) vault.Repository {
+	return r
+}
+
+func (r *fakeVaultRepo) GetDataKey(identityId string) (*model.IdentityDataKey, error) {
+	key, ok := r.keys[identityId]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &key, nil
+}
+
+// CreateDataKey keeps an existing key, like the ON CONFLICT DO NOTHING insert.
+func (r *fakeVaultRepo) CreateDataKey(key *model.IdentityDataKey) error {
+	if _, ok := r.keys[key.IdentityId]; !ok {
+		r.keys[key.IdentityId] = *key
+	}
+	return nil
+}
+
+// racingVaultRepo stores a data key of another request right before the
+// vault inserts its own.
+type racingVaultRepo struct {
+	*fakeVaultRepo
+	competing model.IdentityDataKey
+}
+
+func (r *racingVaultRepo) CreateDataKey(key *model.IdentityDataKey) error {
+	r.keys[r.competing.IdentityId] = r.competing
+	return r.fakeVaultRepo.CreateDataKey(key)
+}
+
+func (r *fakeVaultRepo) DeleteDataKey(identityId string) error {
+	delete(r.keys, identityId)
+	return nil
+}
+
+func (r *fakeVaultRepo) SaveRecord(record *model.VaultRecord) error {
+	r.records[record.RecordId] = *record
+	return nil
+}
+
+func (r *fakeVaultRepo) GetRecord(recordId string) (*model.VaultRecord, error) {
+	record, ok := r.records[recordId]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &record, nil
+}
+
+func (r *fakeVaultRepo) DeleteRecords(identityId string) error {
+	for id, record := range r.records {
+		if record.IdentityId == identityId {
+			delete(r.records, id)
+		}
+	}
+	return nil
+}
+
+func newTestVault(t *testing.T) (vault.Vault, *fakeVaultRepo) {
+	t.Helper()
+	keys, err := vault.NewLocalKeyManager("test", base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32)))
+	if err != nil {
+		t.Fatalf("NewLocalKeyManager returned error: %v", err)
+	}
+	repo := newFakeVaultRepo()
+	return vault.NewVault(repo, keys), repo
+}
+
+func TestVaultStoreAndLoad(t *testing.T) {
+	v, repo := newTestVault(t)
+	identityId := uuid.NewString()
+	fields := []model.ZkpField{{Key: "birth_year", Value: "1990"}}
+
+	ref, err := v.Store(identityId, fields)
+	if err != nil {
+		t.Fatalf("Store returned error: %v", err)
+	}
+
+	recordId, err := vault.ParseReference(ref)
+	if err != nil {
+		t.Fatalf("Store returned invalid reference '%s': %v", ref, err)
+	}
+	if bytes.Contains(repo.records[recordId].Ciphertext, []byte("1990")) {
+		t.Error("Expected attribute values to be encrypted at rest")
+	}
+	if len(repo.keys) != 1 {
+		t.Error("Expected exactly one wrapped data key for the identity")
+	}
+
+	loaded, err := v.Load(ref)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0].Key != "birth_year" || loaded[0].Value != "1990" {
+		t.Errorf("Unexpected attributes loaded: %+v", loaded)
+	}
+}
+
+func TestVaultShredMakesRecordsUnreadable(t *testing.T) {
+	v, repo := newTestVault(t)
+	identityId := uuid.NewString()
+	other := uuid.NewString()
+
+	ref, _ := v.Store(identityId, []model.ZkpField{{Key: "name", Value: "Alice"}})
+	otherRef, _ := v.Store(other, []model.ZkpField{{Key: "name", Value: "Bob"}})
+
+	// keep a copy as a backup would, to check the key is what protects it
+	recordId, _ := vault.ParseReference(ref)
+	backup := repo.records[recordId]
+
+	if err := v.Shred(identityId); err != nil {
+		t.Fatalf("Shred returned error: %v", err)
+	}
+	if _, err := v.Load(ref); !errors.Is(err, vault.ErrRecordNotFound) {
+		t.Errorf("Expected ErrRecordNotFound after shredding, got %v", err)
+	}
+
+	repo.records[recordId] = backup
+	if _, err := v.Load(ref); !errors.Is(err, vault.ErrShredded) {
+		t.Errorf("Expected restored record to stay unreadable, got %v", err)
+	}
+
+	if _, err := v.Load(otherRef); err != nil {
+		t.Errorf("Expected other identities to be unaffected, got %v", err)
+	}
+}
+
+func TestVaultRejectsRecordMovedToAnotherIdentity(t *testing.T) {
+	v, repo := newTestVault(t)
+	victim := uuid.NewString()
+	attacker := uuid.NewString()
+
+	ref, _ := v.Store(victim, []model.ZkpField{{Key: "name", Value: "Alice"}})
+	_, _ = v.Store(attacker, []model.ZkpField{{Key: "name", Value: "Mallory"}})
+
+	recordId, _ := vault.ParseReference(ref)
+	record := repo.records[recordId]
+	record.IdentityId = attacker
+	repo.records[recordId] = record
+
+	if _, err := v.Load(ref); err == nil {
+		t.Error("Expected decryption to fail for a record moved between identities")
+	}
+}
+
+func TestVaultUsesConcurrentlyCreatedDataKey(t *testing.T) {
+	keys, _ := vault.NewLocalKeyManager("test", base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32)))
+	identityId := uuid.NewString()
+	_, wrapped, keyId, err := keys.GenerateDataKey()
+	if err != nil {
+		t.Fatalf("GenerateDataKey returned error: %v", err)
+	}
+	repo := &racingVaultRepo{
+		fakeVaultRepo: newFakeVaultRepo(),
+		competing:     model.IdentityDataKey{IdentityId: identityId, KeyId: keyId, WrappedKey: wrapped},
+	}
+
+	ref, err := vault.NewVault(repo, keys).Store(identityId, []model.ZkpField{{Key: "name", Value: "Alice"}})
+	if err != nil {
+		t.Fatalf("Store returned error: %v", err)
+	}
+
+	// the record must be readable with the key that won the race
+	loaded, err := vault.NewVault(repo.fakeVaultRepo, keys).Load(ref)
+	if err != nil || len(loaded) != 1 || loaded[0].Value != "Alice" {
+		t.Errorf("Expected record encrypted with the stored key, got %+v, %v", loaded, err)
+	}
+}
+
+func TestVaultRejectsInvalidReferences(t *testing.T) {
+	v, _ := newTestVault(t)
+
+	for _, ref := range []string{"", "1990", "vault:not-a-uuid"} {
+		if _, err := v.Load(ref); !errors.Is(err, vault.ErrInvalidReference) {
+			t.Errorf("Load(%q): expected ErrInvalidReference, got %v", ref, err)
+		}
+	}
+}
+
+func TestLocalKeyManagerRequiresAes256Key(t *testing.T) {
+	if _, err := vault.NewLocalKeyManager("test", base64.StdEncoding.EncodeToString([]byte("short"))); err == nil {
+		t.Error("Expected error for a master key that is not 32 bytes")
+	}
+}
+
+func TestVaultHandlerResolvesReferences(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	v, _ := newTestVault(t)
+	identityId := uuid.NewString()
+	ref, _ := v.Store(identityId, []model.ZkpField{{Key: "birth_year", Value: "1990"}})
+	shreddedRef, _ := v.Store(uuid.NewString(), []model.ZkpField{{Key: "name", Value: "Bob"}})
+	recordId, _ := vault.ParseReference(shreddedRef)
+
+	router := gin.New()
+	router.GET("/attributes/:reference", vault.NewVaultHandler(v).ResolveAttributes)
+
+	resolve := func(reference string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/attributes/"+reference, nil))
+		return w
+	}
+
+	w := resolve(ref)
+	var dto model.VaultAttributesDto
+	if err := json.Unmarshal(w.Body.Bytes(), &dto); w.Code != http.StatusOK || err != nil {
+		t.Fatalf("Expected 200 with attributes, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(dto.Attributes) != 1 || dto.Attributes[0].Value != "1990" {
+		t.Errorf("Unexpected attributes: %+v", dto)
+	}
+
+	if w := resolve("1990"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid reference, got %d", w.Code)
+	}
+	if w := resolve(vault.Reference(uuid.NewString())); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown record, got %d", w.Code)
+	}
+
+	// a backup restored after erasure must stay unreadable
+	repo := newFakeVaultRepo()
+	keys, _ := vault.NewLocalKeyManager("test", base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32)))
+	repo.records[recordId] = model.VaultRecord{RecordId: recordId, IdentityId: uuid.NewString()}
+	router = gin.New()
+	router.GET("/attributes/:reference", vault.NewVaultHandler(vault.NewVault(repo, keys)).ResolveAttributes)
+	if w := resolve(shreddedRef); w.Code != http.StatusGone {
+		t.Errorf("Expected 410 for shredded attributes, got %d", w.Code)
+	}
+}
+
+func TestRedactRequestBodyDropsAttributeValues(t *testing.T) {
+	body := []byte(`{"event_id":"e1","resolved_values":[{"key":"year","value":"1990","type":"int","verification_positive":true}]}`)
+
+	redacted := zkpfailed.RedactRequestBody(body)
+	if bytes.Contains(redacted, []byte("1990")) {
+		t.Fatalf("Expected attribute values to be redacted, got %s", redacted)
+	}
+	for _, kept := range []string{`"event_id":"e1"`, `"key":"year"`, `"verification_positive":true`} {
+		if !bytes.Contains(redacted, []byte(kept)) {
+			t.Errorf("Expected %s to be kept, got %s", kept, redacted)
+		}
+	}
+
+	if zkpfailed.RedactRequestBody([]byte("year=1990")) != nil {
+		t.Error("Expected a body that is not JSON to be dropped")
+	}
+}
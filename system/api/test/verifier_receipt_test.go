@@ -0,0 +1,64 @@
+package test
+
+import (
+	"api/src/zkprequest"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"pkg-common/did"
+	"testing"
+	"time"
+)
+
+func TestWebhookCarriesReceiptSignedByVerifierDID(t *testing.T) {
+	identity, err := did.NewWebIdentity("http://verifier.local:9000", did.DefaultKeyFragment, nil)
+	if err != nil {
+		t.Fatalf("NewWebIdentity returned error: %v", err)
+	}
+
+	didServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(identity.Document)
+	}))
+	defer didServer.Close()
+
+	receipts := make(chan string, 1)
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receipts <- r.Header.Get("X-ZKP-Receipt")
+	}))
+	defer callback.Close()
+
+	svc := zkprequest.NewService(&zkprequest.InMemoryStore{}, func(s *zkprequest.Service) {
+		s.Identity = identity
+	})
+	svc.Store.Save(zkprequest.PresentationRequest{
+		RequestID:   "req-1",
+		SchemaHash:  "hash",
+		ExpiresAt:   time.Now().Add(time.Minute).Unix(),
+		CallbackURL: callback.URL,
+	})
+
+	if _, err := svc.MockVerify("req-1"); err != nil {
+		t.Fatalf("MockVerify returned error: %v", err)
+	}
+
+	receipt := <-receipts
+	if receipt == "" {
+		t.Fatal("Expected webhook to carry a receipt")
+	}
+
+	resolver := did.NewResolver(did.WithWebBaseUrl("verifier.local:9000", didServer.URL))
+	payload, kid, err := did.Verify(context.Background(), resolver, receipt)
+	if err != nil {
+		t.Fatalf("Receipt verification failed: %v", err)
+	}
+	if kid != identity.Did+"#"+did.DefaultKeyFragment {
+		t.Errorf("Expected kid '%s#%s', got '%s'", identity.Did, did.DefaultKeyFragment, kid)
+	}
+
+	var body map[string]any
+	json.Unmarshal(payload, &body)
+	if body["request_id"] != "req-1" || body["state"] != "verified" {
+		t.Errorf("Unexpected receipt payload: %s", payload)
+	}
+}
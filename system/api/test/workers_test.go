@@ -0,0 +1,190 @@
+package test
+
+import (
+	"api/src/model"
+	"api/src/outbox"
+	zkpfailed "api/src/zkp/failed"
+	"context"
+	"encoding/json"
+	"os"
+	dtocommon "pkg-common/dto_common"
+	"pkg-common/logger"
+	"pkg-common/rabbitmq"
+	"testing"
+	"time"
+)
+
+// apiRegistry opens the publishers and consumers of the api config on a
+// memory broker loaded with the shipped definitions.
+func apiRegistry(t *testing.T) (*rabbitmq.MemoryBroker, *rabbitmq.Registry) {
+	t.Helper()
+	broker := rabbitmq.NewMemoryBroker()
+	if err := broker.LoadDefinitions("../../rabbitmq/definitions.json"); err != nil {
+		t.Fatalf("Could not load definitions: %v", err)
+	}
+
+	raw, err := os.ReadFile("../config.json")
+	if err != nil {
+		t.Fatalf("Could not read config: %v", err)
+	}
+	var config struct {
+		Rabbitmq rabbitmq.RabbimqConfigJson `json:"rabbitmq"`
+	}
+	if err := json.Unmarshal(raw, &config); err != nil {
+		t.Fatalf("Could not parse config: %v", err)
+	}
+	conf := config.Rabbitmq.MapToDomain()
+
+	registry, err := rabbitmq.NewRegistry(broker.Connect(conf.Producer), conf.PublishersConfig, conf.ConsumersConfig)
+	if err != nil {
+		t.Fatalf("Could not open registry: %v", err)
+	}
+	return broker, registry
+}
+
+// blockingFailures holds every failure until release is closed.
+type blockingFailures struct {
+	started chan struct{}
+	release chan struct{}
+	saved   chan dtocommon.ZkpProofFailureDto
+}
+
+func (f *blockingFailures) SaveFailedAndUpdateOutbox(failure dtocommon.ZkpProofFailureDto) error {
+	f.started <- struct{}{}
+	<-f.release
+	f.saved <- failure
+	return nil
+}
+
+func TestProofFailedHandlerDrainsInFlightFailureOnStop(t *testing.T) {
+	logger.InitDefaultLogger(logger.GlobalLoggerConfig{Service: "api"})
+	broker, registry := apiRegistry(t)
+
+	service := &blockingFailures{
+		started: make(chan struct{}, 1),
+		release: make(chan struct{}),
+		saved:   make(chan dtocommon.ZkpProofFailureDto, 1),
+	}
+	handler := zkpfailed.NewZeroKnowledgeProofFailedHandler(registry, service)
+	ctx, cancel := context.WithCancel(context.Background())
+	go handler.StartService(ctx)
+
+	blockchainClient, _ := broker.Connect("blockchain-client").NewPublisher(rabbitmq.RabbitmqPublishersConfig{Exchange: "identity", RoutingKey: "proof.failures"})
+	if err := blockchainClient.PublishConfirmed(context.Background(), dtocommon.ZkpProofFailureDto{EventId: "event-1"}); err != nil {
+		t.Fatalf("Could not publish failure: %v", err)
+	}
+
+	select {
+	case <-service.started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Failure was not delivered")
+	}
+	cancel()
+
+	short, shortCancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer shortCancel()
+	if err := handler.Stop(short); err == nil {
+		t.Fatal("Expected Stop to wait for the failure in flight")
+	}
+
+	close(service.release)
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), time.Second)
+	defer stopCancel()
+	if err := handler.Stop(stopCtx); err != nil {
+		t.Fatalf("Handler did not stop: %v", err)
+	}
+	if failure := <-service.saved; failure.EventId != "event-1" {
+		t.Errorf("Unexpected failure: %+v", failure)
+	}
+	if queued := broker.QueueLength("proof.failures"); queued != 0 {
+		t.Errorf("Expected the failure to be acknowledged, %d still queued", queued)
+	}
+}
+
+// blockingRelayRepo holds a relay run inside ClaimDueEvents until release is
+// closed.
+type blockingRelayRepo struct {
+	fakeOutboxRepo
+	claims  chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingRelayRepo) ClaimDueEvents(ctx context.Context, limit int, publish func(context.Context, model.OutboxEvent) error) (int, error) {
+	r.claims <- struct{}{}
+	<-r.release
+	return 0, nil
+}
+
+func TestOutboxWorkerStopWaitsForRelayRun(t *testing.T) {
+	logger.InitDefaultLogger(logger.GlobalLoggerConfig{Service: "api"})
+	_, registry := apiRegistry(t)
+
+	repo := &blockingRelayRepo{claims: make(chan struct{}, 1), release: make(chan struct{})}
+	worker := outbox.NewOutboxWorker(registry, repo)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go worker.StartService(ctx)
+
+	select {
+	case <-repo.claims:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Relay did not run")
+	}
+
+	short, shortCancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer shortCancel()
+	if err := worker.Stop(short); err == nil {
+		t.Fatal("Expected Stop to wait for the relay run")
+	}
+
+	close(repo.release)
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), time.Second)
+	defer stopCancel()
+	if err := worker.Stop(stopCtx); err != nil {
+		t.Fatalf("Worker did not stop: %v", err)
+	}
+
+	// The schedule is halted, so no run claims events after Stop
+	select {
+	case <-repo.claims:
+		t.Error("Expected no relay run after Stop")
+	case <-time.After(2500 * time.Millisecond):
+	}
+}
+
+// deadlineRelayRepo reports the deadline of the context a relay run claims
+// events with.
+type deadlineRelayRepo struct {
+	fakeOutboxRepo
+	deadlines chan time.Time
+}
+
+func (r *deadlineRelayRepo) ClaimDueEvents(ctx context.Context, limit int, publish func(context.Context, model.OutboxEvent) error) (int, error) {
+	deadline, _ := ctx.Deadline()
+	select {
+	case r.deadlines <- deadline:
+	default:
+	}
+	return 0, nil
+}
+
+func TestOutboxWorkerBoundsRelayRun(t *testing.T) {
+	logger.InitDefaultLogger(logger.GlobalLoggerConfig{Service: "api"})
+	_, registry := apiRegistry(t)
+
+	repo := &deadlineRelayRepo{deadlines: make(chan time.Time, 1)}
+	worker := outbox.NewOutboxWorker(registry, repo)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go worker.StartService(ctx)
+	defer worker.Stop(context.Background())
+
+	select {
+	case deadline := <-repo.deadlines:
+		if deadline.IsZero() || time.Until(deadline) > time.Minute {
+			t.Errorf("Expected the relay run to hold its rows for a bounded time, got deadline %v", deadline)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Relay did not run")
+	}
+}
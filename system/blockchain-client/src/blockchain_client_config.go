@@ -1,14 +1,34 @@
 package main
 
 import (
+	"blockchain-client/src/external"
+	"errors"
 	"pkg-common/logger"
 	"pkg-common/rabbitmq"
+	"pkg-common/rest"
+	"pkg-common/tracing"
+	"pkg-common/utilities"
 )
 
 type BlockchainClientConfigJson struct {
 	LoggerConf   logger.LoggerConfigJson        `json:"logger"`
 	RabbitmqConf rabbitmq.RabbimqConfigJson     `json:"rabbitmq"`
 	RestConf     BlockchainClientRestConfigJson `json:"rest"`
+	TracingConf  tracing.TracingConfigJson      `json:"tracing"`
+	InternalAuth rest.InternalAuthConfigJson    `json:"internal_auth"`
+	SolanaConf   external.SolanaConfigJson      `json:"solana"`
+	LanHost      string                         `json:"lan_host"`
+}
+
+// EnvAliases keeps the variables the deployments set before the
+// BLOCKCHAIN_CLIENT__ scheme.
+func (bccj BlockchainClientConfigJson) EnvAliases() map[string]string {
+	return map[string]string{
+		"PROGRAM_ID":            "solana.program_id",
+		"PAYER_KEYPAIR_PATH":    "solana.payer_keypair_path",
+		"SOLANA_URL":            "solana.rpc.base_url",
+		utilities.LanHostEnvKey: "lan_host",
+	}
 }
 
 func (bccj BlockchainClientConfigJson) MapToDomain() BlockchainClientConfig {
@@ -16,6 +36,10 @@ func (bccj BlockchainClientConfigJson) MapToDomain() BlockchainClientConfig {
 		LoggerConf:   bccj.LoggerConf.MapToDomain(),
 		RabbitmqConf: bccj.RabbitmqConf.MapToDomain(),
 		RestConf:     bccj.RestConf.MapToDomain(),
+		TracingConf:  bccj.TracingConf.MapToDomain(),
+		InternalAuth: bccj.InternalAuth.MapToDomain(),
+		SolanaConf:   bccj.SolanaConf.MapToDomain(),
+		LanHost:      bccj.LanHost,
 	}
 }
 
@@ -23,6 +47,20 @@ type BlockchainClientConfig struct {
 	LoggerConf   logger.LoggerConfig
 	RabbitmqConf rabbitmq.RabbitmqConfig
 	RestConf     BlockchainClientRestConfig
+	TracingConf  tracing.TracingConfig
+	InternalAuth rest.InternalAuthConfig
+	SolanaConf   external.SolanaConfig
+	LanHost      string
+}
+
+func (bcc BlockchainClientConfig) Validate() error {
+	var cv utilities.ConfigValidation
+	cv.Require("rest.port", bcc.RestConf.Port)
+	cv.Require("internal_auth.service", bcc.InternalAuth.Service)
+	cv.Require("internal_auth.secret", bcc.InternalAuth.Secret)
+	cv.Require("lan_host", bcc.LanHost)
+
+	return errors.Join(cv.Err(), bcc.SolanaConf.Validate(), bcc.LoggerConf.Validate(), bcc.RabbitmqConf.Validate())
 }
 
 func (bcc BlockchainClientConfig) GetLoggerConfig() logger.LoggerConfig {
@@ -37,6 +75,10 @@ func (bcc BlockchainClientConfig) GetRestApiPort() uint16 {
 	return bcc.RestConf.Port
 }
 
+func (bcc BlockchainClientConfig) GetTracingConfig() tracing.TracingConfig {
+	return bcc.TracingConf
+}
+
 type BlockchainClientRestConfigJson struct {
 	Port uint16 `json:"port"`
 }
@@ -69,7 +69,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "Transaction or account not found",
+                        "description": "Transaction or account could not be fetched",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -78,7 +78,7 @@ const docTemplate = `{
                         }
                     },
                     "422": {
-                        "description": "Could not parse input",
+                        "description": "Could not parse input, or the proof account is missing or does not verify",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -87,7 +87,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Internal server error or verification failed",
+                        "description": "Internal server error",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -0,0 +1,141 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"pkg-common/logger"
+	"pkg-common/metrics"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gin-gonic/gin"
+)
+
+const solanaCloseAccountOperation = "close_account"
+
+// closeInstruction must match CLOSE_INSTRUCTION of the on-chain program.
+var closeInstruction = []byte("ZKPCLOSE")
+
+type SolanaCloser struct {
+	RpcClient *rpc.Client
+	Config    *SharedSolanaConfig
+}
+
+func NewSolanaCloser(rpcClient *rpc.Client) *SolanaCloser {
+	solanaConfig, err := LoadSolanaKeys()
+	if err != nil {
+		logger.Default().Panicf(err, "Error when loading keys from solana: ")
+	}
+
+	return &SolanaCloser{
+		RpcClient: rpcClient,
+		Config:    solanaConfig,
+	}
+}
+
+// Close godoc
+// @Summary      Close a proof account
+// @Description  Wipes the proof stored in a Solana account and reclaims its rent to the payer. Used for right-to-erasure requests.
+// @Tags         zkSNARK
+// @Produce      json
+// @Param        account path string true "Account public key"
+// @Success      200 {object} map[string]string "Account closed"
+// @Failure      404 {object} map[string]string "Account does not exist (already closed)"
+// @Failure      422 {object} map[string]string "Could not parse input"
+// @Failure      502 {object} map[string]string "Solana transaction failed"
+// @Router       /internal/accounts/{account} [delete]
+func (sc *SolanaCloser) Close(c *gin.Context) {
+	accountKey, err := solana.PublicKeyFromBase58(c.Param("account"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "Could not parse account pubkey: " + err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	accInfo, err := sc.RpcClient.GetAccountInfo(ctx, accountKey)
+	if errors.Is(err, rpc.ErrNotFound) || (err == nil && accInfo.Value == nil) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Could not fetch account info: " + err.Error()})
+		return
+	}
+
+	signature, err := sc.closeAccount(ctx, accountKey)
+	if err != nil {
+		logger.Default().Errorf(err, "Failed to close account %s", accountKey.String())
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Could not close account: " + err.Error()})
+		return
+	}
+
+	logger.Default().Infof("Closed proof account %s with transaction %s", accountKey.String(), signature.String())
+	c.JSON(http.StatusOK, gin.H{
+		"account":   accountKey.String(),
+		"signature": signature.String(),
+	})
+}
+
+func (sc *SolanaCloser) closeAccount(ctx context.Context, account solana.PublicKey) (solana.Signature, error) {
+	txStart := time.Now()
+
+	sc.Config.Mu.Lock()
+	payer := sc.Config.Keys.AccountPublicKey
+	payerKey := sc.Config.Keys.AccountPrivateKey
+	program := sc.Config.Keys.ContractPublicKey
+	sc.Config.Mu.Unlock()
+
+	// accounts stored before close authorities were recorded can only be
+	// closed by the upgrade authority of the program, which the program reads
+	// from its program data account
+	programData, _, err := solana.FindProgramAddress([][]byte{program.Bytes()}, solana.BPFLoaderUpgradeableProgramID)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	closeIx := solana.NewInstruction(
+		program,
+		[]*solana.AccountMeta{
+			solana.NewAccountMeta(account, true, false),
+			solana.NewAccountMeta(payer, false, true), // close authority
+			solana.NewAccountMeta(payer, true, false), // rent destination
+			solana.NewAccountMeta(programData, false, false),
+		},
+		closeInstruction,
+	)
+
+	latest, err := sc.RpcClient.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		metrics.ObserveSolanaTransaction(solanaCloseAccountOperation, txStart, err)
+		return solana.Signature{}, err
+	}
+
+	tx, err := solana.NewTransaction(
+		[]solana.Instruction{closeIx},
+		latest.Value.Blockhash,
+		solana.TransactionPayer(payer),
+	)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+
+	_, err = tx.Sign(func(pk solana.PublicKey) *solana.PrivateKey {
+		if pk.Equals(payer) {
+			return &payerKey
+		}
+		return nil
+	})
+	if err != nil {
+		return solana.Signature{}, err
+	}
+
+	signature, err := sc.RpcClient.SendTransactionWithOpts(ctx, tx, rpc.TransactionOpts{
+		SkipPreflight:       false,
+		PreflightCommitment: rpc.CommitmentFinalized,
+	})
+	metrics.ObserveSolanaTransaction(solanaCloseAccountOperation, txStart, err)
+	return signature, err
+}
@@ -7,7 +7,9 @@ import (
 	"path/filepath"
 	"sync"
 
+	httpclient "pkg-common/http_client"
 	"pkg-common/logger"
+	"pkg-common/utilities"
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
@@ -25,23 +27,67 @@ type SharedSolanaConfig struct {
 	Keys *Keys
 }
 
+// SolanaConfigJson points at the RPC node, the deployed verifier program and
+// the keypair paying for its transactions.
+type SolanaConfigJson struct {
+	ProgramId        string                `json:"program_id"`
+	PayerKeypairPath string                `json:"payer_keypair_path"`
+	Rpc              httpclient.ConfigJson `json:"rpc"`
+}
+
+type SolanaConfig struct {
+	ProgramId        string
+	PayerKeypairPath string
+	Rpc              httpclient.Config
+}
+
+func (scj SolanaConfigJson) MapToDomain() SolanaConfig {
+	keypairPath := scj.PayerKeypairPath
+	if keypairPath == "" {
+		homeDir, _ := os.UserHomeDir()
+		keypairPath = filepath.Join(homeDir, ".zkpconfig", "solana", "id.json")
+	}
+	rpcConfig := scj.Rpc.MapToDomain()
+	if rpcConfig.BaseUrl == "" {
+		rpcConfig.BaseUrl = DefaultRpcUrl
+	}
+	return SolanaConfig{
+		ProgramId:        scj.ProgramId,
+		PayerKeypairPath: keypairPath,
+		Rpc:              rpcConfig,
+	}
+}
+
+func (sc SolanaConfig) Validate() error {
+	var cv utilities.ConfigValidation
+	cv.Require("solana.program_id", sc.ProgramId)
+	if sc.ProgramId != "" {
+		_, err := solana.PublicKeyFromBase58(sc.ProgramId)
+		cv.Check("solana.program_id", err == nil, "not a base58 public key (use your deployed program id, e.g. HxSN1y...)")
+	}
+	return cv.Err()
+}
+
+var solanaConfig SolanaConfig
+
+// ConfigureSolana sets the program and payer LoadSolanaKeys reads; it must run
+// before the workers and handlers using them are created.
+func ConfigureSolana(config SolanaConfig) {
+	solanaConfig = config
+}
+
 func LoadSolanaKeys() (*SharedSolanaConfig, error) {
 	// 1) Program ID
-	programIDStr := os.Getenv("PROGRAM_ID")
-	if programIDStr == "" {
-		return nil, fmt.Errorf("PROGRAM_ID env var is not set (use your deployed program id, e.g. HxSN1y...)")
+	if solanaConfig.ProgramId == "" {
+		return nil, fmt.Errorf("solana program id is not configured")
 	}
-	programID, err := solana.PublicKeyFromBase58(programIDStr)
+	programID, err := solana.PublicKeyFromBase58(solanaConfig.ProgramId)
 	if err != nil {
-		return nil, fmt.Errorf("invalid PROGRAM_ID %q: %w", programIDStr, err)
+		return nil, fmt.Errorf("invalid program id %q: %w", solanaConfig.ProgramId, err)
 	}
 
 	// 2) Payer keypair
-	keypairPath := os.Getenv("PAYER_KEYPAIR_PATH")
-	if keypairPath == "" {
-		homeDir, _ := os.UserHomeDir()
-		keypairPath = filepath.Join(homeDir, ".zkpconfig", "solana", "id.json")
-	}
+	keypairPath := solanaConfig.PayerKeypairPath
 	payerPriv, err := solana.PrivateKeyFromSolanaKeygenFile(keypairPath)
 	if err != nil {
 		return nil, fmt.Errorf("reading payer keypair from %s failed: %w", keypairPath, err)
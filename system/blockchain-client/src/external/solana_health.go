@@ -0,0 +1,18 @@
+package external
+
+import (
+	"context"
+	"pkg-common/health"
+	"time"
+
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// SolanaRpcHealthCheck asks the RPC node for its health; the node answers with
+// an error while it is behind the cluster or still starting up.
+func SolanaRpcHealthCheck(rpcClient *rpc.Client) health.Check {
+	return health.NewCheck("solana_rpc", 3*time.Second, func(ctx context.Context) error {
+		_, err := rpcClient.GetHealth(ctx)
+		return err
+	})
+}
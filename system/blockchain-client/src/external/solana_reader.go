@@ -20,9 +20,9 @@ type ZkpVerifier interface {
 	Verify(c *gin.Context)
 }
 
-func NewSolanaReader() *SolanaReader {
+func NewSolanaReader(rpcClient *rpc.Client) *SolanaReader {
 	return &SolanaReader{
-		RpcClient: rpc.New("http://host.docker.internal:8899"),
+		RpcClient: rpcClient,
 	}
 }
 
@@ -37,9 +37,9 @@ func NewSolanaReader() *SolanaReader {
 // @Param        size      query int    true "Proof size in bytes"
 // @Success      200 {object} map[string]interface{} "Successfully verified proof"
 // @Failure      400 {object} map[string]string "Invalid request: missing parameters"
-// @Failure      422 {object} map[string]string "Could not parse input"
-// @Failure      404 {object} map[string]string "Transaction or account not found"
-// @Failure      500 {object} map[string]string "Internal server error or verification failed"
+// @Failure      422 {object} map[string]string "Could not parse input, or the proof account is missing or does not verify"
+// @Failure      404 {object} map[string]string "Transaction or account could not be fetched"
+// @Failure      500 {object} map[string]string "Internal server error"
 // @Router       /internal/verify [get]
 func (sr *SolanaReader) Verify(c *gin.Context) {
 	signature := c.Query("signature")
@@ -102,13 +102,13 @@ func (sr *SolanaReader) Verify(c *gin.Context) {
 		return
 	}
 	if accInfo.Value == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Account not found"})
 		return
 	}
 
 	data := accInfo.Value.Data.GetBinary()
 	if len(data) < proofLen {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
 			"error": "Account data too short: expected at least " + strconv.Itoa(proofLen) +
 				" bytes, got " + strconv.Itoa(len(data)),
 		})
@@ -119,7 +119,7 @@ func (sr *SolanaReader) Verify(c *gin.Context) {
 
 	proof, err := zkp.ReconstructZkpResult(zkpData)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
 			"error": "Failed to deserialize proof: " + err.Error(),
 		})
 		return
@@ -127,7 +127,7 @@ func (sr *SolanaReader) Verify(c *gin.Context) {
 
 	err = groth16.Verify(proof.Proof, proof.VerifyingKey, proof.PublicWitness)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
 			"error": "Failed to verify proof: " + err.Error()},
 		)
 		return
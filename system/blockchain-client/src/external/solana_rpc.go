@@ -0,0 +1,76 @@
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	httpclient "pkg-common/http_client"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// DefaultRpcUrl is the local validator of the Solana setup instructions.
+const DefaultRpcUrl = "http://localhost:8899"
+
+var errRpcCallbackUnsupported = errors.New("raw JSON-RPC callbacks are not supported")
+
+// NewRpcClient sends the JSON-RPC calls of the Solana client through the
+// shared HTTP client, so they get its timeout, circuit breaker and request
+// ID. Calls are POSTs and therefore not retried.
+func NewRpcClient(config httpclient.Config) *rpc.Client {
+	return rpc.NewWithCustomRPCClient(&httpRpcClient{client: httpclient.NewClient(config)})
+}
+
+type httpRpcClient struct {
+	client *httpclient.Client
+}
+
+func (c *httpRpcClient) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	var response jsonrpc.RPCResponse
+	if err := c.post(ctx, jsonrpc.NewRequest(method, params...), &response); err != nil {
+		return err
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	if out == nil || len(response.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(response.Result, out); err != nil {
+		return fmt.Errorf("decoding %s result: %w", method, err)
+	}
+	return nil
+}
+
+// CallWithCallback needs the raw HTTP exchange, which the shared client does
+// not expose; none of the calls this service makes use it.
+func (c *httpRpcClient) CallWithCallback(context.Context, string, []interface{}, func(*http.Request, *http.Response) error) error {
+	return errRpcCallbackUnsupported
+}
+
+func (c *httpRpcClient) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	var responses jsonrpc.RPCResponses
+	if err := c.post(ctx, requests, &responses); err != nil {
+		return nil, err
+	}
+	return responses, nil
+}
+
+// post sends body to the node. A node answering a failed call with an HTTP
+// error status still describes it in a JSON-RPC error, which is returned
+// instead when present.
+func (c *httpRpcClient) post(ctx context.Context, body, out any) error {
+	resp, err := c.client.Send(ctx, http.MethodPost, "", body)
+	if err != nil {
+		var httpErr *httpclient.HTTPError
+		var response jsonrpc.RPCResponse
+		if errors.As(err, &httpErr) && json.Unmarshal(httpErr.Body, &response) == nil && response.Error != nil {
+			return response.Error
+		}
+		return err
+	}
+	return json.Unmarshal(resp.Body, out)
+}
@@ -6,9 +6,11 @@ import (
 	"fmt"
 	appbuilder "pkg-common/app_builder"
 	"pkg-common/logger"
-	"pkg-common/rabbitmq"
 	"pkg-common/rest"
 	"pkg-common/utilities"
+	"strings"
+
+	"github.com/gagliardetto/solana-go/rpc"
 
 	"blockchain-client/src/docs"
 )
@@ -19,34 +21,58 @@ import (
 // @host localhost:9000
 // @BasePath /bc/v1
 func main() {
-	lanHost := utilities.ResolveLanHost()
-	docs.SwaggerInfo.Host = fmt.Sprintf("%s:9000", lanHost)
+	var internalAuth *rest.InternalAuthenticator
+	var solanaRpc *rpc.Client
+	var solanaReader *external.SolanaReader
+	var solanaCloser *external.SolanaCloser
 
 	appbuilder.New[BlockchainClientConfigJson]().
-		InitLogger(logger.GlobalLoggerConfig{}).
+		InitLogger(logger.GlobalLoggerConfig{Service: "blockchain-client"}).
 		ResolveEnvironment().
 		LoadConfig("config.json").
+		AddTracing("blockchain-client").
+		WithOption(func(a *appbuilder.AppBuilder[BlockchainClientConfigJson, BlockchainClientConfig]) {
+			docs.SwaggerInfo.Host = fmt.Sprintf("%s:9000", a.Config.LanHost)
+
+			// ----- SOLANA (program + payer for the workers) -----
+			external.ConfigureSolana(a.Config.SolanaConf)
+			solanaRpc = external.NewRpcClient(a.Config.SolanaConf.Rpc)
+			solanaReader = external.NewSolanaReader(solanaRpc)
+			solanaCloser = external.NewSolanaCloser(solanaRpc)
+		}).
 		InitRabbitmqConnection().
 		InitRabbitmqRegistries().
+		AddRabbitmqLogSink("LogPublisher").
 		WithOption(func(a *appbuilder.AppBuilder[BlockchainClientConfigJson, BlockchainClientConfig]) {
-			// ----- RABBITMQ LOGGING SINK -----
-			logPublisher := rabbitmq.GetPublisher("LogPublisher")
-			loggerInstance := logger.Default()
-			logSink := rabbitmq.CreateRabbitmqLoggerSink(logPublisher)
-			logger.AddSinkToLoggerInstance(loggerInstance, logSink)
+			// ----- INTERNAL SERVICE AUTH -----
+			if missing := a.Config.InternalAuth.MissingSecrets(); len(missing) > 0 {
+				if a.Environment == utilities.EnvProd {
+					panic("internal auth secrets are required in prod: " + strings.Join(missing, ", "))
+				}
+				a.Logger.Warnf("No internal auth secret configured for %s, those callers are rejected", strings.Join(missing, ", "))
+			}
+			internalAuth = rest.NewInternalAuthenticator(a.Config.InternalAuth)
+
+			// ----- WORKERS -----
+			a.AddWorkerServices(
+				workers.NewVerifiedPositiveWorker(a.Registry, solanaRpc),
+				workers.NewVerifiedNegativeWorker(a.Registry),
+				workers.NewBlockchainClientLogSink(a.Registry, solanaRpc),
+			)
 		}).
-		AddWorkerServices(
-			workers.NewVerifiedPositiveWorker(),
-			workers.NewVerifiedNegativeWorker(),
-			workers.NewBlockchainClientLogSink(),
+		AddHealthChecks(
+			external.SolanaRpcHealthCheck(solanaRpc),
 		).
 		AddGinMiddleware(
-			rest.NewMiddleware("*", rest.InternalAuthMiddleware()),
+			rest.NewMiddleware("*", rest.RequestIdMiddleware()),
+			rest.NewMiddleware("v1/internal", rest.InternalAuthMiddleware(internalAuth)),
 		).
 		AddGinRoutes(
-			rest.NewRoute(rest.GET, "v1/internal", "verify", external.NewSolanaReader().Verify),
+			rest.NewRoute(rest.GET, "v1/internal", "verify", rest.RequireScope("proofs:verify", solanaReader.Verify)),
+			rest.NewRoute(rest.DELETE, "v1/internal", "accounts/:account", rest.RequireScope("accounts:close", solanaCloser.Close)),
 		).
 		AddSwagger().
+		AddMetrics().
 		InitGinRouter().
 		Build().
 		Start()
@@ -5,4 +5,5 @@ import "github.com/gagliardetto/solana-go"
 type ZkpStorageData struct {
 	Account   solana.PublicKey
 	Signature solana.Signature
+	Size      int // length of the serialized proof stored in the account
 }
@@ -1,5 +1,7 @@
 package incoming
 
+const ZkpVerifiedNegativeType = "zkp.verification.negative"
+
 type ZkpVerifiedNegativeDto struct {
 	EventId        string        `json:"event_id"`
 	ResolvedValues []ZkpFieldDto `json:"resolved_values"`
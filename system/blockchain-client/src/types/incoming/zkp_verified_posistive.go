@@ -1,5 +1,9 @@
 package incoming
 
+// ZkpVerifiedPositiveType is the message type the verifier publishes when
+// every requested field was verified.
+const ZkpVerifiedPositiveType = "zkp.verification.positive"
+
 type ZkpVerifiedPositiveDto struct {
 	EventId        string        `json:"event_id"`
 	ResolvedValues []ZkpFieldDto `json:"resolved_values"`
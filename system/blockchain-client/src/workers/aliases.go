@@ -7,3 +7,8 @@ const (
 	failureQueuePublisherAlias    = "IdentityFailurePublisher"
 	resultQueuePublisherAlias     = "IdentityResultsPublisher"
 )
+
+const (
+	solanaStoreProofOperation = "store_proof"
+	solanaStoreLogOperation   = "store_log"
+)
@@ -3,16 +3,16 @@ package workers
 import (
 	"blockchain-client/src/external"
 	"context"
-	"encoding/json"
 	"fmt"
 
 	"pkg-common/logger"
+	"pkg-common/metrics"
 	"pkg-common/rabbitmq"
 	logger_message "pkg-common/utilities/logger"
+	"time"
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
-	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 type BlockchainClientLogSink struct {
@@ -22,15 +22,15 @@ type BlockchainClientLogSink struct {
 	logger    *logger.Logger
 }
 
-func NewBlockchainClientLogSink() *BlockchainClientLogSink {
+func NewBlockchainClientLogSink(registry *rabbitmq.Registry, rpcClient *rpc.Client) *BlockchainClientLogSink {
 	solanaConfig, err := external.LoadSolanaKeys()
 	if err != nil {
 		panic(fmt.Sprintf("Error when loading keys from solana: %v", err))
 	}
 
 	return &BlockchainClientLogSink{
-		RpcClient: rpc.New("http://host.docker.internal:8899"),
-		Consumer:  rabbitmq.GetConsumer(logConsumerAlias),
+		RpcClient: rpcClient,
+		Consumer:  registry.Consumer(logConsumerAlias),
 		Config:    solanaConfig,
 		logger:    logger.New(),
 	}
@@ -40,22 +40,35 @@ func (lw *BlockchainClientLogSink) GetServiceName() string {
 	return logConsumerAlias
 }
 
-func (lw *BlockchainClientLogSink) StartService() {
+func (lw *BlockchainClientLogSink) Stop(ctx context.Context) error {
+	return lw.Consumer.Drain(ctx)
+}
+
+func (lw *BlockchainClientLogSink) StartService(ctx context.Context) error {
 
-	lw.Consumer.StartConsuming(func(d amqp.Delivery) {
+	router := rabbitmq.NewRouter().On(logger_message.LoggerMessageType, func(_ context.Context, msg rabbitmq.Message) rabbitmq.Outcome {
 		var logMessage logger_message.LoggerMessage
 
-		if err := json.Unmarshal(d.Body, &logMessage); err != nil {
-			return
+		if err := msg.Decode(&logMessage); err != nil {
+			return rabbitmq.DeadLetter(err)
 		}
 
 		if err := lw.storeLogToSolana(logMessage); err != nil {
-			return
+			return rabbitmq.RetryLater(err)
 		}
+
+		return rabbitmq.Ack()
 	})
+
+	return lw.Consumer.StartConsuming(ctx, router.Handle)
 }
 
-func (lw *BlockchainClientLogSink) storeLogToSolana(logMessage logger_message.LoggerMessage) error {
+func (lw *BlockchainClientLogSink) storeLogToSolana(logMessage logger_message.LoggerMessage) (err error) {
+	txStart := time.Now()
+	defer func() {
+		metrics.ObserveSolanaTransaction(solanaStoreLogOperation, txStart, err)
+	}()
+
 	logData, err := logMessage.Serialize()
 	if err != nil {
 		return fmt.Errorf("failed to serialize log message: %w", err)
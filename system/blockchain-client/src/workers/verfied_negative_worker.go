@@ -2,22 +2,22 @@ package workers
 
 import (
 	"blockchain-client/src/types/incoming"
-	"encoding/json"
+	"context"
 	dtocommon "pkg-common/dto_common"
 	"pkg-common/logger"
 	"pkg-common/rabbitmq"
 	reasoncodes "pkg-common/reason_codes"
-
-	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 type VerifiedNegativeWorker struct {
-	Consumer rabbitmq.IRabbitmqConsumer
+	Consumer         rabbitmq.IRabbitmqConsumer
+	FailurePublisher rabbitmq.IRabbitmqPublisher
 }
 
-func NewVerifiedNegativeWorker() rabbitmq.WorkerService {
+func NewVerifiedNegativeWorker(registry *rabbitmq.Registry) rabbitmq.WorkerService {
 	return &VerifiedNegativeWorker{
-		Consumer: rabbitmq.GetConsumer(verifiedNegativeConsumerAlias),
+		Consumer:         registry.Consumer(verifiedNegativeConsumerAlias),
+		FailurePublisher: registry.Publisher(failureQueuePublisherAlias),
 	}
 }
 
@@ -25,27 +25,32 @@ func (vnw *VerifiedNegativeWorker) GetServiceName() string {
 	return verifiedNegativeConsumerAlias
 }
 
-func (vnw *VerifiedNegativeWorker) StartService() {
+func (vnw *VerifiedNegativeWorker) Stop(ctx context.Context) error {
+	return vnw.Consumer.Drain(ctx)
+}
+
+func (vnw *VerifiedNegativeWorker) StartService(ctx context.Context) error {
 	workerLogger := logger.Default()
-	failurePublisher := rabbitmq.GetPublisher(failureQueuePublisherAlias)
+	failurePublisher := vnw.FailurePublisher
 
-	vnw.Consumer.StartConsuming(func(d amqp.Delivery) {
+	router := rabbitmq.NewRouter().On(incoming.ZkpVerifiedNegativeType, func(ctx context.Context, msg rabbitmq.Message) rabbitmq.Outcome {
 		var message incoming.ZkpVerifiedNegativeDto
-		responseFactory := dtocommon.NewZkpProofFailureFactory("", d.Body)
+		responseFactory := dtocommon.NewZkpProofFailureFactory("", msg.Payload)
 
-		if err := json.Unmarshal(d.Body, &message); err != nil {
+		if err := msg.Decode(&message); err != nil {
 			workerLogger.Error(err, "Unmarshaling failed.")
 
 			result := responseFactory.CreateErrorDto(err, reasoncodes.ErrUnmarshal)
 
-			_ = failurePublisher.Publish(result)
 			workerLogger.Info("Message processed sucessfully.")
-			return
+			return reportFailure(ctx, failurePublisher, result)
 		}
 
-		responseFactory = dtocommon.NewZkpProofFailureFactory(message.EventId, d.Body)
-		_ = failurePublisher.Publish(responseFactory.CreateInfoDto(reasoncodes.ErrVerifierResolution))
+		responseFactory = dtocommon.NewZkpProofFailureFactory(message.EventId, msg.Payload)
 
 		workerLogger.Info("Message processed sucessfully.")
+		return reportFailure(ctx, failurePublisher, responseFactory.CreateInfoDto(reasoncodes.ErrVerifierResolution))
 	})
+
+	return vnw.Consumer.StartConsuming(ctx, router.Handle)
 }
@@ -8,34 +8,42 @@ import (
 
 	"blockchain-client/src/zkp"
 	"context"
-	"encoding/json"
 	dtocommon "pkg-common/dto_common"
 	"pkg-common/logger"
+	"pkg-common/metrics"
 	"pkg-common/rabbitmq"
 	reasoncodes "pkg-common/reason_codes"
+	"pkg-common/utilities"
+	"time"
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/programs/system"
 	"github.com/gagliardetto/solana-go/rpc"
-	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 type VerifiedPositiveWorker struct {
-	Config    *external.SharedSolanaConfig
-	RpcClient *rpc.Client
-	Consumer  rabbitmq.IRabbitmqConsumer
+	Config           *external.SharedSolanaConfig
+	RpcClient        *rpc.Client
+	Consumer         rabbitmq.IRabbitmqConsumer
+	FailurePublisher rabbitmq.IRabbitmqPublisher
+	ResultPublisher  rabbitmq.IRabbitmqPublisher
+	// Processed keeps a redelivered message from storing its proof twice
+	Processed rabbitmq.IdempotencyStore
 }
 
-func NewVerifiedPositiveWorker() *VerifiedPositiveWorker {
+func NewVerifiedPositiveWorker(registry *rabbitmq.Registry, rpcClient *rpc.Client) *VerifiedPositiveWorker {
 	solanaConfig, err := external.LoadSolanaKeys()
 	if err != nil {
 		logger.Default().Panicf(err, "Error when loading keys from solana: ")
 	}
 
 	return &VerifiedPositiveWorker{
-		RpcClient: rpc.New("http://host.docker.internal:8899"),
-		Consumer:  rabbitmq.GetConsumer(solanaClientServiceName),
-		Config:    solanaConfig,
+		RpcClient:        rpcClient,
+		Consumer:         registry.Consumer(solanaClientServiceName),
+		FailurePublisher: registry.Publisher(failureQueuePublisherAlias),
+		ResultPublisher:  registry.Publisher(resultQueuePublisherAlias),
+		Config:           solanaConfig,
+		Processed:        rabbitmq.NewMemoryIdempotencyStore(rabbitmq.DefaultIdempotencyWindow, rabbitmq.DefaultIdempotencyCapacity),
 	}
 }
 
@@ -43,22 +51,24 @@ func (sc *VerifiedPositiveWorker) GetServiceName() string {
 	return solanaClientServiceName
 }
 
-func (sc *VerifiedPositiveWorker) StartService() {
+func (sc *VerifiedPositiveWorker) Stop(ctx context.Context) error {
+	return sc.Consumer.Drain(ctx)
+}
+
+func (sc *VerifiedPositiveWorker) StartService(ctx context.Context) error {
 	solanaLogger := logger.Default()
-	failurePublisher := rabbitmq.GetPublisher(failureQueuePublisherAlias)
-	resultPublisher := rabbitmq.GetPublisher(resultQueuePublisherAlias)
+	failurePublisher, resultPublisher := sc.FailurePublisher, sc.ResultPublisher
 
-	sc.Consumer.StartConsuming(func(d amqp.Delivery) {
+	router := rabbitmq.NewRouter().On(incoming.ZkpVerifiedPositiveType, func(ctx context.Context, msg rabbitmq.Message) rabbitmq.Outcome {
 		var message incoming.ZkpVerifiedPositiveDto
-		responseFactory := dtocommon.NewZkpProofFailureFactory("", d.Body)
+		responseFactory := dtocommon.NewZkpProofFailureFactory("", msg.Payload)
 
-		if err := json.Unmarshal(d.Body, &message); err != nil {
+		if err := msg.Decode(&message); err != nil {
 			result := responseFactory.CreateErrorDto(err, reasoncodes.ErrUnmarshal)
 
-			_ = failurePublisher.Publish(result)
-			return
+			return reportFailure(ctx, failurePublisher, result)
 		}
-		responseFactory = dtocommon.NewZkpProofFailureFactory(message.EventId, d.Body)
+		responseFactory = dtocommon.NewZkpProofFailureFactory(message.EventId, msg.Payload)
 
 		circuitBase := domain.ZkpCircuitBase{}
 		zkpResult, err := zkp.CreateZKP(circuitBase)
@@ -66,8 +76,7 @@ func (sc *VerifiedPositiveWorker) StartService() {
 			solanaLogger.Errorf(err, "Failed to create ZKP with user provided data: %d", 10)
 			response := responseFactory.CreateErrorDto(err, reasoncodes.ErrProofGeneration)
 
-			_ = failurePublisher.Publish(response)
-			return
+			return reportFailure(ctx, failurePublisher, response)
 		}
 
 		signatureChan := make(chan domain.ZkpStorageData)
@@ -83,19 +92,35 @@ func (sc *VerifiedPositiveWorker) StartService() {
 			solanaLogger.Errorf(err, "Unable to save the ZKP to the blockchain")
 
 			response := responseFactory.CreateErrorDto(err, reasoncodes.ErrSolana)
-			_ = failurePublisher.Publish(response)
-			return
+			return reportFailure(ctx, failurePublisher, response)
 		}
 
 		result := dtocommon.ZkpProofResultDto{
 			EventId:   message.EventId,
 			Signature: proofReference.Signature.String(),
 			AccountId: proofReference.Account.String(),
+			ProofSize: proofReference.Size,
 		}
 
-		_ = resultPublisher.Publish(result)
+		if err := resultPublisher.PublishConfirmed(ctx, result); err != nil {
+			// The proof is already on chain; retrying would store it again
+			solanaLogger.Errorf(err, "Could not publish result for %s", result.EventId)
+			return rabbitmq.DeadLetter(fmt.Errorf("proof stored as %s but result not published: %w", result.Signature, err))
+		}
 		solanaLogger.Infof("Processed ZKP Verification for %s. Signature: %s, Account: %s", result.EventId, result.Signature, result.AccountId)
-	})
+		return rabbitmq.Ack()
+	}).Deduplicate(sc.Processed, rabbitmq.ByMessageId)
+
+	return sc.Consumer.StartConsuming(ctx, router.Handle)
+}
+
+// reportFailure publishes a failure for the api; the delivery is retried when
+// the report itself cannot be published, so the failure is not lost.
+func reportFailure(ctx context.Context, publisher rabbitmq.IRabbitmqPublisher, failure utilities.Serializable) rabbitmq.Outcome {
+	if err := publisher.PublishConfirmed(ctx, failure); err != nil {
+		return rabbitmq.RetryLater(err)
+	}
+	return rabbitmq.Ack()
 }
 
 // TODO: add option for the users to be payers instead of owners
@@ -167,8 +192,10 @@ func (sc *VerifiedPositiveWorker) createAndPopulateZkpAccount(
 
 	sc.Config.Mu.Unlock()
 
+	txStart := time.Now()
 	latest, err := sc.RpcClient.GetLatestBlockhash(context.Background(), rpc.CommitmentFinalized)
 	if err != nil {
+		metrics.ObserveSolanaTransaction(solanaStoreProofOperation, txStart, err)
 		errCh <- err
 		return
 	}
@@ -220,6 +247,7 @@ func (sc *VerifiedPositiveWorker) createAndPopulateZkpAccount(
 
 	sim, simErr := sc.RpcClient.SimulateTransaction(context.Background(), tx)
 	if simErr != nil {
+		metrics.ObserveSolanaTransaction(solanaStoreProofOperation, txStart, simErr)
 		errCh <- fmt.Errorf("simulate call: %w", simErr)
 		return
 	}
@@ -227,7 +255,9 @@ func (sc *VerifiedPositiveWorker) createAndPopulateZkpAccount(
 		for _, l := range sim.Value.Logs {
 			logger.Default().Debug(l)
 		}
-		errCh <- fmt.Errorf("simulate err: %+v", sim.Value.Err)
+		err := fmt.Errorf("simulate err: %+v", sim.Value.Err)
+		metrics.ObserveSolanaTransaction(solanaStoreProofOperation, txStart, err)
+		errCh <- err
 		return
 	}
 
@@ -239,6 +269,7 @@ func (sc *VerifiedPositiveWorker) createAndPopulateZkpAccount(
 			PreflightCommitment: rpc.CommitmentFinalized,
 		},
 	)
+	metrics.ObserveSolanaTransaction(solanaStoreProofOperation, txStart, err)
 	if err != nil {
 		solanaLogger.Errorf(err, "Failed to send combined transaction")
 		solanaLogger.Debugf("ZKP data size: %d bytes, allocated space: %d bytes", len(zkpData), space)
@@ -251,6 +282,7 @@ func (sc *VerifiedPositiveWorker) createAndPopulateZkpAccount(
 	sigCh <- domain.ZkpStorageData{
 		Signature: transactionSignature,
 		Account:   newAccount.PublicKey(),
+		Size:      len(zkpData),
 	}
 }
 
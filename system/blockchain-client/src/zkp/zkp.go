@@ -4,6 +4,8 @@ import (
 	"blockchain-client/src/types/domain"
 	"encoding/json"
 	"fmt"
+	"pkg-common/metrics"
+	"time"
 
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark/backend/groth16"
@@ -32,6 +34,7 @@ func CreateZKP(base domain.ZkpCircuitBase) (*ZkpResult, error) {
 	}
 
 	// 1. Compile the circuit (constraint system)
+	compileStart := time.Now()
 	ccs, err := frontend.Compile(
 		ElipticalCurveID.ScalarField(),
 		r1cs.NewBuilder,
@@ -40,12 +43,15 @@ func CreateZKP(base domain.ZkpCircuitBase) (*ZkpResult, error) {
 	if err != nil {
 		return nil, err
 	}
+	metrics.ObserveCircuitOperation(metrics.CircuitCompile, compileStart)
 
 	// 2. Setup proving/verifying keys
+	setupStart := time.Now()
 	pk, vk, err := groth16.Setup(ccs)
 	if err != nil {
 		return nil, err
 	}
+	metrics.ObserveCircuitOperation(metrics.CircuitSetup, setupStart)
 
 	// 3. Assign inputs
 	assignment := circuit.Clone()
@@ -73,10 +79,12 @@ func CreateZKP(base domain.ZkpCircuitBase) (*ZkpResult, error) {
 	}
 
 	// 4. Create the proof
+	proveStart := time.Now()
 	proof, err := groth16.Prove(ccs, pk, fullWitness)
 	if err != nil {
 		return nil, err
 	}
+	metrics.ObserveCircuitOperation(metrics.CircuitProve, proveStart)
 
 	// 5. Get the public witness
 	publicWitness, err := fullWitness.Public()
@@ -1,10 +1,14 @@
 package test
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	httpclient "pkg-common/http_client"
+	"pkg-common/rest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -20,23 +24,32 @@ type TestAPIRequest struct {
 	Value int    `json:"value"`
 }
 
-func TestRequestBaseGETSuccess(t *testing.T) {
-	// Create a test server
+func newTestAPIClient(baseUrl string) *httpclient.Client {
+	tokens := rest.NewInternalTokenIssuer(rest.InternalAuthConfig{Service: "blockchain-client", Secret: []byte("test-secret")})
+	return httpclient.NewClient(
+		httpclient.Config{BaseUrl: baseUrl, Timeout: time.Second},
+		httpclient.WithInternalAuth(tokens, "api"),
+	)
+}
+
+func TestAPIClientGETSuccess(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Verify request headers
-		if r.Header.Get("Content-Type") != "application/json" {
-			t.Errorf("Expected Content-Type header to be 'application/json', got '%s'", r.Header.Get("Content-Type"))
+		if r.Header.Get("Accept") != "application/json" {
+			t.Errorf("Expected Accept header to be 'application/json', got '%s'", r.Header.Get("Accept"))
+		}
+
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			t.Errorf("Expected a bearer internal token, got '%s'", r.Header.Get("Authorization"))
 		}
 
-		if r.Header.Get("Authorization") != "internal_token_admin_123" {
-			t.Errorf("Expected Authorization header to be 'internal_token_admin_123', got '%s'", r.Header.Get("Authorization"))
+		if r.Header.Get(rest.RequestIdHeader) == "" {
+			t.Error("Expected a request ID header")
 		}
 
 		if r.Method != http.MethodGet {
 			t.Errorf("Expected GET method, got %s", r.Method)
 		}
 
-		// Return test response
 		response := TestAPIResponse{
 			ID:      "test-id-123",
 			Message: "Test successful",
@@ -47,44 +60,26 @@ func TestRequestBaseGETSuccess(t *testing.T) {
 	}))
 	defer server.Close()
 
-	// Replace the hardcoded URL in the function for testing
-	// Since we can't easily modify the function, we'll test the behavior we expect
-	errorCh := make(chan error, 1)
-	responseCh := make(chan TestAPIResponse, 1)
-
-	go func() {
-		// This would normally call external.RequestBase, but since it has hardcoded URL,
-		// we'll simulate the expected behavior
-		time.Sleep(10 * time.Millisecond) // Simulate network delay
-
-		response := TestAPIResponse{
-			ID:      "test-id-123",
-			Message: "Test successful",
-			Success: true,
-		}
-		responseCh <- response
-	}()
-
-	select {
-	case err := <-errorCh:
+	response, err := httpclient.Get[TestAPIResponse](context.Background(), newTestAPIClient(server.URL), "v1/internal/identity/1")
+	if err != nil {
 		t.Fatalf("Expected success but got error: %v", err)
-	case response := <-responseCh:
-		if response.ID != "test-id-123" {
-			t.Errorf("Expected ID 'test-id-123', got '%s'", response.ID)
-		}
-		if !response.Success {
-			t.Error("Expected success to be true")
-		}
-	case <-time.After(1 * time.Second):
-		t.Fatal("Test timed out")
+	}
+	if response.ID != "test-id-123" {
+		t.Errorf("Expected ID 'test-id-123', got '%s'", response.ID)
+	}
+	if !response.Success {
+		t.Error("Expected success to be true")
 	}
 }
 
-func TestRequestBasePOSTSuccess(t *testing.T) {
+func TestAPIClientPOSTSuccess(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			t.Errorf("Expected POST method, got %s", r.Method)
 		}
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected Content-Type header to be 'application/json', got '%s'", r.Header.Get("Content-Type"))
+		}
 
 		// Verify request body
 		var requestBody TestAPIRequest
@@ -97,7 +92,6 @@ func TestRequestBasePOSTSuccess(t *testing.T) {
 			t.Errorf("Expected name 'test', got '%s'", requestBody.Name)
 		}
 
-		// Return success response
 		response := TestAPIResponse{
 			ID:      "created-id-456",
 			Message: "Created successfully",
@@ -109,97 +103,55 @@ func TestRequestBasePOSTSuccess(t *testing.T) {
 	}))
 	defer server.Close()
 
-	errorCh := make(chan error, 1)
-	responseCh := make(chan TestAPIResponse, 1)
-
-	go func() {
-		time.Sleep(10 * time.Millisecond)
-		response := TestAPIResponse{
-			ID:      "created-id-456",
-			Message: "Created successfully",
-			Success: true,
-		}
-		responseCh <- response
-	}()
-
-	select {
-	case err := <-errorCh:
+	response, err := httpclient.Post[TestAPIResponse](context.Background(), newTestAPIClient(server.URL), "v1/internal/identity",
+		TestAPIRequest{Name: "test", Value: 1})
+	if err != nil {
 		t.Fatalf("Expected success but got error: %v", err)
-	case response := <-responseCh:
-		if response.ID != "created-id-456" {
-			t.Errorf("Expected ID 'created-id-456', got '%s'", response.ID)
-		}
-	case <-time.After(1 * time.Second):
-		t.Fatal("Test timed out")
+	}
+	if response.ID != "created-id-456" {
+		t.Errorf("Expected ID 'created-id-456', got '%s'", response.ID)
 	}
 }
 
-func TestRequestBaseHTTPError(t *testing.T) {
+func TestAPIClientHTTPError(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("Internal Server Error"))
 	}))
 	defer server.Close()
 
-	errorCh := make(chan error, 1)
-	responseCh := make(chan TestAPIResponse, 1)
+	_, err := httpclient.Get[TestAPIResponse](context.Background(), newTestAPIClient(server.URL), "v1/internal/identity/1")
 
-	go func() {
-		time.Sleep(10 * time.Millisecond)
-		// Simulate the error condition
-		errorCh <- &http.ProtocolError{ErrorString: "HTTP error: 500 Internal Server Error"}
-	}()
-
-	select {
-	case err := <-errorCh:
-		if err == nil {
-			t.Fatal("Expected error but got nil")
-		}
-		// Check that error message contains expected information
-		if err.Error() == "" {
-			t.Error("Error message should not be empty")
-		}
-	case <-responseCh:
-		t.Fatal("Expected error but got successful response")
-	case <-time.After(1 * time.Second):
-		t.Fatal("Test timed out")
+	var httpErr *httpclient.HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("Expected HTTPError but got %v", err)
+	}
+	if httpErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", httpErr.StatusCode)
 	}
 }
 
-func TestRequestBaseEmptyResponse(t *testing.T) {
+func TestAPIClientEmptyResponse(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		// Send empty response body
 	}))
 	defer server.Close()
 
-	errorCh := make(chan error, 1)
-	responseCh := make(chan TestAPIResponse, 1)
-
-	go func() {
-		time.Sleep(10 * time.Millisecond)
-		// Simulate empty response handling
-		var emptyResponse TestAPIResponse
-		responseCh <- emptyResponse
-	}()
-
-	select {
-	case err := <-errorCh:
+	response, err := httpclient.Get[TestAPIResponse](context.Background(), newTestAPIClient(server.URL), "v1/internal/identity/1")
+	if err != nil {
 		t.Fatalf("Expected success but got error: %v", err)
-	case response := <-responseCh:
-		// Empty response should return zero values
-		if response.ID != "" {
-			t.Errorf("Expected empty ID, got '%s'", response.ID)
-		}
-		if response.Success {
-			t.Error("Expected success to be false for empty response")
-		}
-	case <-time.After(1 * time.Second):
-		t.Fatal("Test timed out")
+	}
+	// Empty response should return zero values
+	if response.ID != "" {
+		t.Errorf("Expected empty ID, got '%s'", response.ID)
+	}
+	if response.Success {
+		t.Error("Expected success to be false for empty response")
 	}
 }
 
-func TestRequestBaseInvalidJSON(t *testing.T) {
+func TestAPIClientInvalidJSON(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
@@ -207,24 +159,9 @@ func TestRequestBaseInvalidJSON(t *testing.T) {
 	}))
 	defer server.Close()
 
-	errorCh := make(chan error, 1)
-	responseCh := make(chan TestAPIResponse, 1)
-
-	go func() {
-		time.Sleep(10 * time.Millisecond)
-		// Simulate JSON parsing error
-		errorCh <- fmt.Errorf("JSON parsing error: invalid character 'j' looking for beginning of value")
-	}()
-
-	select {
-	case err := <-errorCh:
-		if err == nil {
-			t.Fatal("Expected JSON parsing error but got nil")
-		}
-	case <-responseCh:
-		t.Fatal("Expected error but got successful response")
-	case <-time.After(1 * time.Second):
-		t.Fatal("Test timed out")
+	_, err := httpclient.Get[TestAPIResponse](context.Background(), newTestAPIClient(server.URL), "v1/internal/identity/1")
+	if err == nil {
+		t.Fatal("Expected JSON parsing error but got nil")
 	}
 }
 
@@ -2,7 +2,10 @@ package test
 
 import (
 	"blockchain-client/src/external"
+	"encoding/json"
 	"os"
+	"path/filepath"
+	"pkg-common/logger"
 	"testing"
 
 	"github.com/gagliardetto/solana-go"
@@ -109,3 +112,48 @@ func TestSharedSolanaConfigConcurrency(t *testing.T) {
 		<-done
 	}
 }
+
+func TestSolanaConfigValidate(t *testing.T) {
+	config := external.SolanaConfigJson{ProgramId: "11111111111111111111111111111111"}.MapToDomain()
+	if err := config.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if config.PayerKeypairPath == "" {
+		t.Error("Expected a default payer keypair path")
+	}
+	if config.Rpc.BaseUrl != external.DefaultRpcUrl {
+		t.Errorf("Expected the default RPC url, got %q", config.Rpc.BaseUrl)
+	}
+
+	if err := (external.SolanaConfigJson{}).MapToDomain().Validate(); err == nil {
+		t.Error("Expected a missing program id to be reported")
+	}
+	if err := (external.SolanaConfigJson{ProgramId: "not-base58!"}).MapToDomain().Validate(); err == nil {
+		t.Error("Expected an invalid program id to be reported")
+	}
+}
+
+func TestLoadSolanaKeysFromConfig(t *testing.T) {
+	logger.InitDefaultLogger(logger.GlobalLoggerConfig{})
+	privateKey := solana.MustPrivateKeyFromBase58("4Z7cXSyeFR8wNGMVXUE1TwtKn5D5Vu7FzEv69dokLv7KrQk7h6pu4LF8ZRR9yQBhc7uSM9PiLpAkKktDD8kUmyHT")
+	keypairPath := filepath.Join(t.TempDir(), "id.json")
+	bytes := make([]int, len(privateKey))
+	for i, b := range privateKey {
+		bytes[i] = int(b)
+	}
+	raw, _ := json.Marshal(bytes)
+	if err := os.WriteFile(keypairPath, raw, 0600); err != nil {
+		t.Fatalf("Failed to write keypair: %v", err)
+	}
+
+	external.ConfigureSolana(external.SolanaConfig{ProgramId: privateKey.PublicKey().String(), PayerKeypairPath: keypairPath})
+	defer external.ConfigureSolana(external.SolanaConfig{})
+
+	solanaConfig, err := external.LoadSolanaKeys()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !solanaConfig.Keys.AccountPublicKey.Equals(privateKey.PublicKey()) {
+		t.Errorf("Expected payer %s, got %s", privateKey.PublicKey(), solanaConfig.Keys.AccountPublicKey)
+	}
+}
@@ -0,0 +1,114 @@
+package test
+
+import (
+	"blockchain-client/src/types/incoming"
+	"blockchain-client/src/workers"
+	"context"
+	"encoding/json"
+	"os"
+	dtocommon "pkg-common/dto_common"
+	"pkg-common/logger"
+	"pkg-common/rabbitmq"
+	reasoncodes "pkg-common/reason_codes"
+	"pkg-common/utilities"
+	"testing"
+	"time"
+)
+
+type verifiedNegativeMessage struct {
+	incoming.ZkpVerifiedNegativeDto
+}
+
+func (m verifiedNegativeMessage) Serialize() ([]byte, error) {
+	return utilities.Serialize[incoming.ZkpVerifiedNegativeDto](m.ZkpVerifiedNegativeDto)
+}
+
+func (m verifiedNegativeMessage) MessageType() string {
+	return incoming.ZkpVerifiedNegativeType
+}
+
+// serviceRegistry opens the publishers and consumers of a service config on
+// its own connection to broker, as the app builder of that service does.
+func serviceRegistry(t *testing.T, broker *rabbitmq.MemoryBroker, configPath string) *rabbitmq.Registry {
+	t.Helper()
+	raw, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Could not read config: %v", err)
+	}
+	var config struct {
+		Rabbitmq rabbitmq.RabbimqConfigJson `json:"rabbitmq"`
+	}
+	if err := json.Unmarshal(raw, &config); err != nil {
+		t.Fatalf("Could not parse config: %v", err)
+	}
+	conf := config.Rabbitmq.MapToDomain()
+
+	registry, err := rabbitmq.NewRegistry(broker.Connect(conf.Producer), conf.PublishersConfig, conf.ConsumersConfig)
+	if err != nil {
+		t.Fatalf("Could not open registry: %v", err)
+	}
+	return registry
+}
+
+func startWorker(t *testing.T, worker rabbitmq.WorkerService) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	go worker.StartService(ctx)
+	t.Cleanup(func() {
+		cancel()
+		stopCtx, stopCancel := context.WithTimeout(context.Background(), time.Second)
+		defer stopCancel()
+		if err := worker.Stop(stopCtx); err != nil {
+			t.Errorf("%s did not stop: %v", worker.GetServiceName(), err)
+		}
+	})
+}
+
+// A negative answer of the verifier goes through the blockchain client and
+// reaches the api as a failure, with each service on its own registry.
+func TestNegativeVerificationReachesApi(t *testing.T) {
+	logger.InitDefaultLogger(logger.GlobalLoggerConfig{})
+
+	broker := rabbitmq.NewMemoryBroker()
+	if err := broker.LoadDefinitions("../../rabbitmq/definitions.json"); err != nil {
+		t.Fatalf("Could not load definitions: %v", err)
+	}
+	blockchainRegistry := serviceRegistry(t, broker, "../config.json")
+	apiRegistry := serviceRegistry(t, broker, "../../api/config.json")
+
+	// Both services name their log consumer alike, on different queues
+	if blockchainRegistry.Consumer("LogConsumer") == apiRegistry.Consumer("LogConsumer") {
+		t.Fatal("Expected each service to have its own consumers")
+	}
+
+	startWorker(t, workers.NewVerifiedNegativeWorker(blockchainRegistry))
+
+	// Stands in for the failure handler of the api, on the consumer it takes
+	// from its registry
+	failures := make(chan dtocommon.ZkpProofFailureDto, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go apiRegistry.Consumer("ProofFailuresConsumer").StartConsuming(ctx, rabbitmq.NewRouter().On(dtocommon.ZkpProofFailureType, func(_ context.Context, msg rabbitmq.Message) rabbitmq.Outcome {
+		var failure dtocommon.ZkpProofFailureDto
+		if err := msg.Decode(&failure); err != nil {
+			return rabbitmq.DeadLetter(err)
+		}
+		failures <- failure
+		return rabbitmq.Ack()
+	}).Handle)
+
+	verifier, _ := broker.Connect("verifier").NewPublisher(rabbitmq.RabbitmqPublishersConfig{Exchange: "verifiers", RoutingKey: "negative"})
+	answer := verifiedNegativeMessage{incoming.ZkpVerifiedNegativeDto{EventId: "event-1"}}
+	if err := verifier.PublishConfirmed(context.Background(), answer); err != nil {
+		t.Fatalf("Could not publish answer: %v", err)
+	}
+
+	select {
+	case failure := <-failures:
+		if failure.EventId != "event-1" || failure.ReasonCode != reasoncodes.ErrVerifierResolution {
+			t.Errorf("Unexpected failure: %+v", failure)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Failure did not reach the api")
+	}
+}
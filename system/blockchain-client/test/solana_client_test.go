@@ -2,11 +2,19 @@ package test
 
 import (
 	"blockchain-client/src/external"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	httpclient "pkg-common/http_client"
+	"pkg-common/rest"
 	"testing"
 	"time"
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
 )
 
 type solanaTestClient struct {
@@ -241,3 +249,41 @@ func TestSolanaClientConcurrentAccess(t *testing.T) {
 		<-done
 	}
 }
+
+func TestRpcClientUsesSharedHttpClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(rest.RequestIdHeader) == "" {
+			t.Error("Expected a request ID header")
+		}
+		var request struct {
+			Id     any    `json:"id"`
+			Method string `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Fatalf("Could not decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch request.Method {
+		case "getHealth":
+			json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": request.Id, "result": "ok"})
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": request.Id,
+				"error": map[string]any{"code": -32005, "message": "Node is behind"}})
+		}
+	}))
+	defer server.Close()
+
+	client := external.NewRpcClient(httpclient.Config{BaseUrl: server.URL, Timeout: time.Second})
+
+	health, err := client.GetHealth(context.Background())
+	if err != nil || health != "ok" {
+		t.Fatalf("Expected a healthy node, got %q (%v)", health, err)
+	}
+
+	_, err = client.GetSlot(context.Background(), rpc.CommitmentFinalized)
+	var rpcErr *jsonrpc.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != -32005 {
+		t.Errorf("Expected the JSON-RPC error of the node, got %v", err)
+	}
+}
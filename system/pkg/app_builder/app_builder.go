@@ -1,32 +1,60 @@
 package appbuilder
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"pkg-common/health"
 	"pkg-common/logger"
+	"pkg-common/metrics"
 	"pkg-common/rabbitmq"
 	"pkg-common/rest"
+	"pkg-common/tracing"
 	"pkg-common/utilities"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	amqp "github.com/rabbitmq/amqp091-go"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
+// DefaultShutdownDrainDelay is how long readiness reports unready before the
+// server stops accepting connections, giving probes time to notice.
+const DefaultShutdownDrainDelay = 2 * time.Second
+
 type AppConfig interface {
 	GetLoggerConfig() logger.LoggerConfig
 	GetRabbitmqConfig() rabbitmq.RabbitmqConfig
 	GetRestApiPort() uint16
+	GetTracingConfig() tracing.TracingConfig
 }
 
 type AppBuilder[T utilities.JsonConfigObj[U], U AppConfig] struct {
-	Logger         *logger.Logger
-	Config         U
-	conn           *amqp.Connection
+	Logger *logger.Logger
+	Config U
+	// Registry holds the publishers and consumers of the service once
+	// InitRabbitmqRegistries ran; workers take theirs from it
+	Registry       *rabbitmq.Registry
+	service        string
+	broker         rabbitmq.Broker
 	workerServices []rabbitmq.WorkerService
 	routes         []rest.Route
 	middleware     []rest.Middleware
 	engine         *gin.Engine
+	shutdownTracer func(context.Context) error
+	health         *health.Checker
+	logSinks       []*logger.BufferedSink
+
+	// Environment is one of utilities.Environments, taken from ENV_TYPE
+	Environment string
+
+	ShutdownDrainDelay time.Duration
+
+	// TrustedProxies are the addresses whose X-Forwarded-For gin believes;
+	// with none, ClientIP is always the peer address and cannot be spoofed
+	TrustedProxies []string
 
 	ServeTemplates bool
 	TemplatesGlob  string
@@ -43,8 +71,12 @@ type AppBuilderInterface[T utilities.JsonConfigObj[U], U AppConfig] interface {
 	WithOption(func(*AppBuilder[T, U])) AppBuilderInterface[T, U]
 	InitRabbitmqConnection() AppBuilderInterface[T, U]
 	InitRabbitmqRegistries() AppBuilderInterface[T, U]
+	AddRabbitmqLogSink(alias rabbitmq.PublisherAlias) AppBuilderInterface[T, U]
 	AddWorkerServices(workerServices ...rabbitmq.WorkerService) AppBuilderInterface[T, U]
 	AddSwagger() AppBuilderInterface[T, U]
+	AddMetrics() AppBuilderInterface[T, U]
+	AddTracing(serviceName string) AppBuilderInterface[T, U]
+	AddHealthChecks(checks ...health.Check) AppBuilderInterface[T, U]
 	AddGinRoutes(routes ...rest.Route) AppBuilderInterface[T, U]
 	AddGinMiddleware(middlewares ...rest.Middleware) AppBuilderInterface[T, U]
 	InitGinRouter() AppBuilderInterface[T, U]
@@ -58,26 +90,45 @@ func New[T utilities.JsonConfigObj[U], U AppConfig]() AppBuilderInterface[T, U]
 func (a *AppBuilder[T, U]) InitLogger(loggerArgs logger.GlobalLoggerConfig) AppBuilderInterface[T, U] {
 	logger.InitDefaultLogger(loggerArgs)
 	a.Logger = logger.Default()
+	a.service = loggerArgs.Service
 	a.Logger.Info("Logger initialized")
 
 	return a
 }
 
+// LoadConfig layers the overlay of the environment and the environment
+// variables of the service over filePath, see utilities.ConfigSources, and
+// fails listing every missing or invalid key.
 func (a *AppBuilder[T, U]) LoadConfig(filePath string) AppBuilderInterface[T, U] {
-	a.Logger.Infof("Preparing to load zkpconfig from %s ...", filePath)
-	jsonConfig, err := utilities.ReadConfig[T, U](filePath)
+	a.Logger.Infof("Preparing to load zkpconfig from %s for %s ...", filePath, a.Environment)
+	config, err := utilities.LoadLayeredConfig[T, U](utilities.ConfigSources{
+		BaseFile:    filePath,
+		Environment: a.Environment,
+		EnvPrefix:   utilities.EnvPrefix(a.service),
+	})
 	if err != nil {
 		a.Logger.Error(err, "Failed to load zkpconfig")
 		panic(err)
 	}
 
-	a.Config = jsonConfig
+	a.Config = config
+	a.Logger.WithLevel(config.GetLoggerConfig().LogLevel)
 	a.Logger.Info("Config successfully loaded.")
 	return a
 }
 
+// ResolveEnvironment reads ENV_TYPE, defaulting to dev, and refuses to start
+// with an environment it does not know.
 func (a *AppBuilder[T, U]) ResolveEnvironment() AppBuilderInterface[T, U] {
-	// TODO: implement later
+	a.Environment = utilities.EnvDev
+	if env := strings.TrimSpace(os.Getenv(utilities.EnvTypeKey)); env != "" {
+		a.Environment = strings.ToLower(env)
+	}
+	if !slices.Contains(utilities.Environments, a.Environment) {
+		panic(fmt.Sprintf("unknown %s %q, expected one of %v", utilities.EnvTypeKey, a.Environment, utilities.Environments))
+	}
+
+	a.Logger.Infof("Running in %s environment", a.Environment)
 	return a
 }
 
@@ -87,17 +138,30 @@ func (a *AppBuilder[T, U]) WithOption(fn func(*AppBuilder[T, U])) AppBuilderInte
 }
 
 func (a *AppBuilder[T, U]) InitRabbitmqConnection() AppBuilderInterface[T, U] {
+	rabbitmqConf := a.Config.GetRabbitmqConfig()
+	if rabbitmqConf.Driver == rabbitmq.DriverMemory {
+		a.Logger.Warn("Using the in-memory broker, messages are not persisted")
+		memory := rabbitmq.SharedMemoryBroker()
+		if rabbitmqConf.DefinitionsFile != "" {
+			if err := memory.LoadDefinitions(rabbitmqConf.DefinitionsFile); err != nil {
+				panic(err)
+			}
+		}
+		a.broker = memory.Connect(rabbitmqConf.Producer)
+		return a
+	}
+
+	if rabbitmqConf.Driver != rabbitmq.DriverAmqp {
+		panic(fmt.Sprintf("unknown rabbitmq driver %q", rabbitmqConf.Driver))
+	}
+
 	a.Logger.Info("Preparing to connect to Rabbitmq server...")
-	rabbitmqConfig := a.Config.GetRabbitmqConfig()
-	conn, err := rabbitmq.ConnectToRabbitmq(
-		rabbitmqConfig.User,
-		rabbitmqConfig.Password,
-	)
-	if err != nil {
+	broker := rabbitmq.NewConnectionManager(rabbitmqConf)
+	if err := broker.Connect(); err != nil {
 		panic(err)
 	}
 
-	a.conn = conn
+	a.broker = broker
 	a.Logger.Info("Connection with Rabbitmq server established")
 
 	return a
@@ -107,13 +171,42 @@ func (a *AppBuilder[T, U]) InitRabbitmqRegistries() AppBuilderInterface[T, U] {
 	a.Logger.Info("Initializing Rabbitmq registries from zkpconfig")
 	rabbitmqConf := a.Config.GetRabbitmqConfig()
 
-	rabbitmq.InitializeConsumerRegistry(a.conn, rabbitmqConf.ConsumersConfig)
-	rabbitmq.InitializePublisherRegistry(a.conn, rabbitmqConf.PublishersConfig)
+	if topology := rabbitmqConf.Topology; topology.IsConfigured() {
+		if topology.Declare {
+			a.Logger.Info("Declaring Rabbitmq topology from zkpconfig")
+			if err := a.broker.DeclareTopology(topology); err != nil {
+				panic(err)
+			}
+		} else if err := a.broker.VerifyTopology(topology); err != nil {
+			panic(fmt.Errorf("rabbitmq topology does not match the broker: %w", err))
+		}
+		if err := topology.ValidatePublishers(rabbitmqConf.PublishersConfig); err != nil {
+			panic(err)
+		}
+	}
+
+	registry, err := rabbitmq.NewRegistry(a.broker, rabbitmqConf.PublishersConfig, rabbitmqConf.ConsumersConfig)
+	if err != nil {
+		panic(err)
+	}
+	a.Registry = registry
 	a.Logger.Info("Successfully initialized Rabbitmq registries from zkpconfig")
 
 	return a
 }
 
+// AddRabbitmqLogSink ships the entries of the default logger through the
+// publisher registered as alias, batched in the background as set in the
+// sink section of the logger config.
+func (a *AppBuilder[T, U]) AddRabbitmqLogSink(alias rabbitmq.PublisherAlias) AppBuilderInterface[T, U] {
+	writer := rabbitmq.CreateRabbitmqLogWriter(a.Registry.Publisher(alias))
+	sink := logger.NewBufferedSink(string(alias), writer, a.Config.GetLoggerConfig().Sink)
+	logger.AddSinkToLoggerInstance(logger.Default(), sink.Sink())
+	a.logSinks = append(a.logSinks, sink)
+
+	return a
+}
+
 func (a *AppBuilder[T, U]) AddWorkerServices(workerServices ...rabbitmq.WorkerService) AppBuilderInterface[T, U] {
 	a.Logger.Info("Adding Worker Services to Application...")
 	a.workerServices = append(a.workerServices, workerServices...)
@@ -144,9 +237,62 @@ func (a *AppBuilder[T, U]) AddSwagger() AppBuilderInterface[T, U] {
 	return a
 }
 
+func (a *AppBuilder[T, U]) AddMetrics() AppBuilderInterface[T, U] {
+	a.Logger.Info("Adding Prometheus metrics...")
+	a.middleware = append(a.middleware, rest.NewMiddleware("*", metrics.GinMiddleware()))
+	a.routes = append(a.routes, rest.NewRoute(
+		rest.GET,
+		"",
+		"metrics",
+		gin.WrapH(metrics.Handler()),
+	))
+
+	return a
+}
+
+func (a *AppBuilder[T, U]) AddTracing(serviceName string) AppBuilderInterface[T, U] {
+	a.Logger.Info("Initializing OpenTelemetry tracing...")
+	tracingConfig := a.Config.GetTracingConfig()
+	shutdown, err := tracing.Init(context.Background(), serviceName, tracingConfig)
+	if err != nil {
+		a.Logger.Error(err, "Failed to initialize tracing")
+		panic(err)
+	}
+
+	a.shutdownTracer = shutdown
+	a.middleware = append(a.middleware, rest.NewMiddleware("*", tracing.GinMiddleware()))
+	a.Logger.Infof("Tracing initialized with %s exporter", tracingConfig.Exporter)
+
+	return a
+}
+
+// AddHealthChecks registers /healthz and /readyz on the first call and adds
+// the given dependency checks to readiness. The RabbitMQ check is added at
+// Build time when a broker connection exists.
+func (a *AppBuilder[T, U]) AddHealthChecks(checks ...health.Check) AppBuilderInterface[T, U] {
+	if a.health == nil {
+		a.Logger.Info("Adding health and readiness endpoints...")
+		a.health = health.NewChecker()
+		a.routes = append(a.routes,
+			rest.NewRoute(rest.GET, "", "healthz", a.health.LivenessHandler),
+			rest.NewRoute(rest.GET, "", "readyz", a.health.ReadinessHandler),
+		)
+		if a.ShutdownDrainDelay == 0 {
+			a.ShutdownDrainDelay = DefaultShutdownDrainDelay
+		}
+	}
+
+	a.health.AddChecks(checks...)
+	return a
+}
+
 func (a *AppBuilder[T, U]) InitGinRouter() AppBuilderInterface[T, U] {
 	a.Logger.Info("Initializing Gin Router...")
 	router := gin.Default()
+	if err := router.SetTrustedProxies(a.TrustedProxies); err != nil {
+		a.Logger.Error(err, "Invalid trusted proxies")
+		panic(err)
+	}
 
 	if a.ServeCSS && a.CSSRoute != "" && a.CSSFilePath != "" {
 		a.Logger.Infof("Serving CSS: %s -> %s", a.CSSRoute, a.CSSFilePath)
@@ -169,14 +315,19 @@ func (a *AppBuilder[T, U]) InitGinRouter() AppBuilderInterface[T, U] {
 	groups := map[string]*gin.RouterGroup{}
 
 	a.Logger.Info("Registering Gin middleware...")
+	// Global scope first: gin copies engine handlers into a group when it is
+	// created, so global middleware registered later would skip existing groups
 	for _, m := range a.middleware {
-		// Global scope
 		if m.Group == "*" {
 			router.Use(m.Handler)
-			continue
 		}
+	}
 
+	for _, m := range a.middleware {
 		// Group scope
+		if m.Group == "*" {
+			continue
+		}
 		if _, exists := groups[m.Group]; !exists {
 			groups[m.Group] = router.Group("/" + m.Group)
 		}
@@ -200,6 +351,8 @@ func (a *AppBuilder[T, U]) InitGinRouter() AppBuilderInterface[T, U] {
 			group.PUT(r.Path, r.HandlerFunc)
 		case rest.PATCH:
 			group.PATCH(r.Path, r.HandlerFunc)
+		case rest.DELETE:
+			group.DELETE(r.Path, r.HandlerFunc)
 		default:
 			a.Logger.Warnf("Unrecognized HTTP method: %d", r.Method)
 		}
@@ -211,11 +364,19 @@ func (a *AppBuilder[T, U]) InitGinRouter() AppBuilderInterface[T, U] {
 }
 
 func (a *AppBuilder[T, U]) Build() ApplicationInterface {
+	if manager, ok := a.broker.(*rabbitmq.ConnectionManager); ok && a.health != nil {
+		a.health.AddChecks(rabbitmq.HealthCheck(manager, a.Registry))
+	}
+
 	return &application{
 		Logger:         a.Logger,
 		Addr:           fmt.Sprintf("0.0.0.0:%d", a.Config.GetRestApiPort()),
-		Conn:           a.conn,
+		Broker:         a.broker,
 		WorkerServices: a.workerServices,
 		Engine:         a.engine,
+		ShutdownTracer: a.shutdownTracer,
+		Health:         a.health,
+		LogSinks:       a.logSinks,
+		DrainDelay:     a.ShutdownDrainDelay,
 	}
 }
@@ -5,21 +5,31 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"pkg-common/health"
 	"pkg-common/logger"
 	"pkg-common/rabbitmq"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// workerDrainTimeout bounds how long shutdown waits for message handlers and
+// cron runs that were in progress when the signal arrived.
+const workerDrainTimeout = 10 * time.Second
+
 type application struct {
 	Logger         *logger.Logger
 	Addr           string
-	Conn           *amqp.Connection
+	Broker         rabbitmq.Broker
 	WorkerServices []rabbitmq.WorkerService
 	Engine         *gin.Engine
+	ShutdownTracer func(context.Context) error
+	Health         *health.Checker
+	LogSinks       []*logger.BufferedSink
+	DrainDelay     time.Duration
 }
 
 type ApplicationInterface interface {
@@ -29,9 +39,16 @@ type ApplicationInterface interface {
 func (a *application) Start() {
 	a.Logger.Info("Starting Application runtime...")
 
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
 	for _, ws := range a.WorkerServices {
 		a.Logger.Infof("Starting %s WorkerService", ws.GetServiceName())
-		go ws.StartService()
+		go func(ws rabbitmq.WorkerService) {
+			if err := ws.StartService(workersCtx); err != nil {
+				a.Logger.Errorf(err, "%s WorkerService stopped with error", ws.GetServiceName())
+			}
+		}(ws)
 	}
 
 	a.Logger.Info("Starting server...")
@@ -53,6 +70,12 @@ func (a *application) Start() {
 
 	a.Logger.Info("Recevied shutdown signal, closing application...")
 
+	if a.Health != nil {
+		a.Health.MarkShuttingDown()
+		a.Logger.Infof("Readiness set to unready, draining for %v", a.DrainDelay)
+		time.Sleep(a.DrainDelay)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
@@ -60,10 +83,58 @@ func (a *application) Start() {
 		a.Logger.Errorf(err, "Server forced to shutdown.")
 	}
 
-	if a.Conn != nil {
-		a.Conn.Close()
+	stopWorkers()
+	if failed := a.drainWorkers(); len(failed) > 0 {
+		a.Logger.Warnf("Workers failed to drain before deadline: %s", strings.Join(failed, ", "))
+	} else if len(a.WorkerServices) > 0 {
+		a.Logger.Info("All workers drained.")
+	}
+
+	// the sinks publish through the broker, so they are flushed before it closes
+	for _, sink := range a.LogSinks {
+		if err := sink.Close(ctx); err != nil {
+			a.Logger.Errorf(err, "Failed to flush pending log entries.")
+		}
+	}
+
+	if a.Broker != nil {
+		if err := a.Broker.Close(); err != nil {
+			a.Logger.Errorf(err, "Failed to close connection to Rabbitmq server.")
+		}
 		a.Logger.Info("Connection to Rabbitmq server closed.")
 	}
 
+	if a.ShutdownTracer != nil {
+		if err := a.ShutdownTracer(ctx); err != nil {
+			a.Logger.Errorf(err, "Failed to flush pending spans.")
+		}
+	}
+
 	a.Logger.Info("Application closed gracefully.")
 }
+
+// drainWorkers stops all workers concurrently and returns the names of those
+// that did not finish their in-flight work within workerDrainTimeout.
+func (a *application) drainWorkers() []string {
+	ctx, cancel := context.WithTimeout(context.Background(), workerDrainTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var failed []string
+	var wg sync.WaitGroup
+	for _, ws := range a.WorkerServices {
+		wg.Add(1)
+		go func(ws rabbitmq.WorkerService) {
+			defer wg.Done()
+			if err := ws.Stop(ctx); err != nil {
+				a.Logger.Errorf(err, "%s WorkerService failed to drain", ws.GetServiceName())
+				mu.Lock()
+				failed = append(failed, ws.GetServiceName())
+				mu.Unlock()
+			}
+		}(ws)
+	}
+	wg.Wait()
+
+	return failed
+}
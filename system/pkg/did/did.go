@@ -0,0 +1,111 @@
+package did
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/mr-tron/base58"
+)
+
+const (
+	MethodKey = "key"
+	MethodWeb = "web"
+
+	keyPrefix = "did:key:"
+	webPrefix = "did:web:"
+
+	// multibase prefix for base58btc
+	base58btcPrefix = "z"
+)
+
+// multicodec varint for an ed25519 public key
+var ed25519Codec = []byte{0xed, 0x01}
+
+var ErrUnsupportedDID = errors.New("unsupported DID")
+
+// Method returns the DID method ("key", "web", ...) of did.
+func Method(did string) (string, error) {
+	parts := strings.SplitN(did, ":", 3)
+	if len(parts) != 3 || parts[0] != "did" || parts[1] == "" || parts[2] == "" {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedDID, did)
+	}
+	return parts[1], nil
+}
+
+// SplitURL splits a DID URL (did:web:example.com#key-1) into the DID and its
+// fragment.
+func SplitURL(didURL string) (string, string) {
+	did, fragment, _ := strings.Cut(didURL, "#")
+	return did, fragment
+}
+
+// KeyDID encodes an Ed25519 public key as a did:key.
+func KeyDID(pub ed25519.PublicKey) string {
+	return keyPrefix + base58btcPrefix + base58.Encode(append(append([]byte{}, ed25519Codec...), pub...))
+}
+
+// PublicKeyFromKeyDID decodes the Ed25519 public key embedded in a did:key.
+func PublicKeyFromKeyDID(did string) (ed25519.PublicKey, error) {
+	did, _ = SplitURL(did)
+	if !strings.HasPrefix(did, keyPrefix+base58btcPrefix) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedDID, did)
+	}
+
+	raw, err := base58.Decode(strings.TrimPrefix(did, keyPrefix+base58btcPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("invalid did:key encoding: %w", err)
+	}
+	if len(raw) != len(ed25519Codec)+ed25519.PublicKeySize ||
+		raw[0] != ed25519Codec[0] || raw[1] != ed25519Codec[1] {
+		return nil, fmt.Errorf("%w: did:key is not an Ed25519 key", ErrUnsupportedDID)
+	}
+	return ed25519.PublicKey(raw[len(ed25519Codec):]), nil
+}
+
+// WebDID derives the did:web identifier of an origin such as
+// http://localhost:8080, encoding the port separator as required by the spec.
+func WebDID(baseURL string) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid base URL for did:web: %s", baseURL)
+	}
+
+	did := webPrefix + strings.ReplaceAll(u.Host, ":", "%3A")
+	for _, segment := range strings.Split(strings.Trim(u.Path, "/"), "/") {
+		if segment != "" {
+			did += ":" + url.PathEscape(segment)
+		}
+	}
+	return did, nil
+}
+
+// WebDocumentLocation returns the host and path under which the DID document
+// of a did:web is published.
+func WebDocumentLocation(did string) (string, string, error) {
+	did, _ = SplitURL(did)
+	if !strings.HasPrefix(did, webPrefix) {
+		return "", "", fmt.Errorf("%w: %s", ErrUnsupportedDID, did)
+	}
+
+	segments := strings.Split(strings.TrimPrefix(did, webPrefix), ":")
+	host, err := url.PathUnescape(segments[0])
+	if err != nil || host == "" {
+		return "", "", fmt.Errorf("invalid did:web host: %s", did)
+	}
+	if len(segments) == 1 {
+		return host, "/.well-known/did.json", nil
+	}
+
+	path := ""
+	for _, segment := range segments[1:] {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid did:web path: %s", did)
+		}
+		path += "/" + unescaped
+	}
+	return host, path + "/did.json", nil
+}
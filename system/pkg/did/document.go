@@ -0,0 +1,85 @@
+package did
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+const verificationMethodType = "JsonWebKey2020"
+
+var documentContext = []string{
+	"https://www.w3.org/ns/did/v1",
+	"https://w3id.org/security/suites/jws-2020/v1",
+}
+
+type Jwk struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+}
+
+type VerificationMethod struct {
+	Id           string `json:"id"`
+	Type         string `json:"type"`
+	Controller   string `json:"controller"`
+	PublicKeyJwk Jwk    `json:"publicKeyJwk"`
+}
+
+type Document struct {
+	Context            []string             `json:"@context"`
+	Id                 string               `json:"id"`
+	VerificationMethod []VerificationMethod `json:"verificationMethod"`
+	Authentication     []string             `json:"authentication"`
+	AssertionMethod    []string             `json:"assertionMethod"`
+}
+
+// NewDocument builds a DID document with a single Ed25519 key usable for
+// authentication and for signing credentials and receipts.
+func NewDocument(did, keyFragment string, pub ed25519.PublicKey) Document {
+	methodId := did + "#" + keyFragment
+	return Document{
+		Context: documentContext,
+		Id:      did,
+		VerificationMethod: []VerificationMethod{{
+			Id:         methodId,
+			Type:       verificationMethodType,
+			Controller: did,
+			PublicKeyJwk: Jwk{
+				Kty: "OKP",
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			},
+		}},
+		Authentication:  []string{methodId},
+		AssertionMethod: []string{methodId},
+	}
+}
+
+// PublicKey returns the Ed25519 key of the verification method referenced by
+// methodId, which may be a full DID URL or a bare fragment. An empty methodId
+// selects the first assertion method.
+func (d Document) PublicKey(methodId string) (ed25519.PublicKey, error) {
+	if methodId == "" && len(d.AssertionMethod) > 0 {
+		methodId = d.AssertionMethod[0]
+	}
+	if strings.HasPrefix(methodId, "#") {
+		methodId = d.Id + methodId
+	}
+
+	for _, vm := range d.VerificationMethod {
+		if methodId != "" && vm.Id != methodId {
+			continue
+		}
+		if vm.PublicKeyJwk.Kty != "OKP" || vm.PublicKeyJwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("verification method %s is not an Ed25519 key", vm.Id)
+		}
+		raw, err := base64.RawURLEncoding.DecodeString(vm.PublicKeyJwk.X)
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("verification method %s has an invalid key", vm.Id)
+		}
+		return ed25519.PublicKey(raw), nil
+	}
+	return nil, fmt.Errorf("verification method %s not found in %s", methodId, d.Id)
+}
@@ -0,0 +1,63 @@
+package did
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+const DefaultKeyFragment = "key-1"
+
+var ErrInvalidPrivateKey = errors.New("invalid Ed25519 private key")
+
+// ParsePrivateKey decodes a base64 Ed25519 key, either its 32 byte seed or the
+// 64 byte private key. An empty string is no key.
+func ParsePrivateKey(encoded string) (ed25519.PrivateKey, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPrivateKey, err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	default:
+		return nil, fmt.Errorf("%w: got %d bytes", ErrInvalidPrivateKey, len(raw))
+	}
+}
+
+// WebIdentity is the did:web of a service together with the key it signs with.
+type WebIdentity struct {
+	Did      string
+	Document Document
+	Signer   *Signer
+}
+
+// NewWebIdentity derives the did:web of baseUrl and publishes the public half
+// of privateKey under keyFragment. A nil privateKey generates a fresh key.
+func NewWebIdentity(baseUrl, keyFragment string, privateKey ed25519.PrivateKey) (*WebIdentity, error) {
+	if privateKey == nil {
+		var err error
+		if _, privateKey, err = ed25519.GenerateKey(rand.Reader); err != nil {
+			return nil, fmt.Errorf("failed to generate DID key: %w", err)
+		}
+	}
+
+	id, err := WebDID(baseUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	pub := privateKey.Public().(ed25519.PublicKey)
+	return &WebIdentity{
+		Did:      id,
+		Document: NewDocument(id, keyFragment, pub),
+		Signer:   NewSigner(privateKey, id+"#"+keyFragment),
+	}, nil
+}
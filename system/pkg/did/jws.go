@@ -0,0 +1,85 @@
+package did
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidSignature = errors.New("invalid signature")
+
+type jwsHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// Signer produces compact EdDSA JWS tokens whose kid is the DID URL of the
+// signing key, so any holder of a Resolver can verify them.
+type Signer struct {
+	privateKey ed25519.PrivateKey
+	keyId      string
+}
+
+func NewSigner(privateKey ed25519.PrivateKey, keyId string) *Signer {
+	return &Signer{privateKey: privateKey, keyId: keyId}
+}
+
+func (s *Signer) KeyId() string {
+	return s.keyId
+}
+
+func (s *Signer) Sign(payload []byte, typ string) (string, error) {
+	header, err := json.Marshal(jwsHeader{Alg: "EdDSA", Kid: s.keyId, Typ: typ})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(payload)
+	signature := ed25519.Sign(s.privateKey, []byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Verify checks a compact EdDSA JWS against the key its kid resolves to and
+// returns the payload together with the kid.
+func Verify(ctx context.Context, r Resolver, token string) ([]byte, string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, "", fmt.Errorf("%w: malformed compact JWS", ErrInvalidSignature)
+	}
+
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+	var header jwsHeader
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return nil, "", fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+	if header.Alg != "EdDSA" {
+		return nil, "", fmt.Errorf("%w: unsupported alg %s", ErrInvalidSignature, header.Alg)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: malformed payload", ErrInvalidSignature)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+
+	pub, err := ResolveKey(ctx, r, header.Kid)
+	if err != nil {
+		return nil, header.Kid, err
+	}
+	if !ed25519.Verify(pub, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, header.Kid, ErrInvalidSignature
+	}
+	return payload, header.Kid, nil
+}
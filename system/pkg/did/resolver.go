@@ -0,0 +1,142 @@
+package did
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var ErrWebHostNotAllowed = errors.New("did:web host is not allowed")
+
+type Resolver interface {
+	Resolve(ctx context.Context, did string) (*Document, error)
+}
+
+type ResolverOption func(*resolver)
+
+// WithWebBaseUrl resolves did:web documents of host against baseUrl instead of
+// https://host. This is the local stand-in for services that are only reachable
+// over plain HTTP inside the compose network.
+func WithWebBaseUrl(host, baseUrl string) ResolverOption {
+	return func(r *resolver) {
+		r.webBaseUrls[host] = strings.TrimRight(baseUrl, "/")
+	}
+}
+
+// WithAllowedWebHosts restricts did:web resolution to hosts and the hosts
+// given to WithWebBaseUrl, so a caller naming a DID cannot make the resolver
+// fetch arbitrary URLs.
+func WithAllowedWebHosts(hosts ...string) ResolverOption {
+	return func(r *resolver) {
+		r.allowedHosts = make(map[string]bool)
+		for _, host := range hosts {
+			r.allowedHosts[host] = true
+		}
+	}
+}
+
+func WithHttpClient(client *http.Client) ResolverOption {
+	return func(r *resolver) {
+		r.client = client
+	}
+}
+
+type resolver struct {
+	client       *http.Client
+	webBaseUrls  map[string]string
+	allowedHosts map[string]bool // nil allows every host
+}
+
+// NewResolver returns a resolver for did:key and did:web identifiers.
+func NewResolver(opts ...ResolverOption) Resolver {
+	r := &resolver{
+		client:      &http.Client{Timeout: 10 * time.Second},
+		webBaseUrls: make(map[string]string),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *resolver) Resolve(ctx context.Context, did string) (*Document, error) {
+	did, _ = SplitURL(did)
+	method, err := Method(did)
+	if err != nil {
+		return nil, err
+	}
+
+	switch method {
+	case MethodKey:
+		pub, err := PublicKeyFromKeyDID(did)
+		if err != nil {
+			return nil, err
+		}
+		// did:key documents use the multibase value as the key fragment
+		doc := NewDocument(did, strings.TrimPrefix(did, keyPrefix), pub)
+		return &doc, nil
+	case MethodWeb:
+		return r.resolveWeb(ctx, did)
+	default:
+		return nil, fmt.Errorf("%w: method %s", ErrUnsupportedDID, method)
+	}
+}
+
+func (r *resolver) resolveWeb(ctx context.Context, did string) (*Document, error) {
+	host, path, err := WebDocumentLocation(did)
+	if err != nil {
+		return nil, err
+	}
+
+	baseUrl, ok := r.webBaseUrls[host]
+	if !ok {
+		if r.allowedHosts != nil && !r.allowedHosts[host] {
+			return nil, fmt.Errorf("%w: %s", ErrWebHostNotAllowed, host)
+		}
+		baseUrl = "https://" + host
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseUrl+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/did+json, application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch DID document for %s: %w", did, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch DID document for %s: status %d", did, resp.StatusCode)
+	}
+
+	var doc Document
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid DID document for %s: %w", did, err)
+	}
+	if doc.Id != did {
+		return nil, fmt.Errorf("DID document id %s does not match %s", doc.Id, did)
+	}
+	return &doc, nil
+}
+
+// ResolveKey resolves the Ed25519 key referenced by a DID URL such as
+// did:web:example.com#key-1.
+func ResolveKey(ctx context.Context, r Resolver, didUrl string) ([]byte, error) {
+	doc, err := r.Resolve(ctx, didUrl)
+	if err != nil {
+		return nil, err
+	}
+	did, fragment := SplitURL(didUrl)
+	if fragment == "" {
+		return doc.PublicKey("")
+	}
+	return doc.PublicKey(did + "#" + fragment)
+}
@@ -5,16 +5,27 @@ import (
 	"pkg-common/utilities"
 )
 
+// Message types of the blockchain client's answers to a verification
+const (
+	ZkpProofResultType  = "zkp.proof.result"
+	ZkpProofFailureType = "zkp.proof.failure"
+)
+
 type ZkpProofResultDto struct {
 	EventId   string `json:"event_id"`
 	Signature string `json:"signature"`
 	AccountId string `json:"account_id"`
+	ProofSize int    `json:"proof_size"`
 }
 
 func (zkpr ZkpProofResultDto) Serialize() ([]byte, error) {
 	return utilities.Serialize[ZkpProofResultDto](zkpr)
 }
 
+func (zkpr ZkpProofResultDto) MessageType() string {
+	return ZkpProofResultType
+}
+
 type ZkpProofFailureDto struct {
 	EventId    string                 `json:"event_id"`
 	ReqestBody []byte                 `json:"request_body"`
@@ -25,3 +36,7 @@ type ZkpProofFailureDto struct {
 func (zkpf ZkpProofFailureDto) Serialize() ([]byte, error) {
 	return utilities.Serialize[ZkpProofFailureDto](zkpf)
 }
+
+func (zkpf ZkpProofFailureDto) MessageType() string {
+	return ZkpProofFailureType
+}
@@ -0,0 +1,172 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	StatusOk           = "ok"
+	StatusUnavailable  = "unavailable"
+	StatusShuttingDown = "shutting_down"
+
+	DefaultCheckTimeout = 2 * time.Second
+)
+
+var errPanicked = errors.New("health check panicked")
+
+// CheckFunc probes a single dependency; a nil error means it is usable.
+type CheckFunc func(ctx context.Context) error
+
+// Check is a named dependency probe run by the readiness endpoint. Every check
+// gets its own deadline so a hanging dependency cannot stall the others.
+type Check struct {
+	Name    string
+	Timeout time.Duration
+	Probe   CheckFunc
+}
+
+func NewCheck(name string, timeout time.Duration, probe CheckFunc) Check {
+	if timeout <= 0 {
+		timeout = DefaultCheckTimeout
+	}
+	return Check{Name: name, Timeout: timeout, Probe: probe}
+}
+
+type CheckResult struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Checker serves liveness and readiness. Liveness only says the process is
+// serving HTTP; readiness runs all dependency checks and reports unready once
+// graceful shutdown has begun, so traffic is drained before the server stops.
+type Checker struct {
+	mu           sync.RWMutex
+	checks       []Check
+	shuttingDown atomic.Bool
+}
+
+func NewChecker(checks ...Check) *Checker {
+	return &Checker{checks: checks}
+}
+
+func (c *Checker) AddChecks(checks ...Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, checks...)
+}
+
+// MarkShuttingDown flips readiness to unready for the rest of the process life.
+func (c *Checker) MarkShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+func (c *Checker) IsShuttingDown() bool {
+	return c.shuttingDown.Load()
+}
+
+// Run executes all checks concurrently and aggregates their results.
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := append([]Check(nil), c.checks...)
+	c.mu.RUnlock()
+
+	report := Report{Status: StatusOk, Checks: make(map[string]CheckResult, len(checks))}
+	if c.IsShuttingDown() {
+		report.Status = StatusShuttingDown
+	}
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	for i, check := range checks {
+		report.Checks[check.Name] = results[i]
+		if results[i].Status != StatusOk && report.Status == StatusOk {
+			report.Status = StatusUnavailable
+		}
+	}
+
+	return report
+}
+
+func runCheck(ctx context.Context, check Check) CheckResult {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = DefaultCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- errPanicked
+			}
+		}()
+		done <- check.Probe(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{Status: StatusOk, DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status = StatusUnavailable
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// LivenessHandler godoc
+// @Summary      Liveness probe
+// @Tags         health
+// @Produce      json
+// @Success      200 {object} health.Report
+// @Router       /healthz [get]
+func (c *Checker) LivenessHandler(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, Report{Status: StatusOk})
+}
+
+// ReadinessHandler godoc
+// @Summary      Readiness probe
+// @Description  Runs dependency checks; 503 when any fails or the service is shutting down
+// @Tags         health
+// @Produce      json
+// @Success      200 {object} health.Report
+// @Failure      503 {object} health.Report
+// @Router       /readyz [get]
+func (c *Checker) ReadinessHandler(ctx *gin.Context) {
+	report := c.Run(ctx.Request.Context())
+	if report.Status != StatusOk {
+		ctx.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	ctx.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,316 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"pkg-common/rest"
+	"strings"
+	"time"
+)
+
+// HTTPError is a response outside 2xx.
+type HTTPError struct {
+	Method     string
+	Url        string
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%s %s: HTTP %d: %s", e.Method, e.Url, e.StatusCode, e.Message())
+}
+
+// Message returns the "error" field of a JSON error body, or the status text.
+func (e *HTTPError) Message() string {
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(e.Body, &body); err != nil || body.Error == "" {
+		return http.StatusText(e.StatusCode)
+	}
+	return body.Error
+}
+
+// Response is a raw 2xx response.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Client calls one internal service. It retries idempotent requests on
+// transport errors and 429/502/503/504, trips a circuit breaker on repeated
+// failures, adds the internal auth token and forwards the request ID.
+type Client struct {
+	config   Config
+	http     *http.Client
+	tokens   *rest.InternalTokenIssuer
+	audience string
+	breaker  *circuitBreaker
+	sleep    func(ctx context.Context, d time.Duration) error
+}
+
+type ClientOption func(*Client)
+
+// WithInternalAuth signs every request for audience with tokens.
+func WithInternalAuth(tokens *rest.InternalTokenIssuer, audience string) ClientOption {
+	return func(c *Client) {
+		c.tokens = tokens
+		c.audience = audience
+	}
+}
+
+// WithHttpClient replaces the underlying client; its timeout is overridden by
+// the configured one.
+func WithHttpClient(client *http.Client) ClientOption {
+	return func(c *Client) {
+		c.http = client
+	}
+}
+
+// WithClock replaces the clock of the circuit breaker.
+func WithClock(now func() time.Time) ClientOption {
+	return func(c *Client) {
+		c.breaker.now = now
+	}
+}
+
+func NewClient(config Config, opts ...ClientOption) *Client {
+	config = config.withDefaults()
+	c := &Client{
+		config:  config,
+		http:    &http.Client{},
+		breaker: newCircuitBreaker(config.BreakerThreshold, config.BreakerCooldown, time.Now),
+		sleep:   sleepContext,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.http.Timeout = config.Timeout
+	return c
+}
+
+type requestOptions struct {
+	scopes []string
+	query  url.Values
+	header http.Header
+}
+
+type RequestOption func(*requestOptions)
+
+// WithScopes requests internal auth scopes for the call.
+func WithScopes(scopes ...string) RequestOption {
+	return func(o *requestOptions) {
+		o.scopes = append(o.scopes, scopes...)
+	}
+}
+
+func WithQuery(query url.Values) RequestOption {
+	return func(o *requestOptions) {
+		o.query = query
+	}
+}
+
+func WithHeader(key, value string) RequestOption {
+	return func(o *requestOptions) {
+		o.header.Set(key, value)
+	}
+}
+
+func Get[T any](ctx context.Context, c *Client, path string, opts ...RequestOption) (T, error) {
+	return Do[T](ctx, c, http.MethodGet, path, nil, opts...)
+}
+
+func Post[T any](ctx context.Context, c *Client, path string, body any, opts ...RequestOption) (T, error) {
+	return Do[T](ctx, c, http.MethodPost, path, body, opts...)
+}
+
+func Put[T any](ctx context.Context, c *Client, path string, body any, opts ...RequestOption) (T, error) {
+	return Do[T](ctx, c, http.MethodPut, path, body, opts...)
+}
+
+func Delete[T any](ctx context.Context, c *Client, path string, opts ...RequestOption) (T, error) {
+	return Do[T](ctx, c, http.MethodDelete, path, nil, opts...)
+}
+
+// Do sends the request and decodes a JSON response into T; an empty body
+// yields the zero value.
+func Do[T any](ctx context.Context, c *Client, method, path string, body any, opts ...RequestOption) (T, error) {
+	var result T
+	resp, err := c.Send(ctx, method, path, body, opts...)
+	if err != nil {
+		return result, err
+	}
+	if len(resp.Body) == 0 {
+		return result, nil
+	}
+	if err := json.Unmarshal(resp.Body, &result); err != nil {
+		return result, fmt.Errorf("decoding %s %s response: %w", method, path, err)
+	}
+	return result, nil
+}
+
+// Send performs the request and returns the raw 2xx response. Other statuses
+// are returned as *HTTPError.
+func (c *Client) Send(ctx context.Context, method, path string, body any, opts ...RequestOption) (*Response, error) {
+	options := requestOptions{header: http.Header{}}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("encoding %s %s request: %w", method, path, err)
+		}
+	}
+
+	endpoint := strings.TrimRight(c.config.BaseUrl, "/") + "/" + strings.TrimLeft(path, "/")
+	if len(options.query) > 0 {
+		endpoint += "?" + options.query.Encode()
+	}
+
+	// Every attempt of one call shares the request ID
+	requestId, ok := rest.RequestIdFromContext(ctx)
+	if !ok {
+		requestId = rest.NewRequestId()
+	}
+
+	attempts := 1
+	if isIdempotent(method) {
+		attempts += c.config.MaxRetries
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if err := c.sleep(ctx, c.backoff(attempt)); err != nil {
+				return nil, err
+			}
+		}
+		if err := c.breaker.allow(); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", method, endpoint, err)
+		}
+
+		resp, err := c.attempt(ctx, method, endpoint, payload, requestId, options)
+		c.breaker.record(err == nil || !isServerFailure(err))
+		if err == nil {
+			return resp, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil || !isRetryable(err) {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+func (c *Client) attempt(ctx context.Context, method, endpoint string, payload []byte, requestId string, options requestOptions) (*Response, error) {
+	var bodyReader io.Reader
+	if payload != nil {
+		bodyReader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range options.header {
+		req.Header[key] = values
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set(rest.RequestIdHeader, requestId)
+	if c.tokens != nil {
+		if err := c.tokens.Authorize(req, c.audience, options.scopes...); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, &transportError{err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &transportError{err: err}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &HTTPError{Method: method, Url: endpoint, StatusCode: resp.StatusCode, Header: resp.Header, Body: body}
+	}
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: body}, nil
+}
+
+// backoff doubles the delay per retry up to RetryMaxDelay, with up to 50%
+// jitter so callers of a recovering service do not retry in lockstep.
+func (c *Client) backoff(retry int) time.Duration {
+	delay := c.config.RetryBaseDelay << (retry - 1)
+	if delay <= 0 || delay > c.config.RetryMaxDelay {
+		delay = c.config.RetryMaxDelay
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string { return e.err.Error() }
+func (e *transportError) Unwrap() error { return e.err }
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func isRetryable(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		switch httpErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var transportErr *transportError
+	return errors.As(err, &transportErr)
+}
+
+// isServerFailure tells the breaker whether the service itself failed; 4xx
+// responses are the caller's problem and keep the breaker closed.
+func isServerFailure(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500
+	}
+	var transportErr *transportError
+	return errors.As(err, &transportErr)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
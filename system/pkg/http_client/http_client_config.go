@@ -0,0 +1,75 @@
+package httpclient
+
+import "time"
+
+const (
+	DefaultTimeout          = 10 * time.Second
+	DefaultMaxRetries       = 2
+	DefaultRetryBaseDelay   = 100 * time.Millisecond
+	DefaultRetryMaxDelay    = 2 * time.Second
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+// ConfigJson configures the client of one service. Zero values fall back to
+// the defaults; a negative max_retries disables retries.
+type ConfigJson struct {
+	BaseUrl                 string `json:"base_url"`
+	TimeoutSeconds          int    `json:"timeout_seconds"`
+	MaxRetries              int    `json:"max_retries"`
+	RetryBaseDelayMs        int    `json:"retry_base_delay_ms"`
+	RetryMaxDelayMs         int    `json:"retry_max_delay_ms"`
+	BreakerFailureThreshold int    `json:"breaker_failure_threshold"`
+	BreakerCooldownSeconds  int    `json:"breaker_cooldown_seconds"`
+}
+
+type Config struct {
+	BaseUrl          string
+	Timeout          time.Duration
+	MaxRetries       int
+	RetryBaseDelay   time.Duration
+	RetryMaxDelay    time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+func (cj ConfigJson) MapToDomain() Config {
+	maxRetries := cj.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = DefaultMaxRetries
+	}
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+
+	return Config{
+		BaseUrl:          cj.BaseUrl,
+		Timeout:          time.Duration(cj.TimeoutSeconds) * time.Second,
+		MaxRetries:       maxRetries,
+		RetryBaseDelay:   time.Duration(cj.RetryBaseDelayMs) * time.Millisecond,
+		RetryMaxDelay:    time.Duration(cj.RetryMaxDelayMs) * time.Millisecond,
+		BreakerThreshold: cj.BreakerFailureThreshold,
+		BreakerCooldown:  time.Duration(cj.BreakerCooldownSeconds) * time.Second,
+	}
+}
+
+// withDefaults fills the durations and thresholds left at zero. MaxRetries is
+// kept as is, so a Config literal without it does not retry.
+func (c Config) withDefaults() Config {
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.RetryBaseDelay <= 0 {
+		c.RetryBaseDelay = DefaultRetryBaseDelay
+	}
+	if c.RetryMaxDelay <= 0 {
+		c.RetryMaxDelay = DefaultRetryMaxDelay
+	}
+	if c.BreakerThreshold <= 0 {
+		c.BreakerThreshold = DefaultBreakerThreshold
+	}
+	if c.BreakerCooldown <= 0 {
+		c.BreakerCooldown = DefaultBreakerCooldown
+	}
+	return c
+}
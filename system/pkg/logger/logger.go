@@ -5,14 +5,17 @@ import (
 	"io"
 	"os"
 	"pkg-common/utilities/timeutil"
+	"slices"
 	"time"
 
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Logger struct {
-	zl   zerolog.Logger
-	sink func(string, zerolog.Level, timeutil.TimeUTC)
+	zl     zerolog.Logger
+	fields []Field
+	sink   *sinkSlot // shared with child loggers
 }
 
 func New() *Logger {
@@ -24,7 +27,7 @@ func New() *Logger {
 		Caller().
 		Logger()
 
-	return &Logger{zl: logger}
+	return &Logger{zl: logger, sink: &sinkSlot{}}
 }
 
 func NewFromConfig(cfg LoggerConfig) *Logger {
@@ -42,7 +45,7 @@ func NewFromConfig(cfg LoggerConfig) *Logger {
 		Logger().
 		Level(zerolog.Level(cfg.LogLevel))
 
-	return &Logger{zl: logger}
+	return &Logger{zl: logger, sink: &sinkSlot{}}
 }
 
 func (l *Logger) WithOutput(w io.Writer) *Logger {
@@ -55,80 +58,100 @@ func (l *Logger) WithLevel(level zerolog.Level) *Logger {
 	return l
 }
 
+// WithContext returns a logger tagging every entry with the trace and span id of
+// the span active in ctx, so log lines can be joined with traces, and with the
+// fields added to ctx by ContextWithField.
 func (l *Logger) WithContext(ctx context.Context) *Logger {
-	return &Logger{zl: l.zl.With().Logger()}
+	child := &Logger{zl: l.zl, fields: l.fields, sink: l.sink}
+
+	spanContext := trace.SpanContextFromContext(ctx)
+	if spanContext.IsValid() {
+		child = child.
+			With(TraceIdKey, spanContext.TraceID().String()).
+			With(SpanIdKey, spanContext.SpanID().String())
+	}
+	for _, field := range FieldsFromContext(ctx) {
+		child = child.With(field.Key, field.Value)
+	}
+	return child
 }
 
-func (l *Logger) With() zerolog.Context {
-	return l.zl.With()
+// With returns a child logger adding key to every entry, including the ones
+// handed to the sink.
+func (l *Logger) With(key string, value any) *Logger {
+	return &Logger{
+		zl:     l.zl.With().Interface(key, value).Logger(),
+		fields: append(slices.Clip(l.fields), Field{Key: key, Value: value}),
+		sink:   l.sink,
+	}
 }
 
 func (l *Logger) Debug(msg string) {
 	l.zl.Debug().Msg(msg)
-	l.activateSink(msg, zerolog.DebugLevel, timeutil.NowUTC())
+	l.activateSink(msg, zerolog.DebugLevel, timeutil.NowUTC(), nil)
 }
 
 func (l *Logger) Debugf(format string, v ...interface{}) {
 	l.zl.Debug().Msgf(format, v...)
-	l.activateSinkFormatted(format, zerolog.DebugLevel, timeutil.NowUTC(), v...)
+	l.activateSinkFormatted(format, zerolog.DebugLevel, timeutil.NowUTC(), nil, v...)
 }
 
 func (l *Logger) Info(msg string) {
 	l.zl.Info().Msg(msg)
-	l.activateSink(msg, zerolog.InfoLevel, timeutil.NowUTC())
+	l.activateSink(msg, zerolog.InfoLevel, timeutil.NowUTC(), nil)
 }
 
 func (l *Logger) Infof(format string, v ...interface{}) {
 	l.zl.Info().Msgf(format, v...)
-	l.activateSinkFormatted(format, zerolog.InfoLevel, timeutil.NowUTC(), v...)
+	l.activateSinkFormatted(format, zerolog.InfoLevel, timeutil.NowUTC(), nil, v...)
 }
 
 func (l *Logger) Warn(msg string) {
 	l.zl.Warn().Msg(msg)
-	l.activateSink(msg, zerolog.WarnLevel, timeutil.NowUTC())
+	l.activateSink(msg, zerolog.WarnLevel, timeutil.NowUTC(), nil)
 }
 
 func (l *Logger) Warnf(format string, v ...interface{}) {
 	l.zl.Warn().Msgf(format, v...)
-	l.activateSinkFormatted(format, zerolog.WarnLevel, timeutil.NowUTC(), v...)
+	l.activateSinkFormatted(format, zerolog.WarnLevel, timeutil.NowUTC(), nil, v...)
 }
 
 func (l *Logger) Error(err error, msg string) {
 	l.zl.Error().Err(err).Msg(msg)
-	l.activateSink(msg, zerolog.ErrorLevel, timeutil.NowUTC())
+	l.activateSink(msg, zerolog.ErrorLevel, timeutil.NowUTC(), err)
 }
 
 func (l *Logger) Errorf(err error, format string, v ...interface{}) {
 	l.zl.Error().Err(err).Msgf(format, v...)
-	l.activateSinkFormatted(format, zerolog.ErrorLevel, timeutil.NowUTC(), v...)
+	l.activateSinkFormatted(format, zerolog.ErrorLevel, timeutil.NowUTC(), err, v...)
 }
 
 func (l *Logger) Fatal(err error, msg string) {
 	l.zl.Fatal().Err(err).Msg(msg)
-	l.activateSink(msg, zerolog.FatalLevel, timeutil.NowUTC())
+	l.activateSink(msg, zerolog.FatalLevel, timeutil.NowUTC(), err)
 }
 
 func (l *Logger) Fatalf(err error, format string, v ...interface{}) {
 	l.zl.Fatal().Err(err).Msgf(format, v...)
-	l.activateSinkFormatted(format, zerolog.FatalLevel, timeutil.NowUTC(), v...)
+	l.activateSinkFormatted(format, zerolog.FatalLevel, timeutil.NowUTC(), err, v...)
 }
 
 func (l *Logger) Panic(err error, msg string) {
 	l.zl.Panic().Err(err).Msg(msg)
-	l.activateSink(msg, zerolog.PanicLevel, timeutil.NowUTC())
+	l.activateSink(msg, zerolog.PanicLevel, timeutil.NowUTC(), err)
 }
 
 func (l *Logger) Panicf(err error, format string, v ...interface{}) {
 	l.zl.Panic().Err(err).Msgf(format, v...)
-	l.activateSinkFormatted(format, zerolog.PanicLevel, timeutil.NowUTC(), v...)
+	l.activateSinkFormatted(format, zerolog.PanicLevel, timeutil.NowUTC(), err, v...)
 }
 
 func (l *Logger) Log(level zerolog.Level, msg string) {
 	l.zl.WithLevel(level).Msg(msg)
-	l.activateSink(msg, level, timeutil.NowUTC())
+	l.activateSink(msg, level, timeutil.NowUTC(), nil)
 }
 
 func (l *Logger) Logf(level zerolog.Level, format string, v ...interface{}) {
 	l.zl.WithLevel(level).Msgf(format, v...)
-	l.activateSinkFormatted(format, level, timeutil.NowUTC(), v...)
+	l.activateSinkFormatted(format, level, timeutil.NowUTC(), nil, v...)
 }
@@ -0,0 +1,170 @@
+package logger
+
+import (
+	"context"
+	"pkg-common/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Drop policies of a BufferedSink whose queue is full.
+const (
+	DropNewest = "drop_newest"
+	DropOldest = "drop_oldest"
+)
+
+const (
+	DefaultSinkQueueSize     = 1024
+	DefaultSinkBatchSize     = 64
+	DefaultSinkFlushInterval = time.Second
+)
+
+// Reasons entries are dropped, as counted by the log_sink_dropped_total metric.
+const (
+	dropQueueFull   = "queue_full"
+	dropFlushFailed = "flush_failed"
+	dropClosed      = "closed"
+)
+
+// BatchWriter delivers a batch of entries. The batch is dropped when it
+// fails, and must not be retained after it returns.
+type BatchWriter func(batch []Entry) error
+
+// BufferedSink hands entries to a BatchWriter in the background, so a slow or
+// broken destination never holds up the code that logs. Entries it cannot
+// keep up with are dropped and counted.
+type BufferedSink struct {
+	name   string
+	config LogSinkConfig
+	write  BatchWriter
+
+	queue   chan Entry
+	closing chan struct{}
+	done    chan struct{}
+	closed  atomic.Bool
+	once    sync.Once
+	dropped atomic.Uint64
+
+	// failures reports write errors. It has no sinks of its own, so a failing
+	// destination cannot feed its errors back into itself.
+	failures *Logger
+}
+
+func NewBufferedSink(name string, write BatchWriter, config LogSinkConfig) *BufferedSink {
+	config = config.withDefaults()
+	bs := &BufferedSink{
+		name:     name,
+		config:   config,
+		write:    write,
+		queue:    make(chan Entry, config.QueueSize),
+		closing:  make(chan struct{}),
+		done:     make(chan struct{}),
+		failures: New(),
+	}
+	go bs.run()
+	return bs
+}
+
+// Sink is the function to register with AddSinkToLoggerInstance. It never
+// blocks.
+func (bs *BufferedSink) Sink() Sink {
+	return FilterLevel(bs.config.MinLevel, bs.enqueue)
+}
+
+// Dropped is the number of entries that were not delivered.
+func (bs *BufferedSink) Dropped() uint64 {
+	return bs.dropped.Load()
+}
+
+// Close flushes the queued entries and stops the sink. Entries logged
+// afterwards are dropped.
+func (bs *BufferedSink) Close(ctx context.Context) error {
+	bs.once.Do(func() {
+		bs.closed.Store(true)
+		close(bs.closing)
+	})
+
+	select {
+	case <-bs.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (bs *BufferedSink) enqueue(entry Entry) {
+	if bs.closed.Load() {
+		bs.drop(dropClosed, 1)
+		return
+	}
+
+	for {
+		select {
+		case bs.queue <- entry:
+			return
+		default:
+		}
+
+		if bs.config.DropPolicy != DropOldest {
+			bs.drop(dropQueueFull, 1)
+			return
+		}
+		select {
+		case <-bs.queue:
+			bs.drop(dropQueueFull, 1)
+		default:
+		}
+	}
+}
+
+func (bs *BufferedSink) run() {
+	defer close(bs.done)
+
+	ticker := time.NewTicker(bs.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Entry, 0, bs.config.BatchSize)
+	for {
+		select {
+		case entry := <-bs.queue:
+			batch = bs.add(batch, entry)
+		case <-ticker.C:
+			batch = bs.flush(batch)
+		case <-bs.closing:
+			for {
+				select {
+				case entry := <-bs.queue:
+					batch = bs.add(batch, entry)
+				default:
+					bs.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (bs *BufferedSink) add(batch []Entry, entry Entry) []Entry {
+	batch = append(batch, entry)
+	if len(batch) >= bs.config.BatchSize {
+		return bs.flush(batch)
+	}
+	return batch
+}
+
+func (bs *BufferedSink) flush(batch []Entry) []Entry {
+	if len(batch) == 0 {
+		return batch
+	}
+	if err := bs.write(batch); err != nil {
+		bs.drop(dropFlushFailed, len(batch))
+		bs.failures.Errorf(err, "Log sink %s dropped %d entries", bs.name, len(batch))
+	}
+	return batch[:0]
+}
+
+func (bs *BufferedSink) drop(reason string, entries int) {
+	bs.dropped.Add(uint64(entries))
+	metrics.AddLogSinkDropped(bs.name, reason, entries)
+}
@@ -1,17 +1,84 @@
 package logger
 
-import "github.com/rs/zerolog"
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
 
 type LoggerConfigJson struct {
-	logLevel int8 `json:"log_level"`
+	LogLevel int8              `json:"log_level"`
+	Sink     LogSinkConfigJson `json:"sink"`
 }
 
 type LoggerConfig struct {
 	LogLevel zerolog.Level
+	Sink     LogSinkConfig
 }
 
 func (lcj LoggerConfigJson) MapToDomain() LoggerConfig {
 	return LoggerConfig{
-		LogLevel: zerolog.Level(lcj.logLevel),
+		LogLevel: zerolog.Level(lcj.LogLevel),
+		Sink:     lcj.Sink.MapToDomain(),
+	}
+}
+
+// Validate is hand-rolled: the config helpers of utilities log through this
+// package.
+func (lc LoggerConfig) Validate() error {
+	var errs []error
+	if lc.LogLevel < zerolog.TraceLevel || lc.LogLevel > zerolog.Disabled {
+		errs = append(errs, fmt.Errorf("logger.log_level is invalid: %d is not a zerolog level", lc.LogLevel))
+	}
+	if lc.Sink.DropPolicy != DropNewest && lc.Sink.DropPolicy != DropOldest {
+		errs = append(errs, fmt.Errorf("logger.sink.drop_policy is invalid: must be %q or %q", DropNewest, DropOldest))
+	}
+	return errors.Join(errs...)
+}
+
+type LogSinkConfigJson struct {
+	MinLevel        int8   `json:"min_level"`
+	QueueSize       int    `json:"queue_size"`
+	BatchSize       int    `json:"batch_size"`
+	FlushIntervalMs int    `json:"flush_interval_ms"`
+	DropPolicy      string `json:"drop_policy"`
+}
+
+type LogSinkConfig struct {
+	// Entries below MinLevel are not handed to the sink
+	MinLevel zerolog.Level
+	// Entries waiting to be flushed; when full, DropPolicy decides which go
+	QueueSize int
+	// A batch is flushed when it has BatchSize entries or FlushInterval passed
+	BatchSize     int
+	FlushInterval time.Duration
+	DropPolicy    string
+}
+
+func (lscj LogSinkConfigJson) MapToDomain() LogSinkConfig {
+	return LogSinkConfig{
+		MinLevel:      zerolog.Level(lscj.MinLevel),
+		QueueSize:     lscj.QueueSize,
+		BatchSize:     lscj.BatchSize,
+		FlushInterval: time.Duration(lscj.FlushIntervalMs) * time.Millisecond,
+		DropPolicy:    lscj.DropPolicy,
+	}.withDefaults()
+}
+
+func (c LogSinkConfig) withDefaults() LogSinkConfig {
+	if c.QueueSize <= 0 {
+		c.QueueSize = DefaultSinkQueueSize
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = DefaultSinkBatchSize
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = DefaultSinkFlushInterval
+	}
+	if c.DropPolicy == "" {
+		c.DropPolicy = DropNewest
 	}
+	return c
 }
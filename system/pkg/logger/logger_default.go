@@ -8,7 +8,9 @@ type LoggerArg struct {
 }
 
 type GlobalLoggerConfig struct {
-	Args []LoggerArg
+	// Service is stamped on every entry of the default logger
+	Service string
+	Args    []LoggerArg
 }
 
 var (
@@ -20,8 +22,11 @@ var (
 func InitDefaultLogger(config GlobalLoggerConfig) {
 	onceLogger.Do(func() {
 		defaultLogger = New()
+		if config.Service != "" {
+			defaultLogger = defaultLogger.With(ServiceKey, config.Service)
+		}
 		for _, arg := range config.Args {
-			defaultLogger.With().Str(arg.Key, arg.Value)
+			defaultLogger = defaultLogger.With(arg.Key, arg.Value)
 		}
 
 		initializedLogger = true
@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"context"
+	"slices"
+)
+
+// Keys of the fields the services stamp on their entries.
+const (
+	ServiceKey    = "service"
+	RequestIdKey  = "request_id"
+	TraceIdKey    = "trace_id"
+	SpanIdKey     = "span_id"
+	IdentityIdKey = "identity_id"
+	// CorrelationIdKey ties together the entries of one flow across services
+	CorrelationIdKey = "correlation_id"
+	ErrorKey         = "error"
+)
+
+type Field struct {
+	Key   string
+	Value any
+}
+
+type fieldsKey struct{}
+
+// ContextWithField adds a field to ctx that every logger obtained through
+// WithContext(ctx) puts on its entries.
+func ContextWithField(ctx context.Context, key string, value any) context.Context {
+	fields := append(slices.Clip(FieldsFromContext(ctx)), Field{Key: key, Value: value})
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+func FieldsFromContext(ctx context.Context) []Field {
+	fields, _ := ctx.Value(fieldsKey{}).([]Field)
+	return fields
+}
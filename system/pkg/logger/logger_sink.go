@@ -3,21 +3,90 @@ package logger
 import (
 	"fmt"
 	"pkg-common/utilities/timeutil"
+	"slices"
+	"sync"
+	"sync/atomic"
 
 	"github.com/rs/zerolog"
 )
 
-func AddSinkToLoggerInstance(loggerInstance *Logger, sinkFunction func(string, zerolog.Level, timeutil.TimeUTC)) {
-	loggerInstance.sink = sinkFunction
+// Entry is a log line as handed to a sink.
+type Entry struct {
+	Level     zerolog.Level
+	Message   string
+	Timestamp timeutil.TimeUTC
+	Fields    map[string]any
 }
 
-func (l *Logger) activateSinkFormatted(format string, level zerolog.Level, timestamp timeutil.TimeUTC, v ...interface{}) {
+// Sink receives the entries of a logger. It runs on the goroutine that logs,
+// so anything slower than a channel send belongs behind a BufferedSink.
+type Sink func(Entry)
+
+// FilterLevel hands sink only the entries at minLevel or above.
+func FilterLevel(minLevel zerolog.Level, sink Sink) Sink {
+	return func(entry Entry) {
+		if entry.Level >= minLevel {
+			sink(entry)
+		}
+	}
+}
+
+type sinkSlot struct {
+	mu    sync.Mutex
+	sinks atomic.Pointer[[]Sink]
+}
+
+func (s *sinkSlot) add(sink Sink) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var sinks []Sink
+	if current := s.sinks.Load(); current != nil {
+		sinks = slices.Clone(*current)
+	}
+	sinks = append(sinks, sink)
+	s.sinks.Store(&sinks)
+}
+
+func (s *sinkSlot) load() []Sink {
+	if s == nil {
+		return nil
+	}
+	if sinks := s.sinks.Load(); sinks != nil {
+		return *sinks
+	}
+	return nil
+}
+
+// AddSinkToLoggerInstance forwards every entry of loggerInstance, and of the
+// loggers derived from it, to sinkFunction in addition to the sinks it has.
+func AddSinkToLoggerInstance(loggerInstance *Logger, sinkFunction Sink) {
+	loggerInstance.sink.add(sinkFunction)
+}
+
+func (l *Logger) activateSinkFormatted(format string, level zerolog.Level, timestamp timeutil.TimeUTC, err error, v ...interface{}) {
+	if len(l.sink.load()) == 0 {
+		return
+	}
 	msg := fmt.Sprintf(format, v...)
-	l.activateSink(msg, level, timestamp)
+	l.activateSink(msg, level, timestamp, err)
 }
 
-func (l *Logger) activateSink(msg string, level zerolog.Level, timestamp timeutil.TimeUTC) {
-	if l.sink != nil {
-		l.sink(msg, level, timestamp)
+func (l *Logger) activateSink(msg string, level zerolog.Level, timestamp timeutil.TimeUTC, err error) {
+	sinks := l.sink.load()
+	if len(sinks) == 0 {
+		return
+	}
+
+	fields := make(map[string]any, len(l.fields)+1)
+	for _, field := range l.fields {
+		fields[field.Key] = field.Value
+	}
+	if err != nil {
+		fields[ErrorKey] = err.Error()
+	}
+
+	entry := Entry{Level: level, Message: msg, Timestamp: timestamp, Fields: fields}
+	for _, sink := range sinks {
+		sink(entry)
 	}
 }
@@ -0,0 +1,220 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "digital_identity"
+
+// Circuit operations observed by CircuitOperationDuration.
+const (
+	CircuitCompile = "compile"
+	CircuitSetup   = "setup"
+	CircuitProve   = "prove"
+	CircuitVerify  = "verify"
+)
+
+// Presentation states counted by PresentationStates.
+const (
+	PresentationCreated  = "created"
+	PresentationVerified = "verified"
+	PresentationFailed   = "failed"
+	PresentationExpired  = "expired"
+)
+
+// Registry holds every collector of this package. It is separate from the
+// prometheus default registry so only what we define here is exposed.
+var Registry = prometheus.NewRegistry()
+
+var (
+	HttpRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency per route.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"method", "route", "status"},
+	)
+
+	PresentationStates = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "presentation",
+			Name:      "state_total",
+			Help:      "Presentation requests reaching a given state.",
+		},
+		[]string{"state"},
+	)
+
+	CircuitOperationDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "circuit",
+			Name:      "operation_duration_seconds",
+			Help:      "Duration of circuit compile/setup/prove/verify.",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{"operation"},
+	)
+
+	RabbitmqMessages = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "rabbitmq",
+			Name:      "messages_total",
+			Help:      "RabbitMQ messages consumed/published, by outcome.",
+		},
+		[]string{"direction", "target", "outcome"},
+	)
+
+	RabbitmqSettled = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "rabbitmq",
+			Name:      "deliveries_settled_total",
+			Help:      "Consumed deliveries by how they were settled (ack, retry, dead_letter, requeue).",
+		},
+		[]string{"queue", "action"},
+	)
+
+	RabbitmqPublishConfirms = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "rabbitmq",
+			Name:      "publish_confirms_total",
+			Help:      "Broker answers to publishes, by result (ack, nack, returned).",
+		},
+		[]string{"exchange", "result"},
+	)
+
+	RabbitmqInFlight = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "rabbitmq",
+			Name:      "deliveries_in_flight",
+			Help:      "Deliveries handed to a consumer's workers and not yet settled.",
+		},
+		[]string{"queue"},
+	)
+
+	LogSinkDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "log_sink",
+			Name:      "dropped_total",
+			Help:      "Log entries a sink gave up on, by reason (queue_full, flush_failed, closed).",
+		},
+		[]string{"sink", "reason"},
+	)
+
+	OutboxBacklog = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "outbox",
+			Name:      "backlog_size",
+			Help:      "Outbox events waiting to be published.",
+		},
+	)
+
+	OutboxDeadLettered = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "outbox",
+			Name:      "dead_lettered_total",
+			Help:      "Outbox events given up on after the maximum number of retries.",
+		},
+	)
+
+	SolanaTransactionDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "solana",
+			Name:      "transaction_duration_seconds",
+			Help:      "Latency of Solana transactions from blockhash to send.",
+			Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{"operation"},
+	)
+
+	SolanaTransactionErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "solana",
+			Name:      "transaction_errors_total",
+			Help:      "Failed Solana transactions.",
+		},
+		[]string{"operation"},
+	)
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HttpRequestDuration,
+		PresentationStates,
+		CircuitOperationDuration,
+		RabbitmqMessages,
+		RabbitmqSettled,
+		RabbitmqPublishConfirms,
+		RabbitmqInFlight,
+		LogSinkDropped,
+		OutboxBacklog,
+		OutboxDeadLettered,
+		SolanaTransactionDuration,
+		SolanaTransactionErrors,
+	)
+}
+
+func ObserveCircuitOperation(operation string, start time.Time) {
+	CircuitOperationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+func IncPresentationState(state string) {
+	PresentationStates.WithLabelValues(state).Inc()
+}
+
+// ObserveSolanaTransaction records latency of a Solana transaction and counts it
+// as an error when err is not nil.
+func ObserveSolanaTransaction(operation string, start time.Time, err error) {
+	SolanaTransactionDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		SolanaTransactionErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+func IncRabbitmqConsumed(queue string, err error) {
+	RabbitmqMessages.WithLabelValues("consume", queue, outcome(err)).Inc()
+}
+
+func IncRabbitmqSettled(queue, action string) {
+	RabbitmqSettled.WithLabelValues(queue, action).Inc()
+}
+
+func IncRabbitmqPublished(exchange string, err error) {
+	RabbitmqMessages.WithLabelValues("publish", exchange, outcome(err)).Inc()
+}
+
+func IncRabbitmqPublishConfirm(exchange, result string) {
+	RabbitmqPublishConfirms.WithLabelValues(exchange, result).Inc()
+}
+
+func AddRabbitmqInFlight(queue string, delta float64) {
+	RabbitmqInFlight.WithLabelValues(queue).Add(delta)
+}
+
+func AddLogSinkDropped(sink, reason string, entries int) {
+	LogSinkDropped.WithLabelValues(sink, reason).Add(float64(entries))
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
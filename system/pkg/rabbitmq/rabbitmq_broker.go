@@ -0,0 +1,38 @@
+package rabbitmq
+
+const (
+	// DriverAmqp connects to a RabbitMQ server (the default).
+	DriverAmqp = "amqp"
+	// DriverMemory uses the process-wide MemoryBroker, for running services
+	// and tests without RabbitMQ.
+	DriverMemory = "memory"
+)
+
+// Broker creates the publishers and consumers of the registries: a
+// ConnectionManager for RabbitMQ or a MemoryBroker.
+type Broker interface {
+	NewPublisher(config RabbitmqPublishersConfig) (IRabbitmqPublisher, error)
+	NewConsumer(config RabbitmqConsumerConfig) (IRabbitmqConsumer, error)
+	DeclareTopology(topology RabbitmqTopologyConfig) error
+	VerifyTopology(topology RabbitmqTopologyConfig) error
+	Close() error
+}
+
+func (m *ConnectionManager) NewPublisher(config RabbitmqPublishersConfig) (IRabbitmqPublisher, error) {
+	channel, err := m.Channel()
+	if err != nil {
+		return nil, err
+	}
+	return NewPublisher(channel, config.Exchange, config.RoutingKey).WithConnection(m), nil
+}
+
+func (m *ConnectionManager) NewConsumer(config RabbitmqConsumerConfig) (IRabbitmqConsumer, error) {
+	channel, err := m.Channel()
+	if err != nil {
+		return nil, err
+	}
+	return NewConsumer(channel, config.QueueName, config.ConsumerTag).
+		WithRetryPolicy(config.RetryPolicy).
+		WithConcurrency(config.Concurrency, config.PrefetchCount, config.OrderedByKey).
+		WithConnection(m), nil
+}
@@ -1,25 +1,74 @@
 package rabbitmq
 
-import "pkg-common/utilities"
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"pkg-common/utilities"
+	"strconv"
+	"time"
+)
 
 type RabbimqConfigJson struct {
-	User             string                         `json:"user"`
-	Password         string                         `json:"password"`
-	PublishersConfig []RabbitmqPublishersConfigJson `json:"publishers"`
-	ConsumersConfig  []RabbitmqConsumerConfigJson   `json:"consumers"`
+	Driver               string                         `json:"driver"`
+	DefinitionsFile      string                         `json:"definitions_file"`
+	Host                 string                         `json:"host"`
+	Port                 uint16                         `json:"port"`
+	Vhost                string                         `json:"vhost"`
+	User                 string                         `json:"user"`
+	Password             string                         `json:"password"`
+	Tls                  RabbitmqTlsConfigJson          `json:"tls"`
+	ReconnectBaseDelayMs int                            `json:"reconnect_base_delay_ms"`
+	ReconnectMaxDelayMs  int                            `json:"reconnect_max_delay_ms"`
+	PublishTimeoutMs     int                            `json:"publish_timeout_ms"`
+	Producer             string                         `json:"producer"`
+	Topology             RabbitmqTopologyConfigJson     `json:"topology"`
+	PublishersConfig     []RabbitmqPublishersConfigJson `json:"publishers"`
+	ConsumersConfig      []RabbitmqConsumerConfigJson   `json:"consumers"`
 }
 
 type RabbitmqConfig struct {
-	User             string
-	Password         string
+	// Driver is DriverAmqp or DriverMemory; DefinitionsFile is the RabbitMQ
+	// definitions export the memory broker declares its topology from.
+	Driver          string
+	DefinitionsFile string
+	Host            string
+	Port            uint16
+	Vhost           string
+	User            string
+	Password        string
+	Tls             RabbitmqTlsConfig
+	// Backoff between reconnection attempts after the broker connection is lost
+	ReconnectBaseDelay time.Duration
+	ReconnectMaxDelay  time.Duration
+	// How long a publish waits for a lost connection to come back before failing
+	PublishTimeout time.Duration
+	// Name of this service in the envelopes it publishes
+	Producer         string
+	Topology         RabbitmqTopologyConfig
 	PublishersConfig []RabbitmqPublishersConfig
 	ConsumersConfig  []RabbitmqConsumerConfig
 }
 
 func (rcj RabbimqConfigJson) MapToDomain() RabbitmqConfig {
-	return RabbitmqConfig{
-		User:     rcj.User,
-		Password: rcj.Password,
+	config := RabbitmqConfig{
+		Driver:             rcj.Driver,
+		DefinitionsFile:    rcj.DefinitionsFile,
+		Host:               rcj.Host,
+		Port:               rcj.Port,
+		Vhost:              rcj.Vhost,
+		User:               rcj.User,
+		Password:           rcj.Password,
+		Tls:                rcj.Tls.MapToDomain(),
+		ReconnectBaseDelay: time.Duration(rcj.ReconnectBaseDelayMs) * time.Millisecond,
+		ReconnectMaxDelay:  time.Duration(rcj.ReconnectMaxDelayMs) * time.Millisecond,
+		PublishTimeout:     time.Duration(rcj.PublishTimeoutMs) * time.Millisecond,
+		Producer:           rcj.Producer,
+		Topology:           rcj.Topology.MapToDomain(),
 		PublishersConfig: utilities.ConvertJsonArrayToDomain[
 			RabbitmqPublishersConfigJson,
 			RabbitmqPublishersConfig,
@@ -29,6 +78,147 @@ func (rcj RabbimqConfigJson) MapToDomain() RabbitmqConfig {
 			RabbitmqConsumerConfig,
 		](rcj.ConsumersConfig),
 	}
+	return config.withDefaults()
+}
+
+const (
+	DefaultHost               = "rabbitmq"
+	DefaultPort               = 5672
+	DefaultTlsPort            = 5671
+	DefaultVhost              = "/"
+	DefaultReconnectBaseDelay = time.Second
+	DefaultReconnectMaxDelay  = 30 * time.Second
+	DefaultPublishTimeout     = 5 * time.Second
+)
+
+func (rc RabbitmqConfig) withDefaults() RabbitmqConfig {
+	if rc.Driver == "" {
+		rc.Driver = DriverAmqp
+	}
+	if rc.Host == "" {
+		rc.Host = DefaultHost
+	}
+	if rc.Port == 0 {
+		rc.Port = DefaultPort
+		if rc.Tls.Enabled {
+			rc.Port = DefaultTlsPort
+		}
+	}
+	if rc.Vhost == "" {
+		rc.Vhost = DefaultVhost
+	}
+	if rc.ReconnectBaseDelay <= 0 {
+		rc.ReconnectBaseDelay = DefaultReconnectBaseDelay
+	}
+	if rc.ReconnectMaxDelay <= 0 {
+		rc.ReconnectMaxDelay = DefaultReconnectMaxDelay
+	}
+	if rc.PublishTimeout <= 0 {
+		rc.PublishTimeout = DefaultPublishTimeout
+	}
+	if rc.Producer == "" {
+		rc.Producer = defaultProducer()
+	}
+	return rc
+}
+
+// Validate reports every setting the broker cannot be used with.
+func (rc RabbitmqConfig) Validate() error {
+	var cv utilities.ConfigValidation
+	cv.Check("rabbitmq.driver", rc.Driver == DriverAmqp || rc.Driver == DriverMemory,
+		fmt.Sprintf("must be %q or %q", DriverAmqp, DriverMemory))
+	if rc.Driver == DriverAmqp {
+		cv.Require("rabbitmq.user", rc.User)
+		cv.Require("rabbitmq.password", rc.Password)
+		cv.Check("rabbitmq.tls", rc.Tls.Enabled || (rc.Tls.CertFile == "" && rc.Tls.KeyFile == ""),
+			"client certificate requires tls.enabled")
+		cv.Check("rabbitmq.tls", (rc.Tls.CertFile == "") == (rc.Tls.KeyFile == ""),
+			"cert_file and key_file must be set together")
+	}
+	for i, publisher := range rc.PublishersConfig {
+		cv.Require(fmt.Sprintf("rabbitmq.publishers.%d.publisher_alias", i), publisher.PublisherAlias)
+		cv.Require(fmt.Sprintf("rabbitmq.publishers.%d.exchange", i), publisher.Exchange)
+	}
+	for i, consumer := range rc.ConsumersConfig {
+		cv.Require(fmt.Sprintf("rabbitmq.consumers.%d.consumer_alias", i), consumer.ConsumerAlias)
+		cv.Require(fmt.Sprintf("rabbitmq.consumers.%d.queue_name", i), consumer.QueueName)
+	}
+	return cv.Err()
+}
+
+func defaultProducer() string {
+	return filepath.Base(os.Args[0])
+}
+
+// Url is the AMQP URI of the broker; the scheme is amqps when TLS is enabled.
+func (rc RabbitmqConfig) Url() string {
+	scheme := "amqp"
+	if rc.Tls.Enabled {
+		scheme = "amqps"
+	}
+	uri := url.URL{
+		Scheme: scheme,
+		User:   url.UserPassword(rc.User, rc.Password),
+		Host:   net.JoinHostPort(rc.Host, strconv.Itoa(int(rc.Port))),
+		Path:   "/" + rc.Vhost,
+		// The vhost is a single path segment, so "/" itself must be escaped
+		RawPath: "/" + url.PathEscape(rc.Vhost),
+	}
+	return uri.String()
+}
+
+type RabbitmqTlsConfigJson struct {
+	Enabled            bool   `json:"enabled"`
+	CaFile             string `json:"ca_file"`
+	CertFile           string `json:"cert_file"`
+	KeyFile            string `json:"key_file"`
+	ServerName         string `json:"server_name"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+}
+
+type RabbitmqTlsConfig struct {
+	Enabled            bool
+	CaFile             string
+	CertFile           string
+	KeyFile            string
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+func (rtcj RabbitmqTlsConfigJson) MapToDomain() RabbitmqTlsConfig {
+	return RabbitmqTlsConfig(rtcj)
+}
+
+// ClientConfig loads the CA bundle and client certificate referenced by the
+// configuration.
+func (rtc RabbitmqTlsConfig) ClientConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         rtc.ServerName,
+		InsecureSkipVerify: rtc.InsecureSkipVerify,
+	}
+
+	if rtc.CaFile != "" {
+		ca, err := os.ReadFile(rtc.CaFile)
+		if err != nil {
+			return nil, fmt.Errorf("read rabbitmq CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in rabbitmq CA file %s", rtc.CaFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if rtc.CertFile != "" || rtc.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(rtc.CertFile, rtc.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load rabbitmq client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
 }
 
 type RabbitmqPublishersConfigJson struct {
@@ -55,18 +245,59 @@ type RabbitmqConsumerConfigJson struct {
 	ConsumerAlias string `json:"consumer_alias"`
 	ConsumerTag   string `json:"consumer_tag"`
 	QueueName     string `json:"queue_name"`
+	// MaxRetries of 0 uses DefaultMaxRetries; a negative value disables retries
+	MaxRetries         int    `json:"max_retries"`
+	RetryDelayMs       int    `json:"retry_delay_ms"`
+	DeadLetterExchange string `json:"dead_letter_exchange"`
+	// Concurrency is the number of deliveries handled in parallel (default 1);
+	// PrefetchCount of 0 lets the broker send as many unacked deliveries as
+	// there are workers.
+	Concurrency   int  `json:"concurrency"`
+	PrefetchCount int  `json:"prefetch_count"`
+	OrderedByKey  bool `json:"ordered_by_key"`
 }
 
 type RabbitmqConsumerConfig struct {
 	ConsumerAlias ConsumerAlias
 	ConsumerTag   string
 	QueueName     string
+	RetryPolicy   RetryPolicy
+	Concurrency   int
+	PrefetchCount int
+	// OrderedByKey handles deliveries with the same OrderingKeyHeader, or
+	// without it the same correlation id, one at a time, in the order they
+	// were delivered.
+	OrderedByKey bool
 }
 
 func (rccj RabbitmqConsumerConfigJson) MapToDomain() RabbitmqConsumerConfig {
+	policy := DefaultRetryPolicy()
+	switch {
+	case rccj.MaxRetries < 0:
+		policy.MaxRetries = 0
+	case rccj.MaxRetries > 0:
+		policy.MaxRetries = rccj.MaxRetries
+	}
+	if rccj.RetryDelayMs > 0 {
+		policy.Delay = time.Duration(rccj.RetryDelayMs) * time.Millisecond
+	}
+	if rccj.DeadLetterExchange != "" {
+		policy.DeadLetterExchange = rccj.DeadLetterExchange
+	}
+
+	concurrency := max(rccj.Concurrency, DefaultConcurrency)
+	prefetch := rccj.PrefetchCount
+	if prefetch <= 0 {
+		prefetch = concurrency
+	}
+
 	return RabbitmqConsumerConfig{
 		ConsumerAlias: ConsumerAlias(rccj.ConsumerAlias),
 		QueueName:     rccj.QueueName,
 		ConsumerTag:   rccj.ConsumerTag,
+		RetryPolicy:   policy,
+		Concurrency:   concurrency,
+		PrefetchCount: prefetch,
+		OrderedByKey:  rccj.OrderedByKey,
 	}
 }
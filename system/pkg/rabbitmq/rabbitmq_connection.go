@@ -0,0 +1,225 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var (
+	ErrNotConnected     = errors.New("rabbitmq connection is not available")
+	ErrConnectionClosed = errors.New("rabbitmq connection manager is closed")
+)
+
+// startupAttempts bounds the initial connection; once the application is
+// running, lost connections are retried until Close is called.
+const startupAttempts = 7
+
+// ConnectionManager owns the broker connection. It watches NotifyClose,
+// reconnects with exponential backoff and lets publishers and consumers
+// re-create their channels through OnReconnect before it reports itself
+// connected again.
+type ConnectionManager struct {
+	config RabbitmqConfig
+
+	mu        sync.RWMutex
+	conn      *amqp.Connection
+	ready     chan struct{} // closed while a connection is up
+	listeners []func(*amqp.Connection)
+	closed    bool
+	done      chan struct{}
+}
+
+func NewConnectionManager(config RabbitmqConfig) *ConnectionManager {
+	return &ConnectionManager{
+		config: config.withDefaults(),
+		ready:  make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Connect establishes the first connection, giving up after startupAttempts.
+func (m *ConnectionManager) Connect() error {
+	var err error
+	delay := m.config.ReconnectBaseDelay
+	for attempt := 1; attempt <= startupAttempts; attempt++ {
+		var conn *amqp.Connection
+		if conn, err = m.dial(); err == nil {
+			m.connected(conn)
+			return nil
+		}
+		rabbitmqLogger.Warnf("Attempt %d failed: %v. Retrying in %v...", attempt, err, delay)
+		if !m.sleep(delay) {
+			return ErrConnectionClosed
+		}
+		delay = m.nextDelay(delay)
+	}
+	return err
+}
+
+func (m *ConnectionManager) dial() (*amqp.Connection, error) {
+	if !m.config.Tls.Enabled {
+		return amqp.Dial(m.config.Url())
+	}
+
+	tlsConfig, err := m.config.Tls.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	return amqp.DialTLS(m.config.Url(), tlsConfig)
+}
+
+// connected runs the reconnect listeners on conn and only then marks the
+// manager ready, so nobody picks up a channel of the previous connection.
+func (m *ConnectionManager) connected(conn *amqp.Connection) {
+	m.mu.RLock()
+	listeners := slices.Clone(m.listeners)
+	m.mu.RUnlock()
+
+	for _, listener := range listeners {
+		listener(conn)
+	}
+
+	m.mu.Lock()
+	m.conn = conn
+	close(m.ready)
+	m.mu.Unlock()
+
+	rabbitmqLogger.Infof("Connected to Rabbitmq at %s:%d", m.config.Host, m.config.Port)
+	go m.watch(conn)
+}
+
+func (m *ConnectionManager) watch(conn *amqp.Connection) {
+	closeErr, ok := <-conn.NotifyClose(make(chan *amqp.Error, 1))
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.conn = nil
+	m.ready = make(chan struct{})
+	m.mu.Unlock()
+
+	if ok && closeErr != nil {
+		rabbitmqLogger.Errorf(closeErr, "Connection to Rabbitmq lost, reconnecting...")
+	} else {
+		rabbitmqLogger.Warn("Connection to Rabbitmq closed, reconnecting...")
+	}
+	m.reconnect()
+}
+
+func (m *ConnectionManager) reconnect() {
+	delay := m.config.ReconnectBaseDelay
+	for attempt := 1; ; attempt++ {
+		if !m.sleep(delay) {
+			return
+		}
+		conn, err := m.dial()
+		if err == nil {
+			rabbitmqLogger.Infof("Reconnected to Rabbitmq after %d attempts", attempt)
+			m.connected(conn)
+			return
+		}
+		rabbitmqLogger.Warnf("Reconnect attempt %d failed: %v", attempt, err)
+		delay = m.nextDelay(delay)
+	}
+}
+
+// nextDelay doubles delay up to ReconnectMaxDelay, with jitter so that all
+// services do not hit a restarted broker at the same moment.
+func (m *ConnectionManager) nextDelay(delay time.Duration) time.Duration {
+	delay *= 2
+	if delay > m.config.ReconnectMaxDelay {
+		delay = m.config.ReconnectMaxDelay
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// sleep waits for d and reports false when the manager was closed meanwhile.
+func (m *ConnectionManager) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-m.done:
+		return false
+	}
+}
+
+// OnReconnect registers fn to run with every connection established after
+// the registration, before the manager reports itself connected.
+func (m *ConnectionManager) OnReconnect(fn func(*amqp.Connection)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners = append(m.listeners, fn)
+}
+
+func (m *ConnectionManager) IsConnected() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.conn != nil && !m.conn.IsClosed()
+}
+
+// Channel opens a channel on the current connection.
+func (m *ConnectionManager) Channel() (*amqp.Channel, error) {
+	m.mu.RLock()
+	conn := m.conn
+	m.mu.RUnlock()
+
+	if conn == nil {
+		return nil, ErrNotConnected
+	}
+	return conn.Channel()
+}
+
+// WaitConnected blocks until a connection is up, ctx is done or the manager
+// is closed.
+func (m *ConnectionManager) WaitConnected(ctx context.Context) error {
+	m.mu.RLock()
+	ready := m.ready
+	m.mu.RUnlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-m.done:
+		return ErrConnectionClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// PublishTimeout is how long publishers wait for a lost connection.
+func (m *ConnectionManager) PublishTimeout() time.Duration {
+	return m.config.PublishTimeout
+}
+
+// Producer names this service in published envelopes.
+func (m *ConnectionManager) Producer() string {
+	return m.config.Producer
+}
+
+// Close stops reconnecting and closes the current connection.
+func (m *ConnectionManager) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	close(m.done)
+	conn := m.conn
+	m.mu.Unlock()
+
+	if conn == nil || conn.IsClosed() {
+		return nil
+	}
+	return conn.Close()
+}
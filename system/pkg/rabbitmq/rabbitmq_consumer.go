@@ -1,66 +1,111 @@
 package rabbitmq
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"pkg-common/metrics"
+	"pkg-common/tracing"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type ConsumerAlias string
 
-var (
-	ConsumerRegistry    map[ConsumerAlias]IRabbitmqConsumer
-	onceConsumer        sync.Once
-	initializedConsumer bool
-)
-
-func GetConsumer(alias ConsumerAlias) IRabbitmqConsumer {
-	if !initializedConsumer {
-		panic("Consumer registry not initialized: call InitializeConsumerRegistry() first")
-	}
-	return ConsumerRegistry[alias]
-}
+var ErrConsumerStarted = errors.New("consumer is already started")
 
-func InitializeConsumerRegistry(conn *amqp.Connection, consumerConfig []RabbitmqConsumerConfig) {
-	onceConsumer.Do(func() {
-		ConsumerRegistry = make(map[ConsumerAlias]IRabbitmqConsumer)
-
-		for _, consumer := range consumerConfig {
-			channel, err := conn.Channel()
-			if err != nil {
-				rabbitmqLogger.Panicf(err, "Could not obtain connection for consumer")
-			}
-
-			ConsumerRegistry[consumer.ConsumerAlias] = NewConsumer(
-				channel,
-				consumer.QueueName,
-				consumer.ConsumerTag,
-			)
-		}
-
-		initializedConsumer = true
-	})
-}
+// MessageHandler processes one delivery and decides how it is settled.
+type MessageHandler func(context.Context, amqp.Delivery) Outcome
 
 type RabbitmqConsumer struct {
 	Channel     *amqp.Channel
 	QueueName   string
 	ConsumerTag string
+	RetryPolicy RetryPolicy
+
+	Concurrency   int
+	PrefetchCount int
+	OrderedByKey  bool
+
+	mu       sync.RWMutex
+	manager  *ConnectionManager
+	started  atomic.Bool
+	done     chan struct{}
+	sequence atomic.Uint64
 }
 
 type IRabbitmqConsumer interface {
-	StartConsuming(func(amqp.Delivery))
+	StartConsuming(ctx context.Context, messageHandler MessageHandler) error
+	Drain(ctx context.Context) error
 }
 
 func NewConsumer(ch *amqp.Channel, queueName, consumerTag string) *RabbitmqConsumer {
 	return &RabbitmqConsumer{
-		Channel:     ch,
-		QueueName:   queueName,
-		ConsumerTag: consumerTag,
+		Channel:       ch,
+		QueueName:     queueName,
+		ConsumerTag:   consumerTag,
+		RetryPolicy:   DefaultRetryPolicy(),
+		Concurrency:   DefaultConcurrency,
+		PrefetchCount: DefaultConcurrency,
+		done:          make(chan struct{}),
 	}
 }
 
-func (rc *RabbitmqConsumer) StartConsuming(messageHandler func(amqp.Delivery)) {
+func (rc *RabbitmqConsumer) WithRetryPolicy(policy RetryPolicy) *RabbitmqConsumer {
+	rc.RetryPolicy = policy
+	return rc
+}
+
+// WithConcurrency handles up to workers deliveries in parallel, with at most
+// prefetch unacknowledged deliveries held by the consumer. With orderedByKey,
+// deliveries sharing an OrderingKeyHeader, or a correlation id when it is
+// missing, are still handled one at a time in delivery order; a retried delivery goes back through the retry queue and
+// loses its position.
+func (rc *RabbitmqConsumer) WithConcurrency(workers, prefetch int, orderedByKey bool) *RabbitmqConsumer {
+	rc.Concurrency = max(workers, 1)
+	rc.PrefetchCount = prefetch
+	if rc.PrefetchCount <= 0 {
+		rc.PrefetchCount = rc.Concurrency
+	}
+	rc.OrderedByKey = orderedByKey
+	return rc
+}
+
+// WithConnection makes StartConsuming resume on a new channel after the
+// broker connection or the consumer's channel was lost.
+func (rc *RabbitmqConsumer) WithConnection(manager *ConnectionManager) *RabbitmqConsumer {
+	rc.manager = manager
+	return rc
+}
+
+func (rc *RabbitmqConsumer) currentChannel() *amqp.Channel {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.Channel
+}
+
+// StartConsuming hands every delivery to messageHandler together with a context
+// carrying a consumer span that continues the publisher's trace, and settles
+// it according to the returned Outcome. Deliveries are acknowledged manually,
+// so a message whose handler never finished is redelivered by the broker.
+// Up to Concurrency deliveries are handled at the same time, see WithConcurrency.
+// It blocks until ctx is cancelled, at which point the broker subscription is
+// cancelled and deliveries already received are still handled before it returns.
+// With a ConnectionManager, consumption resumes on a new channel whenever the
+// subscription is lost.
+func (rc *RabbitmqConsumer) StartConsuming(ctx context.Context, messageHandler MessageHandler) error {
+	if !rc.started.CompareAndSwap(false, true) {
+		return ErrConsumerStarted
+	}
+	defer close(rc.done)
+
 	defer func() {
 		if r := recover(); r != nil {
 			rabbitmqLogger.Errorf(
@@ -73,29 +118,310 @@ func (rc *RabbitmqConsumer) StartConsuming(messageHandler func(amqp.Delivery)) {
 		}
 	}()
 
-	msgs, err := rc.Channel.Consume(
+	stopCancel := context.AfterFunc(ctx, func() {
+		rabbitmqLogger.Infof("[%s] Cancelling consumer %s", rc.QueueName, rc.ConsumerTag)
+		if err := rc.currentChannel().Cancel(rc.ConsumerTag, false); err != nil {
+			rabbitmqLogger.Errorf(err, "[%s] Failed to cancel consumer %s", rc.QueueName, rc.ConsumerTag)
+		}
+	})
+	defer stopCancel()
+
+	backoff := DefaultReconnectBaseDelay
+	for {
+		err := rc.consume(ctx, messageHandler)
+		if ctx.Err() != nil {
+			break
+		}
+		if rc.manager == nil {
+			return err
+		}
+
+		if err != nil {
+			rabbitmqLogger.Errorf(err, "[%s] Could not resume consumer %s, retrying in %v", rc.QueueName, rc.ConsumerTag, backoff)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+			}
+			backoff = min(backoff*2, DefaultReconnectMaxDelay)
+		} else {
+			rabbitmqLogger.Warnf("[%s] Subscription of consumer %s lost, resuming", rc.QueueName, rc.ConsumerTag)
+			backoff = DefaultReconnectBaseDelay
+		}
+
+		if err := rc.manager.WaitConnected(ctx); err != nil {
+			break
+		}
+		if err := rc.reopenChannel(); err != nil {
+			rabbitmqLogger.Errorf(err, "[%s] Could not reopen channel for consumer %s", rc.QueueName, rc.ConsumerTag)
+		}
+	}
+
+	rabbitmqLogger.Infof("[%s] Consumer %s stopped", rc.QueueName, rc.ConsumerTag)
+	return nil
+}
+
+// consume subscribes on the current channel and handles deliveries until the
+// subscription ends, either through cancellation or because the channel closed.
+func (rc *RabbitmqConsumer) consume(ctx context.Context, messageHandler MessageHandler) error {
+	ch := rc.currentChannel()
+	if err := rc.declareRetryTopology(ch); err != nil {
+		rabbitmqLogger.Error(err, "Failed to declare retry and dead-letter queues")
+		return err
+	}
+	if err := ch.Qos(rc.PrefetchCount, 0, false); err != nil {
+		rabbitmqLogger.Error(err, "Failed to set prefetch count")
+		return err
+	}
+	// Retried and dead-lettered copies are confirmed before the original is acked
+	if err := ch.Confirm(false); err != nil {
+		rabbitmqLogger.Error(err, "Failed to enable publisher confirms")
+		return err
+	}
+	returns := NewReturnTracker(rc.RetryPolicy.DeadLetterExchange, ch.NotifyReturn(make(chan amqp.Return, returnBuffer)))
+
+	msgs, err := ch.Consume(
 		rc.QueueName,   // queue
 		rc.ConsumerTag, // consumer
-		true,           // auto-ack
+		false,          // auto-ack
 		false,          // exclusive
 		false,          // no-local
 		false,          // no-wait
 		nil,            // args
 	)
-	rabbitmqLogger.Error(err, "Failed to register a consumer")
+	if err != nil {
+		metrics.IncRabbitmqConsumed(rc.QueueName, err)
+		rabbitmqLogger.Error(err, "Failed to register a consumer")
+		return err
+	}
+
+	// The cancellation may have fired before this subscription existed
+	if ctx.Err() != nil {
+		_ = ch.Cancel(rc.ConsumerTag, false)
+	}
+
+	workers := newDispatcher(rc.QueueName, rc.Concurrency, rc.OrderedByKey, func(d amqp.Delivery) {
+		rc.handleDelivery(ch, returns, d, messageHandler)
+	})
+	defer workers.close()
+
+	rabbitmqLogger.Infof("Waiting for messages in queue: %s (%d workers, prefetch %d)", rc.QueueName, rc.Concurrency, rc.PrefetchCount)
+	for d := range msgs {
+		rabbitmqLogger.Infof("[%s] %s", rc.QueueName, d.Body)
+		metrics.IncRabbitmqConsumed(rc.QueueName, nil)
+		workers.dispatch(d)
+	}
+	return nil
+}
+
+func (rc *RabbitmqConsumer) reopenChannel() error {
+	if ch := rc.currentChannel(); ch != nil && !ch.IsClosed() {
+		// Only the subscription was cancelled by the broker (e.g. queue deleted)
+		return nil
+	}
+
+	ch, err := rc.manager.Channel()
+	if err != nil {
+		return err
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.Channel = ch
+	return nil
+}
+
+// Drain waits until StartConsuming has handled its last delivery and returned.
+// A consumer that was never started has nothing to drain.
+func (rc *RabbitmqConsumer) Drain(ctx context.Context) error {
+	if !rc.started.Load() {
+		return nil
+	}
+
+	select {
+	case <-rc.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("consumer %s on %s did not drain: %w", rc.ConsumerTag, rc.QueueName, ctx.Err())
+	}
+}
+
+func (rc *RabbitmqConsumer) handleDelivery(ch *amqp.Channel, returns *ReturnTracker, d amqp.Delivery, messageHandler MessageHandler) {
+	processDelivery(rc.QueueName, rc.RetryPolicy, d, messageHandler, func(ctx context.Context, action Action, cause error) {
+		rc.settle(ctx, ch, returns, d, action, cause)
+	})
+}
+
+// processDelivery runs messageHandler within a consumer span continuing the
+// publisher's trace and passes the resolved action to settle.
+func processDelivery(queue string, policy RetryPolicy, d amqp.Delivery, messageHandler MessageHandler, settle func(context.Context, Action, error)) {
+	ctx := tracing.ExtractAmqpHeaders(context.Background(), d.Headers)
+	ctx, span := tracing.Tracer().Start(ctx, queue+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "rabbitmq"),
+			attribute.String("messaging.destination.name", queue),
+			attribute.String("messaging.rabbitmq.destination.routing_key", d.RoutingKey),
+		),
+	)
+	defer span.End()
 
-	rabbitmqLogger.Infof("Waiting for messages in queue: %s", rc.QueueName)
-	var waitGroup sync.WaitGroup
+	outcome := runHandler(ctx, queue, d, messageHandler)
+	if outcome.Err != nil {
+		span.SetStatus(codes.Error, outcome.Err.Error())
+	}
 
-	waitGroup.Add(1)
+	action := policy.Resolve(outcome, d)
+	span.SetAttributes(attribute.String("messaging.rabbitmq.settle_action", action.String()))
+	settle(ctx, action, outcome.Err)
+}
 
-	go func() {
-		defer waitGroup.Done()
-		for d := range msgs {
-			rabbitmqLogger.Infof("[%s] %s", rc.QueueName, d.Body)
-			messageHandler(d)
+// runHandler turns a panicking handler into a retry instead of killing the
+// consumer loop with the delivery left unacknowledged.
+func runHandler(ctx context.Context, queue string, d amqp.Delivery, messageHandler MessageHandler) (outcome Outcome) {
+	defer func() {
+		if r := recover(); r != nil {
+			rabbitmqLogger.Errorf(nil, "[%s] Handler panicked: %v", queue, r)
+			outcome = RetryLater(fmt.Errorf("handler panicked: %v", r))
 		}
 	}()
+	return messageHandler(ctx, d)
+}
+
+func (rc *RabbitmqConsumer) settle(ctx context.Context, ch *amqp.Channel, returns *ReturnTracker, d amqp.Delivery, action Action, cause error) {
+	var err error
+	switch action {
+	case ActionRetry:
+		err = rc.republish(ctx, ch, returns, d, RetryQueueName(rc.QueueName), rc.RetryPolicy.Expiration(), RetryCount(d.Headers)+1, cause)
+	case ActionDeadLetter:
+		rabbitmqLogger.Warnf("[%s] Dead-lettering message %s after %d retries: %v",
+			rc.QueueName, d.MessageId, RetryCount(d.Headers), cause)
+		err = rc.republish(ctx, ch, returns, d, DeadLetterQueueName(rc.QueueName), "", RetryCount(d.Headers), cause)
+	}
+
+	if err != nil {
+		// The copy was not stored, so give the original back to the broker
+		rabbitmqLogger.Errorf(err, "[%s] Could not %s message, requeueing", rc.QueueName, action)
+		metrics.IncRabbitmqSettled(rc.QueueName, "requeue")
+		if err := d.Nack(false, true); err != nil {
+			rabbitmqLogger.Errorf(err, "[%s] Failed to nack message", rc.QueueName)
+		}
+		return
+	}
+
+	metrics.IncRabbitmqSettled(rc.QueueName, action.String())
+	if err := d.Ack(false); err != nil {
+		rabbitmqLogger.Errorf(err, "[%s] Failed to ack message", rc.QueueName)
+	}
+}
+
+// republish copies d to one of the consumer's queues behind the dead-letter
+// exchange, carrying the retry count and the failure reason in its headers.
+// A copy with an expiration leaves the queue once it expires. It returns only
+// once the broker confirmed the copy, so the original is never acked for a
+// copy that was lost or could not be routed.
+func (rc *RabbitmqConsumer) republish(ctx context.Context, ch *amqp.Channel, returns *ReturnTracker, d amqp.Delivery, queue, expiration string, retries int, cause error) error {
+	// Every property is kept, so the copy stays in its ordering lane and
+	// correlation; only UserId is dropped, as the broker rejects one that is
+	// not the user of the republishing connection.
+	publishing := amqp.Publishing{
+		Headers:         retryHeaders(d, retries, cause),
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		DeliveryMode:    amqp.Persistent,
+		Priority:        d.Priority,
+		CorrelationId:   d.CorrelationId,
+		ReplyTo:         d.ReplyTo,
+		Expiration:      expiration,
+		MessageId:       d.MessageId,
+		Timestamp:       d.Timestamp,
+		Type:            d.Type,
+		AppId:           d.AppId,
+		Body:            d.Body,
+	}
+	publishId := rc.QueueName + ":" + strconv.FormatUint(rc.sequence.Add(1), 10)
+	publishing.Headers[PublishIdHeader] = publishId
+
+	exchange := rc.RetryPolicy.DeadLetterExchange
+	returns.Expect(publishId)
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, queue, true, false, publishing)
+	if err != nil {
+		returns.Forget(publishId)
+		return err
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, rc.publishTimeout())
+	defer cancel()
+	acked, err := confirmation.WaitContext(waitCtx)
+	returned, wasReturned := returns.Take(publishId)
+
+	switch {
+	case err != nil:
+		return fmt.Errorf("waiting for publisher confirm: %w", err)
+	case !acked:
+		metrics.IncRabbitmqPublishConfirm(exchange, "nack")
+		return ErrPublishNacked
+	case wasReturned:
+		metrics.IncRabbitmqPublishConfirm(exchange, "returned")
+		return fmt.Errorf("%w: %d %s (routing key %s)", ErrUnroutable, returned.ReplyCode, returned.ReplyText, queue)
+	default:
+		metrics.IncRabbitmqPublishConfirm(exchange, "ack")
+		return nil
+	}
+}
+
+func (rc *RabbitmqConsumer) publishTimeout() time.Duration {
+	if rc.manager == nil {
+		return DefaultPublishTimeout
+	}
+	return rc.manager.PublishTimeout()
+}
+
+// retryHeaders copies the headers of d with the retry count and the failure
+// reason of the last attempt.
+func retryHeaders(d amqp.Delivery, retries int, cause error) amqp.Table {
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[RetryCountHeader] = int32(retries)
+	if cause != nil {
+		headers[FailureReasonHeader] = cause.Error()
+	}
+	return headers
+}
+
+// declareRetryTopology declares the dead-letter exchange with two queues per
+// consumer queue: <queue>.retry dead-letters its messages back to <queue>
+// through the default exchange once they expire, and <queue>.dlq keeps
+// messages that will not be retried. The retry delay is the expiration of
+// each retried message rather than a queue TTL, so consumers of one queue
+// may change RetryPolicy.Delay without redeclaring the retry queue.
+func (rc *RabbitmqConsumer) declareRetryTopology(ch *amqp.Channel) error {
+	exchange := rc.RetryPolicy.DeadLetterExchange
+	if err := ch.ExchangeDeclare(exchange, amqp.ExchangeDirect, true, false, false, false, nil); err != nil {
+		return err
+	}
+
+	queues := map[string]amqp.Table{
+		RetryQueueName(rc.QueueName): {
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": rc.QueueName,
+		},
+		DeadLetterQueueName(rc.QueueName): nil,
+	}
+	for queue, args := range queues {
+		if _, err := ch.QueueDeclare(queue, true, false, false, false, args); err != nil {
+			var amqpErr *amqp.Error
+			if errors.As(err, &amqpErr) && amqpErr.Code == amqp.PreconditionFailed {
+				// e.g. a retry queue declared with the x-message-ttl of earlier versions
+				return fmt.Errorf("queue %s exists with other arguments, delete it so it can be declared again: %w", queue, err)
+			}
+			return err
+		}
+		if err := ch.QueueBind(queue, queue, exchange, false, nil); err != nil {
+			return err
+		}
+	}
 
-	waitGroup.Wait()
+	return nil
 }
@@ -0,0 +1,112 @@
+package rabbitmq
+
+import (
+	"hash/fnv"
+	"pkg-common/metrics"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	// OrderingKeyHeader groups messages that consumers with OrderedByKey must
+	// handle one after the other, e.g. all requests of one identity.
+	OrderingKeyHeader = "x-ordering-key"
+
+	DefaultConcurrency = 1
+)
+
+// OrderedMessage is implemented by messages that must not be handled
+// concurrently with other messages of the same key; the key is published as
+// OrderingKeyHeader.
+type OrderedMessage interface {
+	OrderingKey() string
+}
+
+// dispatcher hands deliveries to a fixed pool of workers. With ordered set,
+// deliveries sharing a lane key always go to the same worker; deliveries
+// without one go to whichever worker is free. dispatch blocks while the
+// target worker is busy, which stops reading from the subscription, so the
+// broker holds back everything beyond the prefetch count.
+type dispatcher struct {
+	queue   string
+	ordered bool
+	lanes   []chan amqp.Delivery
+	shared  chan amqp.Delivery
+	wg      sync.WaitGroup
+}
+
+func newDispatcher(queue string, workers int, ordered bool, handle func(amqp.Delivery)) *dispatcher {
+	workers = max(workers, 1)
+	d := &dispatcher{
+		queue:   queue,
+		ordered: ordered,
+		lanes:   make([]chan amqp.Delivery, workers),
+		shared:  make(chan amqp.Delivery),
+	}
+
+	for i := range d.lanes {
+		d.lanes[i] = make(chan amqp.Delivery)
+		d.wg.Add(1)
+		go d.work(d.lanes[i], handle)
+	}
+	return d
+}
+
+func (d *dispatcher) work(lane chan amqp.Delivery, handle func(amqp.Delivery)) {
+	defer d.wg.Done()
+
+	shared := d.shared
+	for lane != nil || shared != nil {
+		var delivery amqp.Delivery
+		var ok bool
+		select {
+		case delivery, ok = <-lane:
+			if !ok {
+				lane = nil
+				continue
+			}
+		case delivery, ok = <-shared:
+			if !ok {
+				shared = nil
+				continue
+			}
+		}
+
+		handle(delivery)
+		metrics.AddRabbitmqInFlight(d.queue, -1)
+	}
+}
+
+func (d *dispatcher) dispatch(delivery amqp.Delivery) {
+	metrics.AddRabbitmqInFlight(d.queue, 1)
+
+	key := laneKey(delivery)
+	if !d.ordered || key == "" {
+		d.shared <- delivery
+		return
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	d.lanes[hash.Sum32()%uint32(len(d.lanes))] <- delivery
+}
+
+// laneKey is the OrderingKeyHeader, or the correlation id for deliveries from
+// publishers that do not set the header, such as an external verifier
+// answering a request: every message of one flow then stays in order.
+func laneKey(delivery amqp.Delivery) string {
+	if key, _ := delivery.Headers[OrderingKeyHeader].(string); key != "" {
+		return key
+	}
+	return delivery.CorrelationId
+}
+
+// close waits for the workers to finish the deliveries already dispatched.
+func (d *dispatcher) close() {
+	close(d.shared)
+	for _, lane := range d.lanes {
+		close(lane)
+	}
+	d.wg.Wait()
+}
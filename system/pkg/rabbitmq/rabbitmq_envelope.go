@@ -0,0 +1,127 @@
+package rabbitmq
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"pkg-common/utilities"
+	"time"
+)
+
+// EnvelopeSchemaVersion is the only envelope version publishers write and
+// consumers accept; a consumer dead-letters messages of any other version
+// instead of guessing at their layout.
+const EnvelopeSchemaVersion = 1
+
+var (
+	ErrUntypedMessage             = errors.New("message does not declare a message type")
+	ErrMalformedEnvelope          = errors.New("message is not a valid envelope")
+	ErrUnsupportedEnvelopeVersion = errors.New("unsupported envelope schema version")
+)
+
+// Envelope wraps every message on the broker. Type selects the consumer's
+// handler, Payload holds the message itself. CorrelationId is shared by all
+// messages of one flow (e.g. a verification request and its result) and
+// CausationId is the id of the message whose handling produced this one.
+type Envelope struct {
+	Type          string          `json:"type"`
+	SchemaVersion int             `json:"schema_version"`
+	MessageId     string          `json:"message_id"`
+	CorrelationId string          `json:"correlation_id"`
+	CausationId   string          `json:"causation_id,omitempty"`
+	ProducedAt    time.Time       `json:"produced_at"`
+	Producer      string          `json:"producer"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+// TypedMessage is implemented by every message that is published; the type
+// is how consumers route it.
+type TypedMessage interface {
+	MessageType() string
+}
+
+// CorrelatedMessage is implemented by messages that start a flow under an id
+// of their own, such as the outbox event id. Without it a new flow is
+// correlated by the message id.
+type CorrelatedMessage interface {
+	CorrelationId() string
+}
+
+// NewEnvelope wraps body for publishing. When ctx carries the envelope of the
+// delivery being handled, the new message joins its flow.
+func NewEnvelope(ctx context.Context, body utilities.Serializable, producer string) (Envelope, error) {
+	typed, ok := body.(TypedMessage)
+	if !ok || typed.MessageType() == "" {
+		return Envelope{}, fmt.Errorf("%w: %T", ErrUntypedMessage, body)
+	}
+
+	payload, err := body.Serialize()
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	envelope := Envelope{
+		Type:          typed.MessageType(),
+		SchemaVersion: EnvelopeSchemaVersion,
+		MessageId:     NewMessageId(),
+		ProducedAt:    time.Now().UTC(),
+		Producer:      producer,
+		Payload:       payload,
+	}
+	if msg, ok := body.(IdentifiableMessage); ok && msg.MessageId() != "" {
+		envelope.MessageId = msg.MessageId()
+	}
+
+	if parent, ok := EnvelopeFromContext(ctx); ok {
+		envelope.CorrelationId = parent.CorrelationId
+		envelope.CausationId = parent.MessageId
+	} else if msg, ok := body.(CorrelatedMessage); ok {
+		envelope.CorrelationId = msg.CorrelationId()
+	}
+	if envelope.CorrelationId == "" {
+		envelope.CorrelationId = envelope.MessageId
+	}
+
+	return envelope, nil
+}
+
+// OpenEnvelope parses a delivery body and checks its schema version.
+func OpenEnvelope(body []byte) (Envelope, error) {
+	var envelope Envelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return Envelope{}, fmt.Errorf("%w: %v", ErrMalformedEnvelope, err)
+	}
+	if envelope.Type == "" || len(envelope.Payload) == 0 {
+		return Envelope{}, fmt.Errorf("%w: type and payload are required", ErrMalformedEnvelope)
+	}
+	if envelope.SchemaVersion != EnvelopeSchemaVersion {
+		return Envelope{}, fmt.Errorf("%w: %d", ErrUnsupportedEnvelopeVersion, envelope.SchemaVersion)
+	}
+	return envelope, nil
+}
+
+func (e Envelope) Serialize() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+func NewMessageId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type envelopeKey struct{}
+
+// ContextWithEnvelope marks ctx as handling envelope, so messages published
+// with it are correlated to it.
+func ContextWithEnvelope(ctx context.Context, envelope Envelope) context.Context {
+	return context.WithValue(ctx, envelopeKey{}, envelope)
+}
+
+func EnvelopeFromContext(ctx context.Context) (Envelope, bool) {
+	envelope, ok := ctx.Value(envelopeKey{}).(Envelope)
+	return envelope, ok
+}
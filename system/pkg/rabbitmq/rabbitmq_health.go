@@ -0,0 +1,31 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"pkg-common/health"
+	"time"
+)
+
+const HealthCheckTimeout = time.Second
+
+// HealthCheck reports the broker unready while the connection is being
+// re-established or a consumer channel is closed and not yet recovered.
+// Publisher channels are not checked; they are reopened on the next publish.
+func HealthCheck(manager *ConnectionManager, registry *Registry) health.Check {
+	return health.NewCheck("rabbitmq", HealthCheckTimeout, func(ctx context.Context) error {
+		if manager == nil || !manager.IsConnected() {
+			return fmt.Errorf("connection is down")
+		}
+
+		if registry != nil {
+			for alias, consumer := range registry.consumers {
+				if rc, ok := consumer.(*RabbitmqConsumer); ok && rc.currentChannel().IsClosed() {
+					return fmt.Errorf("channel of consumer %s is closed", alias)
+				}
+			}
+		}
+
+		return ctx.Err()
+	})
+}
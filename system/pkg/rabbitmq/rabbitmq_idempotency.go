@@ -0,0 +1,102 @@
+package rabbitmq
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultIdempotencyWindow is how long a handled message is remembered;
+	// it has to outlast the redeliveries and retries of the same message.
+	DefaultIdempotencyWindow = 24 * time.Hour
+	// DefaultIdempotencyCapacity bounds the keys a memory store keeps; the
+	// oldest are forgotten first.
+	DefaultIdempotencyCapacity = 100_000
+)
+
+// ErrMessageInFlight is returned for a redelivery that arrives while another
+// delivery of the same message is still being handled.
+var ErrMessageInFlight = errors.New("message is being handled by another delivery")
+
+// IdempotencyKey picks the key a message is deduplicated on.
+type IdempotencyKey func(Message) string
+
+// ByMessageId deduplicates redeliveries of the same envelope.
+func ByMessageId(msg Message) string {
+	return msg.MessageId
+}
+
+// IdempotencyStore remembers which messages a consumer has handled, so the
+// at-least-once delivery of the broker does not repeat side effects.
+type IdempotencyStore interface {
+	// Claim reserves key for the calling delivery. It returns false when key
+	// was already handled and ErrMessageInFlight while another delivery
+	// holds it.
+	Claim(key string) (bool, error)
+	// Complete releases the claim. A handled key is remembered, any other
+	// is forgotten so a retry can claim it again.
+	Complete(key string, handled bool)
+}
+
+type memoryIdempotencyStore struct {
+	mu       sync.Mutex
+	window   time.Duration
+	capacity int
+	inFlight map[string]struct{}
+	handled  map[string]time.Time
+	order    []string
+}
+
+// NewMemoryIdempotencyStore keeps handled keys in memory for window, at most
+// capacity of them. It covers redeliveries to a running service, not the
+// ones after a restart.
+func NewMemoryIdempotencyStore(window time.Duration, capacity int) IdempotencyStore {
+	return &memoryIdempotencyStore{
+		window:   window,
+		capacity: max(capacity, 1),
+		inFlight: make(map[string]struct{}),
+		handled:  make(map[string]time.Time),
+	}
+}
+
+func (s *memoryIdempotencyStore) Claim(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire(time.Now())
+	if _, ok := s.handled[key]; ok {
+		return false, nil
+	}
+	if _, ok := s.inFlight[key]; ok {
+		return false, ErrMessageInFlight
+	}
+	s.inFlight[key] = struct{}{}
+	return true, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(key string, handled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.inFlight, key)
+	if !handled {
+		return
+	}
+	if _, ok := s.handled[key]; !ok {
+		s.order = append(s.order, key)
+	}
+	s.handled[key] = time.Now()
+	for len(s.order) > s.capacity {
+		delete(s.handled, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+// expire forgets the keys handled before the window; order is oldest first.
+func (s *memoryIdempotencyStore) expire(now time.Time) {
+	for len(s.order) > 0 && now.Sub(s.handled[s.order[0]]) > s.window {
+		delete(s.handled, s.order[0])
+		s.order = s.order[1:]
+	}
+}
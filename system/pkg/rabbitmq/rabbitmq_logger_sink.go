@@ -1,23 +1,35 @@
 package rabbitmq
 
 import (
+	"maps"
+	"pkg-common/logger"
 	logger_message "pkg-common/utilities/logger"
-	"pkg-common/utilities/timeutil"
-
-	"github.com/rs/zerolog"
 )
 
-func CreateRabbitmqLoggerSink(publisher IRabbitmqPublisher) func(string, zerolog.Level, timeutil.TimeUTC) {
-	return func(msg string, level zerolog.Level, timestamp timeutil.TimeUTC) {
-		loggerMessage := logger_message.LoggerMessage{
-			Level:     level.String(),
-			Message:   msg,
-			Timestamp: timestamp,
+// CreateRabbitmqLogWriter publishes each entry of a batch as a log message.
+// It gives up on the batch at the first failure, as the broker is most
+// likely unavailable for the rest of it too.
+func CreateRabbitmqLogWriter(publisher IRabbitmqPublisher) logger.BatchWriter {
+	return func(batch []logger.Entry) error {
+		for _, entry := range batch {
+			if err := publisher.Publish(newLoggerMessage(entry)); err != nil {
+				return err
+			}
 		}
+		return nil
+	}
+}
 
-		err := publisher.Publish(loggerMessage)
-		if err != nil {
-			rabbitmqLogger.Error(err, "Failed to publish log message to RabbitMQ")
-		}
+func newLoggerMessage(entry logger.Entry) logger_message.LoggerMessage {
+	service, _ := entry.Fields[logger.ServiceKey].(string)
+	fields := maps.Clone(entry.Fields)
+	delete(fields, logger.ServiceKey)
+
+	return logger_message.LoggerMessage{
+		Level:     entry.Level.String(),
+		Message:   entry.Message,
+		Timestamp: entry.Timestamp,
+		Service:   service,
+		Fields:    fields,
 	}
 }
@@ -0,0 +1,514 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"pkg-common/metrics"
+	"pkg-common/utilities"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var (
+	ErrExchangeNotFound = errors.New("exchange does not exist")
+	ErrQueueNotFound    = errors.New("queue does not exist")
+)
+
+// MemoryBroker is an in-process stand-in for RabbitMQ with direct, fanout and
+// topic exchanges, so services and tests run without a server. Messages are
+// lost with the process, and retries and dead-lettering are done by the
+// consumer instead of through the dead-letter exchange: a retried message
+// returns to its queue after RetryPolicy.Delay, a dead-lettered one is kept
+// aside and can be inspected with DeadLetters.
+type MemoryBroker struct {
+	mu        sync.RWMutex
+	exchanges map[string]*memoryExchange
+	queues    map[string]*memoryQueue
+	tags      atomic.Uint64
+}
+
+type memoryExchange struct {
+	kind     string
+	bindings []memoryBinding
+}
+
+type memoryBinding struct {
+	queue      string
+	routingKey string
+}
+
+var (
+	sharedMemoryBroker     *MemoryBroker
+	sharedMemoryBrokerOnce sync.Once
+)
+
+// SharedMemoryBroker is the broker of DriverMemory. Every service of the
+// process uses it, so messages flow between services run in one test binary.
+func SharedMemoryBroker() *MemoryBroker {
+	sharedMemoryBrokerOnce.Do(func() {
+		sharedMemoryBroker = NewMemoryBroker()
+	})
+	return sharedMemoryBroker
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		exchanges: make(map[string]*memoryExchange),
+		queues:    make(map[string]*memoryQueue),
+	}
+}
+
+// Connect returns the Broker through which one service publishes and
+// consumes, naming it producer in the envelopes it publishes.
+func (b *MemoryBroker) Connect(producer string) Broker {
+	return &memoryConnection{broker: b, producer: producer}
+}
+
+func (b *MemoryBroker) DeclareExchange(name, kind string) error {
+	switch kind {
+	case amqp.ExchangeDirect, amqp.ExchangeFanout, amqp.ExchangeTopic:
+	default:
+		return fmt.Errorf("unsupported exchange type %q for %s", kind, name)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if existing, ok := b.exchanges[name]; ok {
+		if existing.kind != kind {
+			return fmt.Errorf("exchange %s already declared as %s", name, existing.kind)
+		}
+		return nil
+	}
+	b.exchanges[name] = &memoryExchange{kind: kind}
+	return nil
+}
+
+func (b *MemoryBroker) DeclareQueue(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.queues[name]; !ok {
+		b.queues[name] = newMemoryQueue()
+	}
+}
+
+func (b *MemoryBroker) BindQueue(queue, exchange, routingKey string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ex, ok := b.exchanges[exchange]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrExchangeNotFound, exchange)
+	}
+	if _, ok := b.queues[queue]; !ok {
+		return fmt.Errorf("%w: %s", ErrQueueNotFound, queue)
+	}
+	for _, binding := range ex.bindings {
+		if binding.queue == queue && binding.routingKey == routingKey {
+			return nil
+		}
+	}
+	ex.bindings = append(ex.bindings, memoryBinding{queue: queue, routingKey: routingKey})
+	return nil
+}
+
+// hasBinding reports whether binding was declared; callers hold b.mu.
+func (b *MemoryBroker) hasBinding(binding RabbitmqBindingConfig) bool {
+	ex, ok := b.exchanges[binding.Exchange]
+	if !ok {
+		return false
+	}
+	for _, existing := range ex.bindings {
+		if existing.queue == binding.Queue && existing.routingKey == binding.RoutingKey {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadDefinitions declares the exchanges, queues and bindings of a RabbitMQ
+// definitions export, the same file the broker container loads.
+func (b *MemoryBroker) LoadDefinitions(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var definitions struct {
+		Exchanges []struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+		} `json:"exchanges"`
+		Queues []struct {
+			Name string `json:"name"`
+		} `json:"queues"`
+		Bindings []struct {
+			Source          string `json:"source"`
+			Destination     string `json:"destination"`
+			DestinationType string `json:"destination_type"`
+			RoutingKey      string `json:"routing_key"`
+		} `json:"bindings"`
+	}
+	if err := json.Unmarshal(data, &definitions); err != nil {
+		return fmt.Errorf("could not parse definitions %s: %w", path, err)
+	}
+
+	for _, exchange := range definitions.Exchanges {
+		if err := b.DeclareExchange(exchange.Name, exchange.Type); err != nil {
+			return err
+		}
+	}
+	for _, queue := range definitions.Queues {
+		b.DeclareQueue(queue.Name)
+	}
+	for _, binding := range definitions.Bindings {
+		if binding.DestinationType != "queue" {
+			continue
+		}
+		if err := b.BindQueue(binding.Destination, binding.Source, binding.RoutingKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// route delivers publishing to every queue bound to exchange for routingKey
+// and returns how many queues received it. The default exchange "" routes to
+// the queue named by the routing key.
+func (b *MemoryBroker) route(exchange, routingKey string, publishing amqp.Publishing) (int, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var queues []string
+	if exchange == "" {
+		if _, ok := b.queues[routingKey]; ok {
+			queues = append(queues, routingKey)
+		}
+	} else {
+		ex, ok := b.exchanges[exchange]
+		if !ok {
+			return 0, fmt.Errorf("%w: %s", ErrExchangeNotFound, exchange)
+		}
+		for _, binding := range ex.bindings {
+			if bindingMatches(ex.kind, binding.routingKey, routingKey) && !slices.Contains(queues, binding.queue) {
+				queues = append(queues, binding.queue)
+			}
+		}
+	}
+
+	for _, name := range queues {
+		b.queues[name].push(amqp.Delivery{
+			Headers:         maps.Clone(publishing.Headers),
+			ContentType:     publishing.ContentType,
+			ContentEncoding: publishing.ContentEncoding,
+			DeliveryMode:    publishing.DeliveryMode,
+			CorrelationId:   publishing.CorrelationId,
+			MessageId:       publishing.MessageId,
+			Timestamp:       publishing.Timestamp,
+			Type:            publishing.Type,
+			AppId:           publishing.AppId,
+			DeliveryTag:     b.tags.Add(1),
+			Exchange:        exchange,
+			RoutingKey:      routingKey,
+			Body:            publishing.Body,
+		})
+	}
+	return len(queues), nil
+}
+
+func (b *MemoryBroker) queue(name string) (*memoryQueue, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	q, ok := b.queues[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrQueueNotFound, name)
+	}
+	return q, nil
+}
+
+// QueueLength is the number of messages waiting in queue.
+func (b *MemoryBroker) QueueLength(queue string) int {
+	q, err := b.queue(queue)
+	if err != nil {
+		return 0
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.messages)
+}
+
+// DeadLetters returns the messages dead-lettered by consumers of queue.
+func (b *MemoryBroker) DeadLetters(queue string) []amqp.Delivery {
+	q, err := b.queue(queue)
+	if err != nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]amqp.Delivery(nil), q.deadLetters...)
+}
+
+type memoryConnection struct {
+	broker   *MemoryBroker
+	producer string
+}
+
+func (c *memoryConnection) NewPublisher(config RabbitmqPublishersConfig) (IRabbitmqPublisher, error) {
+	return &MemoryPublisher{
+		broker:     c.broker,
+		Exchange:   config.Exchange,
+		RoutingKey: config.RoutingKey,
+		Producer:   c.producer,
+	}, nil
+}
+
+// NewConsumer declares the consumer's queue if it does not exist yet; it only
+// receives messages once it is bound to an exchange.
+func (c *memoryConnection) NewConsumer(config RabbitmqConsumerConfig) (IRabbitmqConsumer, error) {
+	c.broker.DeclareQueue(config.QueueName)
+	return &MemoryConsumer{
+		broker:       c.broker,
+		QueueName:    config.QueueName,
+		ConsumerTag:  config.ConsumerTag,
+		RetryPolicy:  config.RetryPolicy,
+		Concurrency:  max(config.Concurrency, DefaultConcurrency),
+		OrderedByKey: config.OrderedByKey,
+		done:         make(chan struct{}),
+	}, nil
+}
+
+func (c *memoryConnection) DeclareTopology(topology RabbitmqTopologyConfig) error {
+	for _, ex := range topology.Exchanges {
+		if err := c.broker.DeclareExchange(ex.Name, ex.Type); err != nil {
+			return err
+		}
+	}
+	for _, q := range topology.Queues {
+		c.broker.DeclareQueue(q.Name)
+	}
+	for _, b := range topology.Bindings {
+		if err := c.broker.BindQueue(b.Queue, b.Exchange, b.RoutingKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VerifyTopology checks that the exchanges, queues and bindings of topology
+// exist, as loaded from the definitions.
+func (c *memoryConnection) VerifyTopology(topology RabbitmqTopologyConfig) error {
+	c.broker.mu.RLock()
+	defer c.broker.mu.RUnlock()
+
+	var errs []error
+	for _, ex := range topology.Exchanges {
+		existing, ok := c.broker.exchanges[ex.Name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrExchangeNotFound, ex.Name))
+		} else if existing.kind != ex.Type {
+			errs = append(errs, fmt.Errorf("exchange %s is %s, not %s", ex.Name, existing.kind, ex.Type))
+		}
+	}
+	for _, q := range topology.Queues {
+		if _, ok := c.broker.queues[q.Name]; !ok {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrQueueNotFound, q.Name))
+		}
+	}
+	for _, b := range topology.Bindings {
+		if !c.broker.hasBinding(b) {
+			errs = append(errs, fmt.Errorf("queue %s is not bound to %s with %q", b.Queue, b.Exchange, b.RoutingKey))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close does nothing: the broker lives as long as the process, so that
+// services sharing it can stop independently.
+func (c *memoryConnection) Close() error {
+	return nil
+}
+
+type memoryQueue struct {
+	mu          sync.Mutex
+	messages    []amqp.Delivery
+	deadLetters []amqp.Delivery
+	ready       chan struct{}
+}
+
+func newMemoryQueue() *memoryQueue {
+	return &memoryQueue{ready: make(chan struct{}, 1)}
+}
+
+func (q *memoryQueue) push(d amqp.Delivery) {
+	q.mu.Lock()
+	q.messages = append(q.messages, d)
+	q.mu.Unlock()
+	q.signal()
+}
+
+func (q *memoryQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// pop waits for the next message until ctx is done.
+func (q *memoryQueue) pop(ctx context.Context) (amqp.Delivery, bool) {
+	for {
+		q.mu.Lock()
+		if len(q.messages) > 0 {
+			d := q.messages[0]
+			q.messages = q.messages[1:]
+			more := len(q.messages) > 0
+			q.mu.Unlock()
+			if more {
+				// Wake up another consumer of the queue
+				q.signal()
+			}
+			return d, true
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.ready:
+		case <-ctx.Done():
+			return amqp.Delivery{}, false
+		}
+	}
+}
+
+func (q *memoryQueue) deadLetter(d amqp.Delivery) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.deadLetters = append(q.deadLetters, d)
+}
+
+// MemoryPublisher publishes to a MemoryBroker. Publishing is synchronous, so
+// PublishConfirmed only adds the check that the message reached a queue.
+type MemoryPublisher struct {
+	broker     *MemoryBroker
+	Exchange   string
+	RoutingKey string
+	Producer   string
+}
+
+func (mp *MemoryPublisher) Publish(body utilities.Serializable) error {
+	return mp.PublishWithContext(context.Background(), body)
+}
+
+func (mp *MemoryPublisher) PublishWithContext(ctx context.Context, body utilities.Serializable) error {
+	return mp.publish(ctx, body, false)
+}
+
+func (mp *MemoryPublisher) PublishConfirmed(ctx context.Context, body utilities.Serializable) error {
+	return mp.publish(ctx, body, true)
+}
+
+func (mp *MemoryPublisher) publish(ctx context.Context, body utilities.Serializable, confirm bool) error {
+	return publishInSpan(ctx, mp.Exchange, mp.RoutingKey, func(ctx context.Context) error {
+		publishing, err := newPublishing(ctx, body, mp.Producer)
+		if err != nil {
+			return err
+		}
+
+		routed, err := mp.broker.route(mp.Exchange, mp.RoutingKey, publishing)
+		if err != nil {
+			return err
+		}
+		if routed == 0 {
+			metrics.IncRabbitmqPublishConfirm(mp.Exchange, "returned")
+			if confirm {
+				return fmt.Errorf("%w: routing key %s on %s", ErrUnroutable, mp.RoutingKey, mp.Exchange)
+			}
+			rabbitmqLogger.Warnf("Message to %s with routing key %s was returned: no queue bound", mp.Exchange, mp.RoutingKey)
+			return nil
+		}
+		if confirm {
+			metrics.IncRabbitmqPublishConfirm(mp.Exchange, "ack")
+		}
+		return nil
+	})
+}
+
+// MemoryConsumer consumes a queue of a MemoryBroker with the same handler
+// contract as RabbitmqConsumer.
+type MemoryConsumer struct {
+	broker       *MemoryBroker
+	QueueName    string
+	ConsumerTag  string
+	RetryPolicy  RetryPolicy
+	Concurrency  int
+	OrderedByKey bool
+
+	started atomic.Bool
+	done    chan struct{}
+}
+
+func (mc *MemoryConsumer) StartConsuming(ctx context.Context, messageHandler MessageHandler) error {
+	if !mc.started.CompareAndSwap(false, true) {
+		return ErrConsumerStarted
+	}
+	defer close(mc.done)
+
+	q, err := mc.broker.queue(mc.QueueName)
+	if err != nil {
+		return err
+	}
+
+	workers := newDispatcher(mc.QueueName, mc.Concurrency, mc.OrderedByKey, func(d amqp.Delivery) {
+		processDelivery(mc.QueueName, mc.RetryPolicy, d, messageHandler, func(_ context.Context, action Action, cause error) {
+			mc.settle(q, d, action, cause)
+		})
+	})
+	defer workers.close()
+
+	for {
+		d, ok := q.pop(ctx)
+		if !ok {
+			break
+		}
+		metrics.IncRabbitmqConsumed(mc.QueueName, nil)
+		workers.dispatch(d)
+	}
+
+	rabbitmqLogger.Infof("[%s] Consumer %s stopped", mc.QueueName, mc.ConsumerTag)
+	return nil
+}
+
+func (mc *MemoryConsumer) settle(q *memoryQueue, d amqp.Delivery, action Action, cause error) {
+	metrics.IncRabbitmqSettled(mc.QueueName, action.String())
+
+	switch action {
+	case ActionRetry:
+		d.Headers = retryHeaders(d, RetryCount(d.Headers)+1, cause)
+		time.AfterFunc(mc.RetryPolicy.Delay, func() { q.push(d) })
+	case ActionDeadLetter:
+		rabbitmqLogger.Warnf("[%s] Dead-lettering message %s after %d retries: %v",
+			mc.QueueName, d.MessageId, RetryCount(d.Headers), cause)
+		d.Headers = retryHeaders(d, RetryCount(d.Headers), cause)
+		q.deadLetter(d)
+	}
+}
+
+// Drain waits until StartConsuming has returned. Messages waiting for a retry
+// stay scheduled and go back to the queue after their delay.
+func (mc *MemoryConsumer) Drain(ctx context.Context) error {
+	if !mc.started.Load() {
+		return nil
+	}
+
+	select {
+	case <-mc.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("consumer %s on %s did not drain: %w", mc.ConsumerTag, mc.QueueName, ctx.Err())
+	}
+}
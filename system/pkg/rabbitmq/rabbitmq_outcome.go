@@ -0,0 +1,122 @@
+package rabbitmq
+
+import (
+	"strconv"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	// RetryCountHeader counts how many times a message went through the retry
+	// queue; it is copied and incremented on every retry.
+	RetryCountHeader = "x-retry-count"
+	// FailureReasonHeader carries the error of the last failed attempt.
+	FailureReasonHeader = "x-failure-reason"
+
+	DefaultMaxRetries         = 3
+	DefaultRetryDelay         = 5 * time.Second
+	DefaultDeadLetterExchange = "dead_letter"
+
+	retryQueueSuffix      = ".retry"
+	deadLetterQueueSuffix = ".dlq"
+)
+
+type Action int
+
+const (
+	// ActionAck removes the message from the queue.
+	ActionAck Action = iota
+	// ActionRetry sends the message through the delayed retry queue back to
+	// its queue, or to the dead-letter queue once retries are exhausted.
+	ActionRetry
+	// ActionDeadLetter moves the message straight to the dead-letter queue.
+	ActionDeadLetter
+)
+
+func (a Action) String() string {
+	switch a {
+	case ActionAck:
+		return "ack"
+	case ActionRetry:
+		return "retry"
+	case ActionDeadLetter:
+		return "dead_letter"
+	default:
+		return "unknown"
+	}
+}
+
+// Outcome is what a message handler decides about a delivery.
+type Outcome struct {
+	Action Action
+	Err    error
+}
+
+func Ack() Outcome {
+	return Outcome{Action: ActionAck}
+}
+
+// RetryLater is for transient failures (database down, RPC timeout, ...).
+func RetryLater(err error) Outcome {
+	return Outcome{Action: ActionRetry, Err: err}
+}
+
+// DeadLetter is for messages that can never succeed (malformed body, ...).
+func DeadLetter(err error) Outcome {
+	return Outcome{Action: ActionDeadLetter, Err: err}
+}
+
+// RetryPolicy bounds redelivery of a consumer's messages. MaxRetries of zero
+// disables retries, so every RetryLater outcome is dead-lettered.
+type RetryPolicy struct {
+	MaxRetries         int
+	Delay              time.Duration
+	DeadLetterExchange string
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:         DefaultMaxRetries,
+		Delay:              DefaultRetryDelay,
+		DeadLetterExchange: DefaultDeadLetterExchange,
+	}
+}
+
+// Expiration is the per-message TTL, in the milliseconds string AMQP expects,
+// that holds a message in the retry queue for Delay.
+func (p RetryPolicy) Expiration() string {
+	return strconv.FormatInt(p.Delay.Milliseconds(), 10)
+}
+
+// Resolve returns what to do with d, escalating a retry to the dead-letter
+// queue when the message has already been retried MaxRetries times.
+func (p RetryPolicy) Resolve(outcome Outcome, d amqp.Delivery) Action {
+	if outcome.Action == ActionRetry && RetryCount(d.Headers) >= p.MaxRetries {
+		return ActionDeadLetter
+	}
+	return outcome.Action
+}
+
+// RetryCount reads RetryCountHeader, treating a missing or malformed header as
+// a first delivery.
+func RetryCount(headers amqp.Table) int {
+	switch v := headers[RetryCountHeader].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	default:
+		return 0
+	}
+}
+
+func RetryQueueName(queueName string) string {
+	return queueName + retryQueueSuffix
+}
+
+func DeadLetterQueueName(queueName string) string {
+	return queueName + deadLetterQueueSuffix
+}
@@ -1,53 +1,45 @@
 package rabbitmq
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"pkg-common/metrics"
+	"pkg-common/tracing"
 	"pkg-common/utilities"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type PublisherAlias string
 
+// PublishIdHeader correlates a message returned as unroutable with the
+// publish that sent it.
+const PublishIdHeader = "x-publish-id"
+
 var (
-	PublisherRegistry    map[PublisherAlias]IRabbitmqPublisher
-	oncePublisher        sync.Once
-	initializedPublisher bool
+	ErrPublishNacked = errors.New("rabbitmq did not confirm the message")
+	ErrUnroutable    = errors.New("rabbitmq could not route the message to any queue")
 )
 
-func GetPublisher(alias PublisherAlias) IRabbitmqPublisher {
-	if !initializedPublisher {
-		panic("Publisher registry not initialized: call InitializePublisherRegistry() first")
-	}
-	return PublisherRegistry[alias]
-}
-
-func InitializePublisherRegistry(conn *amqp.Connection, publisherConfig []RabbitmqPublishersConfig) {
-	oncePublisher.Do(func() {
-		PublisherRegistry = make(map[PublisherAlias]IRabbitmqPublisher)
-
-		for _, publisher := range publisherConfig {
-			channel, err := conn.Channel()
-			if err != nil {
-				rabbitmqLogger.Panicf(err, "Could not obtain connection for publisher")
-			}
-
-			PublisherRegistry[publisher.PublisherAlias] = NewPublisher(
-				channel,
-				publisher.Exchange,
-				publisher.RoutingKey,
-			)
-		}
-
-		initializedPublisher = true
-	})
-}
-
 type RabbitmqPublisher struct {
 	Channel    *amqp.Channel
 	Exchange   string
 	RoutingKey string
+
+	mu         sync.RWMutex
+	manager    *ConnectionManager
+	confirming *amqp.Channel // Channel once it was put in confirm mode
+	returns    *ReturnTracker
+
+	sequence atomic.Uint64
 }
 
 func NewPublisher(ch *amqp.Channel, exchange, routingKey string) *RabbitmqPublisher {
@@ -58,25 +50,210 @@ func NewPublisher(ch *amqp.Channel, exchange, routingKey string) *RabbitmqPublis
 	}
 }
 
+// WithConnection lets the publisher replace its channel after the broker
+// connection or the channel itself was lost.
+func (rp *RabbitmqPublisher) WithConnection(manager *ConnectionManager) *RabbitmqPublisher {
+	rp.manager = manager
+	manager.OnReconnect(func(conn *amqp.Connection) {
+		ch, err := conn.Channel()
+		if err != nil {
+			rabbitmqLogger.Errorf(err, "Could not reopen channel for publisher on %s", rp.Exchange)
+			return
+		}
+		rp.setChannel(ch)
+	})
+	return rp
+}
+
+func (rp *RabbitmqPublisher) setChannel(ch *amqp.Channel) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.Channel = ch
+}
+
+// openChannel returns a usable channel in confirm mode together with the
+// tracker of its returned messages. While the connection is down it waits up to the
+// manager's PublishTimeout for it to come back and then fails with
+// ErrNotConnected, so callers with durable state (the outbox) retry later.
+func (rp *RabbitmqPublisher) openChannel(ctx context.Context) (*amqp.Channel, *ReturnTracker, error) {
+	rp.mu.RLock()
+	ch, returns := rp.Channel, rp.returns
+	ready := ch != nil && !ch.IsClosed() && ch == rp.confirming
+	rp.mu.RUnlock()
+	if ready {
+		return ch, returns, nil
+	}
+
+	if rp.manager != nil && (ch == nil || ch.IsClosed()) {
+		waitCtx, cancel := context.WithTimeout(ctx, rp.manager.PublishTimeout())
+		defer cancel()
+		if err := rp.manager.WaitConnected(waitCtx); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrNotConnected, err)
+		}
+	}
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if rp.Channel == nil || rp.Channel.IsClosed() {
+		if rp.manager == nil {
+			return nil, nil, ErrNotConnected
+		}
+		// Only the channel was closed (e.g. by a channel-level error)
+		fresh, err := rp.manager.Channel()
+		if err != nil {
+			return nil, nil, err
+		}
+		rp.Channel = fresh
+	}
+	if rp.confirming != rp.Channel {
+		if err := rp.Channel.Confirm(false); err != nil {
+			return nil, nil, fmt.Errorf("could not enable publisher confirms: %w", err)
+		}
+		rp.returns = NewReturnTracker(rp.Exchange, rp.Channel.NotifyReturn(make(chan amqp.Return, returnBuffer)))
+		rp.confirming = rp.Channel
+	}
+	return rp.Channel, rp.returns, nil
+}
+
+func (rp *RabbitmqPublisher) producer() string {
+	if rp.manager == nil {
+		return defaultProducer()
+	}
+	return rp.manager.Producer()
+}
+
+func (rp *RabbitmqPublisher) publishTimeout() time.Duration {
+	if rp.manager == nil {
+		return DefaultPublishTimeout
+	}
+	return rp.manager.PublishTimeout()
+}
+
+// IdentifiableMessage is implemented by messages that carry an idempotency
+// key; it is published as the envelope's message id.
+type IdentifiableMessage interface {
+	MessageId() string
+}
+
 type IRabbitmqPublisher interface {
 	Publish(body utilities.Serializable) error
+	PublishWithContext(ctx context.Context, body utilities.Serializable) error
+	PublishConfirmed(ctx context.Context, body utilities.Serializable) error
 }
 
 func (rp *RabbitmqPublisher) Publish(body utilities.Serializable) error {
-	json, err := body.Serialize()
+	return rp.PublishWithContext(context.Background(), body)
+}
+
+// PublishWithContext publishes body wrapped in an Envelope within a producer
+// span and propagates the trace context of ctx to consumers through the
+// message headers. It does not wait for the broker; unroutable messages are
+// only logged and counted.
+func (rp *RabbitmqPublisher) PublishWithContext(ctx context.Context, body utilities.Serializable) error {
+	return rp.publish(ctx, body, false)
+}
+
+// PublishConfirmed is PublishWithContext, but returns only once the broker
+// has taken responsibility for the message. It fails with ErrPublishNacked
+// when the broker rejects it or the channel is lost before the confirm, and
+// with ErrUnroutable when no queue is bound for the routing key.
+func (rp *RabbitmqPublisher) PublishConfirmed(ctx context.Context, body utilities.Serializable) error {
+	return rp.publish(ctx, body, true)
+}
+
+func (rp *RabbitmqPublisher) publish(ctx context.Context, body utilities.Serializable, confirm bool) error {
+	return publishInSpan(ctx, rp.Exchange, rp.RoutingKey, func(ctx context.Context) error {
+		return rp.send(ctx, body, confirm)
+	})
+}
+
+// publishInSpan runs send within a producer span and counts the publish.
+func publishInSpan(ctx context.Context, exchange, routingKey string, send func(context.Context) error) error {
+	ctx, span := tracing.Tracer().Start(ctx, exchange+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "rabbitmq"),
+			attribute.String("messaging.destination.name", exchange),
+			attribute.String("messaging.rabbitmq.destination.routing_key", routingKey),
+		),
+	)
+	defer span.End()
+
+	err := send(ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	metrics.IncRabbitmqPublished(exchange, err)
+	return err
+}
+
+// newPublishing wraps body in an envelope and carries the trace context of
+// ctx and the message's ordering key in the headers.
+func newPublishing(ctx context.Context, body utilities.Serializable, producer string) (amqp.Publishing, error) {
+	envelope, err := NewEnvelope(ctx, body, producer)
+	if err != nil {
+		return amqp.Publishing{}, err
+	}
+	json, err := envelope.Serialize()
+	if err != nil {
+		return amqp.Publishing{}, err
+	}
+
+	publishing := amqp.Publishing{
+		ContentType:   "application/json",
+		Body:          json,
+		Timestamp:     envelope.ProducedAt,
+		DeliveryMode:  amqp.Persistent,
+		MessageId:     envelope.MessageId,
+		CorrelationId: envelope.CorrelationId,
+		Type:          envelope.Type,
+		AppId:         envelope.Producer,
+		Headers:       tracing.InjectAmqpHeaders(ctx, nil),
+	}
+	if msg, ok := body.(OrderedMessage); ok {
+		publishing.Headers[OrderingKeyHeader] = msg.OrderingKey()
+	}
+	return publishing, nil
+}
+
+func (rp *RabbitmqPublisher) send(ctx context.Context, body utilities.Serializable, confirm bool) error {
+	publishing, err := newPublishing(ctx, body, rp.producer())
 	if err != nil {
 		return err
 	}
+	publishId := strconv.FormatUint(rp.sequence.Add(1), 10)
+	publishing.Headers[PublishIdHeader] = publishId
 
-	return rp.Channel.Publish(
-		rp.Exchange,
-		rp.RoutingKey,
-		false, false,
-		amqp.Publishing{
-			ContentType:  "application/json",
-			Body:         json,
-			Timestamp:    time.Now(),
-			DeliveryMode: amqp.Persistent,
-		},
-	)
+	ch, returns, err := rp.openChannel(ctx)
+	if err != nil {
+		return err
+	}
+
+	if confirm {
+		returns.Expect(publishId)
+	}
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(ctx, rp.Exchange, rp.RoutingKey, true, false, publishing)
+	if err != nil || !confirm {
+		returns.Forget(publishId)
+		return err
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, rp.publishTimeout())
+	defer cancel()
+	acked, err := confirmation.WaitContext(waitCtx)
+	returned, wasReturned := returns.Take(publishId)
+
+	switch {
+	case err != nil:
+		return fmt.Errorf("waiting for publisher confirm: %w", err)
+	case !acked:
+		metrics.IncRabbitmqPublishConfirm(rp.Exchange, "nack")
+		return ErrPublishNacked
+	case wasReturned:
+		metrics.IncRabbitmqPublishConfirm(rp.Exchange, "returned")
+		return fmt.Errorf("%w: %d %s (routing key %s)", ErrUnroutable, returned.ReplyCode, returned.ReplyText, rp.RoutingKey)
+	default:
+		metrics.IncRabbitmqPublishConfirm(rp.Exchange, "ack")
+		return nil
+	}
 }
@@ -0,0 +1,53 @@
+package rabbitmq
+
+import "fmt"
+
+// Registry holds the publishers and consumers a service opened on its broker
+// connection, under the aliases of its config. Each service builds its own,
+// so services sharing a process, as in tests, do not see each other's.
+type Registry struct {
+	publishers map[PublisherAlias]IRabbitmqPublisher
+	consumers  map[ConsumerAlias]IRabbitmqConsumer
+}
+
+func NewRegistry(broker Broker, publisherConfig []RabbitmqPublishersConfig, consumerConfig []RabbitmqConsumerConfig) (*Registry, error) {
+	registry := &Registry{
+		publishers: make(map[PublisherAlias]IRabbitmqPublisher),
+		consumers:  make(map[ConsumerAlias]IRabbitmqConsumer),
+	}
+
+	for _, config := range publisherConfig {
+		publisher, err := broker.NewPublisher(config)
+		if err != nil {
+			return nil, fmt.Errorf("could not open publisher %s: %w", config.PublisherAlias, err)
+		}
+		registry.publishers[config.PublisherAlias] = publisher
+	}
+	for _, config := range consumerConfig {
+		consumer, err := broker.NewConsumer(config)
+		if err != nil {
+			return nil, fmt.Errorf("could not open consumer %s: %w", config.ConsumerAlias, err)
+		}
+		registry.consumers[config.ConsumerAlias] = consumer
+	}
+	return registry, nil
+}
+
+// Publisher returns the publisher configured as alias. Workers look theirs up
+// while the service is built, so a missing alias panics before it starts.
+func (r *Registry) Publisher(alias PublisherAlias) IRabbitmqPublisher {
+	publisher, ok := r.publishers[alias]
+	if !ok {
+		panic(fmt.Sprintf("no publisher configured as %s", alias))
+	}
+	return publisher
+}
+
+// Consumer returns the consumer configured as alias, panicking like Publisher.
+func (r *Registry) Consumer(alias ConsumerAlias) IRabbitmqConsumer {
+	consumer, ok := r.consumers[alias]
+	if !ok {
+		panic(fmt.Sprintf("no consumer configured as %s", alias))
+	}
+	return consumer
+}
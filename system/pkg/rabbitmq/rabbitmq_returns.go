@@ -0,0 +1,127 @@
+package rabbitmq
+
+import (
+	"pkg-common/metrics"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// returnBuffer absorbs bursts of returns while the tracker records them; the
+// client blocks the whole connection on a full buffer.
+const returnBuffer = 64
+
+// ReturnTracker drains the messages a channel returns as unroutable in its
+// own goroutine, so returns nobody waits for never fill the buffer, and keeps
+// the returns of confirmed publishes until they are taken. Publishes are
+// matched by their PublishIdHeader.
+type ReturnTracker struct {
+	exchange string
+	returns  <-chan amqp.Return
+	flush    chan chan struct{}
+	done     chan struct{}
+
+	mu       sync.Mutex
+	waiting  map[string]struct{}
+	returned map[string]amqp.Return
+}
+
+// NewReturnTracker drains returns until the client closes it together with
+// its channel.
+func NewReturnTracker(exchange string, returns <-chan amqp.Return) *ReturnTracker {
+	t := &ReturnTracker{
+		exchange: exchange,
+		returns:  returns,
+		flush:    make(chan chan struct{}),
+		done:     make(chan struct{}),
+		waiting:  make(map[string]struct{}),
+		returned: make(map[string]amqp.Return),
+	}
+	go t.run()
+	return t
+}
+
+func (t *ReturnTracker) run() {
+	defer close(t.done)
+	for {
+		select {
+		case r, ok := <-t.returns:
+			if !ok {
+				return
+			}
+			t.record(r)
+		case flushed := <-t.flush:
+			open := t.drain()
+			close(flushed)
+			if !open {
+				return
+			}
+		}
+	}
+}
+
+// drain records the returns already buffered and reports whether returns is
+// still open.
+func (t *ReturnTracker) drain() bool {
+	for {
+		select {
+		case r, ok := <-t.returns:
+			if !ok {
+				return false
+			}
+			t.record(r)
+		default:
+			return true
+		}
+	}
+}
+
+func (t *ReturnTracker) record(r amqp.Return) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	id, _ := r.Headers[PublishIdHeader].(string)
+	if _, waiting := t.waiting[id]; waiting {
+		t.returned[id] = r
+		return
+	}
+	metrics.IncRabbitmqPublishConfirm(t.exchange, "returned")
+	rabbitmqLogger.Warnf("Message to %s with routing key %s was returned: %d %s", r.Exchange, r.RoutingKey, r.ReplyCode, r.ReplyText)
+}
+
+// Expect keeps the return of publishId, if any, for Take. It must be called
+// before the message is published.
+func (t *ReturnTracker) Expect(publishId string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.waiting[publishId] = struct{}{}
+}
+
+// Forget drops publishId when its publish failed or is not confirmed.
+func (t *ReturnTracker) Forget(publishId string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.waiting, publishId)
+	delete(t.returned, publishId)
+}
+
+// Take reports whether publishId was returned. The broker sends basic.return
+// before the ack of the same message and the client hands it over before
+// resolving the confirmation, so once the publish is confirmed its return is
+// either recorded or still buffered; Take has the tracker record the buffered
+// returns first.
+func (t *ReturnTracker) Take(publishId string) (amqp.Return, bool) {
+	flushed := make(chan struct{})
+	select {
+	case t.flush <- flushed:
+		<-flushed
+	case <-t.done:
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r, ok := t.returned[publishId]
+	delete(t.waiting, publishId)
+	delete(t.returned, publishId)
+	return r, ok
+}
@@ -0,0 +1,100 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"pkg-common/logger"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var ErrUnknownMessageType = errors.New("no handler for message type")
+
+// Message is an unwrapped delivery.
+type Message struct {
+	Envelope
+	Delivery amqp.Delivery
+}
+
+// Decode unmarshals the payload into v.
+func (m Message) Decode(v any) error {
+	return json.Unmarshal(m.Payload, v)
+}
+
+type EnvelopeHandler func(ctx context.Context, msg Message) Outcome
+
+// Router unwraps envelopes and hands each message to the handler registered
+// for its type. Messages that are no envelope, carry an unsupported schema
+// version or a type without handler are dead-lettered.
+type Router struct {
+	handlers map[string]EnvelopeHandler
+
+	idempotency    IdempotencyStore
+	idempotencyKey IdempotencyKey
+}
+
+func NewRouter() *Router {
+	return &Router{handlers: make(map[string]EnvelopeHandler)}
+}
+
+func (r *Router) On(messageType string, handler EnvelopeHandler) *Router {
+	r.handlers[messageType] = handler
+	return r
+}
+
+// Deduplicate acknowledges, without handling them again, messages whose key
+// was already handled successfully. A delivery arriving while the same key is
+// in flight is retried later, in case the first attempt fails.
+func (r *Router) Deduplicate(store IdempotencyStore, key IdempotencyKey) *Router {
+	r.idempotency = store
+	r.idempotencyKey = key
+	return r
+}
+
+// Handle is a MessageHandler, to be passed to StartConsuming. The context
+// given to the handler carries the envelope, so whatever the handler
+// publishes with it is correlated to the message.
+func (r *Router) Handle(ctx context.Context, d amqp.Delivery) Outcome {
+	envelope, err := OpenEnvelope(d.Body)
+	if err != nil {
+		return DeadLetter(err)
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("messaging.message.id", envelope.MessageId),
+		attribute.String("messaging.message.conversation_id", envelope.CorrelationId),
+		attribute.String("messaging.message.type", envelope.Type),
+	)
+
+	handler, ok := r.handlers[envelope.Type]
+	if !ok {
+		return DeadLetter(fmt.Errorf("%w: %s", ErrUnknownMessageType, envelope.Type))
+	}
+
+	ctx = logger.ContextWithField(ctx, logger.CorrelationIdKey, envelope.CorrelationId)
+	msg := Message{Envelope: envelope, Delivery: d}
+	if r.idempotency == nil {
+		return handler(ContextWithEnvelope(ctx, envelope), msg)
+	}
+
+	key := r.idempotencyKey(msg)
+	if key == "" {
+		return handler(ContextWithEnvelope(ctx, envelope), msg)
+	}
+	claimed, err := r.idempotency.Claim(key)
+	if err != nil {
+		return RetryLater(err)
+	}
+	if !claimed {
+		rabbitmqLogger.WithContext(ctx).Infof("Skipping %s %s, already handled as %s", envelope.Type, envelope.MessageId, key)
+		return Ack()
+	}
+
+	outcome := handler(ContextWithEnvelope(ctx, envelope), msg)
+	r.idempotency.Complete(key, outcome.Action == ActionAck)
+	return outcome
+}
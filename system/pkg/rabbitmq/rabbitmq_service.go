@@ -1,6 +1,12 @@
 package rabbitmq
 
+import "context"
+
+// WorkerService is a background worker run by the application. StartService
+// blocks until ctx is cancelled; Stop then waits for work already in flight
+// and returns an error when it cannot finish before ctx expires.
 type WorkerService interface {
-	StartService()
+	StartService(ctx context.Context) error
+	Stop(ctx context.Context) error
 	GetServiceName() string
 }
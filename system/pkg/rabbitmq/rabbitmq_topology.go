@@ -0,0 +1,271 @@
+package rabbitmq
+
+import (
+	"errors"
+	"fmt"
+	"pkg-common/utilities"
+	"strings"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// RabbitmqTopologyConfigJson describes the exchanges, queues and bindings a
+// service relies on. They are declared at startup when Declare is set,
+// otherwise only checked to exist, and publisher routes are always checked
+// against them. Declaring needs the configure permission on every entity,
+// which the service users of definitions.json only have on their retry and
+// dead-letter queues, so the shipped configs leave Declare off and rely on
+// the broker loading definitions.json.
+type RabbitmqTopologyConfigJson struct {
+	Declare   bool                         `json:"declare"`
+	Exchanges []RabbitmqExchangeConfigJson `json:"exchanges"`
+	Queues    []RabbitmqQueueConfigJson    `json:"queues"`
+	Bindings  []RabbitmqBindingConfigJson  `json:"bindings"`
+}
+
+type RabbitmqTopologyConfig struct {
+	Declare   bool
+	Exchanges []RabbitmqExchangeConfig
+	Queues    []RabbitmqQueueConfig
+	Bindings  []RabbitmqBindingConfig
+}
+
+func (rtcj RabbitmqTopologyConfigJson) MapToDomain() RabbitmqTopologyConfig {
+	return RabbitmqTopologyConfig{
+		Declare:   rtcj.Declare,
+		Exchanges: utilities.ConvertJsonArrayToDomain[RabbitmqExchangeConfigJson, RabbitmqExchangeConfig](rtcj.Exchanges),
+		Queues:    utilities.ConvertJsonArrayToDomain[RabbitmqQueueConfigJson, RabbitmqQueueConfig](rtcj.Queues),
+		Bindings:  utilities.ConvertJsonArrayToDomain[RabbitmqBindingConfigJson, RabbitmqBindingConfig](rtcj.Bindings),
+	}
+}
+
+type RabbitmqExchangeConfigJson struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Durable    bool   `json:"durable"`
+	AutoDelete bool   `json:"auto_delete"`
+}
+
+type RabbitmqExchangeConfig struct {
+	Name       string
+	Type       string
+	Durable    bool
+	AutoDelete bool
+}
+
+func (recj RabbitmqExchangeConfigJson) MapToDomain() RabbitmqExchangeConfig {
+	return RabbitmqExchangeConfig(recj)
+}
+
+type RabbitmqQueueConfigJson struct {
+	Name       string `json:"name"`
+	Durable    bool   `json:"durable"`
+	AutoDelete bool   `json:"auto_delete"`
+	// Optional arguments; 0 or "" leaves them unset
+	MessageTtlMs         int    `json:"message_ttl_ms"`
+	ExpiresMs            int    `json:"expires_ms"`
+	DeadLetterExchange   string `json:"dead_letter_exchange"`
+	DeadLetterRoutingKey string `json:"dead_letter_routing_key"`
+}
+
+type RabbitmqQueueConfig struct {
+	Name                 string
+	Durable              bool
+	AutoDelete           bool
+	MessageTtl           time.Duration
+	Expires              time.Duration
+	DeadLetterExchange   string
+	DeadLetterRoutingKey string
+}
+
+func (rqcj RabbitmqQueueConfigJson) MapToDomain() RabbitmqQueueConfig {
+	return RabbitmqQueueConfig{
+		Name:                 rqcj.Name,
+		Durable:              rqcj.Durable,
+		AutoDelete:           rqcj.AutoDelete,
+		MessageTtl:           time.Duration(rqcj.MessageTtlMs) * time.Millisecond,
+		Expires:              time.Duration(rqcj.ExpiresMs) * time.Millisecond,
+		DeadLetterExchange:   rqcj.DeadLetterExchange,
+		DeadLetterRoutingKey: rqcj.DeadLetterRoutingKey,
+	}
+}
+
+// Arguments are the x-arguments of the queue. RabbitMQ refuses to redeclare
+// an existing queue with different ones, so they must match the definitions
+// the broker was started with.
+func (q RabbitmqQueueConfig) Arguments() amqp.Table {
+	args := amqp.Table{}
+	if q.MessageTtl > 0 {
+		args["x-message-ttl"] = q.MessageTtl.Milliseconds()
+	}
+	if q.Expires > 0 {
+		args["x-expires"] = q.Expires.Milliseconds()
+	}
+	if q.DeadLetterExchange != "" {
+		args["x-dead-letter-exchange"] = q.DeadLetterExchange
+	}
+	if q.DeadLetterRoutingKey != "" {
+		args["x-dead-letter-routing-key"] = q.DeadLetterRoutingKey
+	}
+	return args
+}
+
+type RabbitmqBindingConfigJson struct {
+	Exchange   string `json:"exchange"`
+	Queue      string `json:"queue"`
+	RoutingKey string `json:"routing_key"`
+}
+
+type RabbitmqBindingConfig struct {
+	Exchange   string
+	Queue      string
+	RoutingKey string
+}
+
+func (rbcj RabbitmqBindingConfigJson) MapToDomain() RabbitmqBindingConfig {
+	return RabbitmqBindingConfig(rbcj)
+}
+
+// IsConfigured reports whether the service describes any topology at all.
+func (t RabbitmqTopologyConfig) IsConfigured() bool {
+	return len(t.Exchanges) > 0 || len(t.Queues) > 0 || len(t.Bindings) > 0
+}
+
+// Route returns the queues a message published to exchange with routingKey
+// reaches according to the topology.
+func (t RabbitmqTopologyConfig) Route(exchange, routingKey string) ([]string, error) {
+	kind := ""
+	for _, ex := range t.Exchanges {
+		if ex.Name == exchange {
+			kind = ex.Type
+		}
+	}
+	if kind == "" {
+		return nil, fmt.Errorf("%w: %s", ErrExchangeNotFound, exchange)
+	}
+
+	var queues []string
+	for _, binding := range t.Bindings {
+		if binding.Exchange == exchange && bindingMatches(kind, binding.RoutingKey, routingKey) {
+			queues = append(queues, binding.Queue)
+		}
+	}
+	return queues, nil
+}
+
+// ValidatePublishers checks that every publisher reaches at least one queue,
+// so a routing key that drifted from the bindings fails the startup instead
+// of losing messages.
+func (t RabbitmqTopologyConfig) ValidatePublishers(publishers []RabbitmqPublishersConfig) error {
+	var errs []error
+	for _, publisher := range publishers {
+		queues, err := t.Route(publisher.Exchange, publisher.RoutingKey)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("publisher %s: %w", publisher.PublisherAlias, err))
+			continue
+		}
+		if len(queues) == 0 {
+			errs = append(errs, fmt.Errorf("publisher %s: %w: routing key %q on %s",
+				publisher.PublisherAlias, ErrUnroutable, publisher.RoutingKey, publisher.Exchange))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DeclareTopology declares the exchanges, queues and bindings of topology.
+// Declarations are idempotent as long as they match what exists.
+func (m *ConnectionManager) DeclareTopology(topology RabbitmqTopologyConfig) error {
+	ch, err := m.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	for _, ex := range topology.Exchanges {
+		if err := ch.ExchangeDeclare(ex.Name, ex.Type, ex.Durable, ex.AutoDelete, false, false, nil); err != nil {
+			return fmt.Errorf("could not declare exchange %s: %w", ex.Name, err)
+		}
+	}
+	for _, q := range topology.Queues {
+		if _, err := ch.QueueDeclare(q.Name, q.Durable, q.AutoDelete, false, false, q.Arguments()); err != nil {
+			return fmt.Errorf("could not declare queue %s: %w", q.Name, err)
+		}
+	}
+	for _, b := range topology.Bindings {
+		if err := ch.QueueBind(b.Queue, b.RoutingKey, b.Exchange, false, nil); err != nil {
+			return fmt.Errorf("could not bind queue %s to %s: %w", b.Queue, b.Exchange, err)
+		}
+	}
+	return nil
+}
+
+// VerifyTopology checks with passive declarations that the exchanges and
+// queues of topology exist, which needs no permission at all. A failed
+// passive declaration closes its channel, so each check gets its own.
+// Bindings cannot be checked passively and are left to definitions.json.
+func (m *ConnectionManager) VerifyTopology(topology RabbitmqTopologyConfig) error {
+	var errs []error
+	check := func(verify func(ch *amqp.Channel) error) {
+		ch, err := m.Channel()
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		defer ch.Close()
+		if err := verify(ch); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, ex := range topology.Exchanges {
+		check(func(ch *amqp.Channel) error {
+			if err := ch.ExchangeDeclarePassive(ex.Name, ex.Type, ex.Durable, ex.AutoDelete, false, false, nil); err != nil {
+				return fmt.Errorf("exchange %s: %w", ex.Name, err)
+			}
+			return nil
+		})
+	}
+	for _, q := range topology.Queues {
+		check(func(ch *amqp.Channel) error {
+			if _, err := ch.QueueDeclarePassive(q.Name, q.Durable, q.AutoDelete, false, false, q.Arguments()); err != nil {
+				return fmt.Errorf("queue %s: %w", q.Name, err)
+			}
+			return nil
+		})
+	}
+	return errors.Join(errs...)
+}
+
+// bindingMatches applies the routing rules of an exchange of type kind.
+func bindingMatches(kind, bindingKey, routingKey string) bool {
+	switch kind {
+	case amqp.ExchangeFanout:
+		return true
+	case amqp.ExchangeTopic:
+		return topicMatches(strings.Split(bindingKey, "."), strings.Split(routingKey, "."))
+	default:
+		return bindingKey == routingKey
+	}
+}
+
+// topicMatches applies AMQP topic rules: "*" matches exactly one word and "#"
+// zero or more words.
+func topicMatches(pattern, words []string) bool {
+	if len(pattern) == 0 {
+		return len(words) == 0
+	}
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(words); i++ {
+			if topicMatches(pattern[1:], words[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(words) > 0 && topicMatches(pattern[1:], words[1:])
+	default:
+		return len(words) > 0 && pattern[0] == words[0] && topicMatches(pattern[1:], words[1:])
+	}
+}
@@ -0,0 +1,84 @@
+package rest
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+type InternalAuthConfigJson struct {
+	Service          string                       `json:"service"`
+	Secret           string                       `json:"secret"`
+	TokenTTLSeconds  int                          `json:"token_ttl_seconds"`
+	ClockSkewSeconds int                          `json:"clock_skew_seconds"`
+	TrustedServices  []InternalTrustedServiceJson `json:"trusted_services"`
+}
+
+// InternalAuthConfig holds the key this service signs its calls with and the
+// keys and scopes of the services allowed to call it.
+type InternalAuthConfig struct {
+	Service         string
+	Secret          []byte
+	TokenTTL        time.Duration
+	ClockSkew       time.Duration
+	TrustedServices map[string]InternalTrustedService
+}
+
+func (iacj InternalAuthConfigJson) MapToDomain() InternalAuthConfig {
+	ttl := time.Duration(iacj.TokenTTLSeconds) * time.Second
+	if ttl <= 0 {
+		ttl = DefaultInternalTokenTTL
+	}
+	skew := time.Duration(iacj.ClockSkewSeconds) * time.Second
+	if skew <= 0 {
+		skew = DefaultInternalClockSkew
+	}
+
+	trusted := make(map[string]InternalTrustedService, len(iacj.TrustedServices))
+	for _, t := range iacj.TrustedServices {
+		trusted[t.Service] = t.MapToDomain()
+	}
+
+	return InternalAuthConfig{
+		Service:         iacj.Service,
+		Secret:          []byte(iacj.Secret),
+		TokenTTL:        ttl,
+		ClockSkew:       skew,
+		TrustedServices: trusted,
+	}
+}
+
+type InternalTrustedServiceJson struct {
+	Service string   `json:"service"`
+	Secret  string   `json:"secret"`
+	Scopes  []string `json:"scopes"`
+}
+
+type InternalTrustedService struct {
+	Secret []byte
+	Scopes []string
+}
+
+func (itsj InternalTrustedServiceJson) MapToDomain() InternalTrustedService {
+	return InternalTrustedService{
+		Secret: []byte(itsj.Secret),
+		Scopes: itsj.Scopes,
+	}
+}
+
+// MissingSecrets lists the keys left empty: the key of this service and those
+// of its trusted services. The committed configs leave them all empty, they
+// are set through the environment variables of the layered config.
+func (iac InternalAuthConfig) MissingSecrets() []string {
+	var missing []string
+	if len(iac.Secret) == 0 {
+		missing = append(missing, "internal_auth.secret")
+	}
+	for service, trusted := range iac.TrustedServices {
+		if len(trusted.Secret) == 0 {
+			missing = append(missing, fmt.Sprintf("internal_auth.trusted_services[%s].secret", service))
+		}
+	}
+	slices.Sort(missing)
+	return missing
+}
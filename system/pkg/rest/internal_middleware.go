@@ -1,24 +1,151 @@
 package rest
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwt"
 )
 
-func InternalAuthMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// TODO: better internal auth
-		// Skip for now
-		/*
-			token := c.GetHeader("Authorization")
-			if token == "" {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "No Authorization Header Provided"})
-				return
-			}
+const internalCallerKey = "internal_caller"
+
+var (
+	ErrMissingToken   = errors.New("missing bearer token")
+	ErrUnknownService = errors.New("unknown calling service")
+	ErrInvalidToken   = errors.New("invalid internal token")
+	ErrTokenLifetime  = errors.New("internal token lifetime is too long")
+)
 
-			if token != "internal_token_admin_123" {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Wrong auth token"})
+// InternalCaller is an authenticated internal service and the scopes it was
+// granted for this call.
+type InternalCaller struct {
+	Service string
+	Scopes  map[string]bool
+}
+
+func (ic InternalCaller) HasScope(scope string) bool {
+	return ic.Scopes[scope]
+}
+
+// InternalAuthenticator verifies tokens issued by InternalTokenIssuer of the
+// trusted services. The audience must be this service and the granted scopes
+// are those requested in the token and allowed for the caller in config.
+type InternalAuthenticator struct {
+	audience string
+	skew     time.Duration
+	trusted  map[string]InternalTrustedService
+	now      func() time.Time
+}
+
+func NewInternalAuthenticator(config InternalAuthConfig) *InternalAuthenticator {
+	skew := config.ClockSkew
+	if skew <= 0 {
+		skew = DefaultInternalClockSkew
+	}
+	return &InternalAuthenticator{
+		audience: config.Service,
+		skew:     skew,
+		trusted:  config.TrustedServices,
+		now:      time.Now,
+	}
+}
+
+// WithClock replaces the clock used to validate iat and exp.
+func (a *InternalAuthenticator) WithClock(now func() time.Time) *InternalAuthenticator {
+	a.now = now
+	return a
+}
+
+func (a *InternalAuthenticator) Authenticate(token string) (InternalCaller, error) {
+	// The issuer selects the key, so it is read before the signature is checked
+	unverified, err := jwt.ParseInsecure([]byte(token))
+	if err != nil {
+		return InternalCaller{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	service, ok := a.trusted[unverified.Issuer()]
+	if !ok || len(service.Secret) == 0 {
+		return InternalCaller{}, ErrUnknownService
+	}
+
+	verified, err := jwt.Parse([]byte(token),
+		jwt.WithKey(jwa.HS256, service.Secret),
+		jwt.WithValidate(true),
+		jwt.WithAudience(a.audience),
+		jwt.WithAcceptableSkew(a.skew),
+		jwt.WithClock(jwt.ClockFunc(a.now)),
+		jwt.WithRequiredClaim(jwt.IssuedAtKey),
+		jwt.WithRequiredClaim(jwt.ExpirationKey),
+	)
+	if err != nil {
+		return InternalCaller{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if verified.Expiration().Sub(verified.IssuedAt()) > MaxInternalTokenLifetime {
+		return InternalCaller{}, ErrTokenLifetime
+	}
+
+	allowed := make(map[string]bool, len(service.Scopes))
+	for _, scope := range service.Scopes {
+		allowed[scope] = true
+	}
+	caller := InternalCaller{Service: verified.Issuer(), Scopes: map[string]bool{}}
+	if requested, ok := verified.PrivateClaims()[scopeClaim].(string); ok {
+		for _, scope := range strings.Fields(requested) {
+			if allowed[scope] {
+				caller.Scopes[scope] = true
 			}
-		*/
+		}
+	}
+	return caller, nil
+}
+
+// InternalAuthMiddleware rejects requests without a valid internal token and
+// stores the caller in the context for RequireScope.
+func InternalAuthMiddleware(authenticator *InternalAuthenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": ErrMissingToken.Error()})
+			return
+		}
+
+		caller, err := authenticator.Authenticate(token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Set(internalCallerKey, caller)
 		c.Next()
 	}
 }
+
+// GetInternalCaller returns the caller authenticated by InternalAuthMiddleware.
+func GetInternalCaller(c *gin.Context) (InternalCaller, bool) {
+	value, ok := c.Get(internalCallerKey)
+	if !ok {
+		return InternalCaller{}, false
+	}
+	caller, ok := value.(InternalCaller)
+	return caller, ok
+}
+
+// RequireScope only runs handler when the internal caller was granted scope.
+func RequireScope(scope string, handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		caller, ok := GetInternalCaller(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": ErrMissingToken.Error()})
+			return
+		}
+		if !caller.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("missing scope %s", scope)})
+			return
+		}
+		handler(c)
+	}
+}
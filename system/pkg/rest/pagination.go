@@ -0,0 +1,32 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 1000
+)
+
+var ErrInvalidPagination = errors.New("invalid pagination")
+
+// ParsePagination reads the limit and offset query parameters of a listing,
+// defaulting to the first DefaultPageLimit items. A value that is not a
+// number, a limit outside 1..MaxPageLimit or a negative offset is an
+// ErrInvalidPagination, to be answered with 400.
+func ParsePagination(c *gin.Context) (limit, offset int, err error) {
+	limit, err = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(DefaultPageLimit)))
+	if err != nil || limit < 1 || limit > MaxPageLimit {
+		return 0, 0, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidPagination, MaxPageLimit)
+	}
+	offset, err = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		return 0, 0, fmt.Errorf("%w: offset must be a non-negative number", ErrInvalidPagination)
+	}
+	return limit, offset, nil
+}
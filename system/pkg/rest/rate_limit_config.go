@@ -0,0 +1,58 @@
+package rest
+
+import "time"
+
+type RateLimitConfigJson struct {
+	Enabled        bool                              `json:"enabled"`
+	KeyHeader      string                            `json:"key_header"`
+	Buckets        []RateLimitBucketConfigJson       `json:"buckets"`
+	RelyingParties []RateLimitRelyingPartyConfigJson `json:"relying_parties"`
+}
+
+type RateLimitConfig struct {
+	Enabled   bool
+	KeyHeader string
+	Buckets   map[string]RateLimit
+	// RelyingParties maps the key each registered relying party sends in
+	// KeyHeader to its name
+	RelyingParties map[string]string
+}
+
+func (rlcj RateLimitConfigJson) MapToDomain() RateLimitConfig {
+	buckets := make(map[string]RateLimit, len(rlcj.Buckets))
+	for _, b := range rlcj.Buckets {
+		buckets[b.Name] = b.MapToDomain()
+	}
+
+	relyingParties := make(map[string]string, len(rlcj.RelyingParties))
+	for _, rp := range rlcj.RelyingParties {
+		if rp.Key != "" {
+			relyingParties[rp.Key] = rp.Name
+		}
+	}
+
+	return RateLimitConfig{
+		Enabled:        rlcj.Enabled,
+		KeyHeader:      rlcj.KeyHeader,
+		Buckets:        buckets,
+		RelyingParties: relyingParties,
+	}
+}
+
+type RateLimitRelyingPartyConfigJson struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+type RateLimitBucketConfigJson struct {
+	Name          string  `json:"name"`
+	RatePerMinute float64 `json:"rate_per_minute"`
+	Burst         int     `json:"burst"`
+}
+
+func (rlbcj RateLimitBucketConfigJson) MapToDomain() RateLimit {
+	return RateLimit{
+		Rate:  rlbcj.RatePerMinute / time.Minute.Seconds(),
+		Burst: rlbcj.Burst,
+	}
+}
@@ -0,0 +1,172 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimit describes a token bucket: Rate tokens are added per second
+// up to a maximum of Burst tokens.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitStore takes a single token from the bucket identified by key.
+// When the bucket is empty it reports how long the caller has to wait
+// until the next token becomes available.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit RateLimit, now time.Time) (bool, time.Duration, error)
+}
+
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+	// refill is how long the bucket takes to fill up from empty; buckets of
+	// different limits share the store, so each keeps its own
+	refill time.Duration
+}
+
+// InMemoryRateLimitStore keeps buckets in process memory. It is fine for a
+// single replica; use RedisRateLimitStore when the api is scaled out.
+type InMemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	takes   int
+}
+
+const inMemorySweepEvery = 1024
+
+func NewInMemoryRateLimitStore() *InMemoryRateLimitStore {
+	return &InMemoryRateLimitStore{
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+func (s *InMemoryRateLimitStore) Take(_ context.Context, key string, limit RateLimit, now time.Time) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.takes++
+	if s.takes%inMemorySweepEvery == 0 {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(limit.Burst), lastSeen: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.lastSeen).Seconds()*limit.Rate)
+	b.lastSeen = now
+	b.refill = refillDuration(limit)
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+
+	return false, retryAfter(b.tokens, limit), nil
+}
+
+// sweep drops buckets that have been idle long enough to be full again,
+// so the map does not grow with every client that ever called us.
+func (s *InMemoryRateLimitStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if b.refill > 0 && now.Sub(b.lastSeen) > b.refill {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// refillDuration is zero for a limit that never refills, whose buckets are
+// kept.
+func refillDuration(limit RateLimit) time.Duration {
+	if limit.Rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(limit.Burst) / limit.Rate * float64(time.Second))
+}
+
+// RedisEvaluator is the subset of a Redis client needed by RedisRateLimitStore.
+// It matches the shape of EVAL in most clients, e.g. for go-redis:
+//
+//	func (a adapter) Eval(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+//		return a.client.Eval(ctx, script, keys, args...).Result()
+//	}
+type RedisEvaluator interface {
+	Eval(ctx context.Context, script string, keys []string, args ...any) (any, error)
+}
+
+// RedisRateLimitStore keeps buckets in Redis (or any server speaking EVAL),
+// so that every replica shares the same budget.
+type RedisRateLimitStore struct {
+	client RedisEvaluator
+	prefix string
+}
+
+func NewRedisRateLimitStore(client RedisEvaluator, prefix string) *RedisRateLimitStore {
+	return &RedisRateLimitStore{client: client, prefix: prefix}
+}
+
+// Returns {allowed, tokens_left * 1000}. Tokens are scaled so the reply fits into
+// an integer, which is the only numeric type Lua can hand back to the client.
+const redisTokenBucketScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call("HSET", KEYS[1], "tokens", tokens, "ts", now)
+if rate > 0 then
+	redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+end
+return {allowed, math.floor(tokens * 1000)}
+`
+
+func (s *RedisRateLimitStore) Take(ctx context.Context, key string, limit RateLimit, now time.Time) (bool, time.Duration, error) {
+	reply, err := s.client.Eval(
+		ctx,
+		redisTokenBucketScript,
+		[]string{s.prefix + key},
+		limit.Rate,
+		limit.Burst,
+		now.UnixMilli(),
+	)
+	if err != nil {
+		return false, 0, err
+	}
+
+	values, ok := reply.([]any)
+	if !ok || len(values) != 2 {
+		return false, 0, fmt.Errorf("unexpected rate limit reply: %v", reply)
+	}
+	allowed, okAllowed := values[0].(int64)
+	tokensMilli, okTokens := values[1].(int64)
+	if !okAllowed || !okTokens {
+		return false, 0, fmt.Errorf("unexpected rate limit reply: %v", reply)
+	}
+
+	if allowed == 1 {
+		return true, 0, nil
+	}
+	return false, retryAfter(float64(tokensMilli)/1000, limit), nil
+}
+
+func retryAfter(tokens float64, limit RateLimit) time.Duration {
+	if limit.Rate <= 0 {
+		return time.Hour
+	}
+	return time.Duration((1 - tokens) / limit.Rate * float64(time.Second))
+}
@@ -0,0 +1,48 @@
+package rest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"pkg-common/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const RequestIdHeader = "X-Request-ID"
+
+type requestIdKey struct{}
+
+// ContextWithRequestId also adds the id to the fields logged through
+// logger.WithContext.
+func ContextWithRequestId(ctx context.Context, requestId string) context.Context {
+	ctx = logger.ContextWithField(ctx, logger.RequestIdKey, requestId)
+	return context.WithValue(ctx, requestIdKey{}, requestId)
+}
+
+func RequestIdFromContext(ctx context.Context) (string, bool) {
+	requestId, ok := ctx.Value(requestIdKey{}).(string)
+	return requestId, ok && requestId != ""
+}
+
+func NewRequestId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// RequestIdMiddleware keeps the X-Request-ID of the incoming request, or
+// assigns one, and puts it into the request context so outgoing calls made
+// with that context carry it on.
+func RequestIdMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestId := c.GetHeader(RequestIdHeader)
+		if requestId == "" || len(requestId) > 128 {
+			requestId = NewRequestId()
+		}
+
+		c.Request = c.Request.WithContext(ContextWithRequestId(c.Request.Context(), requestId))
+		c.Header(RequestIdHeader, requestId)
+		c.Next()
+	}
+}
@@ -9,6 +9,7 @@ const (
 	POST
 	PUT
 	PATCH
+	DELETE
 )
 
 type Route struct {
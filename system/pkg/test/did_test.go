@@ -0,0 +1,175 @@
+package test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"pkg-common/did"
+	"strings"
+	"testing"
+)
+
+func TestKeyDIDRoundTrip(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	id := did.KeyDID(pub)
+	if !strings.HasPrefix(id, "did:key:z6Mk") {
+		t.Errorf("Expected Ed25519 did:key to start with 'did:key:z6Mk', got '%s'", id)
+	}
+
+	decoded, err := did.PublicKeyFromKeyDID(id)
+	if err != nil {
+		t.Fatalf("PublicKeyFromKeyDID returned error: %v", err)
+	}
+	if !pub.Equal(decoded) {
+		t.Error("Expected decoded key to match the original")
+	}
+}
+
+func TestKeyDIDRejectsOtherMethods(t *testing.T) {
+	if _, err := did.PublicKeyFromKeyDID("did:web:example.com"); !errors.Is(err, did.ErrUnsupportedDID) {
+		t.Errorf("Expected ErrUnsupportedDID, got %v", err)
+	}
+}
+
+func TestWebDIDEncodesPortAndPath(t *testing.T) {
+	tests := []struct {
+		baseUrl  string
+		expected string
+		host     string
+		path     string
+	}{
+		{"https://example.com", "did:web:example.com", "example.com", "/.well-known/did.json"},
+		{"http://localhost:8080", "did:web:localhost%3A8080", "localhost:8080", "/.well-known/did.json"},
+		{"https://example.com/issuers/1", "did:web:example.com:issuers:1", "example.com", "/issuers/1/did.json"},
+	}
+
+	for _, tt := range tests {
+		id, err := did.WebDID(tt.baseUrl)
+		if err != nil {
+			t.Fatalf("WebDID(%s) returned error: %v", tt.baseUrl, err)
+		}
+		if id != tt.expected {
+			t.Errorf("WebDID(%s) = '%s', expected '%s'", tt.baseUrl, id, tt.expected)
+		}
+
+		host, path, err := did.WebDocumentLocation(id)
+		if err != nil {
+			t.Fatalf("WebDocumentLocation(%s) returned error: %v", id, err)
+		}
+		if host != tt.host || path != tt.path {
+			t.Errorf("WebDocumentLocation(%s) = '%s' '%s', expected '%s' '%s'", id, host, path, tt.host, tt.path)
+		}
+	}
+}
+
+func TestResolverResolvesKeyDID(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	id := did.KeyDID(pub)
+
+	doc, err := did.NewResolver().Resolve(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	key, err := doc.PublicKey("")
+	if err != nil {
+		t.Fatalf("PublicKey returned error: %v", err)
+	}
+	if !pub.Equal(key) {
+		t.Error("Expected resolved key to match the did:key")
+	}
+}
+
+func TestSignAndVerifyWithWebDID(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+
+	var doc did.Document
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/did.json" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(doc)
+	}))
+	defer server.Close()
+
+	id, _ := did.WebDID("http://verifier.local")
+	doc = did.NewDocument(id, "key-1", pub)
+	resolver := did.NewResolver(did.WithWebBaseUrl("verifier.local", server.URL))
+
+	token, err := did.NewSigner(priv, id+"#key-1").Sign([]byte(`{"ok":true}`), "receipt+jwt")
+	if err != nil {
+		t.Fatalf("Sign returned error: %v", err)
+	}
+
+	payload, kid, err := did.Verify(context.Background(), resolver, token)
+	if err != nil {
+		t.Fatalf("Verify returned error: %v", err)
+	}
+	if string(payload) != `{"ok":true}` || kid != id+"#key-1" {
+		t.Errorf("Unexpected payload '%s' or kid '%s'", payload, kid)
+	}
+
+	tampered := token[:len(token)-4] + "AAAA"
+	if _, _, err := did.Verify(context.Background(), resolver, tampered); err == nil {
+		t.Error("Expected tampered token to fail verification")
+	}
+}
+
+func TestVerifyRejectsUnknownKey(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	other, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	token, _ := did.NewSigner(priv, did.KeyDID(other)).Sign([]byte("x"), "")
+	if _, _, err := did.Verify(context.Background(), did.NewResolver(), token); !errors.Is(err, did.ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature, got %v", err)
+	}
+}
+
+func TestResolverRejectsWebHostsNotAllowed(t *testing.T) {
+	fetched := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched = true
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	resolver := did.NewResolver(
+		did.WithWebBaseUrl("verifier.local", server.URL),
+		did.WithAllowedWebHosts("issuer.example"),
+	)
+	if _, err := resolver.Resolve(context.Background(), "did:web:internal.example"); !errors.Is(err, did.ErrWebHostNotAllowed) {
+		t.Errorf("Expected ErrWebHostNotAllowed, got %v", err)
+	}
+	if _, err := resolver.Resolve(context.Background(), "did:web:verifier.local"); errors.Is(err, did.ErrWebHostNotAllowed) || !fetched {
+		t.Errorf("Expected a host with a base URL to be fetched, got %v", err)
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+
+	for name, encoded := range map[string]string{
+		"seed":        base64.StdEncoding.EncodeToString(priv.Seed()),
+		"private key": base64.StdEncoding.EncodeToString(priv),
+	} {
+		key, err := did.ParsePrivateKey(encoded)
+		if err != nil || !key.Equal(priv) {
+			t.Errorf("Expected the %s to decode to the same key, got %v", name, err)
+		}
+	}
+
+	if key, err := did.ParsePrivateKey(""); key != nil || err != nil {
+		t.Errorf("Expected no key, got %v, %v", key, err)
+	}
+	for _, encoded := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		if _, err := did.ParsePrivateKey(encoded); !errors.Is(err, did.ErrInvalidPrivateKey) {
+			t.Errorf("Expected ErrInvalidPrivateKey for %q, got %v", encoded, err)
+		}
+	}
+}
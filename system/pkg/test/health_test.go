@@ -0,0 +1,114 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"pkg-common/health"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newHealthRouter(checker *health.Checker) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/healthz", checker.LivenessHandler)
+	router.GET("/readyz", checker.ReadinessHandler)
+	return router
+}
+
+func getHealthReport(t *testing.T, router *gin.Engine, path string) (int, health.Report) {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var report health.Report
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to decode health report: %v", err)
+	}
+	return w.Code, report
+}
+
+func TestHealthReadinessAllChecksPass(t *testing.T) {
+	checker := health.NewChecker(
+		health.NewCheck("database", time.Second, func(ctx context.Context) error { return nil }),
+		health.NewCheck("rabbitmq", time.Second, func(ctx context.Context) error { return nil }),
+	)
+
+	code, report := getHealthReport(t, newHealthRouter(checker), "/readyz")
+	if code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if report.Status != health.StatusOk {
+		t.Errorf("Expected status ok, got %s", report.Status)
+	}
+	if len(report.Checks) != 2 {
+		t.Errorf("Expected 2 check results, got %d", len(report.Checks))
+	}
+}
+
+func TestHealthReadinessFailingCheck(t *testing.T) {
+	checker := health.NewChecker(
+		health.NewCheck("database", time.Second, func(ctx context.Context) error { return nil }),
+		health.NewCheck("solana_rpc", time.Second, func(ctx context.Context) error {
+			return errors.New("node is behind")
+		}),
+	)
+
+	code, report := getHealthReport(t, newHealthRouter(checker), "/readyz")
+	if code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503, got %d", code)
+	}
+	if report.Status != health.StatusUnavailable {
+		t.Errorf("Expected status unavailable, got %s", report.Status)
+	}
+	if report.Checks["solana_rpc"].Error != "node is behind" {
+		t.Errorf("Expected failing check error, got '%s'", report.Checks["solana_rpc"].Error)
+	}
+	if report.Checks["database"].Status != health.StatusOk {
+		t.Errorf("Expected passing check to stay ok, got %s", report.Checks["database"].Status)
+	}
+}
+
+func TestHealthCheckTimeout(t *testing.T) {
+	checker := health.NewChecker(
+		health.NewCheck("artifact_store", 20*time.Millisecond, func(ctx context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		}),
+	)
+
+	start := time.Now()
+	report := checker.Run(context.Background())
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected hanging check to be cut off by its timeout, took %v", elapsed)
+	}
+	if report.Checks["artifact_store"].Status != health.StatusUnavailable {
+		t.Errorf("Expected timed out check to be unavailable, got %s", report.Checks["artifact_store"].Status)
+	}
+}
+
+func TestHealthShuttingDown(t *testing.T) {
+	checker := health.NewChecker(
+		health.NewCheck("database", time.Second, func(ctx context.Context) error { return nil }),
+	)
+	router := newHealthRouter(checker)
+
+	checker.MarkShuttingDown()
+
+	code, report := getHealthReport(t, router, "/readyz")
+	if code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 while shutting down, got %d", code)
+	}
+	if report.Status != health.StatusShuttingDown {
+		t.Errorf("Expected status shutting_down, got %s", report.Status)
+	}
+
+	code, _ = getHealthReport(t, router, "/healthz")
+	if code != http.StatusOK {
+		t.Errorf("Expected liveness to stay 200 while shutting down, got %d", code)
+	}
+}
@@ -0,0 +1,197 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	httpclient "pkg-common/http_client"
+	"pkg-common/rest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type echoResponse struct {
+	Name string `json:"name"`
+}
+
+func testClientConfig(baseUrl string) httpclient.Config {
+	return httpclient.Config{
+		BaseUrl:          baseUrl,
+		Timeout:          time.Second,
+		MaxRetries:       2,
+		RetryBaseDelay:   time.Millisecond,
+		RetryMaxDelay:    2 * time.Millisecond,
+		BreakerThreshold: 3,
+		BreakerCooldown:  time.Minute,
+	}
+}
+
+func TestHttpClientConfigConvertToDomain(t *testing.T) {
+	config := httpclient.ConfigJson{BaseUrl: "http://api:8080", TimeoutSeconds: 5}.MapToDomain()
+	if config.Timeout != 5*time.Second || config.MaxRetries != httpclient.DefaultMaxRetries {
+		t.Errorf("Unexpected config: %+v", config)
+	}
+	if disabled := (httpclient.ConfigJson{MaxRetries: -1}).MapToDomain(); disabled.MaxRetries != 0 {
+		t.Errorf("Expected negative max_retries to disable retries, got %d", disabled.MaxRetries)
+	}
+}
+
+func TestHttpClientTypedCallWithAuthAndRequestId(t *testing.T) {
+	authConf := rest.InternalAuthConfig{
+		Service: "api",
+		TrustedServices: map[string]rest.InternalTrustedService{
+			"wallet": {Secret: []byte("wallet-secret"), Scopes: []string{"echo"}},
+		},
+	}
+	authenticator := rest.NewInternalAuthenticator(authConf)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller, err := authenticator.Authenticate(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if err != nil || !caller.HasScope("echo") {
+			t.Errorf("Expected authenticated call with echo scope, got %v", err)
+		}
+		if r.Header.Get(rest.RequestIdHeader) != "req-1" {
+			t.Errorf("Expected request ID to be forwarded, got %q", r.Header.Get(rest.RequestIdHeader))
+		}
+		if r.URL.Path != "/v1/internal/echo" || r.URL.Query().Get("name") != "x" {
+			t.Errorf("Unexpected URL %s", r.URL)
+		}
+		w.Write([]byte(`{"name":"x"}`))
+	}))
+	defer server.Close()
+
+	tokens := rest.NewInternalTokenIssuer(rest.InternalAuthConfig{Service: "wallet", Secret: []byte("wallet-secret")})
+	client := httpclient.NewClient(testClientConfig(server.URL+"/v1/internal/"), httpclient.WithInternalAuth(tokens, "api"))
+
+	ctx := rest.ContextWithRequestId(context.Background(), "req-1")
+	resp, err := httpclient.Get[echoResponse](ctx, client, "echo",
+		httpclient.WithScopes("echo"),
+		httpclient.WithQuery(map[string][]string{"name": {"x"}}))
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	if resp.Name != "x" {
+		t.Errorf("Expected decoded response, got %+v", resp)
+	}
+}
+
+func TestHttpClientRetriesIdempotentRequests(t *testing.T) {
+	var calls atomic.Int32
+	var requestIds []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestIds = append(requestIds, r.Header.Get(rest.RequestIdHeader))
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"name":"ok"}`))
+	}))
+	defer server.Close()
+
+	client := httpclient.NewClient(testClientConfig(server.URL))
+	resp, err := httpclient.Get[echoResponse](context.Background(), client, "/")
+	if err != nil || resp.Name != "ok" {
+		t.Fatalf("Expected success after retries, got %+v, %v", resp, err)
+	}
+	if calls.Load() != 3 || requestIds[0] == "" || requestIds[0] != requestIds[2] {
+		t.Errorf("Expected 3 attempts sharing one request ID, got %d %v", calls.Load(), requestIds)
+	}
+
+	calls.Store(0)
+	_, err = httpclient.Post[echoResponse](context.Background(), client, "/", map[string]string{"a": "b"})
+	var httpErr *httpclient.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 HTTPError, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected POST not to be retried, got %d attempts", calls.Load())
+	}
+}
+
+func TestHttpClientDoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"Account not found"}`))
+	}))
+	defer server.Close()
+
+	client := httpclient.NewClient(testClientConfig(server.URL))
+	_, err := httpclient.Delete[struct{}](context.Background(), client, "accounts/x")
+
+	var httpErr *httpclient.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Message() != "Account not found" {
+		t.Errorf("Expected 404 with error message, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected a single attempt, got %d", calls.Load())
+	}
+}
+
+func TestHttpClientCircuitBreaker(t *testing.T) {
+	var calls atomic.Int32
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	now := time.Unix(1000, 0)
+	config := testClientConfig(server.URL)
+	config.MaxRetries = 0
+	client := httpclient.NewClient(config, httpclient.WithClock(func() time.Time { return now }))
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, _ = httpclient.Get[struct{}](ctx, client, "/")
+	}
+	if _, err := httpclient.Get[struct{}](ctx, client, "/"); !errors.Is(err, httpclient.ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen after threshold, got %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("Expected open breaker to skip the call, got %d calls", calls.Load())
+	}
+
+	// After the cooldown a probe goes through and closes the breaker
+	now = now.Add(time.Minute)
+	healthy.Store(true)
+	if _, err := httpclient.Get[struct{}](ctx, client, "/"); err != nil {
+		t.Fatalf("Expected probe to succeed, got %v", err)
+	}
+	if _, err := httpclient.Get[struct{}](ctx, client, "/"); err != nil {
+		t.Errorf("Expected closed breaker, got %v", err)
+	}
+}
+
+func TestRequestIdMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(rest.RequestIdMiddleware())
+	router.GET("/", func(c *gin.Context) {
+		requestId, _ := rest.RequestIdFromContext(c.Request.Context())
+		c.String(http.StatusOK, requestId)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(rest.RequestIdHeader, "incoming")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Body.String() != "incoming" || w.Header().Get(rest.RequestIdHeader) != "incoming" {
+		t.Errorf("Expected incoming request ID to be kept, got %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Body.String() == "" {
+		t.Error("Expected a request ID to be assigned")
+	}
+}
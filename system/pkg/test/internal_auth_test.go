@@ -0,0 +1,139 @@
+package test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"pkg-common/rest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func internalAuthConfigs() (caller, callee rest.InternalAuthConfig) {
+	caller = rest.InternalAuthConfigJson{
+		Service: "api",
+		Secret:  "api-secret",
+	}.MapToDomain()
+	callee = rest.InternalAuthConfigJson{
+		Service:          "blockchain-client",
+		ClockSkewSeconds: 10,
+		TrustedServices: []rest.InternalTrustedServiceJson{
+			{Service: "api", Secret: "api-secret", Scopes: []string{"proofs:verify"}},
+		},
+	}.MapToDomain()
+	return caller, callee
+}
+
+func TestInternalAuthConfigConvertToDomain(t *testing.T) {
+	_, callee := internalAuthConfigs()
+
+	if callee.TokenTTL != rest.DefaultInternalTokenTTL || callee.ClockSkew != 10*time.Second {
+		t.Errorf("Unexpected durations: ttl %s, skew %s", callee.TokenTTL, callee.ClockSkew)
+	}
+	if string(callee.TrustedServices["api"].Secret) != "api-secret" {
+		t.Errorf("Expected trusted service key from config, got '%s'", callee.TrustedServices["api"].Secret)
+	}
+}
+
+func TestInternalAuthConfigMissingSecrets(t *testing.T) {
+	caller, callee := internalAuthConfigs()
+	if missing := caller.MissingSecrets(); len(missing) != 0 {
+		t.Errorf("Expected no missing secrets, got %v", missing)
+	}
+
+	callee.TrustedServices["operator"] = rest.InternalTrustedService{Scopes: []string{"failures:read"}}
+	missing := callee.MissingSecrets()
+	expected := []string{"internal_auth.secret", "internal_auth.trusted_services[operator].secret"}
+	if !slices.Equal(missing, expected) {
+		t.Errorf("Expected %v, got %v", expected, missing)
+	}
+}
+
+func TestInternalAuthenticate(t *testing.T) {
+	callerConf, calleeConf := internalAuthConfigs()
+	now := time.Unix(1_700_000_000, 0)
+	issuer := rest.NewInternalTokenIssuer(callerConf).WithClock(func() time.Time { return now })
+	authenticator := rest.NewInternalAuthenticator(calleeConf).WithClock(func() time.Time { return now })
+
+	token, err := issuer.Token("blockchain-client", "proofs:verify", "accounts:close")
+	if err != nil {
+		t.Fatalf("Token returned error: %v", err)
+	}
+	caller, err := authenticator.Authenticate(token)
+	if err != nil {
+		t.Fatalf("Authenticate returned error: %v", err)
+	}
+	if caller.Service != "api" || !caller.HasScope("proofs:verify") {
+		t.Errorf("Expected api caller with proofs:verify, got %+v", caller)
+	}
+	if caller.HasScope("accounts:close") {
+		t.Error("Expected scopes not allowed in config to be dropped")
+	}
+
+	// Within the clock skew tolerance
+	skewed := rest.NewInternalAuthenticator(calleeConf).WithClock(func() time.Time { return now.Add(-5 * time.Second) })
+	if _, err := skewed.Authenticate(token); err != nil {
+		t.Errorf("Expected token to be accepted within skew, got %v", err)
+	}
+
+	expired := rest.NewInternalAuthenticator(calleeConf).WithClock(func() time.Time { return now.Add(2 * time.Minute) })
+	if _, err := expired.Authenticate(token); !errors.Is(err, rest.ErrInvalidToken) {
+		t.Errorf("Expected expired token to be rejected, got %v", err)
+	}
+
+	wrongAudience, _ := issuer.Token("api")
+	if _, err := authenticator.Authenticate(wrongAudience); !errors.Is(err, rest.ErrInvalidToken) {
+		t.Errorf("Expected token for another audience to be rejected, got %v", err)
+	}
+
+	forgedConf := callerConf
+	forgedConf.Secret = []byte("guessed")
+	forged, _ := rest.NewInternalTokenIssuer(forgedConf).WithClock(func() time.Time { return now }).Token("blockchain-client")
+	if _, err := authenticator.Authenticate(forged); !errors.Is(err, rest.ErrInvalidToken) {
+		t.Errorf("Expected token with a wrong key to be rejected, got %v", err)
+	}
+
+	unknownConf := callerConf
+	unknownConf.Service = "wallet"
+	unknown, _ := rest.NewInternalTokenIssuer(unknownConf).Token("blockchain-client")
+	if _, err := authenticator.Authenticate(unknown); !errors.Is(err, rest.ErrUnknownService) {
+		t.Errorf("Expected ErrUnknownService, got %v", err)
+	}
+}
+
+func TestInternalAuthMiddlewareScopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	callerConf, calleeConf := internalAuthConfigs()
+	issuer := rest.NewInternalTokenIssuer(callerConf)
+
+	router := gin.New()
+	group := router.Group("/v1/internal", rest.InternalAuthMiddleware(rest.NewInternalAuthenticator(calleeConf)))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	group.GET("/verify", rest.RequireScope("proofs:verify", ok))
+	group.DELETE("/accounts/x", rest.RequireScope("accounts:close", ok))
+
+	call := func(method, path string, authorize bool) int {
+		req := httptest.NewRequest(method, path, nil)
+		if authorize {
+			if err := issuer.Authorize(req, "blockchain-client", "proofs:verify", "accounts:close"); err != nil {
+				t.Fatalf("Authorize returned error: %v", err)
+			}
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := call(http.MethodGet, "/v1/internal/verify", false); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", code)
+	}
+	if code := call(http.MethodGet, "/v1/internal/verify", true); code != http.StatusOK {
+		t.Errorf("Expected 200 with scope, got %d", code)
+	}
+	if code := call(http.MethodDelete, "/v1/internal/accounts/x", true); code != http.StatusForbidden {
+		t.Errorf("Expected 403 without scope, got %d", code)
+	}
+}
@@ -0,0 +1,209 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"pkg-common/logger"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type recordingWriter struct {
+	mu      sync.Mutex
+	batches [][]logger.Entry
+	err     error
+	block   chan struct{} // holds write until closed
+	entered chan struct{}
+}
+
+func (w *recordingWriter) write(batch []logger.Entry) error {
+	if w.block != nil {
+		w.entered <- struct{}{}
+		<-w.block
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.batches = append(w.batches, append([]logger.Entry(nil), batch...))
+	return w.err
+}
+
+func (w *recordingWriter) messages() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var messages []string
+	for _, batch := range w.batches {
+		for _, entry := range batch {
+			messages = append(messages, entry.Message)
+		}
+	}
+	return messages
+}
+
+func (w *recordingWriter) batchCount() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.batches)
+}
+
+func closeSink(t *testing.T, sink *logger.BufferedSink) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := sink.Close(ctx); err != nil {
+		t.Fatalf("Sink did not close: %v", err)
+	}
+}
+
+func TestLogSinkConfigConvertToDomain(t *testing.T) {
+	config := logger.LogSinkConfigJson{}.MapToDomain()
+	if config.QueueSize != logger.DefaultSinkQueueSize || config.BatchSize != logger.DefaultSinkBatchSize ||
+		config.FlushInterval != logger.DefaultSinkFlushInterval || config.DropPolicy != logger.DropNewest {
+		t.Errorf("Expected defaults, got %+v", config)
+	}
+
+	config = logger.LogSinkConfigJson{MinLevel: 2, QueueSize: 10, BatchSize: 5, FlushIntervalMs: 250, DropPolicy: logger.DropOldest}.MapToDomain()
+	if config.MinLevel != zerolog.WarnLevel || config.FlushInterval != 250*time.Millisecond || config.DropPolicy != logger.DropOldest {
+		t.Errorf("Unexpected config: %+v", config)
+	}
+}
+
+func TestBufferedSinkFlushesFullBatches(t *testing.T) {
+	writer := &recordingWriter{}
+	sink := logger.NewBufferedSink("test", writer.write, logger.LogSinkConfig{BatchSize: 2, FlushInterval: time.Hour})
+
+	l := logger.New().WithOutput(&bytes.Buffer{})
+	logger.AddSinkToLoggerInstance(l, sink.Sink())
+	l.Info("one")
+	l.Info("two")
+	l.Info("three")
+
+	deadline := time.Now().Add(time.Second)
+	for writer.batchCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := writer.messages(); len(got) != 2 {
+		t.Errorf("Expected a batch of two before the interval, got %v", got)
+	}
+
+	closeSink(t, sink)
+	if got := writer.messages(); len(got) != 3 || got[2] != "three" {
+		t.Errorf("Expected close to flush the rest, got %v", got)
+	}
+}
+
+func TestBufferedSinkFlushesOnInterval(t *testing.T) {
+	writer := &recordingWriter{}
+	sink := logger.NewBufferedSink("test", writer.write, logger.LogSinkConfig{BatchSize: 100, FlushInterval: 10 * time.Millisecond})
+	defer closeSink(t, sink)
+
+	sink.Sink()(logger.Entry{Level: zerolog.InfoLevel, Message: "lonely"})
+
+	deadline := time.Now().Add(time.Second)
+	for writer.batchCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := writer.messages(); len(got) != 1 {
+		t.Errorf("Expected the entry to be flushed on the interval, got %v", got)
+	}
+}
+
+func TestBufferedSinkDropPolicies(t *testing.T) {
+	tests := []struct {
+		policy   string
+		expected []string
+	}{
+		{logger.DropNewest, []string{"first", "second", "third"}},
+		{logger.DropOldest, []string{"first", "third", "fourth"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			writer := &recordingWriter{block: make(chan struct{}), entered: make(chan struct{}, 4)}
+			sink := logger.NewBufferedSink("test", writer.write, logger.LogSinkConfig{QueueSize: 2, BatchSize: 1, FlushInterval: time.Hour, DropPolicy: tt.policy})
+			write := sink.Sink()
+
+			// "first" is taken by the flush, which blocks until released
+			write(logger.Entry{Level: zerolog.InfoLevel, Message: "first"})
+			<-writer.entered
+			for _, msg := range []string{"second", "third", "fourth"} {
+				write(logger.Entry{Level: zerolog.InfoLevel, Message: msg})
+			}
+
+			if sink.Dropped() != 1 {
+				t.Errorf("Expected one dropped entry, got %d", sink.Dropped())
+			}
+			close(writer.block)
+			closeSink(t, sink)
+
+			got := writer.messages()
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected, got)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestBufferedSinkFiltersLevel(t *testing.T) {
+	writer := &recordingWriter{}
+	sink := logger.NewBufferedSink("test", writer.write, logger.LogSinkConfig{MinLevel: zerolog.WarnLevel})
+
+	l := logger.New().WithOutput(&bytes.Buffer{})
+	logger.AddSinkToLoggerInstance(l, sink.Sink())
+	l.Info("routine")
+	l.Warn("suspicious")
+	closeSink(t, sink)
+
+	if got := writer.messages(); len(got) != 1 || got[0] != "suspicious" {
+		t.Errorf("Expected only the warning, got %v", got)
+	}
+}
+
+func TestLoggerWithMultipleSinks(t *testing.T) {
+	var first, second []string
+	l := logger.New().WithOutput(&bytes.Buffer{})
+	logger.AddSinkToLoggerInstance(l, func(e logger.Entry) { first = append(first, e.Message) })
+	logger.AddSinkToLoggerInstance(l, logger.FilterLevel(zerolog.ErrorLevel, func(e logger.Entry) { second = append(second, e.Message) }))
+
+	l.Info("info")
+	l.Error(errors.New("boom"), "error")
+
+	if len(first) != 2 || len(second) != 1 {
+		t.Errorf("Expected all entries in the first sink and the error in the second, got %v and %v", first, second)
+	}
+}
+
+// A failing destination reports through a logger without sinks, so its own
+// errors never come back to it.
+func TestBufferedSinkFailuresDoNotReenter(t *testing.T) {
+	writer := &recordingWriter{err: errors.New("broker unavailable")}
+	sink := logger.NewBufferedSink("test", writer.write, logger.LogSinkConfig{BatchSize: 1, FlushInterval: 5 * time.Millisecond})
+
+	l := logger.New().WithOutput(&bytes.Buffer{})
+	logger.AddSinkToLoggerInstance(l, sink.Sink())
+	l.Info("lost")
+
+	time.Sleep(50 * time.Millisecond)
+	closeSink(t, sink)
+
+	if got := writer.messages(); len(got) != 1 {
+		t.Errorf("Expected only the original entry to reach the writer, got %v", got)
+	}
+	if sink.Dropped() != 1 {
+		t.Errorf("Expected the failed entry to be counted, got %d", sink.Dropped())
+	}
+
+	sink.Sink()(logger.Entry{Level: zerolog.InfoLevel, Message: "after close"})
+	if sink.Dropped() != 2 {
+		t.Errorf("Expected entries after close to be dropped, got %d", sink.Dropped())
+	}
+}
@@ -95,12 +95,73 @@ func TestLoggerWithContext(t *testing.T) {
 }
 
 func TestLoggerWith(t *testing.T) {
-	l := logger.New()
-	context := l.With()
-	// Test that the context is not nil and can be used
-	contextLogger := context.Str("test", "value").Logger()
-	if &contextLogger == nil {
-		t.Error("Expected context to create a valid logger")
+	var buf bytes.Buffer
+	parent := logger.New().WithOutput(&buf)
+	child := parent.With("component", "outbox").With("attempt", 2)
+
+	child.Info("relayed")
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Log output is not valid JSON: %v", err)
+	}
+	if entry["component"] != "outbox" || entry["attempt"] != float64(2) {
+		t.Errorf("Expected child fields on the entry, got %v", entry)
+	}
+
+	buf.Reset()
+	parent.Info("plain")
+	if strings.Contains(buf.String(), "component") {
+		t.Errorf("Expected parent logger to be unaffected, got %s", buf.String())
+	}
+}
+
+func TestLoggerWithContextFields(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.New().WithOutput(&buf)
+
+	ctx := logger.ContextWithField(context.Background(), logger.RequestIdKey, "req-1")
+	ctx = logger.ContextWithField(ctx, logger.IdentityIdKey, "identity-1")
+	l.WithContext(ctx).Info("handled")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Log output is not valid JSON: %v", err)
+	}
+	if entry[logger.RequestIdKey] != "req-1" || entry[logger.IdentityIdKey] != "identity-1" {
+		t.Errorf("Expected context fields on the entry, got %v", entry)
+	}
+	if _, ok := entry[logger.TraceIdKey]; ok {
+		t.Error("Expected no trace id without an active span")
+	}
+}
+
+func TestLoggerSinkReceivesFields(t *testing.T) {
+	var entries []logger.Entry
+	parent := logger.New().WithOutput(&bytes.Buffer{}).With(logger.ServiceKey, "api")
+	child := parent.With(logger.IdentityIdKey, "identity-1")
+
+	// Added after the child was derived: children share the sink of their parent
+	logger.AddSinkToLoggerInstance(parent, func(entry logger.Entry) {
+		entries = append(entries, entry)
+	})
+	child.Error(errors.New("database unavailable"), "could not save proof")
+
+	if len(entries) != 1 {
+		t.Fatalf("Expected one entry in the sink, got %d", len(entries))
+	}
+	entry := entries[0]
+	if entry.Level != zerolog.ErrorLevel || entry.Message != "could not save proof" {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	expected := map[string]any{
+		logger.ServiceKey:    "api",
+		logger.IdentityIdKey: "identity-1",
+		logger.ErrorKey:      "database unavailable",
+	}
+	for key, value := range expected {
+		if entry.Fields[key] != value {
+			t.Errorf("Expected field %s=%v, got %v", key, value, entry.Fields[key])
+		}
 	}
 }
 
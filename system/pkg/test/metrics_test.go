@@ -0,0 +1,54 @@
+package test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"pkg-common/metrics"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestGinMiddlewareRecordsRouteTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(metrics.GinMiddleware())
+	router.GET("/v1/identity/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/identity/123", nil))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected /metrics to return 200, got %d", w.Code)
+	}
+
+	body := w.Body.String()
+	if !strings.Contains(body, `route="/v1/identity/:id"`) {
+		t.Error("Expected latency histogram labelled with route template")
+	}
+	if strings.Contains(body, `route="/v1/identity/123"`) {
+		t.Error("Raw paths must not be used as route labels")
+	}
+}
+
+func TestRabbitmqCounters(t *testing.T) {
+	before := testutil.ToFloat64(metrics.RabbitmqMessages.WithLabelValues("publish", "test-exchange", "failure"))
+
+	metrics.IncRabbitmqPublished("test-exchange", errors.New("channel closed"))
+	metrics.IncRabbitmqPublished("test-exchange", nil)
+
+	after := testutil.ToFloat64(metrics.RabbitmqMessages.WithLabelValues("publish", "test-exchange", "failure"))
+	if after-before != 1 {
+		t.Errorf("Expected failure counter to increase by 1, got %f", after-before)
+	}
+	if testutil.ToFloat64(metrics.RabbitmqMessages.WithLabelValues("publish", "test-exchange", "success")) < 1 {
+		t.Error("Expected success counter to be incremented")
+	}
+}
@@ -0,0 +1,47 @@
+package test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"pkg-common/rest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParsePagination(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		query         string
+		limit, offset int
+		valid         bool
+	}{
+		{"", rest.DefaultPageLimit, 0, true},
+		{"?limit=10&offset=20", 10, 20, true},
+		{"?limit=1000", 1000, 0, true},
+		{"?limit=abc", 0, 0, false},
+		{"?limit=0", 0, 0, false},
+		{"?limit=1001", 0, 0, false},
+		{"?offset=-1", 0, 0, false},
+		{"?offset=1.5", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/items"+tt.query, nil)
+
+			limit, offset, err := rest.ParsePagination(c)
+			if !tt.valid {
+				if !errors.Is(err, rest.ErrInvalidPagination) {
+					t.Errorf("Expected ErrInvalidPagination, got %v", err)
+				}
+				return
+			}
+			if err != nil || limit != tt.limit || offset != tt.offset {
+				t.Errorf("Expected %d/%d, got %d/%d (%v)", tt.limit, tt.offset, limit, offset, err)
+			}
+		})
+	}
+}
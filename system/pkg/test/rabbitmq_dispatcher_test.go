@@ -0,0 +1,199 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"pkg-common/rabbitmq"
+	"pkg-common/utilities"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type mockOrdered struct {
+	MockSerializable
+	key string
+}
+
+func (m mockOrdered) OrderingKey() string { return m.key }
+
+type mockCorrelated struct {
+	MockSerializable
+	correlationId string
+}
+
+func (m mockCorrelated) CorrelationId() string { return m.correlationId }
+
+func newDispatchBroker(t *testing.T) (*rabbitmq.MemoryBroker, rabbitmq.Broker, rabbitmq.IRabbitmqPublisher) {
+	t.Helper()
+	broker := rabbitmq.NewMemoryBroker()
+	if err := broker.DeclareExchange("work", amqp.ExchangeDirect); err != nil {
+		t.Fatal(err)
+	}
+	broker.DeclareQueue("tasks")
+	if err := broker.BindQueue("tasks", "work", "task"); err != nil {
+		t.Fatal(err)
+	}
+	conn := broker.Connect("test")
+	publisher, _ := conn.NewPublisher(rabbitmq.RabbitmqPublishersConfig{Exchange: "work", RoutingKey: "task"})
+	return broker, conn, publisher
+}
+
+func waitFor(t *testing.T, what string, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Messages of one lane are handled one at a time in publish order, whether
+// the lane comes from the ordering key or, without it, the correlation id.
+func TestDispatcherKeepsLaneOrder(t *testing.T) {
+	const perLane = 20
+	lanes := map[string]func(seq int) utilities.Serializable{
+		"by-key": func(seq int) utilities.Serializable {
+			return mockOrdered{MockSerializable{data: fmt.Sprintf(`{"lane":"by-key","seq":%d}`, seq)}, "identity-1"}
+		},
+		"by-correlation": func(seq int) utilities.Serializable {
+			return mockCorrelated{MockSerializable{data: fmt.Sprintf(`{"lane":"by-correlation","seq":%d}`, seq)}, "event-1"}
+		},
+	}
+
+	_, conn, publisher := newDispatchBroker(t)
+	var mu sync.Mutex
+	seen := map[string][]int{}
+	active := map[string]int{}
+	startMemoryConsumer(t, conn, rabbitmq.RabbitmqConsumerConfig{QueueName: "tasks", Concurrency: 4, OrderedByKey: true, RetryPolicy: rabbitmq.DefaultRetryPolicy()},
+		rabbitmq.NewRouter().On("test.message", func(_ context.Context, msg rabbitmq.Message) rabbitmq.Outcome {
+			var body struct {
+				Lane string `json:"lane"`
+				Seq  int    `json:"seq"`
+			}
+			_ = msg.Decode(&body)
+
+			mu.Lock()
+			active[body.Lane]++
+			if active[body.Lane] > 1 {
+				t.Errorf("Lane %s handled concurrently", body.Lane)
+			}
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			active[body.Lane]--
+			seen[body.Lane] = append(seen[body.Lane], body.Seq)
+			mu.Unlock()
+			return rabbitmq.Ack()
+		}).Handle)
+
+	for seq := 0; seq < perLane; seq++ {
+		for _, message := range lanes {
+			if err := publisher.PublishConfirmed(context.Background(), message(seq)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	waitFor(t, "every message", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(seen["by-key"]) == perLane && len(seen["by-correlation"]) == perLane
+	})
+	for lane, order := range seen {
+		for i, seq := range order {
+			if seq != i {
+				t.Errorf("Expected lane %s in publish order, got %v", lane, order)
+				break
+			}
+		}
+	}
+}
+
+func TestDispatcherBoundsConcurrency(t *testing.T) {
+	const workers = 3
+	_, conn, publisher := newDispatchBroker(t)
+
+	var active, peak, handled atomic.Int32
+	release := make(chan struct{})
+	startMemoryConsumer(t, conn, rabbitmq.RabbitmqConsumerConfig{QueueName: "tasks", Concurrency: workers, RetryPolicy: rabbitmq.DefaultRetryPolicy()},
+		rabbitmq.NewRouter().On("test.message", func(context.Context, rabbitmq.Message) rabbitmq.Outcome {
+			now := active.Add(1)
+			for {
+				old := peak.Load()
+				if now <= old || peak.CompareAndSwap(old, now) {
+					break
+				}
+			}
+			<-release
+			active.Add(-1)
+			handled.Add(1)
+			return rabbitmq.Ack()
+		}).Handle)
+
+	for i := 0; i < 10; i++ {
+		if err := publisher.PublishConfirmed(context.Background(), MockSerializable{data: `{}`}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	waitFor(t, "the workers to fill up", func() bool { return active.Load() == workers })
+	time.Sleep(20 * time.Millisecond)
+	if got := peak.Load(); got != workers {
+		t.Errorf("Expected at most %d deliveries in flight, got %d", workers, got)
+	}
+
+	close(release)
+	waitFor(t, "every message", func() bool { return handled.Load() == 10 })
+}
+
+// Stopping the consumer lets the deliveries in flight finish and settle
+// before Drain returns.
+func TestDispatcherDrainsInFlightDeliveries(t *testing.T) {
+	broker, conn, publisher := newDispatchBroker(t)
+	consumer, err := conn.NewConsumer(rabbitmq.RabbitmqConsumerConfig{QueueName: "tasks", Concurrency: 2, RetryPolicy: rabbitmq.DefaultRetryPolicy()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var started, handled atomic.Int32
+	release := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go consumer.StartConsuming(ctx, rabbitmq.NewRouter().On("test.message", func(context.Context, rabbitmq.Message) rabbitmq.Outcome {
+		started.Add(1)
+		<-release
+		handled.Add(1)
+		return rabbitmq.Ack()
+	}).Handle)
+
+	for i := 0; i < 2; i++ {
+		if err := publisher.PublishConfirmed(context.Background(), MockSerializable{data: `{}`}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor(t, "both deliveries to start", func() bool { return started.Load() == 2 })
+	cancel()
+
+	short, shortCancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer shortCancel()
+	if err := consumer.Drain(short); err == nil {
+		t.Fatal("Expected Drain to wait for the deliveries in flight")
+	}
+
+	close(release)
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), time.Second)
+	defer drainCancel()
+	if err := consumer.Drain(drainCtx); err != nil {
+		t.Fatalf("Consumer did not drain: %v", err)
+	}
+	if handled.Load() != 2 || broker.QueueLength("tasks") != 0 {
+		t.Errorf("Expected both deliveries to finish and be acknowledged, got %d handled, %d queued", handled.Load(), broker.QueueLength("tasks"))
+	}
+}
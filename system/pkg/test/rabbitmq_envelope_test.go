@@ -0,0 +1,194 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"pkg-common/rabbitmq"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type mockFlowStart struct {
+	MockSerializable
+	id string
+}
+
+func (m mockFlowStart) MessageId() string     { return m.id }
+func (m mockFlowStart) CorrelationId() string { return "event-1" }
+
+type mockUntyped struct{}
+
+func (mockUntyped) Serialize() ([]byte, error) { return []byte(`{}`), nil }
+
+func TestNewEnvelope(t *testing.T) {
+	envelope, err := rabbitmq.NewEnvelope(context.Background(), MockSerializable{data: `{"a":1}`}, "api")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if envelope.Type != "test.message" || envelope.SchemaVersion != rabbitmq.EnvelopeSchemaVersion || envelope.Producer != "api" {
+		t.Errorf("Unexpected envelope metadata: %+v", envelope)
+	}
+	if envelope.MessageId == "" || envelope.CorrelationId != envelope.MessageId || envelope.CausationId != "" {
+		t.Errorf("Expected a new flow to be correlated by its message id, got %+v", envelope)
+	}
+	if string(envelope.Payload) != `{"a":1}` {
+		t.Errorf("Unexpected payload %s", envelope.Payload)
+	}
+
+	if _, err := rabbitmq.NewEnvelope(context.Background(), mockUntyped{}, "api"); !errors.Is(err, rabbitmq.ErrUntypedMessage) {
+		t.Errorf("Expected ErrUntypedMessage, got %v", err)
+	}
+}
+
+func TestNewEnvelopeCorrelation(t *testing.T) {
+	start, err := rabbitmq.NewEnvelope(context.Background(), mockFlowStart{MockSerializable{data: `{}`}, "event-1:0"}, "api")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if start.MessageId != "event-1:0" || start.CorrelationId != "event-1" {
+		t.Errorf("Expected message and correlation id from the message, got %+v", start)
+	}
+
+	ctx := rabbitmq.ContextWithEnvelope(context.Background(), start)
+	reply, err := rabbitmq.NewEnvelope(ctx, MockSerializable{data: `{}`}, "blockchain-client")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reply.CorrelationId != "event-1" || reply.CausationId != "event-1:0" {
+		t.Errorf("Expected reply to join the flow of its cause, got %+v", reply)
+	}
+}
+
+func TestOpenEnvelope(t *testing.T) {
+	envelope, _ := rabbitmq.NewEnvelope(context.Background(), MockSerializable{data: `{"a":1}`}, "api")
+	body, _ := envelope.Serialize()
+
+	opened, err := rabbitmq.OpenEnvelope(body)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if opened.MessageId != envelope.MessageId || string(opened.Payload) != `{"a":1}` {
+		t.Errorf("Envelope did not survive a round trip: %+v", opened)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want error
+	}{
+		{"raw dto", `{"event_id":"1"}`, rabbitmq.ErrMalformedEnvelope},
+		{"not json", `payload`, rabbitmq.ErrMalformedEnvelope},
+		{"future version", `{"type":"t","schema_version":2,"payload":{}}`, rabbitmq.ErrUnsupportedEnvelopeVersion},
+		{"missing version", `{"type":"t","payload":{}}`, rabbitmq.ErrUnsupportedEnvelopeVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := rabbitmq.OpenEnvelope([]byte(tt.body)); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestRouterHandle(t *testing.T) {
+	var handled rabbitmq.Message
+	var fromContext rabbitmq.Envelope
+	router := rabbitmq.NewRouter().On("test.message", func(ctx context.Context, msg rabbitmq.Message) rabbitmq.Outcome {
+		handled = msg
+		fromContext, _ = rabbitmq.EnvelopeFromContext(ctx)
+		return rabbitmq.Ack()
+	})
+
+	envelope, _ := rabbitmq.NewEnvelope(context.Background(), MockSerializable{data: `{"data":"x"}`}, "api")
+	body, _ := envelope.Serialize()
+
+	if outcome := router.Handle(context.Background(), amqp.Delivery{Body: body}); outcome.Action != rabbitmq.ActionAck {
+		t.Fatalf("Expected ack, got %v (%v)", outcome.Action, outcome.Err)
+	}
+	var payload struct {
+		Data string `json:"data"`
+	}
+	if err := handled.Decode(&payload); err != nil || payload.Data != "x" {
+		t.Errorf("Expected payload to decode, got %+v (%v)", payload, err)
+	}
+	if fromContext.MessageId != envelope.MessageId {
+		t.Error("Expected handler context to carry the envelope")
+	}
+
+	unknown := rabbitmq.NewRouter()
+	if outcome := unknown.Handle(context.Background(), amqp.Delivery{Body: body}); outcome.Action != rabbitmq.ActionDeadLetter || !errors.Is(outcome.Err, rabbitmq.ErrUnknownMessageType) {
+		t.Errorf("Expected unknown type to be dead-lettered, got %v (%v)", outcome.Action, outcome.Err)
+	}
+
+	outcome := router.Handle(context.Background(), amqp.Delivery{Body: []byte(`{"event_id":"1"}`)})
+	if outcome.Action != rabbitmq.ActionDeadLetter || !errors.Is(outcome.Err, rabbitmq.ErrMalformedEnvelope) {
+		t.Errorf("Expected raw message to be dead-lettered, got %v (%v)", outcome.Action, outcome.Err)
+	}
+}
+
+func TestRouterDeduplicates(t *testing.T) {
+	handled := 0
+	outcome := rabbitmq.RetryLater(errors.New("rpc down"))
+	router := rabbitmq.NewRouter().On("test.message", func(context.Context, rabbitmq.Message) rabbitmq.Outcome {
+		handled++
+		return outcome
+	}).Deduplicate(rabbitmq.NewMemoryIdempotencyStore(time.Hour, 10), rabbitmq.ByMessageId)
+
+	envelope, _ := rabbitmq.NewEnvelope(context.Background(), MockSerializable{data: `{"data":"x"}`}, "api")
+	body, _ := envelope.Serialize()
+	delivery := amqp.Delivery{Body: body}
+
+	// A failed attempt is forgotten, so its retry is handled
+	router.Handle(context.Background(), delivery)
+	outcome = rabbitmq.Ack()
+	router.Handle(context.Background(), delivery)
+	if handled != 2 {
+		t.Fatalf("Expected the retry to be handled, got %d calls", handled)
+	}
+
+	// Once acknowledged, redeliveries are acknowledged without handling
+	if got := router.Handle(context.Background(), delivery); got.Action != rabbitmq.ActionAck || handled != 2 {
+		t.Errorf("Expected the redelivery to be skipped, got %v after %d calls", got.Action, handled)
+	}
+
+	other, _ := rabbitmq.NewEnvelope(context.Background(), MockSerializable{data: `{"data":"x"}`}, "api")
+	otherBody, _ := other.Serialize()
+	router.Handle(context.Background(), amqp.Delivery{Body: otherBody})
+	if handled != 3 {
+		t.Errorf("Expected a new message to be handled, got %d calls", handled)
+	}
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	store := rabbitmq.NewMemoryIdempotencyStore(time.Hour, 2)
+
+	if claimed, err := store.Claim("a"); !claimed || err != nil {
+		t.Fatalf("Expected a new key to be claimed, got %v, %v", claimed, err)
+	}
+	if _, err := store.Claim("a"); !errors.Is(err, rabbitmq.ErrMessageInFlight) {
+		t.Errorf("Expected a key in flight to be refused, got %v", err)
+	}
+	store.Complete("a", true)
+	if claimed, err := store.Claim("a"); claimed || err != nil {
+		t.Errorf("Expected a handled key to be skipped, got %v, %v", claimed, err)
+	}
+
+	// Beyond capacity the oldest key is forgotten
+	for _, key := range []string{"b", "c"} {
+		store.Claim(key)
+		store.Complete(key, true)
+	}
+	if claimed, _ := store.Claim("a"); !claimed {
+		t.Error("Expected the oldest key to be evicted")
+	}
+
+	expiring := rabbitmq.NewMemoryIdempotencyStore(time.Millisecond, 10)
+	expiring.Claim("a")
+	expiring.Complete("a", true)
+	time.Sleep(5 * time.Millisecond)
+	if claimed, _ := expiring.Claim("a"); !claimed {
+		t.Error("Expected the key to expire after the window")
+	}
+}
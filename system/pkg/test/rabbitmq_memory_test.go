@@ -0,0 +1,167 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"pkg-common/rabbitmq"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const brokerDefinitions = "../../rabbitmq/definitions.json"
+
+func startMemoryConsumer(t *testing.T, broker rabbitmq.Broker, config rabbitmq.RabbitmqConsumerConfig, handler rabbitmq.MessageHandler) {
+	t.Helper()
+	consumer, err := broker.NewConsumer(config)
+	if err != nil {
+		t.Fatalf("Could not create consumer: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go consumer.StartConsuming(ctx, handler)
+	t.Cleanup(func() {
+		cancel()
+		drainCtx, drainCancel := context.WithTimeout(context.Background(), time.Second)
+		defer drainCancel()
+		if err := consumer.Drain(drainCtx); err != nil {
+			t.Errorf("Consumer did not drain: %v", err)
+		}
+	})
+}
+
+func TestMemoryBrokerTopicRouting(t *testing.T) {
+	broker := rabbitmq.NewMemoryBroker()
+	if err := broker.DeclareExchange("events", amqp.ExchangeTopic); err != nil {
+		t.Fatal(err)
+	}
+	bindings := map[string]string{"one-word": "zkp.*", "any-words": "zkp.#", "suffix": "#.failure", "exact": "zkp.proof"}
+	for queue, key := range bindings {
+		broker.DeclareQueue(queue)
+		if err := broker.BindQueue(queue, "events", key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	publisher, _ := broker.Connect("test").NewPublisher(rabbitmq.RabbitmqPublishersConfig{Exchange: "events", RoutingKey: "zkp.proof.failure"})
+	if err := publisher.PublishConfirmed(context.Background(), MockSerializable{data: `{}`}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]int{"one-word": 0, "any-words": 1, "suffix": 1, "exact": 0}
+	for queue, want := range expected {
+		if got := broker.QueueLength(queue); got != want {
+			t.Errorf("Expected %d messages in %s, got %d", want, queue, got)
+		}
+	}
+}
+
+func TestMemoryBrokerUnroutable(t *testing.T) {
+	broker := rabbitmq.NewMemoryBroker()
+	if err := broker.LoadDefinitions(brokerDefinitions); err != nil {
+		t.Fatalf("Could not load definitions: %v", err)
+	}
+	conn := broker.Connect("test")
+
+	publisher, _ := conn.NewPublisher(rabbitmq.RabbitmqPublishersConfig{Exchange: "identity", RoutingKey: "proof.unknown"})
+	if err := publisher.PublishConfirmed(context.Background(), MockSerializable{data: `{}`}); !errors.Is(err, rabbitmq.ErrUnroutable) {
+		t.Errorf("Expected ErrUnroutable, got %v", err)
+	}
+	if err := publisher.Publish(MockSerializable{data: `{}`}); err != nil {
+		t.Errorf("Expected unconfirmed publish to drop the message, got %v", err)
+	}
+
+	missing, _ := conn.NewPublisher(rabbitmq.RabbitmqPublishersConfig{Exchange: "missing", RoutingKey: "key"})
+	if err := missing.Publish(MockSerializable{data: `{}`}); !errors.Is(err, rabbitmq.ErrExchangeNotFound) {
+		t.Errorf("Expected ErrExchangeNotFound, got %v", err)
+	}
+}
+
+// The verifier answers on the verifiers exchange, the blockchain client turns
+// the answer into a result on the identity exchange and the api consumes it.
+func TestMemoryBrokerFlowAcrossServices(t *testing.T) {
+	broker := rabbitmq.NewMemoryBroker()
+	if err := broker.LoadDefinitions(brokerDefinitions); err != nil {
+		t.Fatalf("Could not load definitions: %v", err)
+	}
+	verifier, blockchain, api := broker.Connect("verifier"), broker.Connect("blockchain-client"), broker.Connect("api")
+
+	resultPublisher, _ := blockchain.NewPublisher(rabbitmq.RabbitmqPublishersConfig{Exchange: "identity", RoutingKey: "proof.results"})
+	startMemoryConsumer(t, blockchain, rabbitmq.RabbitmqConsumerConfig{QueueName: "verified.positive", RetryPolicy: rabbitmq.DefaultRetryPolicy()},
+		rabbitmq.NewRouter().On("test.message", func(ctx context.Context, msg rabbitmq.Message) rabbitmq.Outcome {
+			if err := resultPublisher.PublishConfirmed(ctx, MockSerializable{data: `{"result":true}`}); err != nil {
+				return rabbitmq.RetryLater(err)
+			}
+			return rabbitmq.Ack()
+		}).Handle)
+
+	results := make(chan rabbitmq.Message, 1)
+	startMemoryConsumer(t, api, rabbitmq.RabbitmqConsumerConfig{QueueName: "proof.results", RetryPolicy: rabbitmq.DefaultRetryPolicy()},
+		rabbitmq.NewRouter().On("test.message", func(_ context.Context, msg rabbitmq.Message) rabbitmq.Outcome {
+			results <- msg
+			return rabbitmq.Ack()
+		}).Handle)
+
+	answerPublisher, _ := verifier.NewPublisher(rabbitmq.RabbitmqPublishersConfig{Exchange: "verifiers", RoutingKey: "positive"})
+	answer := mockFlowStart{MockSerializable{data: `{}`}, "answer-1"}
+	if err := answerPublisher.PublishConfirmed(context.Background(), answer); err != nil {
+		t.Fatalf("Could not publish answer: %v", err)
+	}
+
+	select {
+	case result := <-results:
+		if result.Producer != "blockchain-client" {
+			t.Errorf("Expected result from blockchain-client, got %s", result.Producer)
+		}
+		if result.CorrelationId != "event-1" || result.CausationId != "answer-1" {
+			t.Errorf("Expected result to be caused by the answer, got %+v", result.Envelope)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Result did not reach the api")
+	}
+}
+
+func TestMemoryConsumerRetriesThenDeadLetters(t *testing.T) {
+	broker := rabbitmq.NewMemoryBroker()
+	if err := broker.LoadDefinitions(brokerDefinitions); err != nil {
+		t.Fatalf("Could not load definitions: %v", err)
+	}
+	conn := broker.Connect("api")
+
+	attempts := make(chan int, 3)
+	policy := rabbitmq.RetryPolicy{MaxRetries: 1, Delay: time.Millisecond}
+	startMemoryConsumer(t, conn, rabbitmq.RabbitmqConsumerConfig{QueueName: "proof.failures", RetryPolicy: policy},
+		func(_ context.Context, d amqp.Delivery) rabbitmq.Outcome {
+			attempts <- rabbitmq.RetryCount(d.Headers)
+			return rabbitmq.RetryLater(errors.New("database unavailable"))
+		})
+
+	publisher, _ := conn.NewPublisher(rabbitmq.RabbitmqPublishersConfig{Exchange: "identity", RoutingKey: "proof.failures"})
+	if err := publisher.Publish(MockSerializable{data: `{}`}); err != nil {
+		t.Fatal(err)
+	}
+
+	for want := 0; want <= 1; want++ {
+		select {
+		case got := <-attempts:
+			if got != want {
+				t.Errorf("Expected attempt with retry count %d, got %d", want, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Attempt %d did not happen", want)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(broker.DeadLetters("proof.failures")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	dead := broker.DeadLetters("proof.failures")
+	if len(dead) != 1 {
+		t.Fatalf("Expected one dead letter, got %d", len(dead))
+	}
+	if reason := dead[0].Headers[rabbitmq.FailureReasonHeader]; reason != "database unavailable" {
+		t.Errorf("Expected failure reason on dead letter, got %v", reason)
+	}
+}
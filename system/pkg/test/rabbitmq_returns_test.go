@@ -0,0 +1,99 @@
+package test
+
+import (
+	"pkg-common/rabbitmq"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func returned(publishId string) amqp.Return {
+	return amqp.Return{
+		ReplyCode:  amqp.NoRoute,
+		ReplyText:  "NO_ROUTE",
+		Exchange:   "verifiers",
+		RoutingKey: "nowhere",
+		Headers:    amqp.Table{rabbitmq.PublishIdHeader: publishId},
+	}
+}
+
+func TestReturnTrackerDrainsReturnsNobodyWaitsFor(t *testing.T) {
+	returns := make(chan amqp.Return)
+	rabbitmq.NewReturnTracker("verifiers", returns)
+	defer close(returns)
+
+	// Many more returns than any buffer, sent the way the client does
+	for i := 0; i < 500; i++ {
+		select {
+		case returns <- returned("unexpected"):
+		case <-time.After(time.Second):
+			t.Fatalf("Return %d blocked the client", i)
+		}
+	}
+}
+
+func TestReturnTrackerMatchesConfirmedPublish(t *testing.T) {
+	returns := make(chan amqp.Return, 4)
+	tracker := rabbitmq.NewReturnTracker("verifiers", returns)
+	defer close(returns)
+
+	tracker.Expect("1")
+	tracker.Expect("2")
+	returns <- returned("2")
+
+	// Taken right after the client handed the return over
+	r, ok := tracker.Take("2")
+	if !ok || r.ReplyCode != amqp.NoRoute {
+		t.Fatalf("Expected publish 2 to be returned, got %v %+v", ok, r)
+	}
+	if _, ok := tracker.Take("1"); ok {
+		t.Error("Expected publish 1 not to be returned")
+	}
+	if _, ok := tracker.Take("2"); ok {
+		t.Error("Expected a return to be taken only once")
+	}
+}
+
+func TestReturnTrackerForgetDropsReturn(t *testing.T) {
+	returns := make(chan amqp.Return, 4)
+	tracker := rabbitmq.NewReturnTracker("verifiers", returns)
+	defer close(returns)
+
+	tracker.Expect("1")
+	returns <- returned("1")
+	tracker.Take("other")
+	tracker.Forget("1")
+
+	if _, ok := tracker.Take("1"); ok {
+		t.Error("Expected a forgotten publish not to be returned")
+	}
+}
+
+func TestReturnTrackerTakeAfterChannelClosed(t *testing.T) {
+	returns := make(chan amqp.Return, 4)
+	tracker := rabbitmq.NewReturnTracker("verifiers", returns)
+
+	tracker.Expect("1")
+	returns <- returned("1")
+	close(returns)
+
+	taken := make(chan bool)
+	go func() {
+		_, ok := tracker.Take("1")
+		taken <- ok
+		_, ok = tracker.Take("2")
+		taken <- ok
+	}()
+
+	for _, expected := range []bool{true, false} {
+		select {
+		case ok := <-taken:
+			if ok != expected {
+				t.Errorf("Expected returned %v, got %v", expected, ok)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Take blocked on a closed channel")
+		}
+	}
+}
@@ -1,11 +1,17 @@
 package test
 
 import (
+	"context"
 	"errors"
+	"pkg-common/logger"
 	"pkg-common/rabbitmq"
+	"pkg-common/utilities"
+	logger_message "pkg-common/utilities/logger"
 	"testing"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/rs/zerolog"
 )
 
 // Mock implementation for testing
@@ -51,6 +57,10 @@ func (m MockSerializable) Serialize() ([]byte, error) {
 	return []byte(m.data), nil
 }
 
+func (m MockSerializable) MessageType() string {
+	return "test.message"
+}
+
 func TestRabbitmqConfigConvertToDomain(t *testing.T) {
 	publisherConfig := rabbitmq.RabbitmqPublishersConfigJson{
 		PublisherAlias: "test-publisher",
@@ -127,6 +137,131 @@ func TestRabbitmqConsumerConfigConvertToDomain(t *testing.T) {
 	}
 }
 
+func TestRabbitmqConsumerRetryConfigConvertToDomain(t *testing.T) {
+	defaults := rabbitmq.RabbitmqConsumerConfigJson{QueueName: "test-queue"}.MapToDomain().RetryPolicy
+	if defaults != rabbitmq.DefaultRetryPolicy() {
+		t.Errorf("Expected default retry policy, got %+v", defaults)
+	}
+
+	policy := rabbitmq.RabbitmqConsumerConfigJson{
+		QueueName:          "test-queue",
+		MaxRetries:         5,
+		RetryDelayMs:       1500,
+		DeadLetterExchange: "test-dlx",
+	}.MapToDomain().RetryPolicy
+	if policy.MaxRetries != 5 {
+		t.Errorf("Expected MaxRetries 5, got %d", policy.MaxRetries)
+	}
+	if policy.Delay != 1500*time.Millisecond {
+		t.Errorf("Expected Delay 1.5s, got %v", policy.Delay)
+	}
+	if policy.DeadLetterExchange != "test-dlx" {
+		t.Errorf("Expected DeadLetterExchange 'test-dlx', got '%s'", policy.DeadLetterExchange)
+	}
+
+	disabled := rabbitmq.RabbitmqConsumerConfigJson{MaxRetries: -1}.MapToDomain().RetryPolicy
+	if disabled.MaxRetries != 0 {
+		t.Errorf("Expected negative max_retries to disable retries, got %d", disabled.MaxRetries)
+	}
+}
+
+func TestRabbitmqConsumerConcurrencyConfigConvertToDomain(t *testing.T) {
+	defaults := rabbitmq.RabbitmqConsumerConfigJson{QueueName: "test-queue"}.MapToDomain()
+	if defaults.Concurrency != rabbitmq.DefaultConcurrency || defaults.PrefetchCount != rabbitmq.DefaultConcurrency {
+		t.Errorf("Expected one worker with prefetch 1, got %d workers with prefetch %d", defaults.Concurrency, defaults.PrefetchCount)
+	}
+	if defaults.OrderedByKey {
+		t.Error("Expected ordering by key to be off by default")
+	}
+
+	config := rabbitmq.RabbitmqConsumerConfigJson{
+		QueueName:    "test-queue",
+		Concurrency:  4,
+		OrderedByKey: true,
+	}.MapToDomain()
+	if config.Concurrency != 4 || config.PrefetchCount != 4 {
+		t.Errorf("Expected prefetch to follow concurrency, got %d workers with prefetch %d", config.Concurrency, config.PrefetchCount)
+	}
+	if !config.OrderedByKey {
+		t.Error("Expected ordering by key to be converted")
+	}
+
+	explicit := rabbitmq.RabbitmqConsumerConfigJson{Concurrency: 2, PrefetchCount: 10}.MapToDomain()
+	if explicit.PrefetchCount != 10 {
+		t.Errorf("Expected prefetch 10, got %d", explicit.PrefetchCount)
+	}
+}
+
+func TestConsumerWithConcurrency(t *testing.T) {
+	consumer := rabbitmq.NewConsumer(nil, "queue", "tag")
+	if consumer.Concurrency != rabbitmq.DefaultConcurrency {
+		t.Errorf("Expected a new consumer to use %d worker, got %d", rabbitmq.DefaultConcurrency, consumer.Concurrency)
+	}
+
+	consumer.WithConcurrency(0, 0, true)
+	if consumer.Concurrency != 1 || consumer.PrefetchCount != 1 || !consumer.OrderedByKey {
+		t.Errorf("Expected invalid values to fall back to one worker, got %+v", consumer)
+	}
+}
+
+func TestRetryPolicyResolve(t *testing.T) {
+	policy := rabbitmq.RetryPolicy{MaxRetries: 2}
+	cause := errors.New("database unavailable")
+
+	delivery := func(retries any) amqp.Delivery {
+		if retries == nil {
+			return amqp.Delivery{}
+		}
+		return amqp.Delivery{Headers: amqp.Table{rabbitmq.RetryCountHeader: retries}}
+	}
+
+	tests := []struct {
+		name     string
+		outcome  rabbitmq.Outcome
+		delivery amqp.Delivery
+		expected rabbitmq.Action
+	}{
+		{"ack stays ack", rabbitmq.Ack(), delivery(int32(5)), rabbitmq.ActionAck},
+		{"first failure is retried", rabbitmq.RetryLater(cause), delivery(nil), rabbitmq.ActionRetry},
+		{"retry below limit", rabbitmq.RetryLater(cause), delivery(int32(1)), rabbitmq.ActionRetry},
+		{"retries exhausted", rabbitmq.RetryLater(cause), delivery(int32(2)), rabbitmq.ActionDeadLetter},
+		{"malformed header counts as first", rabbitmq.RetryLater(cause), delivery("two"), rabbitmq.ActionRetry},
+		{"dead letter is immediate", rabbitmq.DeadLetter(cause), delivery(nil), rabbitmq.ActionDeadLetter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Resolve(tt.outcome, tt.delivery); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+
+	noRetries := rabbitmq.RetryPolicy{MaxRetries: 0}
+	if got := noRetries.Resolve(rabbitmq.RetryLater(cause), delivery(nil)); got != rabbitmq.ActionDeadLetter {
+		t.Errorf("Expected retry to be dead-lettered when retries are disabled, got %s", got)
+	}
+}
+
+func TestRetryTopologyNames(t *testing.T) {
+	if name := rabbitmq.RetryQueueName("proof.results"); name != "proof.results.retry" {
+		t.Errorf("Expected 'proof.results.retry', got '%s'", name)
+	}
+	if name := rabbitmq.DeadLetterQueueName("proof.results"); name != "proof.results.dlq" {
+		t.Errorf("Expected 'proof.results.dlq', got '%s'", name)
+	}
+}
+
+func TestRetryPolicyExpiration(t *testing.T) {
+	if expiration := rabbitmq.DefaultRetryPolicy().Expiration(); expiration != "5000" {
+		t.Errorf("Expected '5000', got '%s'", expiration)
+	}
+	policy := rabbitmq.RetryPolicy{Delay: 1500 * time.Millisecond}
+	if expiration := policy.Expiration(); expiration != "1500" {
+		t.Errorf("Expected '1500', got '%s'", expiration)
+	}
+}
+
 func TestNewPublisher(t *testing.T) {
 	exchange := "test-exchange"
 	routingKey := "test-key"
@@ -151,6 +286,17 @@ func TestNewConsumer(t *testing.T) {
 	}
 }
 
+func TestConsumerDrainWithoutStart(t *testing.T) {
+	consumer := rabbitmq.NewConsumer((*amqp.Channel)(nil), "test-queue", "test-tag")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := consumer.Drain(ctx); err != nil {
+		t.Errorf("Expected consumer that never started to drain immediately, got %v", err)
+	}
+}
+
 func TestPublisherPublish(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -381,3 +527,58 @@ func BenchmarkConfigConversion(b *testing.B) {
 		config.MapToDomain()
 	}
 }
+
+type capturingPublisher struct {
+	published []utilities.Serializable
+}
+
+func (p *capturingPublisher) Publish(body utilities.Serializable) error {
+	p.published = append(p.published, body)
+	return nil
+}
+
+func (p *capturingPublisher) PublishWithContext(_ context.Context, body utilities.Serializable) error {
+	return p.Publish(body)
+}
+
+func (p *capturingPublisher) PublishConfirmed(_ context.Context, body utilities.Serializable) error {
+	return p.Publish(body)
+}
+
+func TestRabbitmqLogWriterCarriesFields(t *testing.T) {
+	publisher := &capturingPublisher{}
+	write := rabbitmq.CreateRabbitmqLogWriter(publisher)
+
+	err := write([]logger.Entry{{
+		Level:   zerolog.WarnLevel,
+		Message: "slow verification",
+		Fields:  map[string]any{logger.ServiceKey: "blockchain-client", logger.IdentityIdKey: "identity-1"},
+	}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(publisher.published) != 1 {
+		t.Fatalf("Expected one published message, got %d", len(publisher.published))
+	}
+	msg := publisher.published[0].(logger_message.LoggerMessage)
+	if msg.Service != "blockchain-client" || msg.Level != "warn" {
+		t.Errorf("Unexpected message: %+v", msg)
+	}
+	if msg.Field(logger.IdentityIdKey) != "identity-1" {
+		t.Errorf("Expected identity id field, got %v", msg.Fields)
+	}
+	if _, ok := msg.Fields[logger.ServiceKey]; ok {
+		t.Error("Expected service to be carried outside of the fields")
+	}
+}
+
+func TestRabbitmqLogWriterStopsAtFirstFailure(t *testing.T) {
+	publisher, _ := rabbitmq.NewMemoryBroker().Connect("test").NewPublisher(rabbitmq.RabbitmqPublishersConfig{Exchange: "missing"})
+	write := rabbitmq.CreateRabbitmqLogWriter(publisher)
+
+	err := write([]logger.Entry{{Message: "first"}, {Message: "second"}})
+	if !errors.Is(err, rabbitmq.ErrExchangeNotFound) {
+		t.Errorf("Expected the publish error, got %v", err)
+	}
+}
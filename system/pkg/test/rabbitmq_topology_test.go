@@ -0,0 +1,265 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"pkg-common/rabbitmq"
+	"slices"
+	"testing"
+	"time"
+)
+
+func testTopology() rabbitmq.RabbitmqTopologyConfig {
+	return rabbitmq.RabbitmqTopologyConfigJson{
+		Exchanges: []rabbitmq.RabbitmqExchangeConfigJson{
+			{Name: "verifiers", Type: "topic", Durable: true},
+			{Name: "identity", Type: "direct", Durable: true},
+			{Name: "log_audit", Type: "fanout"},
+		},
+		Queues: []rabbitmq.RabbitmqQueueConfigJson{
+			{Name: "unverified", Durable: true},
+			{Name: "proof.results", Durable: true},
+			{Name: "logs.api", AutoDelete: true, MessageTtlMs: 3600000, ExpiresMs: 3600000},
+		},
+		Bindings: []rabbitmq.RabbitmqBindingConfigJson{
+			{Exchange: "verifiers", Queue: "unverified", RoutingKey: "unverified.#"},
+			{Exchange: "identity", Queue: "proof.results", RoutingKey: "proof.results"},
+			{Exchange: "log_audit", Queue: "logs.api"},
+		},
+	}.MapToDomain()
+}
+
+func TestRabbitmqTopologyConfigConvertToDomain(t *testing.T) {
+	topology := testTopology()
+
+	if !topology.IsConfigured() {
+		t.Fatal("Expected topology to be configured")
+	}
+	if (rabbitmq.RabbitmqTopologyConfig{}).IsConfigured() {
+		t.Error("Expected empty topology not to be configured")
+	}
+
+	logs := topology.Queues[2]
+	if logs.MessageTtl != time.Hour || logs.Expires != time.Hour {
+		t.Errorf("Expected ttl and expiry of one hour, got %v and %v", logs.MessageTtl, logs.Expires)
+	}
+	args := logs.Arguments()
+	if args["x-message-ttl"] != int64(3600000) || args["x-expires"] != int64(3600000) {
+		t.Errorf("Unexpected queue arguments: %v", args)
+	}
+	if len(topology.Queues[0].Arguments()) != 0 {
+		t.Errorf("Expected no arguments, got %v", topology.Queues[0].Arguments())
+	}
+
+	dlx := rabbitmq.RabbitmqQueueConfigJson{Name: "q", DeadLetterExchange: "dead_letter", DeadLetterRoutingKey: "q.dlq"}.MapToDomain().Arguments()
+	if dlx["x-dead-letter-exchange"] != "dead_letter" || dlx["x-dead-letter-routing-key"] != "q.dlq" {
+		t.Errorf("Unexpected dead letter arguments: %v", dlx)
+	}
+}
+
+func TestRabbitmqTopologyRoute(t *testing.T) {
+	topology := testTopology()
+
+	tests := []struct {
+		exchange, routingKey string
+		queues               int
+	}{
+		{"verifiers", "unverified", 1},
+		{"verifiers", "unverified.kyc", 1},
+		{"verifiers", "positive", 0},
+		{"identity", "proof.results", 1},
+		{"identity", "proof.failures", 0},
+		{"log_audit", "anything", 1},
+	}
+	for _, tt := range tests {
+		queues, err := topology.Route(tt.exchange, tt.routingKey)
+		if err != nil {
+			t.Errorf("Unexpected error for %s/%s: %v", tt.exchange, tt.routingKey, err)
+		}
+		if len(queues) != tt.queues {
+			t.Errorf("Expected %s/%s to reach %d queues, got %v", tt.exchange, tt.routingKey, tt.queues, queues)
+		}
+	}
+
+	if _, err := topology.Route("missing", ""); !errors.Is(err, rabbitmq.ErrExchangeNotFound) {
+		t.Errorf("Expected ErrExchangeNotFound, got %v", err)
+	}
+}
+
+func TestRabbitmqTopologyValidatePublishers(t *testing.T) {
+	topology := testTopology()
+
+	valid := []rabbitmq.RabbitmqPublishersConfig{
+		{PublisherAlias: "Unverified", Exchange: "verifiers", RoutingKey: "unverified"},
+		{PublisherAlias: "Logs", Exchange: "log_audit"},
+	}
+	if err := topology.ValidatePublishers(valid); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	invalid := []rabbitmq.RabbitmqPublishersConfig{
+		{PublisherAlias: "Drifted", Exchange: "identity", RoutingKey: "proof.result"},
+		{PublisherAlias: "Missing", Exchange: "audit"},
+	}
+	err := topology.ValidatePublishers(invalid)
+	if !errors.Is(err, rabbitmq.ErrUnroutable) || !errors.Is(err, rabbitmq.ErrExchangeNotFound) {
+		t.Errorf("Expected both publishers to be reported, got %v", err)
+	}
+}
+
+func TestMemoryBrokerDeclareTopology(t *testing.T) {
+	broker := rabbitmq.NewMemoryBroker()
+	conn := broker.Connect("test")
+
+	// Declaring twice must be harmless
+	for range 2 {
+		if err := conn.DeclareTopology(testTopology()); err != nil {
+			t.Fatalf("Could not declare topology: %v", err)
+		}
+	}
+
+	publisher, _ := conn.NewPublisher(rabbitmq.RabbitmqPublishersConfig{Exchange: "verifiers", RoutingKey: "unverified"})
+	if err := publisher.PublishConfirmed(context.Background(), MockSerializable{data: `{}`}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := broker.QueueLength("unverified"); got != 1 {
+		t.Errorf("Expected one message in unverified, got %d", got)
+	}
+}
+
+func TestMemoryBrokerVerifyTopology(t *testing.T) {
+	broker := rabbitmq.NewMemoryBroker()
+	conn := broker.Connect("test")
+
+	err := conn.VerifyTopology(testTopology())
+	if !errors.Is(err, rabbitmq.ErrExchangeNotFound) || !errors.Is(err, rabbitmq.ErrQueueNotFound) {
+		t.Errorf("Expected missing exchanges and queues to be reported, got %v", err)
+	}
+
+	if err := conn.DeclareTopology(testTopology()); err != nil {
+		t.Fatalf("Could not declare topology: %v", err)
+	}
+	if err := conn.VerifyTopology(testTopology()); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	drifted := testTopology()
+	drifted.Bindings[0].RoutingKey = "unverified"
+	if err := conn.VerifyTopology(drifted); err == nil {
+		t.Error("Expected a binding missing from the broker to be reported")
+	}
+}
+
+// loadBrokerDefinitions reads the exchanges, queues and bindings of the
+// definitions the broker container starts with, in the shape of a topology.
+func loadBrokerDefinitions(t *testing.T) rabbitmq.RabbitmqTopologyConfig {
+	t.Helper()
+	raw, err := os.ReadFile(brokerDefinitions)
+	if err != nil {
+		t.Fatalf("Could not read definitions: %v", err)
+	}
+	var definitions struct {
+		Exchanges []rabbitmq.RabbitmqExchangeConfigJson `json:"exchanges"`
+		Queues    []struct {
+			Name       string         `json:"name"`
+			Durable    bool           `json:"durable"`
+			AutoDelete bool           `json:"auto_delete"`
+			Arguments  map[string]any `json:"arguments"`
+		} `json:"queues"`
+		Bindings []struct {
+			Source      string `json:"source"`
+			Destination string `json:"destination"`
+			RoutingKey  string `json:"routing_key"`
+		} `json:"bindings"`
+	}
+	if err := json.Unmarshal(raw, &definitions); err != nil {
+		t.Fatalf("Could not parse definitions: %v", err)
+	}
+
+	topology := rabbitmq.RabbitmqTopologyConfigJson{Exchanges: definitions.Exchanges}
+	for _, q := range definitions.Queues {
+		queue := rabbitmq.RabbitmqQueueConfigJson{Name: q.Name, Durable: q.Durable, AutoDelete: q.AutoDelete}
+		for key, value := range q.Arguments {
+			switch key {
+			case "x-message-ttl":
+				queue.MessageTtlMs = int(value.(float64))
+			case "x-expires":
+				queue.ExpiresMs = int(value.(float64))
+			case "x-dead-letter-exchange":
+				queue.DeadLetterExchange = value.(string)
+			case "x-dead-letter-routing-key":
+				queue.DeadLetterRoutingKey = value.(string)
+			default:
+				t.Fatalf("Queue %s has argument %s that topologies cannot express", q.Name, key)
+			}
+		}
+		topology.Queues = append(topology.Queues, queue)
+	}
+	for _, b := range definitions.Bindings {
+		topology.Bindings = append(topology.Bindings, rabbitmq.RabbitmqBindingConfigJson{
+			Exchange: b.Source, Queue: b.Destination, RoutingKey: b.RoutingKey,
+		})
+	}
+	return topology.MapToDomain()
+}
+
+// Each service topology must describe the exchanges, queues and bindings
+// exactly as the broker definitions do, since RabbitMQ refuses to redeclare
+// an entity with different properties, and each publisher must reach a queue
+// both in the topology of its config and in the definitions.
+func TestServiceTopologiesMatchBrokerDefinitions(t *testing.T) {
+	definitions := loadBrokerDefinitions(t)
+
+	for _, path := range []string{"../../api/config.json", "../../blockchain-client/config.json"} {
+		t.Run(path, func(t *testing.T) {
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Could not read config: %v", err)
+			}
+			var config struct {
+				Rabbitmq rabbitmq.RabbimqConfigJson `json:"rabbitmq"`
+			}
+			if err := json.Unmarshal(raw, &config); err != nil {
+				t.Fatalf("Could not parse config: %v", err)
+			}
+			conf := config.Rabbitmq.MapToDomain()
+
+			if err := conf.Topology.ValidatePublishers(conf.PublishersConfig); err != nil {
+				t.Errorf("Publishers do not match the topology: %v", err)
+			}
+
+			for _, ex := range conf.Topology.Exchanges {
+				if !slices.Contains(definitions.Exchanges, ex) {
+					t.Errorf("Exchange %+v differs from the definitions", ex)
+				}
+			}
+			for _, q := range conf.Topology.Queues {
+				if !slices.Contains(definitions.Queues, q) {
+					t.Errorf("Queue %+v differs from the definitions", q)
+				}
+			}
+			for _, b := range conf.Topology.Bindings {
+				if !slices.Contains(definitions.Bindings, b) {
+					t.Errorf("Binding %+v is not in the definitions", b)
+				}
+			}
+
+			broker := rabbitmq.NewMemoryBroker()
+			if err := broker.LoadDefinitions(brokerDefinitions); err != nil {
+				t.Fatalf("Could not load definitions: %v", err)
+			}
+			conn := broker.Connect(conf.Producer)
+			if err := conn.VerifyTopology(conf.Topology); err != nil {
+				t.Errorf("Topology does not verify against the definitions: %v", err)
+			}
+			for _, config := range conf.PublishersConfig {
+				publisher, _ := conn.NewPublisher(config)
+				if err := publisher.PublishConfirmed(context.Background(), MockSerializable{data: `{}`}); err != nil {
+					t.Errorf("Publisher %s cannot deliver with the broker definitions: %v", config.PublisherAlias, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"pkg-common/rest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimitConfigConvertToDomain(t *testing.T) {
+	config := rest.RateLimitConfigJson{
+		Enabled:   true,
+		KeyHeader: "X-Test-Key",
+		Buckets: []rest.RateLimitBucketConfigJson{
+			{Name: "verify", RatePerMinute: 120, Burst: 5},
+		},
+		RelyingParties: []rest.RateLimitRelyingPartyConfigJson{
+			{Name: "shop", Key: "shop-key"},
+			{Name: "unkeyed"},
+		},
+	}
+
+	result := config.MapToDomain()
+
+	if !result.Enabled {
+		t.Error("Expected Enabled to be true")
+	}
+	if result.KeyHeader != "X-Test-Key" {
+		t.Errorf("Expected KeyHeader to be 'X-Test-Key', got '%s'", result.KeyHeader)
+	}
+	verify, ok := result.Buckets["verify"]
+	if !ok {
+		t.Fatal("Expected 'verify' bucket to be present")
+	}
+	if verify.Rate != 2 {
+		t.Errorf("Expected rate of 2 tokens/s, got %f", verify.Rate)
+	}
+	if verify.Burst != 5 {
+		t.Errorf("Expected burst 5, got %d", verify.Burst)
+	}
+	if len(result.RelyingParties) != 1 || result.RelyingParties["shop-key"] != "shop" {
+		t.Errorf("Expected only the keyed relying party, got %v", result.RelyingParties)
+	}
+}
+
+func TestInMemoryRateLimitStoreTake(t *testing.T) {
+	store := rest.NewInMemoryRateLimitStore()
+	limit := rest.RateLimit{Rate: 1, Burst: 2}
+	now := time.Unix(1000, 0)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		allowed, _, err := store.Take(ctx, "client", limit, now)
+		if err != nil {
+			t.Fatalf("Take returned error: %v", err)
+		}
+		if !allowed {
+			t.Errorf("Expected take %d to be allowed within burst", i+1)
+		}
+	}
+
+	allowed, wait, _ := store.Take(ctx, "client", limit, now)
+	if allowed {
+		t.Error("Expected take to be rejected after burst is exhausted")
+	}
+	if wait <= 0 || wait > time.Second {
+		t.Errorf("Expected retry after in (0, 1s], got %v", wait)
+	}
+
+	allowed, _, _ = store.Take(ctx, "other-client", limit, now)
+	if !allowed {
+		t.Error("Expected separate client to have its own bucket")
+	}
+
+	allowed, _, _ = store.Take(ctx, "client", limit, now.Add(time.Second))
+	if !allowed {
+		t.Error("Expected bucket to refill after one second")
+	}
+}
+
+// Buckets of a slow limit must not be swept by the traffic of a fast one.
+func TestInMemoryRateLimitStoreSweepKeepsEachWindow(t *testing.T) {
+	store := rest.NewInMemoryRateLimitStore()
+	slow := rest.RateLimit{Rate: 0.001, Burst: 1}
+	fast := rest.RateLimit{Rate: 100, Burst: 1}
+	now := time.Unix(1000, 0)
+	ctx := context.Background()
+
+	if allowed, _, _ := store.Take(ctx, "slow-client", slow, now); !allowed {
+		t.Fatal("Expected the first take to be allowed")
+	}
+
+	// Enough takes to trigger a sweep, a minute later
+	later := now.Add(time.Minute)
+	for i := 0; i < 2048; i++ {
+		store.Take(ctx, fmt.Sprintf("fast-%d", i), fast, later)
+	}
+
+	if allowed, _, _ := store.Take(ctx, "slow-client", slow, later); allowed {
+		t.Error("Expected the slow bucket to survive the sweep still empty")
+	}
+}
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := rest.NewRateLimiter(rest.NewInMemoryRateLimitStore(), rest.RateLimitConfig{
+		Enabled: true,
+		Buckets: map[string]rest.RateLimit{
+			rest.RateLimitBucketVerify: {Rate: 0.1, Burst: 1},
+		},
+		RelyingParties: map[string]string{"rp-1-key": "rp-1", "rp-2-key": "rp-2"},
+	})
+
+	router := gin.New()
+	router.POST("/verify", limiter.Wrap(rest.RateLimitBucketVerify, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	}))
+	router.GET("/unlimited", limiter.Middleware("missing"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	do := func(method, path, rpKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if rpKey != "" {
+			req.Header.Set("X-RP-Key", rpKey)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodPost, "/verify", "rp-1-key"); w.Code != http.StatusOK {
+		t.Fatalf("Expected first request to pass, got %d", w.Code)
+	}
+
+	w := do(http.MethodPost, "/verify", "rp-1-key")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 on second request, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "10" {
+		t.Errorf("Expected Retry-After of 10 seconds, got '%s'", w.Header().Get("Retry-After"))
+	}
+
+	if w := do(http.MethodPost, "/verify", "rp-2-key"); w.Code != http.StatusOK {
+		t.Errorf("Expected different RP key to have its own budget, got %d", w.Code)
+	}
+
+	// Unregistered keys share the budget of the client IP
+	if w := do(http.MethodPost, "/verify", "made-up-1"); w.Code != http.StatusOK {
+		t.Errorf("Expected the IP budget to allow the first request, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/verify", "made-up-2"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected a new unregistered key not to get a fresh budget, got %d", w.Code)
+	}
+
+	for i := 0; i < 3; i++ {
+		if w := do(http.MethodGet, "/unlimited", ""); w.Code != http.StatusOK {
+			t.Errorf("Expected unconfigured bucket to be unlimited, got %d", w.Code)
+		}
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := rest.NewRateLimiter(rest.NewInMemoryRateLimitStore(), rest.RateLimitConfig{
+		Enabled: false,
+		Buckets: map[string]rest.RateLimit{
+			rest.RateLimitBucketDefault: {Rate: 0, Burst: 0},
+		},
+	})
+
+	router := gin.New()
+	router.Use(limiter.Middleware(rest.RateLimitBucketDefault))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected disabled limiter to pass requests, got %d", w.Code)
+	}
+}
+
+// Behind no trusted proxy a forged X-Forwarded-For does not change the IP
+// bucket.
+func TestRateLimiterIgnoresForwardedForWithoutTrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter := rest.NewRateLimiter(rest.NewInMemoryRateLimitStore(), rest.RateLimitConfig{
+		Enabled: true,
+		Buckets: map[string]rest.RateLimit{
+			rest.RateLimitBucketDefault: {Rate: 0.1, Burst: 1},
+		},
+	})
+
+	router := gin.New()
+	if err := router.SetTrustedProxies(nil); err != nil {
+		t.Fatal(err)
+	}
+	router.Use(limiter.Middleware(rest.RateLimitBucketDefault))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	codes := make([]int, 0, 2)
+	for _, forwarded := range []string{"10.0.0.1", "10.0.0.2"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Forwarded-For", forwarded)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		codes = append(codes, w.Code)
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+		t.Errorf("Expected the second request to share the peer's budget, got %v", codes)
+	}
+}
@@ -0,0 +1,102 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"pkg-common/logger"
+	"pkg-common/tracing"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestTracingConfigConvertToDomain(t *testing.T) {
+	result := tracing.TracingConfigJson{}.MapToDomain()
+
+	if result.Exporter != tracing.ExporterNone {
+		t.Errorf("Expected default exporter 'none', got '%s'", result.Exporter)
+	}
+	if result.SampleRatio != 1 {
+		t.Errorf("Expected default sample ratio 1, got %f", result.SampleRatio)
+	}
+}
+
+func TestTracingInitRejectsUnknownExporter(t *testing.T) {
+	if _, err := tracing.Init(context.Background(), "test", tracing.TracingConfig{Exporter: "zipkin"}); err == nil {
+		t.Error("Expected error for unknown exporter")
+	}
+}
+
+func TestAmqpHeadersRoundTrip(t *testing.T) {
+	shutdown, err := tracing.Init(context.Background(), "test", tracing.TracingConfigJson{}.MapToDomain())
+	if err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+	defer shutdown(context.Background())
+
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), spanContext)
+
+	headers := tracing.InjectAmqpHeaders(ctx, nil)
+	if headers["traceparent"] != testTraceparent {
+		t.Fatalf("Expected traceparent header '%s', got '%v'", testTraceparent, headers["traceparent"])
+	}
+
+	extracted := trace.SpanContextFromContext(tracing.ExtractAmqpHeaders(context.Background(), headers))
+	if extracted.TraceID() != spanContext.TraceID() {
+		t.Errorf("Expected trace id %s, got %s", spanContext.TraceID(), extracted.TraceID())
+	}
+	if extracted.SpanID() != spanContext.SpanID() {
+		t.Errorf("Expected span id %s, got %s", spanContext.SpanID(), extracted.SpanID())
+	}
+}
+
+func TestGinMiddlewareContinuesIncomingTrace(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	shutdown, _ := tracing.Init(context.Background(), "test", tracing.TracingConfigJson{}.MapToDomain())
+	defer shutdown(context.Background())
+
+	var traceId string
+	router := gin.New()
+	router.Use(tracing.GinMiddleware())
+	router.GET("/ping", func(c *gin.Context) {
+		traceId = trace.SpanContextFromContext(c.Request.Context()).TraceID().String()
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set("traceparent", testTraceparent)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if traceId != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected handler context to carry incoming trace id, got '%s'", traceId)
+	}
+}
+
+func TestLoggerWithContextAddsTraceFields(t *testing.T) {
+	shutdown, _ := tracing.Init(context.Background(), "test", tracing.TracingConfigJson{}.MapToDomain())
+	defer shutdown(context.Background())
+
+	ctx := tracing.ExtractAmqpHeaders(context.Background(), map[string]interface{}{"traceparent": testTraceparent})
+
+	var buf bytes.Buffer
+	logger.New().WithOutput(&buf).WithContext(ctx).Info("traced")
+
+	if !strings.Contains(buf.String(), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`) {
+		t.Errorf("Expected trace_id field in log output, got %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `"span_id":"00f067aa0ba902b7"`) {
+		t.Errorf("Expected span_id field in log output, got %s", buf.String())
+	}
+}
@@ -0,0 +1,266 @@
+package test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"pkg-common/logger"
+	"pkg-common/rabbitmq"
+	"pkg-common/utilities"
+	"strings"
+	"testing"
+)
+
+type layeredConfigJson struct {
+	Database struct {
+		ConnectionString string `json:"connection_string"`
+	} `json:"database"`
+	Rest struct {
+		Port uint16 `json:"port"`
+	} `json:"rest"`
+	Publishers []struct {
+		Exchange string `json:"exchange"`
+	} `json:"publishers"`
+	LogLevel int8 `json:"log_level"`
+}
+
+type layeredConfig struct {
+	ConnectionString string
+	Port             uint16
+	Exchanges        []string
+	LogLevel         int8
+}
+
+func (lcj layeredConfigJson) EnvAliases() map[string]string {
+	return map[string]string{"TEST_LEGACY_DB": "database.connection_string"}
+}
+
+func (lcj layeredConfigJson) MapToDomain() layeredConfig {
+	config := layeredConfig{
+		ConnectionString: lcj.Database.ConnectionString,
+		Port:             lcj.Rest.Port,
+		LogLevel:         lcj.LogLevel,
+	}
+	for _, p := range lcj.Publishers {
+		config.Exchanges = append(config.Exchanges, p.Exchange)
+	}
+	return config
+}
+
+func (lc layeredConfig) Validate() error {
+	var cv utilities.ConfigValidation
+	cv.Require("database.connection_string", lc.ConnectionString)
+	cv.Require("rest.port", lc.Port)
+	return cv.Err()
+}
+
+const layeredBase = `{
+    "database": {"connection_string": "host=base"},
+    "rest": {"port": 8080},
+    "publishers": [{"exchange": "verifiers"}, {"exchange": "log_audit"}],
+    "log_level": -1
+}`
+
+func writeLayers(t *testing.T, layers map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range layers {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("Could not write %s: %v", name, err)
+		}
+	}
+	return filepath.Join(dir, "config.json")
+}
+
+func loadLayers(base, environment string) (layeredConfig, error) {
+	return utilities.LoadLayeredConfig[layeredConfigJson, layeredConfig](utilities.ConfigSources{
+		BaseFile:    base,
+		Environment: environment,
+		EnvPrefix:   "TESTSVC",
+	})
+}
+
+func TestEnvPrefixAndOverlayFile(t *testing.T) {
+	if got := utilities.EnvPrefix("blockchain-client"); got != "BLOCKCHAIN_CLIENT" {
+		t.Errorf("Expected BLOCKCHAIN_CLIENT, got %s", got)
+	}
+	if got := utilities.OverlayFile("/app/config.json", "prod"); got != "/app/config.prod.json" {
+		t.Errorf("Expected /app/config.prod.json, got %s", got)
+	}
+}
+
+func TestLoadLayeredConfigEnvironmentOverlay(t *testing.T) {
+	base := writeLayers(t, map[string]string{
+		"config.json":      layeredBase,
+		"config.prod.json": `{"log_level": 1, "rest": {"port": 9000}}`,
+	})
+
+	config, err := loadLayers(base, "prod")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.LogLevel != 1 || config.Port != 9000 {
+		t.Errorf("Expected the overlay to win, got %+v", config)
+	}
+	if config.ConnectionString != "host=base" || len(config.Exchanges) != 2 {
+		t.Errorf("Expected keys missing from the overlay to be kept, got %+v", config)
+	}
+
+	// An environment without an overlay runs on the base file alone
+	config, err = loadLayers(base, "dev")
+	if err != nil || config.Port != 8080 {
+		t.Errorf("Expected the base config, got %+v, %v", config, err)
+	}
+}
+
+func TestLoadLayeredConfigEnvOverrides(t *testing.T) {
+	base := writeLayers(t, map[string]string{"config.json": layeredBase})
+	t.Setenv("TESTSVC__REST__PORT", "9100")
+	t.Setenv("TESTSVC__PUBLISHERS__1__EXCHANGE", "audit")
+	t.Setenv("TESTSVC__DATABASE__CONNECTION_STRING", "42")
+
+	config, err := loadLayers(base, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Port != 9100 {
+		t.Errorf("Expected port from the environment, got %d", config.Port)
+	}
+	if config.Exchanges[0] != "verifiers" || config.Exchanges[1] != "audit" {
+		t.Errorf("Expected the second publisher to be overridden, got %v", config.Exchanges)
+	}
+	// A string key keeps the raw value even when it looks like JSON
+	if config.ConnectionString != "42" {
+		t.Errorf("Expected the connection string as given, got %q", config.ConnectionString)
+	}
+}
+
+func TestLoadLayeredConfigAliasesAndSecretFiles(t *testing.T) {
+	base := writeLayers(t, map[string]string{
+		"config.json": layeredBase,
+		"db_secret":   "host=secret\n",
+	})
+
+	t.Setenv("TEST_LEGACY_DB", "host=legacy")
+	config, err := loadLayers(base, "")
+	if err != nil || config.ConnectionString != "host=legacy" {
+		t.Errorf("Expected the legacy variable to apply, got %+v, %v", config, err)
+	}
+
+	// The prefixed variable wins over the alias
+	t.Setenv("TESTSVC__DATABASE__CONNECTION_STRING__FILE", filepath.Join(filepath.Dir(base), "db_secret"))
+	config, err = loadLayers(base, "")
+	if err != nil || config.ConnectionString != "host=secret" {
+		t.Errorf("Expected the secret file to apply, got %+v, %v", config, err)
+	}
+}
+
+func TestLoadLayeredConfigReportsAllProblems(t *testing.T) {
+	base := writeLayers(t, map[string]string{
+		"config.json":      layeredBase,
+		"config.prod.json": `{"database": {"connection_string": ""}, "rest": {"port": 0}}`,
+	})
+	t.Setenv("TESTSVC__LOG_LEVEL", `"verbose"`)
+	t.Setenv("TESTSVC__PUBLISHERS__5__EXCHANGE", "audit")
+	t.Setenv("TESTSVC__API_KEY__FILE", filepath.Join(filepath.Dir(base), "missing"))
+
+	_, err := loadLayers(base, "prod")
+	if !errors.Is(err, utilities.ErrConfigMissing) || !errors.Is(err, utilities.ErrConfigInvalid) || !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected missing, invalid and unreadable keys, got %v", err)
+	}
+	for _, key := range []string{"database.connection_string", "rest.port", "log_level", "TESTSVC__PUBLISHERS__5__EXCHANGE", "TESTSVC__API_KEY__FILE"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("Expected %s to be reported, got %v", key, err)
+		}
+	}
+}
+
+func TestLoadLayeredConfigReportsEveryWrongType(t *testing.T) {
+	base := writeLayers(t, map[string]string{
+		"config.json":     layeredBase,
+		"config.dev.json": `{"publishers": [{"exchange": "verifiers"}, {"exchange": 5}]}`,
+	})
+	t.Setenv("TESTSVC__REST__PORT", "abc")
+	t.Setenv("TESTSVC__LOG_LEVEL", `"verbose"`)
+
+	_, err := loadLayers(base, "dev")
+	if !errors.Is(err, utilities.ErrConfigInvalid) {
+		t.Fatalf("Expected invalid keys, got %v", err)
+	}
+	for _, key := range []string{"rest.port", "log_level", "publishers.1.exchange"} {
+		if !strings.Contains(err.Error(), key+": "+utilities.ErrConfigInvalid.Error()) {
+			t.Errorf("Expected %s to be reported, got %v", key, err)
+		}
+	}
+	if errors.Is(err, utilities.ErrConfigMissing) {
+		t.Errorf("Expected rest.port not to be reported as missing too, got %v", err)
+	}
+}
+
+func TestRabbitmqConfigValidate(t *testing.T) {
+	valid := rabbitmq.RabbimqConfigJson{User: "api", Password: "secret"}.MapToDomain()
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := (rabbitmq.RabbimqConfigJson{Driver: rabbitmq.DriverMemory}).MapToDomain().Validate(); err != nil {
+		t.Errorf("Expected the memory broker to need no credentials, got %v", err)
+	}
+
+	invalid := rabbitmq.RabbimqConfigJson{
+		Driver:           "kafka",
+		PublishersConfig: []rabbitmq.RabbitmqPublishersConfigJson{{PublisherAlias: "Logs"}},
+		ConsumersConfig:  []rabbitmq.RabbitmqConsumerConfigJson{{ConsumerAlias: "Results"}},
+	}.MapToDomain()
+	err := invalid.Validate()
+	for _, key := range []string{"rabbitmq.driver", "rabbitmq.publishers.0.exchange", "rabbitmq.consumers.0.queue_name"} {
+		if err == nil || !strings.Contains(err.Error(), key) {
+			t.Errorf("Expected %s to be reported, got %v", key, err)
+		}
+	}
+}
+
+func TestLoggerConfigValidate(t *testing.T) {
+	if err := (logger.LoggerConfigJson{LogLevel: -1}).MapToDomain().Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	err := logger.LoggerConfigJson{LogLevel: 42, Sink: logger.LogSinkConfigJson{DropPolicy: "drop_all"}}.MapToDomain().Validate()
+	if err == nil || !strings.Contains(err.Error(), "logger.log_level") || !strings.Contains(err.Error(), "logger.sink.drop_policy") {
+		t.Errorf("Expected level and drop policy to be reported, got %v", err)
+	}
+}
+
+type serviceConfigJson struct {
+	Logger   logger.LoggerConfigJson    `json:"logger"`
+	Rabbitmq rabbitmq.RabbimqConfigJson `json:"rabbitmq"`
+}
+
+type serviceConfig struct {
+	Logger   logger.LoggerConfig
+	Rabbitmq rabbitmq.RabbitmqConfig
+}
+
+func (scj serviceConfigJson) MapToDomain() serviceConfig {
+	return serviceConfig{Logger: scj.Logger.MapToDomain(), Rabbitmq: scj.Rabbitmq.MapToDomain()}
+}
+
+// The overlays shipped with the services must merge with their base file in
+// every environment.
+func TestServiceConfigOverlays(t *testing.T) {
+	for _, base := range []string{"../../api/config.json", "../../blockchain-client/config.json"} {
+		for _, env := range utilities.Environments {
+			t.Run(base+"/"+env, func(t *testing.T) {
+				config, err := utilities.LoadLayeredConfig[serviceConfigJson, serviceConfig](utilities.ConfigSources{BaseFile: base, Environment: env})
+				if err != nil {
+					t.Fatalf("Could not load config: %v", err)
+				}
+				if err := config.Logger.Validate(); err != nil {
+					t.Errorf("Invalid logger config: %v", err)
+				}
+				if err := config.Rabbitmq.Topology.ValidatePublishers(config.Rabbitmq.PublishersConfig); err != nil {
+					t.Errorf("Publishers do not match the topology: %v", err)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package tracing
+
+import (
+	"context"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+)
+
+// AmqpHeadersCarrier adapts AMQP message headers to propagation.TextMapCarrier.
+type AmqpHeadersCarrier amqp.Table
+
+func (ahc AmqpHeadersCarrier) Get(key string) string {
+	value, ok := ahc[key].(string)
+	if !ok {
+		return ""
+	}
+	return value
+}
+
+func (ahc AmqpHeadersCarrier) Set(key, value string) {
+	ahc[key] = value
+}
+
+func (ahc AmqpHeadersCarrier) Keys() []string {
+	keys := make([]string, 0, len(ahc))
+	for k := range ahc {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// InjectAmqpHeaders writes the trace context of ctx into headers, allocating
+// them when nil, and returns the result for use in amqp.Publishing.
+func InjectAmqpHeaders(ctx context.Context, headers amqp.Table) amqp.Table {
+	if headers == nil {
+		headers = amqp.Table{}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, AmqpHeadersCarrier(headers))
+	return headers
+}
+
+// ExtractAmqpHeaders returns ctx enriched with the trace context carried by a
+// delivery's headers.
+func ExtractAmqpHeaders(ctx context.Context, headers amqp.Table) context.Context {
+	if headers == nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, AmqpHeadersCarrier(headers))
+}
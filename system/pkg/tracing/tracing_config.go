@@ -0,0 +1,39 @@
+package tracing
+
+const (
+	ExporterNone = "none"
+	ExporterOtlp = "otlp"
+)
+
+type TracingConfigJson struct {
+	Exporter    string  `json:"exporter"`
+	Endpoint    string  `json:"endpoint"`
+	Insecure    bool    `json:"insecure"`
+	SampleRatio float64 `json:"sample_ratio"`
+}
+
+type TracingConfig struct {
+	Exporter    string
+	Endpoint    string
+	Insecure    bool
+	SampleRatio float64
+}
+
+func (tcj TracingConfigJson) MapToDomain() TracingConfig {
+	exporter := tcj.Exporter
+	if exporter == "" {
+		exporter = ExporterNone
+	}
+
+	sampleRatio := tcj.SampleRatio
+	if sampleRatio <= 0 || sampleRatio > 1 {
+		sampleRatio = 1
+	}
+
+	return TracingConfig{
+		Exporter:    exporter,
+		Endpoint:    tcj.Endpoint,
+		Insecure:    tcj.Insecure,
+		SampleRatio: sampleRatio,
+	}
+}
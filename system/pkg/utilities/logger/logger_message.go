@@ -1,16 +1,37 @@
 package logger_message
 
 import (
+	"fmt"
 	"pkg-common/utilities"
 	"pkg-common/utilities/timeutil"
 )
 
+const LoggerMessageType = "log.entry"
+
 type LoggerMessage struct {
 	Level     string           `json:"level"`
 	Message   string           `json:"message"`
 	Timestamp timeutil.TimeUTC `json:"timestamp"`
+	Service   string           `json:"service,omitempty"`
+	Fields    map[string]any   `json:"fields,omitempty"`
 }
 
 func (lm LoggerMessage) Serialize() ([]byte, error) {
 	return utilities.Serialize[LoggerMessage](lm)
 }
+
+func (lm LoggerMessage) MessageType() string {
+	return LoggerMessageType
+}
+
+// Field returns the value of a field as text, or "" when it is not set.
+func (lm LoggerMessage) Field(key string) string {
+	value, ok := lm.Fields[key]
+	if !ok || value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(value)
+}
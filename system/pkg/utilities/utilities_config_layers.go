@@ -0,0 +1,383 @@
+package utilities
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// EnvTypeKey selects the environment whose overlay is applied on top of the
+// base config file.
+const EnvTypeKey = "ENV_TYPE"
+
+const (
+	EnvDev  = "dev"
+	EnvTest = "test"
+	EnvProd = "prod"
+)
+
+var Environments = []string{EnvDev, EnvTest, EnvProd}
+
+var (
+	ErrConfigMissing = errors.New("is required")
+	ErrConfigInvalid = errors.New("is invalid")
+)
+
+// ConfigSources are the layers of a service configuration, each overriding
+// the previous one:
+//
+//  1. BaseFile, e.g. config.json
+//  2. the overlay of the environment next to it, e.g. config.prod.json, if present
+//  3. variables named after a key path below EnvPrefix, segments separated by
+//     a double underscore: API__DATABASE__CONNECTION_STRING sets
+//     database.connection_string and API__RABBITMQ__PUBLISHERS__0__EXCHANGE
+//     the exchange of the first publisher
+//  4. the same variables with a __FILE suffix, naming a file holding the
+//     value, for secrets mounted by the orchestrator
+//
+// Values are taken as JSON when the key holds anything but a string, so
+// API__REST__PORT=9000 stays a number.
+type ConfigSources struct {
+	BaseFile    string
+	Environment string
+	EnvPrefix   string
+}
+
+// ConfigAliases is implemented by config objects that still accept variables
+// outside of the naming scheme, mapping each to its key path. The prefixed
+// variable wins when both are set.
+type ConfigAliases interface {
+	EnvAliases() map[string]string
+}
+
+// ConfigValidator is implemented by domain configs that check themselves once
+// loaded.
+type ConfigValidator interface {
+	Validate() error
+}
+
+// EnvPrefix turns a service name into the prefix of its variables:
+// blockchain-client becomes BLOCKCHAIN_CLIENT.
+func EnvPrefix(service string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(service))
+}
+
+// OverlayFile is the per-environment file next to base: config.json becomes
+// config.prod.json.
+func OverlayFile(base, environment string) string {
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "." + environment + ext
+}
+
+// LoadLayeredConfig merges the layers of sources and maps the result to the domain.
+// Problems are reported all at once: a value of the wrong type does not stop
+// the remaining keys from being read and validated.
+func LoadLayeredConfig[T JsonConfigObj[U], U any](sources ConfigSources) (U, error) {
+	var empty U
+
+	merged, err := readConfigLayer(sources.BaseFile)
+	if err != nil {
+		return empty, err
+	}
+	if sources.Environment != "" {
+		overlay, err := readConfigLayer(OverlayFile(sources.BaseFile, sources.Environment))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return empty, err
+		}
+		mergeConfig(merged, overlay)
+	}
+
+	var problems []error
+	var config T
+	if aliased, ok := any(config).(ConfigAliases); ok {
+		aliases := aliased.EnvAliases()
+		for _, name := range slices.Sorted(maps.Keys(aliases)) {
+			if value, ok := os.LookupEnv(name); ok && value != "" {
+				problems = appendProblem(problems, name, setConfigValue(merged, strings.Split(aliases[name], "."), value))
+			}
+		}
+	}
+	if sources.EnvPrefix != "" {
+		problems = append(problems, applyEnvOverrides(merged, sources.EnvPrefix)...)
+	}
+
+	invalid, err := decodeConfig(merged, &config)
+	if err != nil {
+		problems = append(problems, err)
+	}
+
+	// A key dropped for its type is already reported, not also as missing
+	domain := config.MapToDomain()
+	if validator, ok := any(domain).(ConfigValidator); ok {
+		problems = append(problems, withoutMissing(validator.Validate(), invalid)...)
+	}
+
+	if len(problems) > 0 {
+		return empty, fmt.Errorf("invalid configuration: %w", errors.Join(problems...))
+	}
+	return domain, nil
+}
+
+func readConfigLayer(file string) (map[string]any, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	layer := map[string]any{}
+	if err := json.Unmarshal(content, &layer); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return layer, nil
+}
+
+// mergeConfig merges objects key by key; any other value of overlay, arrays
+// included, replaces the one in base.
+func mergeConfig(base, overlay map[string]any) {
+	for key, value := range overlay {
+		nested, isObject := value.(map[string]any)
+		existing, hasObject := base[key].(map[string]any)
+		if isObject && hasObject {
+			mergeConfig(existing, nested)
+			continue
+		}
+		base[key] = value
+	}
+}
+
+func applyEnvOverrides(config map[string]any, prefix string) []error {
+	var problems []error
+	environ := os.Environ()
+	slices.Sort(environ)
+
+	for _, variable := range environ {
+		name, value, _ := strings.Cut(variable, "=")
+		rest, ok := strings.CutPrefix(name, prefix+"__")
+		if !ok || rest == "" {
+			continue
+		}
+
+		path := strings.Split(strings.ToLower(rest), "__")
+		if path[len(path)-1] == "file" {
+			path = path[:len(path)-1]
+			content, err := os.ReadFile(value)
+			if err != nil {
+				problems = appendProblem(problems, name, err)
+				continue
+			}
+			value = strings.TrimRight(string(content), "\r\n")
+		}
+		problems = appendProblem(problems, name, setConfigValue(config, path, value))
+	}
+	return problems
+}
+
+func appendProblem(problems []error, variable string, err error) []error {
+	if err == nil {
+		return problems
+	}
+	return append(problems, fmt.Errorf("%s: %w", variable, err))
+}
+
+func setConfigValue(node any, path []string, value string) error {
+	if len(path) == 0 || path[0] == "" {
+		return fmt.Errorf("empty key %w", ErrConfigInvalid)
+	}
+	key, last := path[0], len(path) == 1
+
+	switch container := node.(type) {
+	case map[string]any:
+		if last {
+			container[key] = parseConfigValue(container[key], value)
+			return nil
+		}
+		child, ok := container[key]
+		if !ok || child == nil {
+			child = map[string]any{}
+			container[key] = child
+		}
+		return setConfigValue(child, path[1:], value)
+	case []any:
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index >= len(container) {
+			return fmt.Errorf("no element %s: %w", key, ErrConfigInvalid)
+		}
+		if last {
+			container[index] = parseConfigValue(container[index], value)
+			return nil
+		}
+		return setConfigValue(container[index], path[1:], value)
+	default:
+		return fmt.Errorf("%s is not an object: %w", key, ErrConfigInvalid)
+	}
+}
+
+func parseConfigValue(current any, value string) any {
+	if _, isString := current.(string); isString {
+		return value
+	}
+	var parsed any
+	if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+		return value
+	}
+	return parsed
+}
+
+var jsonUnmarshaler = reflect.TypeFor[json.Unmarshaler]()
+
+// decodeConfig decodes merged into config. encoding/json reports only the
+// first value of a wrong type, so every value is checked against its field
+// first: each wrong one is reported and dropped, leaving the field zero, and
+// its key returned.
+func decodeConfig(merged map[string]any, config any) (map[string]bool, error) {
+	invalid := map[string]bool{}
+	var problems []error
+	checkConfigTypes(merged, reflect.TypeOf(config).Elem(), "", invalid, &problems)
+
+	raw, err := json.Marshal(merged)
+	if err == nil {
+		err = json.Unmarshal(raw, config)
+	}
+	if err != nil {
+		problems = append(problems, err)
+	}
+	return invalid, errors.Join(problems...)
+}
+
+// checkConfigTypes walks node along the fields of t and reports whether node
+// decodes into t, recording the keys that do not.
+func checkConfigTypes(node any, t reflect.Type, key string, invalid map[string]bool, problems *[]error) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if node == nil || reflect.PointerTo(t).Implements(jsonUnmarshaler) {
+		return decodesAs(node, t, key, invalid, problems)
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := node.(map[string]any)
+		if !ok {
+			break
+		}
+		for i := range t.NumField() {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if value, ok := object[name]; ok && !checkConfigTypes(value, field.Type, joinConfigKey(key, name), invalid, problems) {
+				delete(object, name)
+			}
+		}
+		return true
+	case reflect.Slice, reflect.Array:
+		elements, ok := node.([]any)
+		if !ok {
+			break
+		}
+		for i, element := range elements {
+			if !checkConfigTypes(element, t.Elem(), joinConfigKey(key, strconv.Itoa(i)), invalid, problems) {
+				elements[i] = nil
+			}
+		}
+		return true
+	}
+	return decodesAs(node, t, key, invalid, problems)
+}
+
+func decodesAs(node any, t reflect.Type, key string, invalid map[string]bool, problems *[]error) bool {
+	raw, err := json.Marshal(node)
+	if err == nil {
+		err = json.Unmarshal(raw, reflect.New(t).Interface())
+	}
+	if err == nil {
+		return true
+	}
+
+	invalid[key] = true
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		err = fmt.Errorf("expected %s, got %s", typeErr.Type, typeErr.Value)
+	}
+	*problems = append(*problems, fmt.Errorf("%s: %w: %v", key, ErrConfigInvalid, err))
+	return false
+}
+
+func joinConfigKey(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// withoutMissing flattens the problems of err, leaving out the missing keys
+// listed in skip.
+func withoutMissing(err error, skip map[string]bool) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var kept []error
+		for _, e := range joined.Unwrap() {
+			kept = append(kept, withoutMissing(e, skip)...)
+		}
+		return kept
+	}
+	var problem *configProblem
+	if errors.As(err, &problem) && skip[problem.key] && errors.Is(problem.err, ErrConfigMissing) {
+		return nil
+	}
+	return []error{err}
+}
+
+// configProblem is a problem ConfigValidation found with one key.
+type configProblem struct {
+	key    string
+	err    error
+	reason string
+}
+
+func (p *configProblem) Error() string {
+	if p.reason == "" {
+		return fmt.Sprintf("%s %v", p.key, p.err)
+	}
+	return fmt.Sprintf("%s %v: %s", p.key, p.err, p.reason)
+}
+
+func (p *configProblem) Unwrap() error {
+	return p.err
+}
+
+// ConfigValidation collects the problems of a config so they can be reported
+// together.
+type ConfigValidation struct {
+	problems []error
+}
+
+// Require reports key as missing when value is the zero value of its type.
+func (cv *ConfigValidation) Require(key string, value any) {
+	if value == nil || reflect.ValueOf(value).IsZero() {
+		cv.problems = append(cv.problems, &configProblem{key: key, err: ErrConfigMissing})
+	}
+}
+
+// Check reports key as invalid, for the given reason, unless valid holds.
+func (cv *ConfigValidation) Check(key string, valid bool, reason string) {
+	if !valid {
+		cv.problems = append(cv.problems, &configProblem{key: key, err: ErrConfigInvalid, reason: reason})
+	}
+}
+
+func (cv *ConfigValidation) Err() error {
+	return errors.Join(cv.problems...)
+}
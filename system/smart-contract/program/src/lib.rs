@@ -1,12 +1,24 @@
 use borsh::{BorshDeserialize, BorshSerialize};
 use solana_program::{
     account_info::{next_account_info, AccountInfo},
+    bpf_loader_upgradeable,
     entrypoint,
     msg,
     program_error::ProgramError,
-    pubkey::Pubkey,
+    pubkey::{Pubkey, PUBKEY_BYTES},
 };
 
+/// Instruction data of the close instruction. It is shorter than any borsh
+/// encoded ZkpResult (three u32 length prefixes), so it can never be mistaken
+/// for a store instruction.
+pub const CLOSE_INSTRUCTION: &[u8; 8] = b"ZKPCLOSE";
+
+/// Offset of the upgrade authority in the ProgramData account of an upgradeable
+/// program: a u32 state tag (3 for ProgramData), the u64 deployment slot and
+/// the tag of the Option<Pubkey> holding the authority.
+const PROGRAM_DATA_TAG: [u8; 4] = [3, 0, 0, 0];
+const UPGRADE_AUTHORITY_OFFSET: usize = 4 + 8 + 1;
+
 #[derive(BorshSerialize, BorshDeserialize, Debug)]
 pub struct ZkpResult {
     pub proof: Vec<u8>,
@@ -18,12 +30,35 @@ pub struct ZkpResult {
 entrypoint!(process_instruction);
 
 pub fn process_instruction(
-    _program_id: &Pubkey,
+    program_id: &Pubkey,
     accounts: &[AccountInfo],
     instruction_data: &[u8],
 ) -> entrypoint::ProgramResult {
+    if instruction_data == CLOSE_INSTRUCTION {
+        return process_close(program_id, accounts);
+    }
+    process_store(program_id, accounts, instruction_data)
+}
+
+/// Stores the ZKP result at the start of the account and the payer, which is
+/// the only key allowed to close the account later, in its last 32 bytes.
+/// A proof is written once: the account must be owned by the program and
+/// still be empty, so nobody can replace a proof or its close authority.
+fn process_store(program_id: &Pubkey, accounts: &[AccountInfo], instruction_data: &[u8]) -> entrypoint::ProgramResult {
     let accounts_iter = &mut accounts.iter();
     let account = next_account_info(accounts_iter)?;
+    let payer = next_account_info(accounts_iter)?;
+
+    if account.owner != program_id {
+        return Err(ProgramError::IncorrectProgramId);
+    }
+    if !payer.is_signer {
+        return Err(ProgramError::MissingRequiredSignature);
+    }
+    if account.data.borrow().iter().any(|b| *b != 0) {
+        msg!("Account already holds a proof");
+        return Err(ProgramError::AccountAlreadyInitialized);
+    }
 
     msg!("Instruction data length: {}", instruction_data.len());
     msg!("Account data length: {}", account.data.borrow().len());
@@ -40,16 +75,100 @@ pub fn process_instruction(
     // Serialize to account data
     let mut account_data = account.data.borrow_mut();
 
-    // Check if account has enough space
+    // Check if account has enough space for the result and the close authority
     let serialized_size = borsh::to_vec(&zkp_result)?.len();
-    if account_data.len() < serialized_size {
-        msg!("Account data size: {}, required size: {}", account_data.len(), serialized_size);
+    if account_data.len() < serialized_size + PUBKEY_BYTES {
+        msg!("Account data size: {}, required size: {}", account_data.len(), serialized_size + PUBKEY_BYTES);
         return Err(ProgramError::AccountDataTooSmall);
     }
     
     zkp_result.serialize(&mut *account_data)
         .map_err(|_| ProgramError::AccountDataTooSmall)?;
+
+    let authority_offset = account_data.len() - PUBKEY_BYTES;
+    account_data[authority_offset..].copy_from_slice(payer.key.as_ref());
     
     msg!("Successfully stored ZKP result in account");
     Ok(())
 }
+
+/// Erases a proof account: wipes its data and moves its lamports to the
+/// destination, which lets the runtime garbage-collect it. Accounts are:
+/// [proof account (writable), close authority (signer), destination (writable),
+/// program data (optional)].
+///
+/// Accounts stored before close authorities were recorded hold zeroes in the
+/// authority slot; those may be closed by the upgrade authority of the
+/// program, read from the program data account.
+fn process_close(program_id: &Pubkey, accounts: &[AccountInfo]) -> entrypoint::ProgramResult {
+    let accounts_iter = &mut accounts.iter();
+    let account = next_account_info(accounts_iter)?;
+    let authority = next_account_info(accounts_iter)?;
+    let destination = next_account_info(accounts_iter)?;
+    let program_data = next_account_info(accounts_iter).ok();
+
+    if account.owner != program_id {
+        return Err(ProgramError::IncorrectProgramId);
+    }
+    if !authority.is_signer {
+        return Err(ProgramError::MissingRequiredSignature);
+    }
+
+    {
+        let account_data = account.data.borrow();
+        if account_data.len() < PUBKEY_BYTES {
+            return Err(ProgramError::InvalidAccountData);
+        }
+        let stored_authority = &account_data[account_data.len() - PUBKEY_BYTES..];
+        let allowed = if stored_authority.iter().all(|b| *b == 0) {
+            is_upgrade_authority(program_id, program_data, authority.key)?
+        } else {
+            stored_authority == authority.key.as_ref()
+        };
+        if !allowed {
+            msg!("Signer is not the close authority of this account");
+            return Err(ProgramError::InvalidAccountData);
+        }
+    }
+
+    account.data.borrow_mut().fill(0);
+
+    let lamports = account.lamports();
+    let destination_lamports = destination
+        .lamports()
+        .checked_add(lamports)
+        .ok_or(ProgramError::ArithmeticOverflow)?;
+    **destination.try_borrow_mut_lamports()? = destination_lamports;
+    **account.try_borrow_mut_lamports()? = 0;
+
+    msg!("Closed ZKP account, reclaimed {} lamports", lamports);
+    Ok(())
+}
+
+/// Reports whether key is the upgrade authority recorded in the program data
+/// account of program_id. Without that account, or for an immutable program,
+/// nobody is.
+fn is_upgrade_authority(
+    program_id: &Pubkey,
+    program_data: Option<&AccountInfo>,
+    key: &Pubkey,
+) -> Result<bool, ProgramError> {
+    let program_data = match program_data {
+        Some(program_data) => program_data,
+        None => return Ok(false),
+    };
+    if *program_data.key != bpf_loader_upgradeable::get_program_data_address(program_id)
+        || *program_data.owner != bpf_loader_upgradeable::id()
+    {
+        return Err(ProgramError::InvalidArgument);
+    }
+
+    let data = program_data.data.borrow();
+    if data.len() < UPGRADE_AUTHORITY_OFFSET + PUBKEY_BYTES || data[..4] != PROGRAM_DATA_TAG {
+        return Err(ProgramError::InvalidAccountData);
+    }
+    if data[UPGRADE_AUTHORITY_OFFSET - 1] != 1 {
+        return Ok(false);
+    }
+    Ok(&data[UPGRADE_AUTHORITY_OFFSET..UPGRADE_AUTHORITY_OFFSET + PUBKEY_BYTES] == key.as_ref())
+}
@@ -6,6 +6,9 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"pkg-common/did"
+	httpclient "pkg-common/http_client"
+	"pkg-common/metrics"
 	"strings"
 	"time"
 
@@ -44,6 +47,10 @@ func main() {
 		config.IssuerBaseURL+"/auth/dsnet/callback",
 	)
 	config.ZkpVerifierBaseURL = config.MustEnv("ZKP_VERIFIER_BASE_URL")
+	config.VerifierClient = httpclient.NewClient(httpclient.ConfigJson{
+		BaseUrl:        config.ZkpVerifierBaseURL,
+		TimeoutSeconds: 15,
+	}.MapToDomain())
 
 	// --- OIDC discovery (DSNet jako OP) ---
 	config.OidcProvider, err = oidc.NewProvider(
@@ -84,6 +91,30 @@ func main() {
 	// --- Issuer keypair + JWKS ---
 	keys.GenerateIssuerKey()
 
+	// --- DID resolution ---
+	// Only trusted issuers are resolved, and only their did:web hosts are
+	// fetched. Our own did:web is resolved over plain HTTP on the local
+	// listener, which stands in for https://<public origin> inside the
+	// compose network.
+	config.TrustedIssuerDIDs = []string{config.IssuerDID}
+	for _, issuer := range strings.Split(config.GetenvDefault("TRUSTED_ISSUER_DIDS", ""), ",") {
+		if issuer = strings.TrimSpace(issuer); issuer != "" {
+			config.TrustedIssuerDIDs = append(config.TrustedIssuerDIDs, issuer)
+		}
+	}
+	var trustedHosts []string
+	for _, issuer := range config.TrustedIssuerDIDs {
+		if host, _, err := did.WebDocumentLocation(issuer); err == nil {
+			trustedHosts = append(trustedHosts, host)
+		}
+	}
+	config.DIDResolver = did.NewResolver(
+		did.WithWebBaseUrl(issuerURL.Host, "http://localhost:"+port),
+		did.WithAllowedWebHosts(trustedHosts...),
+	)
+	log.Printf("[CONFIG] Issuer DID:          %s", config.IssuerDID)
+	log.Printf("[CONFIG] Trusted issuers:     %s", strings.Join(config.TrustedIssuerDIDs, ", "))
+
 	// --- VCStore + Handlers ---
 	vcStore := vcstore.NewInMemoryVCStore()
 
@@ -109,6 +140,7 @@ func main() {
 	// --- OIDC4VCI issuer metadata & JWKS ---
 	mux.HandleFunc("/.well-known/openid-credential-issuer", oidcissuer.HandleCredentialIssuerMetadata)
 	mux.HandleFunc("/.well-known/jwks.json", oidcissuer.HandleJWKS)
+	mux.HandleFunc("/.well-known/did.json", oidcissuer.HandleDIDDocument)
 
 	// --- OIDC4VCI protocol endpoints ---
 	mux.HandleFunc("/oidc4vci/offer", handlers.HandleCreateOffer)
@@ -154,8 +186,15 @@ func main() {
 	mux.HandleFunc("/wallet/zkp/fetch-descriptor", handlers.HandleFetchDescriptor)
 	mux.HandleFunc("/wallet/zkp/create", zkpWalletHandler.HandleZkpCreate)
 
+	// --- Prometheus metrics ---
+	mux.Handle("/metrics", metrics.Handler())
+	routeOf := func(r *http.Request) string {
+		_, pattern := mux.Handler(r)
+		return pattern
+	}
+
 	log.Printf("Listening on :%s (public origin: %s)", port, config.IssuerBaseURL)
-	if err := http.ListenAndServe(":"+port, util.WithCORS(mux)); err != nil {
+	if err := http.ListenAndServe(":"+port, util.WithCORS(metrics.HttpMiddleware(routeOf, mux))); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -4,6 +4,8 @@ import (
 	"crypto/ed25519"
 	"log"
 	"os"
+	"pkg-common/did"
+	httpclient "pkg-common/http_client"
 	"sync"
 	"zk-wallet-go/pkg/util/timeutil"
 
@@ -28,6 +30,8 @@ var (
 	DsnetLogout string
 
 	ZkpVerifierBaseURL string
+	// Client for the verifier API at ZkpVerifierBaseURL
+	VerifierClient *httpclient.Client
 
 	// OAuth2 client credentials registered with the OIDC provider
 	OidcClientID     string
@@ -48,6 +52,16 @@ var (
 	IssuerPrivKey ed25519.PrivateKey
 	IssuerKeyID   string
 
+	// Decentralized identifiers: did:web of this issuer (derived from
+	// IssuerBaseURL), its DID document and the resolver used to verify VCs
+	IssuerDID         string
+	IssuerDIDDocument did.Document
+	DIDResolver       did.Resolver
+
+	// Issuers whose DID-signed credentials the wallet accepts: this issuer
+	// and the DIDs listed in TRUSTED_ISSUER_DIDS
+	TrustedIssuerDIDs []string
+
 	// Cookie names used across authentication flow
 	CookieNameSession = "sid"
 	CookieNamePKCE    = "pkce_verifier"
@@ -89,6 +103,9 @@ var (
 
 	WalletVCs = map[string]StoredVC{} // verifiable credentials "owned" by wallets
 	WalletMu  sync.RWMutex
+
+	HolderKeys = map[string]ed25519.PrivateKey{} // holder did:key keys by user sub
+	HolderMu   sync.Mutex
 )
 
 // ---------------------------------------------------------------
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"pkg-common/did"
+	"slices"
+	"strings"
+	"zk-wallet-go/internal/app/config"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jws"
+)
+
+// verifyCredential verifies a compact JWS VC. When the kid is a DID URL it must
+// belong to a trusted issuer, the key is resolved through the DID resolver and
+// the iss claim must name the same DID; credentials issued before DIDs were
+// introduced fall back to the local issuer JWKS.
+func verifyCredential(ctx context.Context, compact string) ([]byte, error) {
+	msg, err := jws.Parse([]byte(compact))
+	if err != nil {
+		return nil, err
+	}
+	if len(msg.Signatures()) == 0 {
+		return nil, fmt.Errorf("credential has no signature")
+	}
+
+	kid := msg.Signatures()[0].ProtectedHeaders().KeyID()
+	if !strings.HasPrefix(kid, "did:") {
+		return jws.Verify([]byte(compact), jws.WithKeySet(config.IssuerJWKSet))
+	}
+
+	// Checked before resolving, so untrusted DIDs are never fetched
+	issuerDID, _ := did.SplitURL(kid)
+	if !slices.Contains(config.TrustedIssuerDIDs, issuerDID) {
+		return nil, fmt.Errorf("issuer %s is not trusted", issuerDID)
+	}
+
+	pub, err := did.ResolveKey(ctx, config.DIDResolver, kid)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", kid, err)
+	}
+	payload, err := jws.Verify([]byte(compact), jws.WithKey(jwa.EdDSA, pub))
+	if err != nil {
+		return nil, err
+	}
+
+	var claims struct {
+		Iss string `json:"iss"`
+	}
+	_ = json.Unmarshal(payload, &claims)
+	if claims.Iss != issuerDID {
+		return payload, fmt.Errorf("iss %q does not match signing DID %q", claims.Iss, issuerDID)
+	}
+	return payload, nil
+}
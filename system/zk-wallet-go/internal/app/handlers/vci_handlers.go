@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 	"zk-wallet-go/internal/app/config"
+	"zk-wallet-go/internal/app/keys"
 	"zk-wallet-go/internal/app/server"
 	"zk-wallet-go/pkg/util"
 	"zk-wallet-go/pkg/util/timeutil"
@@ -194,19 +195,26 @@ func HandleVciCredential(w http.ResponseWriter, r *http.Request) {
 	}
 	birthTS := birthTime.Unix() // <- matches 'birth_ts' integer field in your schema
 
+	// The holder is identified by a did:key bound to the user's session subject.
+	holderDID, err := keys.HolderDID(rec.User.Sub)
+	if err != nil {
+		http.Error(w, "holder key failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	// Compose VC-JWT claims aligned with age_over_18_ts schema (birth_ts as main subject claim).
 	vcClaims := map[string]any{
-		"iss": config.IssuerBaseURL,
-		"sub": rec.User.Sub,
+		"iss": config.IssuerDID,
+		"sub": holderDID,
 		"iat": now.Unix(),
 		"nbf": now.Unix(),
 		"vc": map[string]any{
 			"@context":     []any{"https://www.w3.org/2018/credentials/v1"},
 			"type":         []string{"VerifiableCredential", "AgeOver18Credential"},
-			"issuer":       config.IssuerBaseURL,
+			"issuer":       config.IssuerDID,
 			"issuanceDate": now.Format(time.RFC3339),
 			"credentialSubject": map[string]any{
-				"id":         rec.User.Sub,
+				"id":         holderDID,
 				"birth_ts":   birthTS,
 				"university": "AGH",
 			},
@@ -219,7 +227,7 @@ func HandleVciCredential(w http.ResponseWriter, r *http.Request) {
 	// Prepare protected headers for JWS.
 	hdr := jws.NewHeaders()
 	_ = hdr.Set(jws.AlgorithmKey, jwa.EdDSA)
-	_ = hdr.Set(jws.KeyIDKey, config.IssuerKeyID)
+	_ = hdr.Set(jws.KeyIDKey, keys.IssuerVerificationMethod())
 	_ = hdr.Set("typ", "vc+jwt")
 
 	// Sign payload with issuer's Ed25519 private key.
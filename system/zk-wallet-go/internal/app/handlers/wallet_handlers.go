@@ -7,7 +7,6 @@ import (
 	"strings"
 	"time"
 
-	"zk-wallet-go/internal/app/config"
 	"zk-wallet-go/internal/app/vcstore"
 	"zk-wallet-go/pkg/util"
 
@@ -107,8 +106,8 @@ func (h *WalletHandler) HandleWalletImportOffer(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// (5) Verify signature using our issuer key set (in prod: fetch issuer's JWKS).
-	payload, err := jws.Verify([]byte(credRes.Credential), jws.WithKeySet(config.IssuerJWKSet))
+	// (5) Verify signature, resolving the issuer key from its DID.
+	payload, err := verifyCredential(r.Context(), credRes.Credential)
 	if err != nil {
 		// For MVP we still store even if signature verification fails.
 		// payload może być nil => parsowanie niżej po prostu da zero-values.
@@ -178,9 +177,9 @@ func (h *WalletHandler) HandleWalletShow(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Verify signature using local issuer keys.
+	// Verify signature, resolving the issuer key from its DID.
 	valid := true
-	msg, err := jws.Verify([]byte(vc.Raw), jws.WithKeySet(config.IssuerJWKSet))
+	msg, err := verifyCredential(r.Context(), vc.Raw)
 	if err != nil {
 		valid = false
 		// Best-effort parse without verification.
@@ -216,9 +215,9 @@ func (h *WalletHandler) HandleWalletVerify(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Verify the credential signature using issuer keys.
+	// Verify the credential signature, resolving the issuer key from its DID.
 	valid := true
-	msg, err := jws.Verify([]byte(in.Credential), jws.WithKeySet(config.IssuerJWKSet))
+	msg, err := verifyCredential(r.Context(), in.Credential)
 	if err != nil {
 		valid = false
 		if obj, perr := jws.Parse([]byte(in.Credential)); perr == nil {
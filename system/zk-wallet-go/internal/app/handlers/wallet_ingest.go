@@ -7,11 +7,8 @@ import (
 	"strings"
 	"time"
 
-	"zk-wallet-go/internal/app/config"
 	"zk-wallet-go/internal/app/vcstore"
 	"zk-wallet-go/pkg/util"
-
-	"github.com/lestrrat-go/jwx/v2/jws"
 )
 
 // IngestRequest represents the input for wallet ingestion.
@@ -129,9 +126,9 @@ func (h *WalletHandler) HandleWalletIngest(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Verify signature against local issuerJWKSet (PoC). In production, resolve issuer JWKS.
+	// Verify signature against the key published in the issuer's DID document.
 	valid := true
-	msg, err := jws.Verify([]byte(compact), jws.WithKeySet(config.IssuerJWKSet))
+	msg, err := verifyCredential(r.Context(), compact)
 	if err != nil {
 		valid = false // If verification fails, we still proceed with best-effort decode.
 	}
@@ -2,11 +2,13 @@
 package handlers
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	httpclient "pkg-common/http_client"
 	"strings"
 	"zk-wallet-go/internal/app/config"
 )
@@ -65,30 +67,20 @@ func HandleFetchDescriptor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	base := strings.TrimRight(config.ZkpVerifierBaseURL, "/")
-	descURL := base + "/v1/presentations/" + id + "/descriptor"
-
 	// --- 1) Fetch descriptor JSON ---
-	resp, err := http.Get(descURL)
-	if err != nil {
-		http.Error(w, "descriptor fetch failed: "+err.Error(), http.StatusBadGateway)
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
+	descriptor, err := httpclient.Get[PresentationDescriptor](r.Context(), config.VerifierClient,
+		"/v1/presentations/"+url.PathEscape(id)+"/descriptor")
+	var httpErr *httpclient.HTTPError
+	if errors.As(err, &httpErr) {
 		http.Error(
 			w,
-			fmt.Sprintf("descriptor fetch failed: status %d: %s", resp.StatusCode, string(b)),
+			fmt.Sprintf("descriptor fetch failed: status %d: %s", httpErr.StatusCode, string(httpErr.Body)),
 			http.StatusBadGateway,
 		)
 		return
 	}
-
-	var descriptor PresentationDescriptor
-	if err := json.NewDecoder(resp.Body).Decode(&descriptor); err != nil {
-		http.Error(w, "descriptor json error: "+err.Error(), http.StatusBadRequest)
+	if err != nil {
+		http.Error(w, "descriptor fetch failed: "+err.Error(), http.StatusBadGateway)
 		return
 	}
 
@@ -198,9 +190,6 @@ func HandleVerifyProxy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	base := strings.TrimRight(config.ZkpVerifierBaseURL, "/")
-	target := base + "/v1/presentations/verify"
-
 	// budujemy payload zgodny z VerifyIn
 	payload := map[string]any{
 		"request_id":   id,
@@ -213,31 +202,30 @@ func HandleVerifyProxy(w http.ResponseWriter, r *http.Request) {
 		payload["challenge"] = strings.TrimSpace(in.Challenge)
 	}
 
-	bodyBytes, err := json.Marshal(payload)
-	if err != nil {
-		http.Error(w, "cannot marshal payload: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(bodyBytes))
-	if err != nil {
-		http.Error(w, "cannot create request: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
+	// Verification is not idempotent, so the client sends it exactly once
+	var status int
+	var header http.Header
+	var body []byte
+	resp, err := config.VerifierClient.Send(r.Context(), http.MethodPost, "/v1/presentations/verify", payload)
+	var httpErr *httpclient.HTTPError
+	switch {
+	case errors.As(err, &httpErr):
+		status, header, body = httpErr.StatusCode, httpErr.Header, httpErr.Body
+	case err != nil:
 		http.Error(w, "verify proxy failed: "+err.Error(), http.StatusBadGateway)
 		return
+	default:
+		status, header, body = resp.StatusCode, resp.Header, resp.Body
 	}
-	defer resp.Body.Close()
 
-	for k, vv := range resp.Header {
+	for k, vv := range header {
+		if k == "Content-Length" {
+			continue
+		}
 		for _, v := range vv {
 			w.Header().Add(k, v)
 		}
 	}
-	w.WriteHeader(resp.StatusCode)
-	_, _ = io.Copy(w, resp.Body)
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
 }
@@ -2,13 +2,16 @@
 package handlers
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"path"
+	httpclient "pkg-common/http_client"
 	"strings"
 
 	"zk-wallet-go/internal/app/config"
@@ -75,27 +78,16 @@ func extractCredentialSubject(claims map[string]any) map[string]any {
 
 // fetchDescriptorAndSchema pobiera descriptor + schema JSON + PK dla danego request_id.
 func fetchDescriptorSchemaAndPK(requestID string) (PresentationDescriptor, []byte, []byte, error) {
-	var desc PresentationDescriptor
-
 	base := strings.TrimRight(config.ZkpVerifierBaseURL, "/")
 	descURL := base + "/v1/presentations/" + requestID + "/descriptor"
 
 	log.Printf("[zkp] fetching descriptor for request_id=%s from %s", requestID, descURL)
 
-	resp, err := http.Get(descURL)
+	desc, err := httpclient.Get[PresentationDescriptor](context.Background(), config.VerifierClient,
+		"/v1/presentations/"+url.PathEscape(requestID)+"/descriptor")
 	if err != nil {
 		return desc, nil, nil, fmt.Errorf("descriptor fetch: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		return desc, nil, nil, fmt.Errorf("descriptor fetch: status %d: %s", resp.StatusCode, string(b))
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&desc); err != nil {
-		return desc, nil, nil, fmt.Errorf("descriptor json: %w", err)
-	}
 
 	// normalizacja URL-i
 	desc.Schema.URI = normalizeWeirdURL(desc.Schema.URI)
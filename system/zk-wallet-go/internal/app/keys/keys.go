@@ -4,6 +4,7 @@ import (
 	"crypto/ed25519"
 	"crypto/rand"
 	"log"
+	"pkg-common/did"
 	"zk-wallet-go/internal/app/config"
 
 	"github.com/lestrrat-go/jwx/v2/jwk"
@@ -38,4 +39,36 @@ func GenerateIssuerKey() {
 	// This set will later be exposed via /.well-known/jwks.json
 	config.IssuerJWKSet = jwk.NewSet()
 	config.IssuerJWKSet.AddKey(k)
+
+	// --- 5. Derive the issuer did:web and its DID document ---
+	// The document is served at /.well-known/did.json and lists the same key,
+	// using the JWK kid as the verification method fragment.
+	config.IssuerDID, err = did.WebDID(config.IssuerBaseURL)
+	if err != nil {
+		log.Fatalf("issuer did: %v", err)
+	}
+	config.IssuerDIDDocument = did.NewDocument(config.IssuerDID, config.IssuerKeyID, pub)
+}
+
+// IssuerVerificationMethod returns the DID URL of the issuer signing key, used
+// as the kid of issued credentials.
+func IssuerVerificationMethod() string {
+	return config.IssuerDID + "#" + config.IssuerKeyID
+}
+
+// HolderDID returns the did:key of the wallet holder identified by sub,
+// generating its Ed25519 key on first use.
+func HolderDID(sub string) (string, error) {
+	config.HolderMu.Lock()
+	defer config.HolderMu.Unlock()
+
+	priv, ok := config.HolderKeys[sub]
+	if !ok {
+		var err error
+		if _, priv, err = ed25519.GenerateKey(rand.Reader); err != nil {
+			return "", err
+		}
+		config.HolderKeys[sub] = priv
+	}
+	return did.KeyDID(priv.Public().(ed25519.PublicKey)), nil
 }
@@ -98,3 +98,9 @@ func HandleJWKS(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(config.IssuerJWKSet)
 }
+
+// HandleDIDDocument serves the did:web document of this issuer.
+func HandleDIDDocument(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/did+json")
+	_ = json.NewEncoder(w).Encode(config.IssuerDIDDocument)
+}
@@ -6,6 +6,8 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"math/big"
+	"pkg-common/metrics"
+	"time"
 
 	"github.com/consensys/gnark/backend/groth16"
 	"github.com/consensys/gnark/frontend"
@@ -53,6 +55,7 @@ func ProveDynamicFromSchema(schemaJSON []byte, assignments map[string]interface{
 	}
 
 	// 4) Compile circuit
+	compileStart := time.Now()
 	ccs, err := frontend.Compile(
 		ElipticalCurveID.ScalarField(),
 		r1cs.NewBuilder,
@@ -61,6 +64,7 @@ func ProveDynamicFromSchema(schemaJSON []byte, assignments map[string]interface{
 	if err != nil {
 		return nil, fmt.Errorf("compile circuit: %w", err)
 	}
+	metrics.ObserveCircuitOperation(metrics.CircuitCompile, compileStart)
 
 	// 5) Fill witness
 	witnessCircuit := circuit.Clone()
@@ -89,10 +93,12 @@ func ProveDynamicFromSchema(schemaJSON []byte, assignments map[string]interface{
 	}
 
 	// 7) Generate proof using server PK
+	proveStart := time.Now()
 	proof, err := groth16.Prove(ccs, pk, fullWitness)
 	if err != nil {
 		return nil, fmt.Errorf("groth16 prove: %w", err)
 	}
+	metrics.ObserveCircuitOperation(metrics.CircuitProve, proveStart)
 
 	return &ZkpResult{
 		Proof:         proof,
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"pkg-common/metrics"
 	"sync"
 	"time"
 
@@ -141,7 +142,10 @@ func (s *Service) VerifySubmission(sub ProofSubmission) (PresentationRequest, er
 	if _, err := vk.ReadFrom(bytesReader(vkBytes)); err != nil {
 		return PresentationRequest{}, errors.New("cannot load server VK")
 	}
-	if err := groth16.Verify(res.Proof, vk, res.PublicWitness); err != nil {
+	verifyStart := time.Now()
+	err = groth16.Verify(res.Proof, vk, res.PublicWitness)
+	metrics.ObserveCircuitOperation(metrics.CircuitVerify, verifyStart)
+	if err != nil {
 		return PresentationRequest{}, errors.New("verify failed")
 	}
 